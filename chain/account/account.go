@@ -18,6 +18,7 @@ import (
 var (
 	sha3Nil            = crypto.Keccak256Hash(nil)
 	ErrNegativeBalance = errors.New("balance can't be negative")
+	ErrNegativeVotes   = errors.New("votes can't be negative")
 	ErrLoadCodeFail    = errors.New("can't load contract code")
 	ErrTrieFail        = errors.New("can't load contract storage trie")
 	ErrTrieChanged     = errors.New("the trie has changed after Finalise")
@@ -106,6 +107,7 @@ func (a *Account) String() string {
 // Implement AccountAccessor. Access Account without changelog
 func (a *Account) GetAddress() common.Address { return a.data.Address }
 func (a *Account) GetBalance() *big.Int       { return new(big.Int).Set(a.data.Balance) }
+func (a *Account) GetNonce() uint64           { return a.data.Nonce }
 func (a *Account) GetVersion(logType types.ChangeLogType) uint32 {
 	return a.data.NewestRecords[logType].Version
 }
@@ -124,6 +126,9 @@ func (a *Account) SetBalance(balance *big.Int) {
 	}
 	a.data.Balance.Set(balance)
 }
+func (a *Account) SetNonce(nonce uint64) {
+	a.data.Nonce = nonce
+}
 func (a *Account) SetVersion(logType types.ChangeLogType, version uint32) {
 	a.data.NewestRecords[logType] = types.VersionRecord{Version: version, Height: a.baseHeight + 1}
 }
@@ -136,6 +141,48 @@ func (a *Account) SetSuicide(suicided bool) {
 	a.suicided = suicided
 }
 
+// GetVoteFor returns the candidate which the account votes for. It is empty address if the account has never voted
+func (a *Account) GetVoteFor() common.Address {
+	if a.data.VoteFor == nil {
+		return common.Address{}
+	}
+	return *a.data.VoteFor
+}
+func (a *Account) SetVoteFor(candidate common.Address) {
+	if candidate == (common.Address{}) {
+		a.data.VoteFor = nil
+		return
+	}
+	a.data.VoteFor = &candidate
+}
+
+// GetVotes returns the votes received by the candidate
+func (a *Account) GetVotes() *big.Int {
+	if a.data.Votes == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(a.data.Votes)
+}
+func (a *Account) SetVotes(votes *big.Int) {
+	if votes.Sign() < 0 {
+		log.Errorf("can't set negative votes %v to account %06x", votes, a.data.Address)
+		panic(ErrNegativeVotes)
+	}
+	if votes.Sign() == 0 {
+		a.data.Votes = nil
+		return
+	}
+	a.data.Votes = new(big.Int).Set(votes)
+}
+
+// GetCandidateProfile returns the registration information of candidate. It is nil if the account has never registered
+func (a *Account) GetCandidateProfile() *types.CandidateProfile {
+	return a.data.Candidate.Copy()
+}
+func (a *Account) SetCandidateProfile(profile *types.CandidateProfile) {
+	a.data.Candidate = profile.Copy()
+}
+
 func (a *Account) SetCodeHash(codeHash common.Hash) {
 	a.data.CodeHash = codeHash
 	a.code = nil
@@ -16,6 +16,10 @@ const (
 	CodeLog
 	AddEventLog
 	SuicideLog
+	NonceLog
+	VoteForLog
+	VotesLog
+	CandidateProfileLog
 )
 
 func init() {
@@ -24,6 +28,10 @@ func init() {
 	types.RegisterChangeLog(CodeLog, "CodeLog", decodeCode, decodeEmptyInterface, redoCode, undoCode)
 	types.RegisterChangeLog(AddEventLog, "AddEventLog", decodeEvent, decodeEmptyInterface, redoAddEvent, undoAddEvent)
 	types.RegisterChangeLog(SuicideLog, "SuicideLog", decodeEmptyInterface, decodeEmptyInterface, redoSuicide, undoSuicide)
+	types.RegisterChangeLog(NonceLog, "NonceLog", decodeUint64, decodeEmptyInterface, redoNonce, undoNonce)
+	types.RegisterChangeLog(VoteForLog, "VoteForLog", decodeAddress, decodeEmptyInterface, redoVoteFor, undoVoteFor)
+	types.RegisterChangeLog(VotesLog, "VotesLog", decodeBigInt, decodeEmptyInterface, redoVotes, undoVotes)
+	types.RegisterChangeLog(CandidateProfileLog, "CandidateProfileLog", decodeCandidateProfile, decodeEmptyInterface, redoCandidateProfile, undoCandidateProfile)
 }
 
 // IsValuable returns true if the change log contains some data change
@@ -42,6 +50,14 @@ func IsValuable(log *types.ChangeLog) bool {
 		valuable = log.NewVal != nil && len(log.NewVal.(types.Code)) > 0
 	case AddEventLog:
 		valuable = log.NewVal != nil
+	case VotesLog:
+		oldVal := log.OldVal.(big.Int)
+		newVal := log.NewVal.(big.Int)
+		valuable = oldVal.Cmp(&newVal) != 0
+	case CandidateProfileLog:
+		oldVal := log.OldVal.(*types.CandidateProfile)
+		newVal := log.NewVal.(*types.CandidateProfile)
+		valuable = !oldVal.Equal(newVal)
 	case SuicideLog:
 		oldAccount := log.OldVal.(*types.AccountData)
 		valuable = oldAccount != nil && (oldAccount.Balance != big.NewInt(0) || !isEmptyHash(oldAccount.CodeHash) || !isEmptyHash(oldAccount.StorageRoot))
@@ -74,6 +90,13 @@ func decodeBigInt(s *rlp.Stream) (interface{}, error) {
 	return result, err
 }
 
+// decodeUint64 decode an interface which contains an uint64
+func decodeUint64(s *rlp.Stream) (interface{}, error) {
+	var result uint64
+	err := s.Decode(&result)
+	return result, err
+}
+
 // decodeBytes decode an interface which contains an []byte
 func decodeBytes(s *rlp.Stream) (interface{}, error) {
 	var result []byte
@@ -88,6 +111,20 @@ func decodeCode(s *rlp.Stream) (interface{}, error) {
 	return types.Code(result), err
 }
 
+// decodeAddress decode an interface which contains an common.Address
+func decodeAddress(s *rlp.Stream) (interface{}, error) {
+	var result common.Address
+	err := s.Decode(&result)
+	return result, err
+}
+
+// decodeCandidateProfile decode an interface which contains an *types.CandidateProfile
+func decodeCandidateProfile(s *rlp.Stream) (interface{}, error) {
+	var result types.CandidateProfile
+	err := s.Decode(&result)
+	return &result, err
+}
+
 // decodeEvents decode an interface which contains an *types.Event
 func decodeEvent(s *rlp.Stream) (interface{}, error) {
 	var result types.Event
@@ -272,3 +309,135 @@ func undoSuicide(c *types.ChangeLog, processor types.ChangeLogProcessor) error {
 	accessor.SetSuicide(false)
 	return nil
 }
+
+// NewNonceLog records the increasing of account's transaction sequence number
+func NewNonceLog(account types.AccountAccessor, newNonce uint64) *types.ChangeLog {
+	return &types.ChangeLog{
+		LogType: NonceLog,
+		Address: account.GetAddress(),
+		Version: increaseVersion(NonceLog, account),
+		OldVal:  account.GetNonce(),
+		NewVal:  newNonce,
+	}
+}
+
+func redoNonce(c *types.ChangeLog, processor types.ChangeLogProcessor) error {
+	newValue, ok := c.NewVal.(uint64)
+	if !ok {
+		log.Errorf("expected NewVal uint64, got %T", c.NewVal)
+		return types.ErrWrongChangeLogData
+	}
+	accessor := processor.GetAccount(c.Address)
+	accessor.SetNonce(newValue)
+	return nil
+}
+
+func undoNonce(c *types.ChangeLog, processor types.ChangeLogProcessor) error {
+	oldValue, ok := c.OldVal.(uint64)
+	if !ok {
+		log.Errorf("expected OldVal uint64, got %T", c.OldVal)
+		return types.ErrWrongChangeLogData
+	}
+	accessor := processor.GetAccount(c.Address)
+	accessor.SetNonce(oldValue)
+	return nil
+}
+
+// NewVoteForLog records the changing of the candidate which the account votes for
+func NewVoteForLog(account types.AccountAccessor, candidate common.Address) *types.ChangeLog {
+	return &types.ChangeLog{
+		LogType: VoteForLog,
+		Address: account.GetAddress(),
+		Version: increaseVersion(VoteForLog, account),
+		OldVal:  account.GetVoteFor(),
+		NewVal:  candidate,
+	}
+}
+
+func redoVoteFor(c *types.ChangeLog, processor types.ChangeLogProcessor) error {
+	newValue, ok := c.NewVal.(common.Address)
+	if !ok {
+		log.Errorf("expected NewVal common.Address, got %T", c.NewVal)
+		return types.ErrWrongChangeLogData
+	}
+	accessor := processor.GetAccount(c.Address)
+	accessor.SetVoteFor(newValue)
+	return nil
+}
+
+func undoVoteFor(c *types.ChangeLog, processor types.ChangeLogProcessor) error {
+	oldValue, ok := c.OldVal.(common.Address)
+	if !ok {
+		log.Errorf("expected OldVal common.Address, got %T", c.OldVal)
+		return types.ErrWrongChangeLogData
+	}
+	accessor := processor.GetAccount(c.Address)
+	accessor.SetVoteFor(oldValue)
+	return nil
+}
+
+// NewVotesLog records the changing of candidate's votes
+func NewVotesLog(account types.AccountAccessor, newVotes *big.Int) *types.ChangeLog {
+	return &types.ChangeLog{
+		LogType: VotesLog,
+		Address: account.GetAddress(),
+		Version: increaseVersion(VotesLog, account),
+		OldVal:  *(new(big.Int).Set(account.GetVotes())),
+		NewVal:  *(new(big.Int).Set(newVotes)),
+	}
+}
+
+func redoVotes(c *types.ChangeLog, processor types.ChangeLogProcessor) error {
+	newValue, ok := c.NewVal.(big.Int)
+	if !ok {
+		log.Errorf("expected NewVal big.Int, got %T", c.NewVal)
+		return types.ErrWrongChangeLogData
+	}
+	accessor := processor.GetAccount(c.Address)
+	accessor.SetVotes(&newValue)
+	return nil
+}
+
+func undoVotes(c *types.ChangeLog, processor types.ChangeLogProcessor) error {
+	oldValue, ok := c.OldVal.(big.Int)
+	if !ok {
+		log.Errorf("expected OldVal big.Int, got %T", c.OldVal)
+		return types.ErrWrongChangeLogData
+	}
+	accessor := processor.GetAccount(c.Address)
+	accessor.SetVotes(&oldValue)
+	return nil
+}
+
+// NewCandidateProfileLog records the registration of candidate
+func NewCandidateProfileLog(account types.AccountAccessor, profile *types.CandidateProfile) *types.ChangeLog {
+	return &types.ChangeLog{
+		LogType: CandidateProfileLog,
+		Address: account.GetAddress(),
+		Version: increaseVersion(CandidateProfileLog, account),
+		OldVal:  account.GetCandidateProfile(),
+		NewVal:  profile.Copy(),
+	}
+}
+
+func redoCandidateProfile(c *types.ChangeLog, processor types.ChangeLogProcessor) error {
+	newValue, ok := c.NewVal.(*types.CandidateProfile)
+	if !ok {
+		log.Errorf("expected NewVal *types.CandidateProfile, got %T", c.NewVal)
+		return types.ErrWrongChangeLogData
+	}
+	accessor := processor.GetAccount(c.Address)
+	accessor.SetCandidateProfile(newValue)
+	return nil
+}
+
+func undoCandidateProfile(c *types.ChangeLog, processor types.ChangeLogProcessor) error {
+	oldValue, ok := c.OldVal.(*types.CandidateProfile)
+	if !ok {
+		log.Errorf("expected OldVal *types.CandidateProfile, got %T", c.OldVal)
+		return types.ErrWrongChangeLogData
+	}
+	accessor := processor.GetAccount(c.Address)
+	accessor.SetCandidateProfile(oldValue)
+	return nil
+}
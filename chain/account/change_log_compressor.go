@@ -20,13 +20,7 @@ func MergeChangeLogs(logs types.ChangeLogSlice) (types.ChangeLogSlice, types.Cha
 		newAccountLogs = removeUnchanged(newAccountLogs)
 		resetVersion(newAccountLogs)
 		logsByAccount[addr] = newAccountLogs
-		if len(newAccountLogs) == 0 {
-			versionRevertLogs = append(versionRevertLogs, &types.ChangeLog{
-				Address: accountLogs[0].Address,
-				LogType: accountLogs[0].LogType,
-				Version: accountLogs[0].Version - 1,
-			})
-		}
+		versionRevertLogs = append(versionRevertLogs, findRemovedTypes(accountLogs, newAccountLogs)...)
 	}
 	// sort all logs by account
 	accounts := make(common.AddressSlice, 0, len(logsByAccount))
@@ -46,7 +40,7 @@ func merge(logs types.ChangeLogSlice) types.ChangeLogSlice {
 	result := make(types.ChangeLogSlice, 0)
 	for _, log := range logs {
 		exist := result.FindByType(log)
-		if exist != nil && (log.LogType == BalanceLog || log.LogType == StorageLog) {
+		if exist != nil && (log.LogType == BalanceLog || log.LogType == StorageLog || log.LogType == VotesLog) {
 			// update the exist one
 			exist.NewVal = log.NewVal
 			exist.Extra = log.Extra
@@ -68,10 +62,28 @@ func removeUnchanged(logs types.ChangeLogSlice) types.ChangeLogSlice {
 	return result
 }
 
-// resetVersion reset change logs version, then return the changed versions
+// resetVersion reset change logs version by log type, so that the versions of same type are continuous
 func resetVersion(logs types.ChangeLogSlice) {
-	count := len(logs)
-	for i := 1; i < count; i++ {
-		logs[i].Version = logs[i-1].Version + 1
+	lastVersions := make(map[types.ChangeLogType]uint32)
+	for _, log := range logs {
+		if last, ok := lastVersions[log.LogType]; ok {
+			log.Version = last + 1
+		}
+		lastVersions[log.LogType] = log.Version
 	}
 }
+
+// findRemovedTypes returns the versions need to be revert, if all the change logs of some type are removed after merging
+func findRemovedTypes(origLogs, mergedLogs types.ChangeLogSlice) types.ChangeLogSlice {
+	result := make(types.ChangeLogSlice, 0)
+	for _, log := range origLogs {
+		if mergedLogs.FindByType(log) == nil && result.FindByType(log) == nil {
+			result = append(result, &types.ChangeLog{
+				Address: log.Address,
+				LogType: log.LogType,
+				Version: log.Version - 1,
+			})
+		}
+	}
+	return result
+}
@@ -133,6 +133,42 @@ func getCustomTypeData(t *testing.T) []testCustomTypeConfig {
 		decoded: "SuicideLog{Account: Lemo8888888888888888888888888888888883WD, Version: 1}",
 	})
 
+	// 5 NonceLog
+	tests = append(tests, testCustomTypeConfig{
+		input:   NewNonceLog(processor.createAccount(NonceLog, 0), 1),
+		str:     "NonceLog{Account: Lemo88888888888888888888888888888888849A, Version: 1, OldVal: 0, NewVal: 1}",
+		hash:    "0x37652486a66fba1540ebf0399f530598bf9f37e1ec40468a6169f3e34cc9a38e",
+		rlp:     "0xd9069400000000000000000000000000000000000000060101c0",
+		decoded: "NonceLog{Account: Lemo88888888888888888888888888888888849A, Version: 1, NewVal: 1}",
+	})
+
+	// 6 VoteForLog
+	tests = append(tests, testCustomTypeConfig{
+		input:   NewVoteForLog(processor.createAccount(VoteForLog, 0), common.HexToAddress("0xaaa")),
+		str:     "VoteForLog{Account: Lemo8888888888888888888888888888888884N7, Version: 1, OldVal: [0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0], NewVal: [0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 10 170]}",
+		hash:    "0x7778ffec643dad74a81f560484fc21b9ff19b6606df2cef29f0e8459cffa04f1",
+		rlp:     "0xed0794000000000000000000000000000000000000000701940000000000000000000000000000000000000aaac0",
+		decoded: "VoteForLog{Account: Lemo8888888888888888888888888888888884N7, Version: 1, NewVal: [0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 10 170]}",
+	})
+
+	// 7 VotesLog
+	tests = append(tests, testCustomTypeConfig{
+		input:   NewVotesLog(processor.createAccount(VotesLog, 0), big.NewInt(3)),
+		str:     "VotesLog{Account: Lemo888888888888888888888888888888888534, Version: 1, OldVal: 0, NewVal: 3}",
+		hash:    "0x3f0a4e2ef0c145e578a50a417cd9529b59cbb0a1c4df4f986f1d2164ceacd870",
+		rlp:     "0xd9089400000000000000000000000000000000000000080103c0",
+		decoded: "VotesLog{Account: Lemo888888888888888888888888888888888534, Version: 1, NewVal: 3}",
+	})
+
+	// 8 CandidateProfileLog
+	tests = append(tests, testCustomTypeConfig{
+		input:   NewCandidateProfileLog(processor.createAccount(CandidateProfileLog, 0), &types.CandidateProfile{IsCandidate: true, MinerAddress: common.HexToAddress("0xaaa"), NodeID: []byte{0x12, 0x34}, Host: "127.0.0.1", Port: 7001}),
+		str:     "CandidateProfileLog{Account: Lemo8888888888888888888888888888888885CZ, Version: 1, OldVal: <nil>, NewVal: {IsCandidate: true, MinerAddress: Lemo88888888888888888888888888888883GR46, NodeID: 0x1234, Host: 127.0.0.1, Port: 7001}}",
+		hash:    "0x3e295e278708b92cc572db024da410bf51468adafa1ac40d77c3928d6edfef3c",
+		rlp:     "0xf83f0994000000000000000000000000000000000000000901e601940000000000000000000000000000000000000aaa821234893132372e302e302e31821b59c0",
+		decoded: "CandidateProfileLog{Account: Lemo8888888888888888888888888888888885CZ, Version: 1, NewVal: {IsCandidate: true, MinerAddress: Lemo88888888888888888888888888888883GR46, NodeID: 0x1234, Host: 127.0.0.1, Port: 7001}}",
+	})
+
 	return tests
 }
 
@@ -254,6 +290,39 @@ func TestChangeLog_Undo(t *testing.T) {
 				assert.Equal(t, false, accessor.GetSuicide())
 			},
 		},
+		// 8 NewNonceLog
+		{
+			input: NewNonceLog(processor.createAccount(NonceLog, 1), 1),
+			afterCheck: func(accessor types.AccountAccessor) {
+				assert.Equal(t, uint64(0), accessor.GetNonce())
+			},
+		},
+		// 9 NewNonceLog no OldVal
+		{
+			input:   &types.ChangeLog{LogType: NonceLog, Address: processor.createAccount(NonceLog, 1).GetAddress(), Version: 1},
+			undoErr: types.ErrWrongChangeLogData,
+		},
+		// 10 NewVoteForLog
+		{
+			input: NewVoteForLog(processor.createAccount(VoteForLog, 1), common.HexToAddress("0xaaa")),
+			afterCheck: func(accessor types.AccountAccessor) {
+				assert.Equal(t, common.Address{}, accessor.GetVoteFor())
+			},
+		},
+		// 11 NewVotesLog
+		{
+			input: NewVotesLog(processor.createAccount(VotesLog, 1), big.NewInt(3)),
+			afterCheck: func(accessor types.AccountAccessor) {
+				assert.Equal(t, new(big.Int), accessor.GetVotes())
+			},
+		},
+		// 12 NewCandidateProfileLog
+		{
+			input: NewCandidateProfileLog(processor.createAccount(CandidateProfileLog, 1), &types.CandidateProfile{IsCandidate: true}),
+			afterCheck: func(accessor types.AccountAccessor) {
+				assert.Nil(t, accessor.GetCandidateProfile())
+			},
+		},
 	}
 
 	for i, test := range tests {
@@ -354,6 +423,18 @@ func TestChangeLog_Redo(t *testing.T) {
 				assert.Equal(t, big.NewInt(0), accessor.GetBalance())
 			},
 		},
+		// 10 NewNonceLog
+		{
+			input: decreaseVersion(NewNonceLog(processor.createAccount(NonceLog, 1), 5)),
+			afterCheck: func(accessor types.AccountAccessor) {
+				assert.Equal(t, uint64(5), accessor.GetNonce())
+			},
+		},
+		// 11 NewNonceLog no NewVal
+		{
+			input:   &types.ChangeLog{LogType: NonceLog, Address: processor.createAccount(NonceLog, 0).GetAddress(), Version: 1},
+			redoErr: types.ErrWrongChangeLogData,
+		},
 	}
 
 	for i, test := range tests {
@@ -91,7 +91,12 @@ func init() {
 
 // newDB creates db for test account module
 func newDB() protocol.ChainDB {
-	db, err := store.NewCacheChain("../../testdata/db_account")
+	return newDBAt("../../testdata/db_account")
+}
+
+// newDBAt creates db for test account module at the path
+func newDBAt(path string) protocol.ChainDB {
+	db, err := store.NewCacheChain(path)
 	if err != nil {
 		panic(err)
 	}
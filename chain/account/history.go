@@ -0,0 +1,373 @@
+package account
+
+import (
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/proof"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/store/trie"
+	"math/big"
+	"sort"
+)
+
+var (
+	ErrChangeLogNotFound = errors.New("can't find the change log of the version")
+)
+
+// GetVersion reads the newest change log version of the account at base block from version trie
+func (am *Manager) GetVersion(address common.Address, logType types.ChangeLogType) (uint32, error) {
+	value, err := am.getVersionTrie().TryGet(versionTrieKey(address, logType))
+	if err != nil {
+		return 0, err
+	}
+	return uint32(new(big.Int).SetBytes(value).Uint64()), nil
+}
+
+// ProveVersion creates the proof of the newest change log version of the account at base block
+func (am *Manager) ProveVersion(address common.Address, logType types.ChangeLogType) (*proof.VersionProof, error) {
+	p := &proof.VersionProof{Address: address, LogType: logType}
+	// the version trie is a secure trie, so the key is hashed
+	key := crypto.Keccak256(versionTrieKey(address, logType))
+	if err := am.getVersionTrie().Prove(key, 0, &p.Nodes); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetHistoricalBalance returns the balance of account at base block
+func (am *Manager) GetHistoricalBalance(address common.Address) (*big.Int, error) {
+	c, isLatest, err := am.findHistoricalLog(address, BalanceLog, nil)
+	if err != nil {
+		return nil, err
+	}
+	if isLatest {
+		return am.GetAccount(address).GetBalance(), nil
+	}
+	if c == nil {
+		return new(big.Int), nil
+	}
+	balance, ok := c.NewVal.(big.Int)
+	if !ok {
+		return nil, types.ErrWrongChangeLogData
+	}
+	return &balance, nil
+}
+
+// GetHistoricalCode returns the contract code of account at base block
+func (am *Manager) GetHistoricalCode(address common.Address) (types.Code, error) {
+	c, isLatest, err := am.findHistoricalLog(address, CodeLog, nil)
+	if err != nil {
+		return nil, err
+	}
+	if isLatest {
+		return am.GetAccount(address).GetCode()
+	}
+	if c == nil {
+		return nil, nil
+	}
+	code, ok := c.NewVal.(types.Code)
+	if !ok {
+		return nil, types.ErrWrongChangeLogData
+	}
+	return code, nil
+}
+
+// GetHistoricalStorageState returns the value of contract storage slot at base block
+func (am *Manager) GetHistoricalStorageState(address common.Address, key common.Hash) ([]byte, error) {
+	matchKey := func(c *types.ChangeLog) bool {
+		logKey, ok := storageLogKey(c)
+		return ok && logKey == key
+	}
+	c, isLatest, err := am.findHistoricalLog(address, StorageLog, matchKey)
+	if err != nil {
+		return nil, err
+	}
+	if isLatest {
+		return am.GetAccount(address).GetStorageState(key)
+	}
+	if c == nil {
+		return nil, nil
+	}
+	value, ok := c.NewVal.([]byte)
+	if !ok {
+		return nil, types.ErrWrongChangeLogData
+	}
+	return value, nil
+}
+
+// GetHistoricalNonce returns the nonce of account at base block
+func (am *Manager) GetHistoricalNonce(address common.Address) (uint64, error) {
+	c, isLatest, err := am.findHistoricalLog(address, NonceLog, nil)
+	if err != nil {
+		return 0, err
+	}
+	if isLatest {
+		return am.GetAccount(address).GetNonce(), nil
+	}
+	if c == nil {
+		return 0, nil
+	}
+	nonce, ok := c.NewVal.(uint64)
+	if !ok {
+		return 0, types.ErrWrongChangeLogData
+	}
+	return nonce, nil
+}
+
+// LoadHistoricalAccounts overrides the accounts changed by the logs with their data at base block. The logs are generated by the
+// blocks after base block. It makes the manager work on the state of a stable block whose accounts in db have been overwritten
+func (am *Manager) LoadHistoricalAccounts(logs []*types.ChangeLog) error {
+	// read all historical data before overriding, because the history is found by comparing with the account in cache
+	setters := make([]func(*Account) error, 0, len(logs))
+	addresses := make([]common.Address, 0, len(logs))
+	for _, c := range logs {
+		setter, err := am.historicalSetter(c)
+		if err != nil {
+			return err
+		}
+		setters = append(setters, setter)
+		addresses = append(addresses, c.Address)
+	}
+	for i, setter := range setters {
+		if err := setter(am.getRawAccount(addresses[i]).(*Account)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// historicalSetter returns a function to set the account data changed by the change log back to base block
+func (am *Manager) historicalSetter(c *types.ChangeLog) (func(*Account) error, error) {
+	version, err := am.GetVersion(c.Address, c.LogType)
+	if err != nil {
+		return nil, err
+	}
+	var set func(*Account) error
+	switch c.LogType {
+	case BalanceLog:
+		balance, err := am.GetHistoricalBalance(c.Address)
+		if err != nil {
+			return nil, err
+		}
+		set = func(a *Account) error { a.SetBalance(balance); return nil }
+	case NonceLog:
+		nonce, err := am.GetHistoricalNonce(c.Address)
+		if err != nil {
+			return nil, err
+		}
+		set = func(a *Account) error { a.SetNonce(nonce); return nil }
+	case CodeLog:
+		code, err := am.GetHistoricalCode(c.Address)
+		if err != nil {
+			return nil, err
+		}
+		set = func(a *Account) error { a.SetCode(code); return nil }
+	case StorageLog:
+		key, ok := storageLogKey(c)
+		if !ok {
+			return nil, types.ErrWrongChangeLogData
+		}
+		value, err := am.GetHistoricalStorageState(c.Address, key)
+		if err != nil {
+			return nil, err
+		}
+		set = func(a *Account) error { return a.SetStorageState(key, value) }
+	case VoteForLog:
+		h, err := am.findHistoricalValue(c.Address, VoteForLog)
+		if err != nil {
+			return nil, err
+		}
+		candidate, _ := h.(common.Address)
+		set = func(a *Account) error { a.SetVoteFor(candidate); return nil }
+	case VotesLog:
+		h, err := am.findHistoricalValue(c.Address, VotesLog)
+		if err != nil {
+			return nil, err
+		}
+		votes, _ := h.(big.Int)
+		set = func(a *Account) error { a.SetVotes(&votes); return nil }
+	case CandidateProfileLog:
+		h, err := am.findHistoricalValue(c.Address, CandidateProfileLog)
+		if err != nil {
+			return nil, err
+		}
+		profile, _ := h.(*types.CandidateProfile)
+		set = func(a *Account) error { a.SetCandidateProfile(profile); return nil }
+	case SuicideLog:
+		// the suicided account is alive at base block
+		set = func(a *Account) error { a.SetSuicide(false); return nil }
+	default:
+		set = func(a *Account) error { return nil }
+	}
+	return func(a *Account) error {
+		a.SetVersion(c.LogType, version)
+		return set(a)
+	}, nil
+}
+
+// findHistoricalValue returns the NewVal of the last change log of the type at base block. It is nil if the data has never been set
+func (am *Manager) findHistoricalValue(address common.Address, logType types.ChangeLogType) (interface{}, error) {
+	c, isLatest, err := am.findHistoricalLog(address, logType, nil)
+	if err != nil {
+		return nil, err
+	}
+	account := am.GetAccount(address)
+	switch {
+	case isLatest && logType == VoteForLog:
+		return account.GetVoteFor(), nil
+	case isLatest && logType == VotesLog:
+		return *account.GetVotes(), nil
+	case isLatest && logType == CandidateProfileLog:
+		return account.GetCandidateProfile(), nil
+	case c == nil:
+		return nil, nil
+	}
+	return c.NewVal, nil
+}
+
+// storageLogKey returns the storage key of the storage change log
+func storageLogKey(c *types.ChangeLog) (common.Hash, bool) {
+	switch extra := c.Extra.(type) {
+	case common.Hash:
+		return extra, true
+	case []byte:
+		// the key is decoded as bytes from db
+		return common.BytesToHash(extra), true
+	}
+	return common.Hash{}, false
+}
+
+// findHistoricalLog finds the last change log of the type which is generated before base block (inclusive). The match function
+// is used to filter the change logs, e.g. the storage logs of a key.
+// It returns isLatest=true if the account data loaded from db is exactly the data at base block, because the account in db may be
+// newer than base block if the block is stable. It returns nil change log if the data has never been set or the account is suicided.
+func (am *Manager) findHistoricalLog(address common.Address, logType types.ChangeLogType, match func(*types.ChangeLog) bool) (c *types.ChangeLog, isLatest bool, err error) {
+	version, err := am.GetVersion(address, logType)
+	if err != nil {
+		return nil, false, err
+	}
+	suicideVersion, err := am.GetVersion(address, SuicideLog)
+	if err != nil {
+		return nil, false, err
+	}
+	account := am.GetAccount(address)
+	if version == account.GetVersion(logType) && suicideVersion == account.GetVersion(SuicideLog) {
+		return nil, true, nil
+	}
+	if version == 0 {
+		return nil, false, nil
+	}
+	var suicideHeight uint32
+	if suicideVersion > 0 {
+		block, err := am.findVersionBlock(address, SuicideLog, suicideVersion)
+		if err != nil {
+			return nil, false, err
+		}
+		suicideHeight = block.Height()
+	}
+
+	// jump to the block which generates the version, instead of walking back through blocks
+	for version > 0 {
+		block, err := am.findVersionBlock(address, logType, version)
+		if err != nil {
+			return nil, false, err
+		}
+		if block.Height() < suicideHeight {
+			return nil, false, nil
+		}
+		for i := len(block.ChangeLogs) - 1; i >= 0; i-- {
+			c := block.ChangeLogs[i]
+			if c.Address != address {
+				continue
+			}
+			if c.LogType == SuicideLog && c.Version <= suicideVersion {
+				return nil, false, nil
+			}
+			if c.LogType == logType && c.Version <= version && (match == nil || match(c)) {
+				return c, false, nil
+			}
+		}
+		if block.Height() == 0 {
+			return nil, false, ErrChangeLogNotFound
+		}
+		// no log matches in this block, so find in the blocks before it
+		parent, err := am.db.GetBlockByHash(block.ParentHash())
+		if err != nil {
+			return nil, false, err
+		}
+		parentVersion, err := am.versionAt(parent, versionTrieKey(address, logType))
+		if err != nil {
+			return nil, false, err
+		}
+		if parentVersion >= version {
+			return nil, false, ErrChangeLogNotFound
+		}
+		version = parentVersion
+	}
+	// there is no more log of the type
+	return nil, false, nil
+}
+
+// findVersionBlock returns the block which generates the version of the account data on the branch of base block. The
+// versions only grow up along the chain, so the stable blocks are searched by binary search
+func (am *Manager) findVersionBlock(address common.Address, logType types.ChangeLogType, version uint32) (*types.Block, error) {
+	key := versionTrieKey(address, logType)
+	block := am.baseBlock
+	// the unstable blocks are not indexed by height, so walk back through them
+	for block.Height() > 0 {
+		if canonical, err := am.db.GetBlockByHeight(block.Height()); err == nil && canonical.Hash() == block.Hash() {
+			break
+		}
+		parent, err := am.db.GetBlockByHash(block.ParentHash())
+		if err != nil {
+			return nil, err
+		}
+		parentVersion, err := am.versionAt(parent, key)
+		if err != nil {
+			return nil, err
+		}
+		if parentVersion < version {
+			return block, nil
+		}
+		block = parent
+	}
+
+	var searchErr error
+	height := sort.Search(int(block.Height()), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		b, err := am.db.GetBlockByHeight(uint32(i))
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		v, err := am.versionAt(b, key)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return v >= version
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+	if uint32(height) == block.Height() {
+		return block, nil
+	}
+	return am.db.GetBlockByHeight(uint32(height))
+}
+
+// versionAt reads the version from the version trie of block
+func (am *Manager) versionAt(block *types.Block, key []byte) (uint32, error) {
+	tr, err := trie.NewSecure(block.Header.VersionRoot, am.trieDb, MaxTrieCacheGen)
+	if err != nil {
+		return 0, err
+	}
+	value, err := tr.TryGet(key)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(new(big.Int).SetBytes(value).Uint64()), nil
+}
@@ -0,0 +1,171 @@
+package account
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/proof"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/store/protocol"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"os"
+	"testing"
+)
+
+func TestManager_GetVersion(t *testing.T) {
+	db := newDB()
+	manager := NewManager(newestBlock.Hash(), db)
+
+	version, err := manager.GetVersion(defaultAccounts[0].Address, BalanceLog)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(100), version)
+	version, err = manager.GetVersion(defaultAccounts[0].Address, StorageLog)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), version)
+	version, err = manager.GetVersion(common.HexToAddress("0xaaa"), BalanceLog)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), version)
+}
+
+func TestManager_GetHistoricalBalance(t *testing.T) {
+	db := newDB()
+	manager := NewManager(newestBlock.Hash(), db)
+
+	// the version at the block is same as the account in db
+	balance, err := manager.GetHistoricalBalance(defaultAccounts[0].Address)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(100), balance)
+	code, err := manager.GetHistoricalCode(defaultAccounts[0].Address)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultCodes[0].code, code)
+	value, err := manager.GetHistoricalStorageState(defaultAccounts[0].Address, defaultStorage[1].key)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultStorage[1].value, value)
+
+	// not exist account
+	balance, err = manager.GetHistoricalBalance(common.HexToAddress("0xaaa"))
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(0), balance)
+}
+
+// makeHistoryBlocks creates count blocks after parent. The accounts in each block are changed by the change function
+func makeHistoryBlocks(t *testing.T, db protocol.ChainDB, parent *types.Block, count int, change func(height uint32, am *Manager)) []*types.Block {
+	blocks := make([]*types.Block, 0, count)
+	for i := 0; i < count; i++ {
+		height := parent.Height() + 1
+		am := NewManager(parent.Hash(), db)
+		change(height, am)
+		assert.NoError(t, am.Finalise())
+		block := &types.Block{ChangeLogs: am.GetChangeLogs()}
+		block.SetHeader(&types.Header{ParentHash: parent.Hash(), Height: height, VersionRoot: am.GetVersionRoot(), Time: parent.Time() + 1})
+		assert.NoError(t, db.SetBlock(block.Hash(), block))
+		assert.NoError(t, am.Save(block.Hash()))
+		blocks = append(blocks, block)
+		parent = block
+	}
+	return blocks
+}
+
+func TestManager_findHistoricalLog(t *testing.T) {
+	path := "../../testdata/db_account_history"
+	assert.NoError(t, os.RemoveAll(path))
+	db := newDBAt(path)
+	address := common.HexToAddress("0x123")
+	suicided := common.HexToAddress("0x456")
+	keyA, keyB := common.HexToHash("0xa"), common.HexToHash("0xb")
+	// block 3 to block 30. The stable block is block 25
+	blocks := makeHistoryBlocks(t, db, newestBlock, 28, func(height uint32, am *Manager) {
+		account := am.GetAccount(address)
+		switch height {
+		case 5, 12, 20:
+			account.SetBalance(big.NewInt(int64(height)))
+		case 7:
+			assert.NoError(t, account.SetStorageState(keyA, []byte{7}))
+		case 15:
+			assert.NoError(t, account.SetStorageState(keyB, []byte{15}))
+		case 4:
+			am.GetAccount(suicided).SetBalance(big.NewInt(4))
+		case 9:
+			am.GetAccount(suicided).SetSuicide(true)
+		case 11:
+			am.GetAccount(suicided).SetBalance(big.NewInt(11))
+		}
+		// make the other accounts changed in every block
+		other := am.GetAccount(common.HexToAddress("0x789"))
+		other.SetBalance(big.NewInt(int64(height)))
+	})
+	blockAt := func(height uint32) *types.Block { return blocks[height-3] }
+	assert.NoError(t, db.SetStableBlock(blockAt(25).Hash()))
+
+	tests := []struct {
+		height   uint32
+		balance  int64
+		valueA   []byte
+		valueB   []byte
+		suicided int64
+	}{
+		{3, 0, nil, nil, 0},
+		{4, 0, nil, nil, 4},
+		{6, 5, nil, nil, 4},
+		{8, 5, []byte{7}, nil, 4},
+		{10, 5, []byte{7}, nil, 0},
+		{13, 12, []byte{7}, nil, 11},
+		{16, 12, []byte{7}, []byte{15}, 11},
+		{24, 20, []byte{7}, []byte{15}, 11},
+		// unstable blocks
+		{28, 20, []byte{7}, []byte{15}, 11},
+	}
+	for _, test := range tests {
+		manager := NewManager(blockAt(test.height).Hash(), db)
+		balance, err := manager.GetHistoricalBalance(address)
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewInt(test.balance), balance, "height=%d", test.height)
+		value, err := manager.GetHistoricalStorageState(address, keyA)
+		assert.NoError(t, err)
+		assert.Equal(t, test.valueA, value, "height=%d", test.height)
+		value, err = manager.GetHistoricalStorageState(address, keyB)
+		assert.NoError(t, err)
+		assert.Equal(t, test.valueB, value, "height=%d", test.height)
+		balance, err = manager.GetHistoricalBalance(suicided)
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewInt(test.suicided), balance, "height=%d", test.height)
+	}
+}
+
+func TestManager_ProveVersion(t *testing.T) {
+	db := newDB()
+	manager := NewManager(newestBlock.Hash(), db)
+
+	p, err := manager.ProveVersion(defaultAccounts[0].Address, CodeLog)
+	assert.NoError(t, err)
+	version, err := proof.VerifyVersion(newestBlock.Header, p)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(101), version)
+}
+
+func TestManager_LoadHistoricalAccounts(t *testing.T) {
+	db := newDB()
+	manager := NewManager(newestBlock.Hash(), db)
+	address := defaultAccounts[0].Address
+	newAddress := common.HexToAddress("0xaaa")
+	key := common.HexToHash("0x99")
+
+	// change the accounts as a newer block does
+	account := manager.GetAccount(address)
+	account.SetNonce(10)
+	assert.NoError(t, account.SetStorageState(key, []byte{1}))
+	newAccount := manager.GetAccount(newAddress)
+	newAccount.SetBalance(big.NewInt(200))
+	assert.Equal(t, uint32(1), newAccount.GetVersion(BalanceLog))
+
+	// restore the data at base block
+	assert.NoError(t, manager.LoadHistoricalAccounts(manager.GetChangeLogs()))
+	assert.Equal(t, uint64(0), account.GetNonce())
+	assert.Equal(t, uint32(0), account.GetVersion(NonceLog))
+	value, err := account.GetStorageState(key)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(value))
+	assert.Equal(t, big.NewInt(0), newAccount.GetBalance())
+	assert.Equal(t, uint32(0), newAccount.GetVersion(BalanceLog))
+	// the data which is not changed is kept
+	assert.Equal(t, big.NewInt(100), account.GetBalance())
+}
@@ -2,6 +2,7 @@ package account
 
 import (
 	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/proof"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"github.com/LemoFoundationLtd/lemochain-go/common/log"
@@ -214,7 +215,7 @@ func (am *Manager) Finalise() error {
 }
 
 func versionTrieKey(address common.Address, logType types.ChangeLogType) []byte {
-	return append(address.Bytes(), big.NewInt(int64(logType)).Bytes()...)
+	return proof.VersionTrieKey(address, logType)
 }
 
 // Save writes dirty data into db.
@@ -359,6 +360,32 @@ func (am *Manager) Rebuild(address common.Address) error {
 	return am.db.SetAccounts(am.baseBlockHash, []*types.AccountData{account.data})
 }
 
+// Undo reverts the change logs of a block in reverse order. The base block should be the block which generates the logs
+func (am *Manager) Undo(logs []*types.ChangeLog) error {
+	// the events will be popped by AddEventLog's undo
+	for _, changeLog := range logs {
+		if event, ok := changeLog.NewVal.(*types.Event); ok && changeLog.LogType == AddEventLog {
+			am.processor.PushEvent(event)
+		}
+	}
+	for i := len(logs) - 1; i >= 0; i-- {
+		if err := logs[i].Undo(am.processor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redo replays the change logs of a block. The base block should be the parent of the block which generates the logs
+func (am *Manager) Redo(logs []*types.ChangeLog) error {
+	for _, changeLog := range logs {
+		if err := changeLog.Redo(am.processor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // MergeChangeLogs merges the change logs for same account in block. Then update the version of change logs and account.
 func (am *Manager) MergeChangeLogs(fromIndex int) {
 	needMerge := am.processor.changeLogs[fromIndex:]
@@ -178,6 +178,34 @@ func TestNewManager_Snapshot_RevertToSnapshot(t *testing.T) {
 	assert.Equal(t, big.NewInt(999), account.GetBalance())
 }
 
+func TestManager_Undo_Redo(t *testing.T) {
+	db := newDB()
+	manager := NewManager(newestBlock.Hash(), db)
+	account := manager.GetAccount(defaultAccounts[0].Address)
+	account.SetBalance(big.NewInt(999))
+	manager.AddEvent(&types.Event{Address: defaultAccounts[0].Address})
+	logs := manager.GetChangeLogs()
+	assert.Equal(t, 2, len(logs))
+
+	// undo in a new manager
+	manager = NewManager(newestBlock.Hash(), db)
+	account = manager.GetAccount(defaultAccounts[0].Address)
+	account.SetBalance(big.NewInt(999))
+	manager.AddEvent(&types.Event{Address: defaultAccounts[0].Address})
+	manager.processor.clear()
+	assert.NoError(t, manager.Undo(logs))
+	assert.Equal(t, defaultAccounts[0].Balance, account.GetBalance())
+	assert.Equal(t, uint32(100), account.GetVersion(BalanceLog))
+	assert.Equal(t, 0, len(manager.GetEvents()))
+
+	// redo
+	assert.NoError(t, manager.Redo(logs))
+	assert.Equal(t, big.NewInt(999), account.GetBalance())
+	assert.Equal(t, uint32(101), account.GetVersion(BalanceLog))
+	assert.Equal(t, 1, len(manager.GetEvents()))
+	assert.Equal(t, types.ErrAlreadyRedo, manager.Redo(logs))
+}
+
 func TestNewManager_AddEvent(t *testing.T) {
 	manager := NewManager(newestBlock.Hash(), newDB())
 
@@ -50,6 +50,7 @@ func (a *SafeAccount) String() string {
 
 func (a *SafeAccount) GetAddress() common.Address { return a.rawAccount.GetAddress() }
 func (a *SafeAccount) GetBalance() *big.Int       { return a.rawAccount.GetBalance() }
+func (a *SafeAccount) GetNonce() uint64           { return a.rawAccount.GetNonce() }
 func (a *SafeAccount) GetVersion(logType types.ChangeLogType) uint32 {
 	return a.rawAccount.GetVersion(logType)
 }
@@ -70,6 +71,11 @@ func (a *SafeAccount) SetBalance(balance *big.Int) {
 	a.rawAccount.SetBalance(balance)
 }
 
+func (a *SafeAccount) SetNonce(nonce uint64) {
+	a.processor.PushChangeLog(NewNonceLog(a.rawAccount, nonce))
+	a.rawAccount.SetNonce(nonce)
+}
+
 func (a *SafeAccount) SetVersion(logType types.ChangeLogType, version uint32) {
 	panic("SafeAccount.SetVersion should not be called")
 }
@@ -79,6 +85,27 @@ func (a *SafeAccount) SetSuicide(suicided bool) {
 	a.rawAccount.SetSuicide(suicided)
 }
 
+func (a *SafeAccount) GetVoteFor() common.Address { return a.rawAccount.GetVoteFor() }
+func (a *SafeAccount) GetVotes() *big.Int         { return a.rawAccount.GetVotes() }
+func (a *SafeAccount) GetCandidateProfile() *types.CandidateProfile {
+	return a.rawAccount.GetCandidateProfile()
+}
+
+func (a *SafeAccount) SetVoteFor(candidate common.Address) {
+	a.processor.PushChangeLog(NewVoteForLog(a.rawAccount, candidate))
+	a.rawAccount.SetVoteFor(candidate)
+}
+
+func (a *SafeAccount) SetVotes(votes *big.Int) {
+	a.processor.PushChangeLog(NewVotesLog(a.rawAccount, votes))
+	a.rawAccount.SetVotes(votes)
+}
+
+func (a *SafeAccount) SetCandidateProfile(profile *types.CandidateProfile) {
+	a.processor.PushChangeLog(NewCandidateProfileLog(a.rawAccount, profile))
+	a.rawAccount.SetCandidateProfile(profile)
+}
+
 func (a *SafeAccount) SetCodeHash(codeHash common.Hash) {
 	panic("SafeAccount.SetCodeHash should not be called")
 }
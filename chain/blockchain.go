@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
@@ -13,7 +14,7 @@ import (
 	"github.com/LemoFoundationLtd/lemochain-go/common/subscribe"
 	"github.com/LemoFoundationLtd/lemochain-go/network/synchronise/protocol"
 	db "github.com/LemoFoundationLtd/lemochain-go/store/protocol"
-	"math"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,8 +24,11 @@ type broadcastConfirmInfoFn func(hash common.Hash, height uint32)
 
 type broadcastBlockFn func(block *types.Block)
 
+type txsReorgFn func(dropped, included types.Transactions)
+
 type BlockChain struct {
 	chainID              uint16
+	config               *params.ChainConfig // fork heights set in genesis
 	flags                flag.CmdFlags
 	db                   db.ChainDB
 	am                   *account.Manager
@@ -33,17 +37,22 @@ type BlockChain struct {
 	genesisBlock         *types.Block           // genesis block
 	BroadcastConfirmInfo broadcastConfirmInfoFn // callback of broadcast confirm info
 	BroadcastStableBlock broadcastBlockFn       // callback of broadcast stable block
+	TxsReorg             txsReorgFn             // callback of transactions changed by fork switching
 
 	chainForksHead map[common.Hash]*types.Block // total latest header of different fork chain
 	chainForksLock sync.Mutex
 
-	engine    Engine       // consensus engine
-	processor *TxProcessor // state processor
-	running   int32
+	engine       Engine        // consensus engine
+	processor    *TxProcessor  // state processor
+	evidencePool *EvidencePool // signatures of deputy nodes to find the equivocation
+	running      int32
 
-	MinedBlockFeed  subscribe.Feed
-	RecvBlockFeed   subscribe.Feed
-	StableBlockFeed subscribe.Feed
+	MinedBlockFeed    subscribe.Feed
+	RecvBlockFeed     subscribe.Feed
+	StableBlockFeed   subscribe.Feed
+	RemovedEventsFeed subscribe.Feed // events in the blocks which are abandoned by fork switching
+	AddedEventsFeed   subscribe.Feed // events in the blocks which become canonical by new block or fork switching
+	EvidenceFeed      subscribe.Feed // evidences of the deputy nodes who signed two blocks at the same height
 
 	quitCh chan struct{}
 }
@@ -56,15 +65,20 @@ func NewBlockChain(chainID uint16, engine Engine, db db.ChainDB, flags flag.CmdF
 		flags:          flags,
 		engine:         engine,
 		chainForksHead: make(map[common.Hash]*types.Block, 16),
+		evidencePool:   NewEvidencePool(),
 		quitCh:         make(chan struct{}),
 	}
 	bc.genesisBlock = bc.GetBlockByHeight(0)
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
 	}
+	if bc.config, err = GetChainConfig(account.NewManager(bc.genesisBlock.Hash(), db)); err != nil {
+		return nil, err
+	}
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	bc.loadSelfSigns()
 	bc.processor = NewTxProcessor(bc)
 	return bc, nil
 }
@@ -96,6 +110,11 @@ func (bc *BlockChain) ChainID() uint16 {
 	return bc.chainID
 }
 
+// Config returns the chain config which decides the protocol rules by height
+func (bc *BlockChain) Config() *params.ChainConfig {
+	return bc.config
+}
+
 func (bc *BlockChain) TxProcessor() *TxProcessor {
 	return bc.processor
 }
@@ -121,9 +140,18 @@ func (bc *BlockChain) getGenesisFromDb() *types.Block {
 }
 
 func (bc *BlockChain) GetBlockByHeight(height uint32) *types.Block {
+	block, err := bc.LoadBlockByHeight(height)
+	if err != nil {
+		panic(fmt.Sprintf("can't get block. height:%d, err: %v", height, err))
+	}
+	return block
+}
+
+// LoadBlockByHeight is like GetBlockByHeight, but it returns the database error instead of panic. The block is nil if the height is higher than current block
+func (bc *BlockChain) LoadBlockByHeight(height uint32) (*types.Block, error) {
 	// genesis block
 	if height == 0 {
-		return bc.getGenesisFromDb()
+		return bc.getGenesisFromDb(), nil
 	}
 
 	// not genesis block
@@ -132,21 +160,18 @@ func (bc *BlockChain) GetBlockByHeight(height uint32) *types.Block {
 	stableBlockHeight := bc.stableBlock.Load().(*types.Block).Height()
 	var err error
 	if stableBlockHeight >= height {
-		block, err = bc.db.GetBlockByHeight(height)
-		if err != nil {
-			panic(fmt.Sprintf("can't get block. height:%d, err: %v", height, err))
-		}
+		return bc.db.GetBlockByHeight(height)
 	} else if height <= currentBlockHeight {
 		for i := currentBlockHeight - height; i > 0; i-- {
 			block, err = bc.db.GetBlockByHash(block.ParentHash())
 			if err != nil {
-				panic(fmt.Sprintf("can't get block. height:%d, err: %v", height, err))
+				return nil, err
 			}
 		}
 	} else {
-		return nil
+		return nil, nil
 	}
-	return block
+	return block, nil
 }
 
 func (bc *BlockChain) GetBlockByHash(hash common.Hash) *types.Block {
@@ -169,11 +194,16 @@ func (bc *BlockChain) StableBlock() *types.Block {
 }
 
 // SetMinedBlock 挖到新块
-func (bc *BlockChain) SetMinedBlock(block *types.Block) error {
+func (bc *BlockChain) SetMinedBlock(block *types.Block, receipts types.Receipts) error {
 	if err := bc.db.SetBlock(block.Hash(), block); err != nil {
 		log.Errorf("can't insert block to cache. height:%d hash:%s", block.Height(), block.Hash().Hex())
 		return ErrSaveBlock
 	}
+	receipts.SetBlockHash(block.Hash())
+	if err := bc.db.SetReceipts(block.Hash(), receipts); err != nil {
+		log.Errorf("can't save receipts. height:%d hash:%s", block.Height(), block.Hash().Hex())
+		return ErrSaveBlock
+	}
 	err := bc.AccountManager().Save(block.Hash())
 	if err != nil {
 		log.Error("save account error!", "hash", block.Hash().Hex(), "err", err)
@@ -188,6 +218,7 @@ func (bc *BlockChain) SetMinedBlock(block *types.Block) error {
 	bc.chainForksHead[block.Hash()] = block
 
 	bc.MinedBlockFeed.Send(block)
+	bc.notifyAddedEvents(block)
 
 	return nil
 }
@@ -198,7 +229,8 @@ func (bc *BlockChain) newBlockNotify(block *types.Block) {
 
 // InsertChain insert block of non-self to chain
 func (bc *BlockChain) InsertChain(block *types.Block, isSynchronising bool) (err error) {
-	if err := bc.Verify(block); err != nil {
+	receipts, err := bc.verify(block)
+	if err != nil {
 		log.Errorf("block verify failed: %v", err)
 		return ErrVerifyBlockFailed
 	}
@@ -206,16 +238,22 @@ func (bc *BlockChain) InsertChain(block *types.Block, isSynchronising bool) (err
 	hash := block.Hash()
 	parentHash := block.ParentHash()
 	currentHash := bc.currentBlock.Load().(*types.Block).Hash()
+	bc.RecordSignedBlock(block)
 	if has, _ := bc.db.IsExistByHash(hash); has {
 		return nil
 	}
 	// save
+	block.SetConfirms(filterConfirms(hash, block.Height(), block.Confirms))
 	block.SetEvents(bc.AccountManager().GetEvents())
 	block.SetChangeLogs(bc.AccountManager().GetChangeLogs())
 	if err = bc.db.SetBlock(hash, block); err != nil {
 		log.Errorf("can't insert block to cache. height:%d hash:%s", block.Height(), hash.Hex())
 		return ErrSaveBlock
 	}
+	if err = bc.db.SetReceipts(hash, receipts); err != nil {
+		log.Errorf("can't save receipts. height:%d hash:%s", block.Height(), hash.Hex())
+		return ErrSaveBlock
+	}
 	if !isSynchronising {
 		log.Infof("Insert block to chain. height: %d. hash: %s", block.Height(), block.Hash().String())
 	}
@@ -228,11 +266,11 @@ func (bc *BlockChain) InsertChain(block *types.Block, isSynchronising bool) (err
 	if nodeCount < 3 {
 		defer bc.SetStableBlock(hash, block.Height(), isSynchronising)
 	} else {
-		minCount := int(math.Ceil(float64(nodeCount) * 2.0 / 3.0))
-		if len(block.Confirms) >= minCount {
+		if len(block.Confirms) >= types.MinConfirmCount(nodeCount) {
 			defer bc.SetStableBlock(hash, block.Height(), isSynchronising)
 		}
 	}
+	defer bc.applyFinality(block, isSynchronising)
 
 	bc.chainForksLock.Lock()
 	defer func() {
@@ -251,19 +289,18 @@ func (bc *BlockChain) InsertChain(block *types.Block, isSynchronising bool) (err
 		bc.currentBlock.Store(block)
 		delete(bc.chainForksHead, currentHash) // remove old record from fork container
 		bc.chainForksHead[hash] = block        // record new fork
-		if !isSynchronising {                  // if synchronising, don't notify
+		bc.notifyAddedEvents(block)
+		if !isSynchronising { // if synchronising, don't notify
 			bc.newBlockNotify(block)
 		}
 		return nil
 	}
 	// new block height higher than current block, switch fork.
-	curHeight := bc.currentBlock.Load().(*types.Block).Height()
-	if block.Height() == curHeight+1 {
-		bc.currentBlock.Store(block)
-		log.Warnf("chain forked! current block: height(%d), hash(%s)", block.Height(), block.Hash().Hex())
-	} else if curHeight == block.Height() { // two block with same height, priority of lower alphabet order
-		if hash.Big().Cmp(currentHash.Big()) < 0 {
-			bc.currentBlock.Store(block)
+	oldCurBlock := bc.currentBlock.Load().(*types.Block)
+	curHeight := oldCurBlock.Height()
+	// switch to the higher block, or the block with lower alphabet order if they have same height
+	if block.Height() == curHeight+1 || (block.Height() == curHeight && hash.Big().Cmp(currentHash.Big()) < 0) {
+		if err := bc.reorg(oldCurBlock, block); err == nil {
 			log.Warnf("chain forked! current block: height(%d), hash(%s)", block.Height(), block.Hash().Hex())
 		}
 	}
@@ -289,15 +326,22 @@ func (bc *BlockChain) SetStableBlock(hash common.Hash, height uint32, logLess bo
 		log.Errorf("SetStableBlock error. height:%d hash:%s", height, common.ToHex(hash[:]))
 		return ErrSetStableBlockToDB
 	}
+	oldStable := bc.stableBlock.Load().(*types.Block)
 	bc.stableBlock.Store(block)
 	defer func() {
 		if !logLess {
 			log.Infof("block has consensus. height:%d hash:%s", block.Height(), block.Hash().Hex())
 		}
 	}()
+	if oldStable.Hash() != hash {
+		// notify after the forks are pruned
+		defer bc.StableBlockFeed.Send(block)
+	}
+	bc.updateDeputyNodes(oldStable.Height(), height)
 
 	// get parent block
 	parBlock := bc.currentBlock.Load().(*types.Block)
+	oldCurBlock := parBlock
 	oldCurHash := parBlock.Hash()
 	if parBlock.Height() < height {
 		log.Error("stable block's height is larger than current block")
@@ -336,29 +380,197 @@ func (bc *BlockChain) SetStableBlock(hash common.Hash, height uint32, logLess bo
 			curBlock = fBlock
 		}
 	}
-	bc.currentBlock.Store(curBlock)
+	if oldCurHash != curBlock.Hash() {
+		if err := bc.reorg(oldCurBlock, curBlock); err != nil {
+			return err
+		}
+	}
 	if !logLess && oldCurHash != curBlock.ParentHash() {
 		log.Infof("chain forked! current block: height(%d), hash(%s)", curBlock.Height(), curBlock.Hash().Hex())
 	}
 	return nil
 }
 
+// reorg switches the chain from old fork to new fork. The account state is rewound through the common ancestor, the events in
+// abandoned blocks are notified as removed and the events in new blocks are notified as added. Then the abandoned transactions
+// are returned to tx pool. The current block is not switched if the account state can't be rewound
+func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
+	oldHead, newHead := oldBlock, newBlock
+	// the blocks from head to the common ancestor (exclusive)
+	abandoned := make([]*types.Block, 0)
+	added := make([]*types.Block, 0)
+	for oldBlock != nil && newBlock != nil && oldBlock.Hash() != newBlock.Hash() {
+		if oldBlock.Height() >= newBlock.Height() {
+			abandoned = append(abandoned, oldBlock)
+			oldBlock = bc.GetBlockByHash(oldBlock.ParentHash())
+		} else {
+			added = append(added, newBlock)
+			newBlock = bc.GetBlockByHash(newBlock.ParentHash())
+		}
+	}
+	if oldBlock == nil || newBlock == nil {
+		log.Warn("Can't find the common ancestor of forks")
+		return ErrBlockNotExist
+	}
+	if err := bc.rewindState(oldHead, newHead, abandoned, added); err != nil {
+		log.Errorf("can't rewind account state from block %s to %s: %v", oldHead.Hash().Hex(), newHead.Hash().Hex(), err)
+		return err
+	}
+	bc.currentBlock.Store(newHead)
+
+	oldTxs := make(types.Transactions, 0)
+	removedEvents := make([]*types.Event, 0)
+	for _, block := range abandoned {
+		oldTxs = append(oldTxs, block.Txs...)
+		removedEvents = append(removedEvents, bc.receiptEvents(block, true)...)
+	}
+	newTxs := make(types.Transactions, 0)
+	addedEvents := make([]*types.Event, 0)
+	for i, block := range added {
+		newTxs = append(newTxs, block.Txs...)
+		// notify the added events from old to new
+		addedEvents = append(addedEvents, bc.receiptEvents(added[len(added)-1-i], false)...)
+	}
+	if len(removedEvents) > 0 {
+		bc.RemovedEventsFeed.Send(removedEvents)
+	}
+	if len(addedEvents) > 0 {
+		bc.AddedEventsFeed.Send(addedEvents)
+	}
+	if bc.TxsReorg == nil {
+		return nil
+	}
+	// the transactions packaged in both forks are not dropped
+	included := make(map[common.Hash]bool, len(newTxs))
+	for _, tx := range newTxs {
+		included[tx.Hash()] = true
+	}
+	dropped := make(types.Transactions, 0)
+	for _, tx := range oldTxs {
+		if !included[tx.Hash()] {
+			dropped = append(dropped, tx)
+		}
+	}
+	// reinject the transactions in nonce order
+	sort.SliceStable(dropped, func(i, j int) bool {
+		return dropped[i].Nonce() < dropped[j].Nonce()
+	})
+	bc.TxsReorg(dropped, newTxs)
+	return nil
+}
+
+// rewindState undoes the change logs of abandoned blocks from old head to the common ancestor, then redoes the change logs of new
+// blocks to new head. It works on the account manager of chain, so the manager is switched to the state of new head if the result
+// matches the version root of new head. The state of new head has been saved when the block is inserted, so it is not saved again
+func (bc *BlockChain) rewindState(oldHead, newHead *types.Block, abandoned, added []*types.Block) (err error) {
+	if has, _ := bc.db.IsExistByHash(oldHead.Hash()); !has {
+		return ErrBlockNotExist
+	}
+	am := bc.am
+	am.Reset(oldHead.Hash())
+	defer func() {
+		if err != nil {
+			am.Reset(oldHead.Hash())
+		}
+	}()
+	for _, block := range abandoned {
+		if err := am.Undo(block.ChangeLogs); err != nil {
+			return err
+		}
+	}
+	for i := len(added) - 1; i >= 0; i-- {
+		if err := am.Redo(added[i].ChangeLogs); err != nil {
+			return err
+		}
+	}
+	if err := am.Finalise(); err != nil {
+		return err
+	}
+	if am.GetVersionRoot() != newHead.VersionRoot() {
+		return ErrRewindState
+	}
+	am.Reset(newHead.Hash())
+	return nil
+}
+
+// parentStateManager creates an account manager which works on the state of the block's parent
+func (bc *BlockChain) parentStateManager(block *types.Block) (*account.Manager, error) {
+	parent := bc.GetBlockByHash(block.ParentHash())
+	if parent == nil {
+		return nil, ErrBlockNotExist
+	}
+	return bc.stateManager(parent)
+}
+
+// stateManager creates an account manager which works on the state of the block. The accounts in db are the newest stable
+// data, so the accounts changed after a stable block are loaded from history
+func (bc *BlockChain) stateManager(block *types.Block) (*account.Manager, error) {
+	am := account.NewManager(block.Hash(), bc.db)
+	stable := bc.StableBlock()
+	if block.Height() >= stable.Height() {
+		return am, nil
+	}
+	// collect the change logs from the stable block back to the block
+	logs := make([]*types.ChangeLog, 0)
+	for b := stable; b.Hash() != block.Hash(); {
+		logs = append(logs, b.ChangeLogs...)
+		if b = bc.GetBlockByHash(b.ParentHash()); b == nil || b.Height() < block.Height() {
+			return nil, ErrBlockNotExist
+		}
+	}
+	if err := am.LoadHistoricalAccounts(logs); err != nil {
+		return nil, err
+	}
+	return am, nil
+}
+
+// notifyAddedEvents sends the events in the new current block to AddedEventsFeed
+func (bc *BlockChain) notifyAddedEvents(block *types.Block) {
+	if events := bc.receiptEvents(block, false); len(events) > 0 {
+		bc.AddedEventsFeed.Send(events)
+	}
+}
+
+// receiptEvents returns the copies of events in block's receipts. The events are marked as removed if the block is abandoned
+func (bc *BlockChain) receiptEvents(block *types.Block, removed bool) []*types.Event {
+	receipts, err := bc.db.GetReceipts(block.Hash())
+	if err != nil {
+		log.Debugf("can't get receipts. height:%d hash:%s", block.Height(), block.Hash().Hex())
+		return nil
+	}
+	result := make([]*types.Event, 0)
+	for _, receipt := range receipts {
+		for _, event := range receipt.Events {
+			e := *event
+			e.Removed = removed
+			result = append(result, &e)
+		}
+	}
+	return result
+}
+
 // Verify verify block
 func (bc *BlockChain) Verify(block *types.Block) error {
+	_, err := bc.verify(block)
+	return err
+}
+
+// verify verifies block and returns the receipts of transactions in it
+func (bc *BlockChain) verify(block *types.Block) (types.Receipts, error) {
 	// verify header
 	if err := bc.engine.VerifyHeader(block); err != nil {
-		return err
+		return nil, err
 	}
 	// verify body
 	if err := bc.verifyBody(block); err != nil {
-		return err
+		return nil, err
 	}
 
 	hash := block.Hash()
 	// execute tx
-	newHeader, err := bc.processor.Process(block)
-	if err == ErrInvalidTxInBlock {
-		return err
+	newHeader, receipts, err := bc.processor.Process(block)
+	if err == ErrInvalidTxInBlock || err == ErrFinalizeBlock {
+		return nil, err
 	} else if err == nil {
 	} else {
 		log.Errorf("processor internal error: %v", err)
@@ -368,9 +580,24 @@ func (bc *BlockChain) Verify(block *types.Block) error {
 	// verify block hash
 	if newHeader.Hash() != hash {
 		log.Errorf("verify block error! hash:%s", hash.Hex())
-		return ErrVerifyBlockFailed
+		return nil, ErrVerifyBlockFailed
+	}
+	return receipts, nil
+}
+
+// updateDeputyNodes records the deputy nodes elected in the snapshot blocks which become stable in (oldStableHeight, newStableHeight]
+func (bc *BlockChain) updateDeputyNodes(oldStableHeight, newStableHeight uint32) {
+	interval := uint32(deputynode.SnapshotBlockInterval)
+	for height := (oldStableHeight/interval + 1) * interval; height <= newStableHeight; height += interval {
+		block, err := bc.db.GetBlockByHeight(height)
+		if err != nil {
+			log.Errorf("can't load snapshot block. height: %d, err: %v", height, err)
+			return
+		}
+		if len(block.DeputyNodes) > 0 {
+			deputynode.Instance().Add(height, block.DeputyNodes)
+		}
 	}
-	return nil
 }
 
 // verifyBody verify block body
@@ -382,6 +609,10 @@ func (bc *BlockChain) verifyBody(block *types.Block) error {
 		return ErrVerifyBlockFailed
 	}
 	// verify deputyRoot
+	if len(block.DeputyNodes) == 0 && len(header.DeputyRoot) > 0 {
+		log.Errorf("verify block failed. deputy nodes are missing. hash:%s height:%d", block.Hash(), block.Height())
+		return ErrVerifyBlockFailed
+	}
 	if len(block.DeputyNodes) > 0 {
 		hash := types.DeriveDeputyRootSha(block.DeputyNodes)
 		root := block.Header.DeputyRoot
@@ -390,6 +621,11 @@ func (bc *BlockChain) verifyBody(block *types.Block) error {
 			return ErrVerifyBlockFailed
 		}
 	}
+	// verify finality certificate
+	if err := bc.verifyFinality(block); err != nil {
+		log.Errorf("verify block failed. invalid finality certificate: %v", err)
+		return ErrVerifyBlockFailed
+	}
 	return nil
 }
 
@@ -413,6 +649,7 @@ func (bc *BlockChain) ReceiveConfirm(info *protocol.BlockConfirmData) (err error
 		log.Warnf("Unavailable confirm info. from: %s", common.ToHex(pubKey[1:]))
 		return ErrInvalidConfirmInfo
 	}
+	bc.recordSign(block.Header, info.SignInfo)
 	// has block consensus
 	stableBlock := bc.stableBlock.Load().(*types.Block)
 	if stableBlock.Height() >= height { // stable block's confirm info
@@ -440,8 +677,7 @@ func (bc *BlockChain) hasEnoughConfirmInfo(hash common.Hash) (bool, error) {
 		return false, err
 	}
 	nodeCount := deputynode.Instance().GetDeputiesCount()
-	minCount := int(math.Ceil(float64(nodeCount) * 2.0 / 3.0))
-	if confirmCount >= minCount {
+	if confirmCount >= types.MinConfirmCount(nodeCount) {
 		return true, nil
 	}
 	return false, nil
@@ -476,10 +712,21 @@ func (bc *BlockChain) GetConfirms(query *protocol.GetConfirmInfo) []types.SignDa
 	return res
 }
 
-// ReceiveConfirms receive confirm package from net connection
+// ReceiveConfirms receive confirm package from net connection. Only the confirms signed by deputy nodes are saved
 func (bc *BlockChain) ReceiveConfirms(pack protocol.BlockConfirms) {
-	if pack.Hash != (common.Hash{}) && pack.Pack != nil && len(pack.Pack) > 0 {
-		bc.db.SetConfirms(pack.Hash, pack.Pack)
+	if pack.Hash == (common.Hash{}) || len(pack.Pack) == 0 {
+		return
+	}
+	block, err := bc.db.GetBlockByHash(pack.Hash)
+	if err != nil {
+		return
+	}
+	// merge with the confirms received before
+	all := make([]types.SignData, 0, len(block.Confirms)+len(pack.Pack))
+	all = append(append(all, block.Confirms...), pack.Pack...)
+	confirms := filterConfirms(pack.Hash, block.Height(), all)
+	if len(confirms) > len(block.Confirms) {
+		bc.db.SetConfirms(pack.Hash, confirms)
 	}
 }
 
@@ -495,3 +742,43 @@ func (bc *BlockChain) Stop() {
 func (bc *BlockChain) Db() db.ChainDB {
 	return bc.db
 }
+
+// GetTxByHash finds a transaction in current chain. It returns the transaction, the block contains it and the index in block
+func (bc *BlockChain) GetTxByHash(hash common.Hash) (*types.Transaction, *types.Block, uint32) {
+	// search the unstable blocks in current fork
+	block := bc.CurrentBlock()
+	stableHeight := bc.StableBlock().Height()
+	for block != nil && block.Height() > stableHeight {
+		for i, tx := range block.Txs {
+			if tx.Hash() == hash {
+				return tx, block, uint32(i)
+			}
+		}
+		block = bc.GetBlockByHash(block.ParentHash())
+	}
+	// search stable blocks
+	entry, err := bc.db.GetTxLookup(hash)
+	if err != nil {
+		return nil, nil, 0
+	}
+	block = bc.GetBlockByHash(entry.BlockHash)
+	if block == nil || int(entry.Index) >= len(block.Txs) {
+		log.Errorf("can't find transaction in block. tx hash: %s, block hash: %s", hash.Hex(), entry.BlockHash.Hex())
+		return nil, nil, 0
+	}
+	return block.Txs[entry.Index], block, entry.Index
+}
+
+// GetReceipt returns the receipt of a transaction in current chain
+func (bc *BlockChain) GetReceipt(hash common.Hash) *types.Receipt {
+	tx, block, index := bc.GetTxByHash(hash)
+	if tx == nil {
+		return nil
+	}
+	receipts, err := bc.db.GetReceipts(block.Hash())
+	if err != nil || int(index) >= len(receipts) {
+		log.Warnf("can't get receipt. tx hash: %s, block hash: %s", hash.Hex(), block.Hash().Hex())
+		return nil
+	}
+	return receipts[index]
+}
@@ -1,6 +1,8 @@
 package chain
 
 import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
@@ -193,6 +195,36 @@ func TestBlockChain_GetBlockByHeight(t *testing.T) {
 	assert.Equal(t, genesis.Hash(), result.ParentHash())
 }
 
+func TestBlockChain_LoadBlockByHeight(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+
+	// stable block
+	block, err := bc.LoadBlockByHeight(1)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultBlocks[1].Hash(), block.Hash())
+	// unstable block
+	bc.currentBlock.Store(defaultBlocks[2])
+	block, err = bc.LoadBlockByHeight(2)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultBlocks[2].Hash(), block.Hash())
+	// higher than current block
+	block, err = bc.LoadBlockByHeight(3)
+	assert.NoError(t, err)
+	assert.Nil(t, block)
+
+	// the block is missing in db
+	missing := types.NewBlock(&types.Header{ParentHash: common.HexToHash("0x1"), Height: 5}, nil, nil, nil, nil)
+	bc.currentBlock.Store(missing)
+	block, err = bc.LoadBlockByHeight(4)
+	assert.Error(t, err)
+	assert.Nil(t, block)
+	bc.stableBlock.Store(missing)
+	block, err = bc.LoadBlockByHeight(4)
+	assert.Error(t, err)
+	assert.Nil(t, block)
+}
+
 // 1、2、31、32{42、52}，set stable #2
 func TestBlockChain_SetStableBlockCurBranch11(t *testing.T) {
 	store.ClearData()
@@ -1201,8 +1233,8 @@ func TestBlockChain_VerifyBodyNormal(t *testing.T) {
 		gasLimit:    1000000000,
 		deputyNodes: genesis.DeputyNodes,
 		txList: []*types.Transaction{
-			makeTx(tmp, accounts[0].Address, big.NewInt(30000)),
-			makeTx(tmp, accounts[1].Address, big.NewInt(40000)),
+			makeTx(tmp, 0, accounts[0].Address, big.NewInt(30000)),
+			makeTx(tmp, 1, accounts[1].Address, big.NewInt(40000)),
 		},
 		time:   1540893799,
 		author: genesis.MinerAddress(),
@@ -1235,8 +1267,8 @@ func TestBlockChain_VerifyBlockBalanceNotEnough(t *testing.T) {
 	}
 	block := makeBlock(blockChain.db, info, false)
 	block.Txs = []*types.Transaction{
-		makeTx(tmp, accounts[0].Address, big.NewInt(30000)),
-		makeTx(tmp, accounts[1].Address, big.NewInt(40000)),
+		makeTx(tmp, 0, accounts[0].Address, big.NewInt(30000)),
+		makeTx(tmp, 1, accounts[1].Address, big.NewInt(40000)),
 	}
 	block.Header.TxRoot = types.DeriveTxsSha(block.Txs)
 	err = blockChain.Verify(block)
@@ -1263,7 +1295,7 @@ func TestBlockChain_VerifyBlockBalanceNotSign(t *testing.T) {
 	}
 	block := makeBlock(blockChain.db, info, false)
 	block.Txs = []*types.Transaction{
-		types.NewTransaction(accounts[0].Address, common.Big2, 30000, common.Big2, []byte{}, 200, 1538210398, "", ""),
+		types.NewTransaction(0, accounts[0].Address, common.Big2, 30000, common.Big2, []byte{}, 200, 1538210398, "", ""),
 	}
 	block.Header.TxRoot = types.DeriveTxsSha(block.Txs)
 	err = blockChain.Verify(block)
@@ -1289,8 +1321,8 @@ func TestBlockChain_VerifyBlockBalanceValidDeputy(t *testing.T) {
 		gasLimit:    1000000000,
 		deputyNodes: genesis.DeputyNodes,
 		txList: []*types.Transaction{
-			makeTx(tmp, accounts[0].Address, big.NewInt(30000)),
-			makeTx(tmp, accounts[1].Address, big.NewInt(40000)),
+			makeTx(tmp, 0, accounts[0].Address, big.NewInt(30000)),
+			makeTx(tmp, 1, accounts[1].Address, big.NewInt(40000)),
 		},
 		time:   1540893799,
 		author: genesis.MinerAddress(),
@@ -1320,8 +1352,8 @@ func TestBlockChain_VerifyBlockBalanceValidTx(t *testing.T) {
 		gasLimit:    1000000000,
 		deputyNodes: genesis.DeputyNodes,
 		txList: []*types.Transaction{
-			makeTx(tmp, accounts[0].Address, big.NewInt(30000)),
-			makeTx(tmp, accounts[1].Address, big.NewInt(40000)),
+			makeTx(tmp, 0, accounts[0].Address, big.NewInt(30000)),
+			makeTx(tmp, 1, accounts[1].Address, big.NewInt(40000)),
 		},
 		time:   1540893799,
 		author: genesis.MinerAddress(),
@@ -1331,3 +1363,158 @@ func TestBlockChain_VerifyBlockBalanceValidTx(t *testing.T) {
 	err = blockChain.Verify(block)
 	assert.Equal(t, err, ErrVerifyBlockFailed)
 }
+
+func TestBlockChain_reorg(t *testing.T) {
+	store.ClearData()
+	blockChain := newChain()
+
+	var dropped, included types.Transactions
+	blockChain.TxsReorg = func(d, i types.Transactions) {
+		dropped, included = d, i
+	}
+
+	// the state of old head is not exist
+	assert.Equal(t, ErrBlockNotExist, blockChain.reorg(defaultBlocks[3], defaultBlocks[1]))
+	assert.Equal(t, 0, len(dropped))
+
+	// switch back to parent
+	makeBlock(blockChain.db, defaultBlockInfos[3], true)
+	assert.NoError(t, blockChain.reorg(defaultBlocks[3], defaultBlocks[1]))
+	assert.Equal(t, types.Transactions{defaultBlocks[2].Txs[0], defaultBlocks[3].Txs[0], defaultBlocks[3].Txs[1]}, dropped)
+	assert.Equal(t, 0, len(included))
+
+	// switch to child
+	blockChain.reorg(defaultBlocks[1], defaultBlocks[3])
+	assert.Equal(t, 0, len(dropped))
+	assert.Equal(t, types.Transactions{defaultBlocks[3].Txs[0], defaultBlocks[3].Txs[1], defaultBlocks[2].Txs[0]}, included)
+
+	// the events in abandoned blocks are notified as removed
+	block2 := defaultBlocks[2]
+	event := &types.Event{Address: testAddr, Topics: []common.Hash{common.HexToHash("0x1")}, TxHash: block2.Txs[0].Hash(), BlockHash: block2.Hash(), BlockHeight: block2.Height()}
+	receipt := types.NewReceipt(block2.Txs[0], block2.Height(), 0, 21000, false)
+	receipt.Events = []*types.Event{event}
+	assert.NoError(t, blockChain.db.SetReceipts(block2.Hash(), types.Receipts{receipt}))
+	removedCh := make(chan []*types.Event, 1)
+	sub := blockChain.RemovedEventsFeed.Subscribe(removedCh)
+	defer sub.Unsubscribe()
+	blockChain.reorg(block2, defaultBlocks[1])
+	removed := <-removedCh
+	assert.Equal(t, 1, len(removed))
+	assert.Equal(t, event.TxHash, removed[0].TxHash)
+	assert.Equal(t, true, removed[0].Removed)
+	assert.Equal(t, false, event.Removed)
+
+	// the events in new blocks are notified as added
+	addedCh := make(chan []*types.Event, 1)
+	sub = blockChain.AddedEventsFeed.Subscribe(addedCh)
+	defer sub.Unsubscribe()
+	blockChain.reorg(defaultBlocks[1], block2)
+	added := <-addedCh
+	assert.Equal(t, 1, len(added))
+	assert.Equal(t, event.TxHash, added[0].TxHash)
+	assert.Equal(t, false, added[0].Removed)
+}
+
+func TestBlockChain_reorgBalance(t *testing.T) {
+	store.ClearData()
+	blockChain := newChain()
+	block1 := defaultBlocks[1]
+	block2 := defaultBlocks[2]
+	// the fork of block 2: testAddr -> defaultAccounts[1] 3
+	fork := makeBlock(blockChain.db, blockInfo{
+		parentHash: block1.Hash(),
+		height:     2,
+		author:     defaultAccounts[0],
+		txList:     []*types.Transaction{makeTransaction(testPrivate, 2, defaultAccounts[1], common.Big3, common.Big2, 1538210395, 2000000)},
+		time:       1538209759,
+		gasLimit:   20000000,
+	}, true)
+	am1 := account.NewManager(block1.Hash(), blockChain.db)
+	balance := am1.GetAccount(testAddr).GetBalance()
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(params.TxGas), common.Big2)
+
+	// switch to the fork
+	assert.NoError(t, blockChain.reorg(block2, fork))
+	assert.Equal(t, fork.Hash(), blockChain.CurrentBlock().Hash())
+	am := blockChain.AccountManager()
+	assert.Equal(t, new(big.Int).Sub(balance, new(big.Int).Add(common.Big3, fee)), am.GetAccount(testAddr).GetBalance())
+	assert.Equal(t, new(big.Int).Add(am1.GetAccount(defaultAccounts[1]).GetBalance(), common.Big3), am.GetAccount(defaultAccounts[1]).GetBalance())
+	assert.Equal(t, new(big.Int).Add(am1.GetAccount(defaultAccounts[0]).GetBalance(), fee), am.GetAccount(defaultAccounts[0]).GetBalance())
+
+	// switch back
+	assert.NoError(t, blockChain.reorg(fork, block2))
+	assert.Equal(t, block2.Hash(), blockChain.CurrentBlock().Hash())
+	am = blockChain.AccountManager()
+	assert.Equal(t, new(big.Int).Sub(balance, new(big.Int).Add(bigNumber, fee)), am.GetAccount(testAddr).GetBalance())
+	assert.Equal(t, am1.GetAccount(defaultAccounts[1]).GetBalance(), am.GetAccount(defaultAccounts[1]).GetBalance())
+
+	// the current block is not switched if rewinding failed
+	assert.Equal(t, ErrBlockNotExist, blockChain.reorg(defaultBlocks[3], fork))
+	assert.Equal(t, block2.Hash(), blockChain.CurrentBlock().Hash())
+}
+
+func TestBlockChain_rewindState(t *testing.T) {
+	store.ClearData()
+	blockChain := newChain()
+	block1 := defaultBlocks[1]
+	block2 := defaultBlocks[2]
+
+	// undo the change logs of abandoned block
+	assert.NoError(t, blockChain.rewindState(block2, block1, []*types.Block{block2}, nil))
+	// redo the change logs of new block
+	assert.NoError(t, blockChain.rewindState(block1, block2, nil, []*types.Block{block2}))
+	// the state of new head is not reached
+	assert.Equal(t, ErrRewindState, blockChain.rewindState(block2, block1, nil, nil))
+	assert.Equal(t, ErrRewindState, blockChain.rewindState(block1, block2, nil, nil))
+	// the state of old head is not exist
+	assert.Equal(t, ErrBlockNotExist, blockChain.rewindState(defaultBlocks[3], block1, []*types.Block{defaultBlocks[3], block2}, nil))
+}
+
+func TestBlockChain_GetTxByHash(t *testing.T) {
+	store.ClearData()
+	blockChain := newChain()
+
+	// stable block
+	block1 := defaultBlocks[1]
+	tx, block, index := blockChain.GetTxByHash(block1.Txs[1].Hash())
+	assert.Equal(t, block1.Txs[1].Hash(), tx.Hash())
+	assert.Equal(t, block1.Hash(), block.Hash())
+	assert.Equal(t, uint32(1), index)
+
+	// unstable block not in current chain
+	block2 := defaultBlocks[2]
+	tx, _, _ = blockChain.GetTxByHash(block2.Txs[0].Hash())
+	assert.Nil(t, tx)
+
+	// unstable block in current chain
+	blockChain.currentBlock.Store(block2)
+	tx, block, index = blockChain.GetTxByHash(block2.Txs[0].Hash())
+	assert.Equal(t, block2.Txs[0].Hash(), tx.Hash())
+	assert.Equal(t, block2.Hash(), block.Hash())
+	assert.Equal(t, uint32(0), index)
+
+	// not exist
+	tx, _, _ = blockChain.GetTxByHash(common.HexToHash("0x1"))
+	assert.Nil(t, tx)
+}
+
+func TestBlockChain_GetReceipt(t *testing.T) {
+	store.ClearData()
+	blockChain := newChain()
+
+	block1 := defaultBlocks[1]
+	assert.Nil(t, blockChain.GetReceipt(block1.Txs[1].Hash()))
+
+	receipts := types.Receipts{
+		types.NewReceipt(block1.Txs[0], block1.Height(), 0, 21000, false),
+		types.NewReceipt(block1.Txs[1], block1.Height(), 1, 21000, true),
+	}
+	receipts.SetBlockHash(block1.Hash())
+	err := blockChain.db.SetReceipts(block1.Hash(), receipts)
+	assert.NoError(t, err)
+	receipt := blockChain.GetReceipt(block1.Txs[1].Hash())
+	assert.Equal(t, block1.Txs[1].Hash(), receipt.TxHash)
+	assert.Equal(t, block1.Hash(), receipt.BlockHash)
+	assert.Equal(t, uint(1), receipt.TxIndex)
+	assert.Equal(t, types.ReceiptStatusFailed, receipt.Status)
+}
@@ -16,15 +16,21 @@ func (engine *EngineTestForChain) Seal(header *types.Header, txs []*types.Transa
 	return nil, nil
 }
 
-func (engine *EngineTestForChain) Finalize(header *types.Header, am *account.Manager) {}
+func (engine *EngineTestForChain) Finalize(header *types.Header, am *account.Manager) error {
+	return nil
+}
 
 func broadcastStableBlock(block *types.Block) {}
 
 func broadcastConfirmInfo(hash common.Hash, height uint32) {}
 
 func NewBlockChainForTest() (*BlockChain, chan *types.Block, error) {
+	return newBlockChainForTestAt(store.GetStorePath())
+}
+
+func newBlockChainForTestAt(path string) (*BlockChain, chan *types.Block, error) {
 	chainId := uint16(99)
-	db, err := store.NewCacheChain(store.GetStorePath())
+	db, err := store.NewCacheChain(path)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -56,49 +56,49 @@ var (
 		},
 		// block 1 is stable block
 		{
-			hash:        common.HexToHash("0xfabb0fff8f44b829f937070de3d8625972900867a6dce77b24c0b5ccd1d591ca"),
+			hash:        common.HexToHash("0x79d2670a4cd3aba8909d897264452b71bafe41cff7ad4a355ed79e2aee6a3d88"),
 			height:      1,
 			author:      common.HexToAddress("0x20000"),
-			versionRoot: common.HexToHash("0xfa470f96f703f04ba85d39bf7f64aa43e8b352e541fe36ea9f053336dfd1b22a"),
-			txRoot:      common.HexToHash("0x44bb706ae39d4e24df62fee83d9daa0e0a8e422e9b3f6d48243e2511cb055479"),
-			logRoot:     common.HexToHash("0xb6062a94cf5ce70ec3910072da83eca56ba584aba8273e4d0837eb03c090e473"),
+			versionRoot: common.HexToHash("0xb8b3c0b35505e8c6cb0affc4a71e36b0b6cf3d04464e2d81d37a19247a2278cd"),
+			txRoot:      common.HexToHash("0x7d6c74c68975e90020d8a6c1d177f3ebad37c44f0597351cea1ec98ec634b480"),
+			logRoot:     common.HexToHash("0x19b86fc79e4dca8eebb2879771381377926b76f1dba00b8757c70186bade786b"),
 			txList: []*types.Transaction{
 				// testAddr -> defaultAccounts[0] 1
-				signTransaction(types.NewTransaction(defaultAccounts[0], common.Big1, 2000000, common.Big2, []byte{12}, chainID, 1538210391, "aa", "aaa"), testPrivate),
+				signTransaction(types.NewTransaction(0, defaultAccounts[0], common.Big1, 2000000, common.Big2, []byte{12}, chainID, 1538210391, "aa", "aaa"), testPrivate),
 				// testAddr -> defaultAccounts[1] 1
-				makeTransaction(testPrivate, defaultAccounts[1], common.Big1, common.Big2, 1538210491, 2000000),
+				makeTransaction(testPrivate, 1, defaultAccounts[1], common.Big1, common.Big2, 1538210491, 2000000),
 			},
 			gasLimit: 20000000,
 			time:     1538209755,
 		},
 		// block 2 is not stable block
 		{
-			hash:        common.HexToHash("0x18229ab6caf0a9f2972eaa97ff4ede9189e0f6d96bbef3a972795313d92319b2"),
+			hash:        common.HexToHash("0x1b822f62603745601474fc43e1e0af93b85c3803346a4b458376b8d8016f1f89"),
 			height:      2,
 			author:      defaultAccounts[0],
-			versionRoot: common.HexToHash("0x407e1d64578f852d97ab3b5a655ece80ca1422ef68ba0145756f35a4b7fb141b"),
-			txRoot:      common.HexToHash("0xf499c76d7f3693700d1cab1618d7c62c619686578ce982375161747927117a8f"),
-			logRoot:     common.HexToHash("0x40ab1668ecddd288ded6cec4135dadb071beee4e2826640e96c416894aaa6469"),
+			versionRoot: common.HexToHash("0x15241aedcc798c6173a7d292494513b471a36cb33b8b8443801e4ffe66d112b3"),
+			txRoot:      common.HexToHash("0x469d9a107416c05587f05c1d47ffbaca8fa35269616024ce75a8a63371ee3dd0"),
+			logRoot:     common.HexToHash("0x4713899e6853193485426f9e1e4a243868e10b513ec7f15e58659ed54d683888"),
 			txList: []*types.Transaction{
 				// testAddr -> defaultAccounts[0] 2
-				makeTransaction(testPrivate, defaultAccounts[0], bigNumber, common.Big2, 1538210395, 2000000),
+				makeTransaction(testPrivate, 2, defaultAccounts[0], bigNumber, common.Big2, 1538210395, 2000000),
 			},
 			time:     1538209758,
 			gasLimit: 20000000,
 		},
 		// block 3 is not store in db
 		{
-			hash:        common.HexToHash("0xfd283c1db324237dcf224ccae7e68bca17f1f806d68a0208458188cca5f20d23"),
+			hash:        common.HexToHash("0xd7a81f2a4172c097173ea19936fdb3a60f5c0fd096113458ef909259bcdc70f3"),
 			height:      3,
 			author:      defaultAccounts[0],
-			versionRoot: common.HexToHash("0x26a8540cd0e07e18d08ad484cfb44b0fd40dc5f197678897fc80076dbfa942b4"),
-			txRoot:      common.HexToHash("0x3f4519bff066109e8b47621fc8fa8bc791abec347478cc8f0d084012fc83f76d"),
-			logRoot:     common.HexToHash("0xa92582c4c7627d130a38c94e568b8c671817fe742930636c7b0190b7f7344064"),
+			versionRoot: common.HexToHash("0xc51982b4195023f0c4dfa19723253e5b0f7676c0d2b5a203b48cc6f9b8d45680"),
+			txRoot:      common.HexToHash("0x314e655999677de11ac6041922e2677518cbe3503245f5fb03b182240127b576"),
+			logRoot:     common.HexToHash("0xd5bb82a0b0f7aa4c48e99b094b412a2ec8f987e2f3215bb207d516ffd9d9310a"),
 			txList: []*types.Transaction{
 				// testAddr -> defaultAccounts[0] 2
-				makeTransaction(testPrivate, defaultAccounts[0], common.Big2, common.Big2, 1538210398, 30000),
+				makeTransaction(testPrivate, 3, defaultAccounts[0], common.Big2, common.Big2, 1538210398, 30000),
 				// testAddr -> defaultAccounts[1] 2
-				makeTransaction(testPrivate, defaultAccounts[1], common.Big2, common.Big3, 1538210425, 30000),
+				makeTransaction(testPrivate, 4, defaultAccounts[1], common.Big2, common.Big3, 1538210425, 30000),
 			},
 			time:     1538209761,
 			gasLimit: 20000000,
@@ -194,6 +194,7 @@ func makeBlock(db protocol.ChainDB, info blockInfo, save bool) *types.Block {
 			to.SetBalance(new(big.Int).Add(to.GetBalance(), tx.Amount()))
 			from.SetBalance(new(big.Int).Sub(from.GetBalance(), cost))
 		}
+		from.SetNonce(tx.Nonce() + 1)
 		gasUsed += gas
 		salary.Add(salary, fee)
 		from.(*account.SafeAccount).AppendTx(tx.Hash())
@@ -275,12 +276,12 @@ func makeBlock(db protocol.ChainDB, info blockInfo, save bool) *types.Block {
 	return block
 }
 
-func makeTx(fromPrivate *ecdsa.PrivateKey, to common.Address, amount *big.Int) *types.Transaction {
-	return makeTransaction(fromPrivate, to, amount, common.Big1, uint64(time.Now().Unix()+300), 1000000)
+func makeTx(fromPrivate *ecdsa.PrivateKey, nonce uint64, to common.Address, amount *big.Int) *types.Transaction {
+	return makeTransaction(fromPrivate, nonce, to, amount, common.Big1, uint64(time.Now().Unix()+300), 1000000)
 }
 
-func makeTransaction(fromPrivate *ecdsa.PrivateKey, to common.Address, amount, gasPrice *big.Int, expiration uint64, gasLimit uint64) *types.Transaction {
-	tx := types.NewTransaction(to, amount, gasLimit, gasPrice, []byte{}, chainID, expiration, "", "")
+func makeTransaction(fromPrivate *ecdsa.PrivateKey, nonce uint64, to common.Address, amount, gasPrice *big.Int, expiration uint64, gasLimit uint64) *types.Transaction {
+	tx := types.NewTransaction(nonce, to, amount, gasLimit, gasPrice, []byte{}, chainID, expiration, "", "")
 	return signTransaction(tx, fromPrivate)
 }
 
@@ -0,0 +1,51 @@
+package chain
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/store/protocol"
+)
+
+var ErrInvalidChainConfig = errors.New("invalid chain config")
+
+// ChainConfigAddress is the system account which stores the chain config set in genesis
+var ChainConfigAddress = common.HexToAddress("0x1004")
+
+// chainConfigKey is the storage key of chain config in ChainConfigAddress
+var chainConfigKey = common.Hash{}
+
+// GetChainConfig returns the chain config set in genesis. It is the default config if genesis doesn't set it
+func GetChainConfig(am *account.Manager) (*params.ChainConfig, error) {
+	value, err := am.GetAccount(ChainConfigAddress).GetStorageState(chainConfigKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) == 0 {
+		return params.DefaultChainConfig, nil
+	}
+	config := new(params.ChainConfig)
+	if err := json.Unmarshal(value, config); err != nil {
+		return nil, ErrInvalidChainConfig
+	}
+	return config, nil
+}
+
+func setChainConfig(am *account.Manager, config *params.ChainConfig) error {
+	value, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return am.GetAccount(ChainConfigAddress).SetStorageState(chainConfigKey, value)
+}
+
+// LoadChainConfig reads the chain config from the state of genesis block in db
+func LoadChainConfig(db protocol.ChainDB) (*params.ChainConfig, error) {
+	genesis, err := db.GetBlockByHeight(0)
+	if err != nil {
+		return nil, err
+	}
+	return GetChainConfig(account.NewManager(genesis.Hash(), db))
+}
@@ -0,0 +1,51 @@
+package chain
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetChainConfig(t *testing.T) {
+	dpovp := loadDpovp()
+	defer dpovp.db.Close()
+
+	// default config
+	am := account.NewManager(common.Hash{}, dpovp.db)
+	config, err := GetChainConfig(am)
+	assert.NoError(t, err)
+	assert.Equal(t, params.DefaultChainConfig, config)
+	assert.False(t, config.IsIstanbul(100000000))
+	assert.False(t, config.IsStrictTime(100000000))
+
+	// the config in genesis
+	istanbulHeight, strictTimeHeight := uint32(100), uint32(0)
+	genesis := DefaultGenesisBlock()
+	genesis.Config = &params.ChainConfig{ChainID: 200, IstanbulHeight: &istanbulHeight, StrictTimeHeight: &strictTimeHeight}
+	hash, err := SetupGenesisBlock(dpovp.db, genesis)
+	assert.NoError(t, err)
+	config, err = GetChainConfig(account.NewManager(hash, dpovp.db))
+	assert.NoError(t, err)
+	assert.Equal(t, genesis.Config, config)
+	assert.False(t, config.IsIstanbul(99))
+	assert.True(t, config.IsIstanbul(100))
+	assert.True(t, config.IsStrictTime(0))
+
+	// loaded by consensus engine
+	config, err = LoadChainConfig(dpovp.db)
+	assert.NoError(t, err)
+	assert.Equal(t, genesis.Config, config)
+	assert.Equal(t, genesis.Config, dpovp.chainConfig())
+}
+
+func TestIntrinsicGas(t *testing.T) {
+	data := []byte{0, 1, 2}
+	gas, err := IntrinsicGas(data, false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, params.TxGas+params.TxDataZeroGas+2*params.TxDataNonZeroGas, gas)
+	gas, err = IntrinsicGas(data, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, params.TxGasContractCreation+params.TxDataZeroGas+2*params.TxDataNonZeroGasIstanbul, gas)
+}
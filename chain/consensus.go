@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
 	"github.com/LemoFoundationLtd/lemochain-go/common/log"
 	"github.com/LemoFoundationLtd/lemochain-go/store/protocol"
+	"sync"
 	"time"
 )
 
@@ -18,12 +20,14 @@ type Engine interface {
 
 	Seal(header *types.Header, txs []*types.Transaction, changeLog []*types.ChangeLog, events []*types.Event) (*types.Block, error)
 
-	Finalize(header *types.Header, am *account.Manager)
+	Finalize(header *types.Header, am *account.Manager) error
 }
 
 type Dpovp struct {
 	timeoutTime int64
 	db          protocol.ChainDB
+	config      *params.ChainConfig // loaded from genesis at the first time it is used
+	configLock  sync.Mutex
 }
 
 func NewDpovp(timeout int64, db protocol.ChainDB) *Dpovp {
@@ -34,6 +38,21 @@ func NewDpovp(timeout int64, db protocol.ChainDB) *Dpovp {
 	return dpovp
 }
 
+// chainConfig returns the chain config set in genesis
+func (d *Dpovp) chainConfig() *params.ChainConfig {
+	d.configLock.Lock()
+	defer d.configLock.Unlock()
+	if d.config == nil {
+		config, err := LoadChainConfig(d.db)
+		if err != nil {
+			log.Warnf("load chain config failed: %v", err)
+			return params.DefaultChainConfig
+		}
+		d.config = config
+	}
+	return d.config
+}
+
 // verifyHeaderTime verify that the block timestamp is less than the current time
 func verifyHeaderTime(block *types.Block) error {
 	header := block.Header
@@ -92,6 +111,10 @@ func (d *Dpovp) VerifyHeader(block *types.Block) error {
 		log.Errorf("verifyHeader: can't get parent block. height:%d, hash:%s", header.Height-1, header.ParentHash)
 		return ErrVerifyHeaderFailed
 	}
+	if d.chainConfig().IsStrictTime(header.Height) && header.Time < parent.Header.Time {
+		log.Errorf("verifyHeader: block time %d is earlier than parent %d", header.Time, parent.Header.Time)
+		return ErrVerifyHeaderFailed
+	}
 	if parent.Header.Height == 0 {
 		log.Debug("verifyHeader: parent block is genesis block")
 		return nil
@@ -128,16 +151,25 @@ func (d *Dpovp) Seal(header *types.Header, txs []*types.Transaction, changeLog [
 	return block, nil
 }
 
-// Finalize
-func (d *Dpovp) Finalize(header *types.Header, am *account.Manager) {
+// Finalize hands out the rewards and elects the deputy nodes. The block must be rejected if it fails
+func (d *Dpovp) Finalize(header *types.Header, am *account.Manager) error {
 	// handout rewards
 	if deputynode.Instance().TimeToHandOutRewards(header.Height) {
-		rewards := deputynode.CalcSalary(header.Height)
-		for _, item := range rewards {
-			account := am.GetAccount(item.Address)
-			balance := account.GetBalance()
-			balance.Add(balance, item.Salary)
-			account.SetBalance(balance)
+		if err := handOutRewards(header.Height, am); err != nil {
+			log.Errorf("hand out rewards failed: %v", err)
+			return err
 		}
 	}
+	// elect deputy nodes of next term in snapshot block
+	if header.Height > 0 && header.Height%deputynode.SnapshotBlockInterval == 0 {
+		nodes, err := ElectDeputyNodes(am, header.Height)
+		if err != nil {
+			log.Errorf("elect deputy nodes failed: %v", err)
+			return err
+		}
+		if len(nodes) > 0 {
+			header.DeputyRoot = types.DeriveDeputyRootSha(nodes).Bytes()
+		}
+	}
+	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
@@ -296,6 +297,28 @@ func TestDpovp_VerifyHeader01(t *testing.T) {
 	assert.Equal(t, nil, dpovp.VerifyHeader(testBlock01))
 }
 
+// TestDpovp_VerifyHeaderStrictTime 测试StrictTime分叉之后区块时间不能早于父区块
+func TestDpovp_VerifyHeaderStrictTime(t *testing.T) {
+	err := initDeputyNode(3, 0)
+	assert.NoError(t, err)
+	dpovp := loadDpovp()
+	defer store.ClearData()
+	forkHeight := uint32(2)
+	genesis := DefaultGenesisBlock()
+	genesis.Config = &params.ChainConfig{StrictTimeHeight: &forkHeight}
+	genesisHash, err := SetupGenesisBlock(dpovp.db, genesis)
+	assert.NoError(t, err)
+
+	// 分叉之前不检查
+	testBlock01, err := newTestBlock(dpovp, genesisHash, 1, common.HexToAddress(block02MinerAddress), genesis.Time-1, deputy02Privkey, true)
+	assert.NoError(t, err)
+	assert.NoError(t, dpovp.VerifyHeader(testBlock01))
+	// 分叉之后区块时间早于父区块
+	testBlock02, err := newTestBlock(dpovp, testBlock01.Hash(), 2, common.HexToAddress(block03MinerAddress), testBlock01.Time()-1, deputy03Privkey, false)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrVerifyHeaderFailed, dpovp.VerifyHeader(testBlock02))
+}
+
 // TestDpovp_VerifyHeader03 测试slot == 0,slot == 1,slot > 1的情况
 func TestDpovp_VerifyHeader02(t *testing.T) {
 	// 创建5个代理节点
@@ -376,8 +399,8 @@ func TestDpovp_Seal(t *testing.T) {
 
 	TestBlockHeader := block01.Header // 得到block01头，为生成TestBlock所用
 	txs := []*types.Transaction{
-		signTransaction(types.NewTransaction(defaultAccounts[0], common.Big1, 2000000, common.Big2, []byte{12}, chainID, 1538210391, "aa", "aaa"), testPrivate),
-		makeTransaction(testPrivate, defaultAccounts[1], common.Big1, common.Big2, 1538210491, 2000000),
+		signTransaction(types.NewTransaction(0, defaultAccounts[0], common.Big1, 2000000, common.Big2, []byte{12}, chainID, 1538210391, "aa", "aaa"), testPrivate),
+		makeTransaction(testPrivate, 1, defaultAccounts[1], common.Big1, common.Big2, 1538210491, 2000000),
 	}
 	block01.Txs = txs // 添加bock01交易
 	TestBlockChangeLog := block01.ChangeLogs
@@ -403,9 +426,9 @@ func TestDpovp_Finalize(t *testing.T) {
 	dpovp := loadDpovp()
 	am := account.NewManager(common.Hash{}, dpovp.db)
 	// 测试挖出的块高度不满足发放奖励高度的时候
-	dpovp.Finalize(&types.Header{Height: 9999}, am)
+	assert.NoError(t, dpovp.Finalize(&types.Header{Height: 9999}, am))
 	// dpovp.handOutRewards(9999)
-	dpovp.Finalize(&types.Header{Height: 19998}, am)
+	assert.NoError(t, dpovp.Finalize(&types.Header{Height: 19998}, am))
 	// dpovp.handOutRewards(19998)
 	addr1, err := common.StringToAddress(block01MinerAddress)
 	assert.NoError(t, err)
@@ -418,12 +441,12 @@ func TestDpovp_Finalize(t *testing.T) {
 	t.Log("When there is no reward,node01Balance = ", account02.GetBalance())
 	// 测试挖出的块高度满足发放奖励高度的时候
 	// dpovp.handOutRewards(11001)
-	dpovp.Finalize(&types.Header{Height: 11001}, account.NewManager(common.Hash{}, dpovp.db))
+	assert.NoError(t, dpovp.Finalize(&types.Header{Height: 11001}, account.NewManager(common.Hash{}, dpovp.db)))
 	t.Log("When it comes to giving out rewards,node01Balance = ", account01.GetBalance())
 	t.Log("When it comes to giving out rewards,node01Balance = ", account02.GetBalance())
 	// 第二轮发放奖励
 	// dpovp.handOutRewards(21001)
-	dpovp.Finalize(&types.Header{Height: 21001}, account.NewManager(common.Hash{}, dpovp.db))
+	assert.NoError(t, dpovp.Finalize(&types.Header{Height: 21001}, account.NewManager(common.Hash{}, dpovp.db)))
 	t.Log("When it comes to giving out rewards,node01Balance = ", account01.GetBalance())
 	t.Log("When it comes to giving out rewards,node01Balance = ", account02.GetBalance())
 
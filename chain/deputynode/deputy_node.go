@@ -17,6 +17,7 @@ import (
 const (
 	SnapshotBlockInterval = 100000
 	TransitionPeriod      = 1000
+	DeputyCount           = 17 // the max count of deputy nodes elected in a term
 )
 
 var (
@@ -95,10 +96,16 @@ type Manager struct {
 	lock            sync.Mutex
 }
 
-// Add 投票结束 统计结果通过add函数缓存起来
+// Add 投票结束 统计结果通过add函数缓存起来。重复添加同一高度的节点列表时替换原来的记录
 func (d *Manager) Add(height uint32, nodes DeputyNodes) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
+	for _, record := range d.DeputyNodesList {
+		if record.height == height {
+			record.nodes = nodes
+			return
+		}
+	}
 	d.DeputyNodesList = append(d.DeputyNodesList, &DeputyNodesRecord{height: height, nodes: nodes})
 }
 
@@ -131,6 +138,14 @@ func (d *Manager) getDeputiesByHeight(height uint32) DeputyNodes {
 	return nodes
 }
 
+// GetDeputiesByHeight 获取height对应的共识节点列表，未设置时返回nil
+func (d *Manager) GetDeputiesByHeight(height uint32) DeputyNodes {
+	if len(d.DeputyNodesList) == 0 {
+		return nil
+	}
+	return d.getDeputiesByHeight(height)
+}
+
 // getDeputyNodeCount 获取共识节点数量
 func (d *Manager) GetDeputiesCount() int {
 	return len(d.DeputyNodesList[0].nodes)
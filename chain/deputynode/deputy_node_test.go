@@ -146,6 +146,10 @@ func TestManager_Add(t *testing.T) {
 	ma.Add(0, deputyNodes01)
 
 	assert.Equal(t, addDeputyNodes01, ma.DeputyNodesList)
+
+	// add the same height again
+	ma.Add(0, deputyNodes01)
+	assert.Equal(t, addDeputyNodes01, ma.DeputyNodesList)
 }
 
 // TestDeputyNode_getDeputiesByHeight
@@ -1,34 +1,95 @@
 package deputynode
 
 import (
+	"errors"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
 	"math/big"
 )
 
 var (
 	minSalary = new(big.Int).SetUint64(uint64(10000000000000000)) // 0.01 lemo
+
+	ErrInvalidRewardSchedule = errors.New("invalid reward schedule")
 )
 
+// PerMille is the denominator of the rates in RewardSchedule
+const PerMille = 1000
+
+//go:generate gencodec -type RewardSchedule --field-override rewardScheduleMarshaling -out gen_reward_schedule_json.go
+
+// RewardSchedule decides the total rewards of deputy nodes in a term by height
+type RewardSchedule struct {
+	TermReward        *big.Int       `json:"termReward"        gencodec:"required"` // the total rewards of a term from genesis
+	ReductionInterval uint32         `json:"reductionInterval"`                     // the term reward is reduced every ReductionInterval blocks. 0 means never
+	ReductionRate     uint32         `json:"reductionRate"`                         // the per mille reduced in every interval. 500 means halving
+	TreasuryRate      uint32         `json:"treasuryRate"`                          // the per mille of term reward paid to treasury
+	Treasury          common.Address `json:"treasury"`                              // receives the treasury share and the rounding remainder
+}
+
+type rewardScheduleMarshaling struct {
+	TermReward        *hexutil.Big10
+	ReductionInterval hexutil.Uint32
+	ReductionRate     hexutil.Uint32
+	TreasuryRate      hexutil.Uint32
+}
+
+// DefaultRewardSchedule pays 1 lemo in every term without reduction
+var DefaultRewardSchedule = &RewardSchedule{
+	TermReward: new(big.Int).SetUint64(1000000000000000000), // 1 lemo
+}
+
+// Check checks if the rates are valid
+func (s *RewardSchedule) Check() error {
+	if s.TermReward == nil || s.TermReward.Sign() < 0 || s.ReductionRate > PerMille || s.TreasuryRate > PerMille {
+		return ErrInvalidRewardSchedule
+	}
+	if s.TreasuryRate > 0 && s.Treasury == (common.Address{}) {
+		return ErrInvalidRewardSchedule
+	}
+	return nil
+}
+
+// TotalReward returns the total rewards of the term which is paid at height
+func (s *RewardSchedule) TotalReward(height uint32) *big.Int {
+	reward := new(big.Int).Set(s.TermReward)
+	if s.ReductionInterval == 0 || s.ReductionRate == 0 {
+		return reward
+	}
+	keep := big.NewInt(int64(PerMille - s.ReductionRate))
+	for i := height / s.ReductionInterval; i > 0 && reward.Sign() > 0; i-- {
+		reward.Mul(reward, keep)
+		reward.Div(reward, big.NewInt(PerMille))
+	}
+	return reward
+}
+
 type DeputySalary struct {
 	Address common.Address
 	Salary  *big.Int
 }
 
-// CalcSalary 计算收益
-func CalcSalary(height uint32) []*DeputySalary {
+// CalcSalary 计算收益. The treasury share and the rounding remainder are paid to treasury in the last item
+func CalcSalary(height uint32, schedule *RewardSchedule) []*DeputySalary {
 	salaries := make([]*DeputySalary, 0)
 	nodes := Instance().getDeputiesByHeight(height)
 	totalVotes := new(big.Int)
 	for _, node := range nodes {
 		totalVotes.Add(totalVotes, new(big.Int).SetUint64(uint64(node.Votes)))
 	}
-	totalRewards := getTotalSalary(height)
-	// realRewards := new(big.Int)
+	totalRewards := schedule.TotalReward(height)
+	treasuryReward := new(big.Int).Mul(totalRewards, big.NewInt(int64(schedule.TreasuryRate)))
+	treasuryReward.Div(treasuryReward, big.NewInt(PerMille))
+	deputyRewards := new(big.Int).Sub(totalRewards, treasuryReward)
+
+	realRewards := new(big.Int)
 	for _, node := range nodes {
-		// n_v := new(big.Int).SetUint64(node.Votes)
+		if totalVotes.Sign() == 0 {
+			break
+		}
 		r := new(big.Int)
-		r.Mul(new(big.Int).SetUint64(uint64(node.Votes)), totalRewards)
-		r.Div(r, totalVotes) // reward = vote * totalRewards / totalVotes
+		r.Mul(new(big.Int).SetUint64(uint64(node.Votes)), deputyRewards)
+		r.Div(r, totalVotes) // reward = vote * deputyRewards / totalVotes
 		r.Div(r, minSalary)  // reward = reward / minSalary * reward
 		r.Mul(r, minSalary)
 		reward := &DeputySalary{
@@ -36,13 +97,14 @@ func CalcSalary(height uint32) []*DeputySalary {
 			Salary:  r,
 		}
 		salaries = append(salaries, reward)
-		// realRewards.Add(realRewards, r)
+		realRewards.Add(realRewards, r)
+	}
+	if schedule.Treasury != (common.Address{}) {
+		remainReward := new(big.Int).Sub(deputyRewards, realRewards)
+		treasuryReward.Add(treasuryReward, remainReward)
+		if treasuryReward.Sign() > 0 {
+			salaries = append(salaries, &DeputySalary{Address: schedule.Treasury, Salary: treasuryReward})
+		}
 	}
-	// remainReward := totalRewards.Sub(totalRewards, realRewards)
 	return salaries
 }
-
-// getTotalSalary 获取当前轮总奖励 todo
-func getTotalSalary(height uint32) *big.Int {
-	return new(big.Int).SetUint64(1000000000000000000) // 1 lemo
-}
@@ -0,0 +1,57 @@
+package deputynode
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+)
+
+func lemo(cent int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(cent), minSalary)
+}
+
+func TestRewardSchedule_Check(t *testing.T) {
+	assert.NoError(t, DefaultRewardSchedule.Check())
+	assert.Equal(t, ErrInvalidRewardSchedule, (&RewardSchedule{}).Check())
+	assert.Equal(t, ErrInvalidRewardSchedule, (&RewardSchedule{TermReward: common.Big1, ReductionRate: 1001}).Check())
+	// treasury is required
+	assert.Equal(t, ErrInvalidRewardSchedule, (&RewardSchedule{TermReward: common.Big1, TreasuryRate: 100}).Check())
+	assert.NoError(t, (&RewardSchedule{TermReward: common.Big1, TreasuryRate: 100, Treasury: common.HexToAddress("0x99")}).Check())
+}
+
+func TestRewardSchedule_TotalReward(t *testing.T) {
+	schedule := &RewardSchedule{TermReward: big.NewInt(1000), ReductionInterval: 100, ReductionRate: 500}
+	assert.Equal(t, big.NewInt(1000), schedule.TotalReward(99))
+	assert.Equal(t, big.NewInt(500), schedule.TotalReward(100))
+	assert.Equal(t, big.NewInt(250), schedule.TotalReward(250))
+	assert.Equal(t, 0, schedule.TotalReward(10000).Sign())
+	// never reduce
+	assert.Equal(t, big.NewInt(1000), (&RewardSchedule{TermReward: big.NewInt(1000)}).TotalReward(10000))
+	assert.Equal(t, big.NewInt(1000), schedule.TermReward)
+}
+
+func TestCalcSalary(t *testing.T) {
+	ma := Instance()
+	ma.Clear()
+	nodes, err := deputyNodes(2)
+	assert.NoError(t, err)
+	ma.Add(0, nodes)
+
+	// without treasury, the remainder is not paid
+	salaries := CalcSalary(1001, DefaultRewardSchedule)
+	assert.Equal(t, 2, len(salaries))
+	assert.Equal(t, lemo(52), salaries[0].Salary)
+	assert.Equal(t, lemo(47), salaries[1].Salary)
+
+	treasury := common.HexToAddress("0x99")
+	schedule := &RewardSchedule{TermReward: lemo(100), TreasuryRate: 100, Treasury: treasury}
+	salaries = CalcSalary(1001, schedule)
+	assert.Equal(t, 3, len(salaries))
+	assert.Equal(t, nodes[0].MinerAddress, salaries[0].Address)
+	assert.Equal(t, lemo(46), salaries[0].Salary)
+	assert.Equal(t, lemo(43), salaries[1].Salary)
+	// treasury share and rounding remainder
+	assert.Equal(t, treasury, salaries[2].Address)
+	assert.Equal(t, lemo(11), salaries[2].Salary)
+}
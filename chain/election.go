@@ -0,0 +1,267 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"math"
+	"math/big"
+	"net"
+	"sort"
+)
+
+var (
+	ErrElectionTxAmount     = errors.New("election transaction can't transfer value")
+	ErrCandidateNotExist    = errors.New("the candidate is not registered")
+	ErrAlreadyVoted         = errors.New("already voted for the candidate")
+	ErrInvalidCandidateList = errors.New("invalid candidate list")
+	ErrNoCandidate          = errors.New("vote transaction has no candidate")
+)
+
+// CandidateListAddress is the system account which stores the addresses of all registered candidates and their voters
+var CandidateListAddress = common.HexToAddress("0x1001")
+
+// candidateListKey is the storage key of candidate list in CandidateListAddress
+var candidateListKey = common.Hash{}
+
+// MinDeputyVotes is the min balance sum of voters for a candidate to be elected. 5 million LEMO
+var MinDeputyVotes, _ = new(big.Int).SetString("5000000000000000000000000", 10)
+
+// votesUnit is the unit of deputy node votes. 1 LEMO
+var votesUnit = big.NewInt(1000000000000000000)
+
+// candidateVotersKey is the storage key of the voter list of a candidate in CandidateListAddress
+func candidateVotersKey(candidate common.Address) common.Hash {
+	return crypto.Keccak256Hash([]byte("voters"), candidate.Bytes())
+}
+
+// applyVoteTx moves the vote of sender from the old candidate to the candidate in tx. It returns the candidate address
+func (p *TxProcessor) applyVoteTx(voter types.AccountAccessor, tx *types.Transaction) (common.Address, error) {
+	if tx.To() == nil {
+		return CandidateListAddress, ErrNoCandidate
+	}
+	candidateAddr := *tx.To()
+	if tx.Amount().Sign() != 0 {
+		return candidateAddr, ErrElectionTxAmount
+	}
+	candidate := p.am.GetAccount(candidateAddr)
+	profile := candidate.GetCandidateProfile()
+	if profile == nil || !profile.IsCandidate {
+		return candidateAddr, ErrCandidateNotExist
+	}
+	oldCandidateAddr := voter.GetVoteFor()
+	if oldCandidateAddr == candidateAddr {
+		return candidateAddr, ErrAlreadyVoted
+	}
+	if oldCandidateAddr != (common.Address{}) {
+		oldCandidate := p.am.GetAccount(oldCandidateAddr)
+		// the votes of slashed candidate have been cut
+		if oldCandidate.GetVotes().Sign() > 0 {
+			oldCandidate.SetVotes(new(big.Int).Sub(oldCandidate.GetVotes(), common.Big1))
+		}
+		if err := removeVoter(p.am, oldCandidateAddr, voter.GetAddress()); err != nil {
+			return candidateAddr, err
+		}
+	}
+	if err := addVoter(p.am, candidateAddr, voter.GetAddress()); err != nil {
+		return candidateAddr, err
+	}
+	candidate.SetVotes(new(big.Int).Add(candidate.GetVotes(), common.Big1))
+	voter.SetVoteFor(candidateAddr)
+	return candidateAddr, nil
+}
+
+// applyRegisterTx sets the candidate profile of sender, and records the sender in candidate list if it is a new candidate
+func (p *TxProcessor) applyRegisterTx(sender types.AccountAccessor, tx *types.Transaction) error {
+	if tx.Amount().Sign() != 0 {
+		return ErrElectionTxAmount
+	}
+	profile := new(types.CandidateProfile)
+	if err := rlp.DecodeBytes(tx.Data(), profile); err != nil {
+		return types.ErrInvalidCandidateProfile
+	}
+	if err := profile.Check(); err != nil {
+		return err
+	}
+	if sender.GetCandidateProfile() == nil {
+		candidates, err := getCandidateList(p.am)
+		if err != nil {
+			return err
+		}
+		if err := setCandidateList(p.am, append(candidates, sender.GetAddress())); err != nil {
+			return err
+		}
+	}
+	sender.SetCandidateProfile(profile)
+	return nil
+}
+
+// getCandidateList returns the addresses of all accounts which have ever registered as candidate
+func getCandidateList(am *account.Manager) ([]common.Address, error) {
+	value, err := am.GetAccount(CandidateListAddress).GetStorageState(candidateListKey)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []common.Address
+	if len(value) == 0 {
+		return candidates, nil
+	}
+	if err := rlp.DecodeBytes(value, &candidates); err != nil {
+		return nil, ErrInvalidCandidateList
+	}
+	return candidates, nil
+}
+
+func setCandidateList(am *account.Manager, candidates []common.Address) error {
+	return setAddressList(am, candidateListKey, candidates)
+}
+
+// getVoters returns the addresses of the accounts which vote for the candidate
+func getVoters(am *account.Manager, candidate common.Address) ([]common.Address, error) {
+	value, err := am.GetAccount(CandidateListAddress).GetStorageState(candidateVotersKey(candidate))
+	if err != nil {
+		return nil, err
+	}
+	var voters []common.Address
+	if len(value) == 0 {
+		return voters, nil
+	}
+	if err := rlp.DecodeBytes(value, &voters); err != nil {
+		return nil, ErrInvalidCandidateList
+	}
+	return voters, nil
+}
+
+func addVoter(am *account.Manager, candidate, voter common.Address) error {
+	voters, err := getVoters(am, candidate)
+	if err != nil {
+		return err
+	}
+	return setAddressList(am, candidateVotersKey(candidate), append(voters, voter))
+}
+
+func removeVoter(am *account.Manager, candidate, voter common.Address) error {
+	voters, err := getVoters(am, candidate)
+	if err != nil {
+		return err
+	}
+	for i, addr := range voters {
+		if addr == voter {
+			voters = append(voters[:i], voters[i+1:]...)
+			break
+		}
+	}
+	return setAddressList(am, candidateVotersKey(candidate), voters)
+}
+
+func setAddressList(am *account.Manager, key common.Hash, addresses []common.Address) error {
+	value, err := rlp.EncodeToBytes(addresses)
+	if err != nil {
+		return err
+	}
+	return am.GetAccount(CandidateListAddress).SetStorageState(key, value)
+}
+
+// setGenesisCandidates registers the genesis deputy nodes as candidates, so that they can be voted for
+func setGenesisCandidates(am *account.Manager, nodes deputynode.DeputyNodes) error {
+	addresses := make([]common.Address, 0, len(nodes))
+	for _, node := range nodes {
+		am.GetAccount(node.MinerAddress).SetCandidateProfile(&types.CandidateProfile{
+			IsCandidate:  true,
+			MinerAddress: node.MinerAddress,
+			NodeID:       common.CopyBytes(node.NodeID),
+			Host:         node.IP.String(),
+			Port:         node.Port,
+		})
+		addresses = append(addresses, node.MinerAddress)
+	}
+	return setCandidateList(am, addresses)
+}
+
+// candidateStake sums the balances of the voters of candidate. So the votes can't be bought by creating accounts
+func candidateStake(am *account.Manager, candidate common.Address) (*big.Int, error) {
+	voters, err := getVoters(am, candidate)
+	if err != nil {
+		return nil, err
+	}
+	stake := new(big.Int)
+	for _, voter := range voters {
+		stake.Add(stake, am.GetAccount(voter).GetBalance())
+	}
+	return stake, nil
+}
+
+// ElectDeputyNodes sorts the candidates by the balances of their voters, and returns the top ones as the deputy nodes of next term.
+// It returns nothing to keep the current deputy nodes if the qualified candidates are less than 2/3 of current deputy nodes
+func ElectDeputyNodes(am *account.Manager, height uint32) (deputynode.DeputyNodes, error) {
+	addresses, err := getCandidateList(am)
+	if err != nil {
+		return nil, err
+	}
+	type candidate struct {
+		address common.Address
+		votes   *big.Int
+		profile *types.CandidateProfile
+	}
+	candidates := make([]candidate, 0, len(addresses))
+	for _, addr := range addresses {
+		acc := am.GetAccount(addr)
+		profile := acc.GetCandidateProfile()
+		if profile == nil || !profile.IsCandidate {
+			continue
+		}
+		slashed, err := isSlashed(am.GetAccount(EvidenceAddress), slashedNodeKey(profile.NodeID))
+		if err != nil {
+			return nil, err
+		}
+		if slashed {
+			continue
+		}
+		votes, err := candidateStake(am, addr)
+		if err != nil {
+			return nil, err
+		}
+		if votes.Cmp(MinDeputyVotes) < 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{addr, votes, profile})
+	}
+	quorum := len(deputynode.Instance().GetDeputiesByHeight(height))*2/3 + 1
+	if len(candidates) < quorum {
+		log.Warnf("Keep the current deputy nodes. There are only %d qualified candidates at height %d", len(candidates), height)
+		return nil, nil
+	}
+	// more votes first. The smaller address wins if the votes are same
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if cmp := candidates[i].votes.Cmp(candidates[j].votes); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(candidates[i].address[:], candidates[j].address[:]) < 0
+	})
+	if len(candidates) > deputynode.DeputyCount {
+		candidates = candidates[:deputynode.DeputyCount]
+	}
+
+	nodes := make(deputynode.DeputyNodes, 0, len(candidates))
+	for i, c := range candidates {
+		votes := uint32(math.MaxUint32)
+		if lemo := new(big.Int).Div(c.votes, votesUnit); lemo.IsUint64() && lemo.Uint64() < math.MaxUint32 {
+			votes = uint32(lemo.Uint64())
+		}
+		nodes = append(nodes, &deputynode.DeputyNode{
+			MinerAddress: c.profile.MinerAddress,
+			NodeID:       common.CopyBytes(c.profile.NodeID),
+			IP:           net.ParseIP(c.profile.Host),
+			Port:         c.profile.Port,
+			Rank:         uint32(i),
+			Votes:        votes,
+		})
+	}
+	return nodes, nil
+}
@@ -0,0 +1,179 @@
+package chain
+
+import (
+	"encoding/json"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func makeRegisterTx(nonce uint64, profile *types.CandidateProfile) *types.Transaction {
+	tx, err := types.NewRegisterTransaction(nonce, profile, 1000000, common.Big1, chainID, uint64(time.Now().Unix()+300), "")
+	if err != nil {
+		panic(err)
+	}
+	return signTransaction(tx, testPrivate)
+}
+
+func makeVoteTx(nonce uint64, candidate common.Address) *types.Transaction {
+	return signTransaction(types.NewVoteTransaction(nonce, candidate, 1000000, common.Big1, chainID, uint64(time.Now().Unix()+300), ""), testPrivate)
+}
+
+// makeNoCandidateVoteTx crafts a vote transaction without recipient, which can't be created by NewVoteTransaction
+func makeNoCandidateVoteTx(nonce uint64) *types.Transaction {
+	raw, err := json.Marshal(makeVoteTx(nonce, common.Address{}))
+	if err != nil {
+		panic(err)
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		panic(err)
+	}
+	delete(fields, "to")
+	if raw, err = json.Marshal(fields); err != nil {
+		panic(err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalJSON(raw); err != nil {
+		panic(err)
+	}
+	return signTransaction(tx, testPrivate)
+}
+
+func TestTxProcessor_election(t *testing.T) {
+	store.ClearData()
+	p := NewTxProcessor(newChain())
+	profile := &types.CandidateProfile{
+		IsCandidate:  true,
+		MinerAddress: defaultAccounts[0],
+		NodeID:       common.FromHex("0x5e3600755f9b512a65603b38e30885c98cbac70259c3235c9b3f42ee563b480edea351ba0ff5748a638fe0aeff5d845bf37a3b437831871b48fd32f33cd9a3c0"),
+		Host:         "127.0.0.1",
+		Port:         7001,
+	}
+	txs := types.Transactions{
+		makeRegisterTx(2, profile),
+		makeVoteTx(3, testAddr),
+		// vote for the same candidate again
+		makeVoteTx(4, testAddr),
+		// vote for an account which is not candidate
+		makeVoteTx(5, defaultAccounts[1]),
+		// invalid profile
+		makeRegisterTx(6, &types.CandidateProfile{IsCandidate: true}),
+		// no candidate
+		makeNoCandidateVoteTx(7),
+	}
+	header := &types.Header{
+		ParentHash:   defaultBlocks[1].Hash(),
+		MinerAddress: defaultBlocks[1].MinerAddress(),
+		Height:       2,
+		GasLimit:     defaultBlocks[1].GasLimit(),
+		Time:         defaultBlocks[1].Time(),
+	}
+	_, selectedTxs, invalidTxs, receipts, err := p.ApplyTxs(header, txs)
+	assert.NoError(t, err)
+	assert.Equal(t, len(txs), len(selectedTxs))
+	assert.Equal(t, 0, len(invalidTxs))
+	expectedStatus := []uint64{types.ReceiptStatusSuccessful, types.ReceiptStatusSuccessful, types.ReceiptStatusFailed, types.ReceiptStatusFailed, types.ReceiptStatusFailed, types.ReceiptStatusFailed}
+	for i, receipt := range receipts {
+		assert.Equal(t, expectedStatus[i], receipt.Status, "index=%d", i)
+	}
+
+	candidate := p.am.GetAccount(testAddr)
+	assert.Equal(t, profile, candidate.GetCandidateProfile())
+	assert.Equal(t, testAddr, candidate.GetVoteFor())
+	assert.Equal(t, big.NewInt(1), candidate.GetVotes())
+	list, err := getCandidateList(p.am)
+	assert.NoError(t, err)
+	assert.Equal(t, []common.Address{testAddr}, list)
+	voters, err := getVoters(p.am, testAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, []common.Address{testAddr}, voters)
+
+	nodes := deputynode.Instance().DeputyNodesList
+	defer func() { deputynode.Instance().DeputyNodesList = nodes }()
+	deputynode.Instance().Clear()
+	deputynode.Instance().Add(0, DefaultDeputyNodes[:1])
+	// not enough votes
+	elected, err := ElectDeputyNodes(p.am, deputynode.SnapshotBlockInterval)
+	assert.NoError(t, err)
+	assert.Empty(t, elected)
+	candidate.SetBalance(MinDeputyVotes)
+	elected, err = ElectDeputyNodes(p.am, deputynode.SnapshotBlockInterval)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(elected))
+	assert.Equal(t, profile.MinerAddress, elected[0].MinerAddress)
+	assert.Equal(t, []byte(profile.NodeID), elected[0].NodeID)
+	assert.Equal(t, "127.0.0.1", elected[0].IP.String())
+	assert.Equal(t, uint32(7001), elected[0].Port)
+	assert.Equal(t, uint32(0), elected[0].Rank)
+	assert.Equal(t, uint32(5000000), elected[0].Votes)
+
+	// the deputy root is set in snapshot block only
+	engine := NewDpovp(10*1000, p.chain.db)
+	snapshotHeader := &types.Header{Height: deputynode.SnapshotBlockInterval}
+	assert.NoError(t, engine.Finalize(snapshotHeader, p.am))
+	assert.Equal(t, types.DeriveDeputyRootSha(elected).Bytes(), snapshotHeader.DeputyRoot)
+	normalHeader := &types.Header{Height: deputynode.SnapshotBlockInterval + 1}
+	assert.NoError(t, engine.Finalize(normalHeader, p.am))
+	assert.Empty(t, normalHeader.DeputyRoot)
+
+	// the block can't be finalized if election failed
+	err = p.am.GetAccount(CandidateListAddress).SetStorageState(candidateListKey, []byte{0x12, 0x34})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrInvalidCandidateList, engine.Finalize(&types.Header{Height: deputynode.SnapshotBlockInterval}, p.am))
+}
+
+func TestElectDeputyNodes_order(t *testing.T) {
+	store.ClearData()
+	p := NewTxProcessor(newChain())
+	p.am.Reset(defaultBlocks[1].Hash())
+	nodes := deputynode.Instance().DeputyNodesList
+	defer func() { deputynode.Instance().DeputyNodesList = nodes }()
+	deputynode.Instance().Clear()
+	deputynode.Instance().Add(0, DefaultDeputyNodes[:5])
+
+	addresses := []common.Address{common.HexToAddress("0x03"), common.HexToAddress("0x02"), common.HexToAddress("0x01"), common.HexToAddress("0x04"), common.HexToAddress("0x05")}
+	// the balances of voters in million LEMO
+	votes := [][]int64{{5}, {2, 3}, {6}, {9}, {1, 1}}
+	for i, addr := range addresses {
+		account := p.am.GetAccount(addr)
+		account.SetCandidateProfile(&types.CandidateProfile{IsCandidate: i != 3, MinerAddress: addr})
+		for j, balance := range votes[i] {
+			voter := common.BigToAddress(big.NewInt(int64(0x100*(i+1) + j)))
+			p.am.GetAccount(voter).SetBalance(new(big.Int).Mul(big.NewInt(balance), new(big.Int).Div(MinDeputyVotes, big.NewInt(5))))
+			assert.NoError(t, addVoter(p.am, addr, voter))
+		}
+	}
+	assert.NoError(t, setCandidateList(p.am, addresses))
+
+	// 0x04 has quit the election, and 0x05 has not enough votes. So there are less than 2/3 of 5 deputy nodes
+	elected, err := ElectDeputyNodes(p.am, deputynode.SnapshotBlockInterval)
+	assert.NoError(t, err)
+	assert.Empty(t, elected)
+
+	deputynode.Instance().Clear()
+	deputynode.Instance().Add(0, DefaultDeputyNodes[:3])
+	elected, err = ElectDeputyNodes(p.am, deputynode.SnapshotBlockInterval)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(elected))
+	assert.Equal(t, addresses[2], elected[0].MinerAddress)
+	assert.Equal(t, addresses[1], elected[1].MinerAddress)
+	assert.Equal(t, addresses[0], elected[2].MinerAddress)
+	assert.Equal(t, uint32(2), elected[2].Rank)
+	assert.Equal(t, uint32(5000000), elected[2].Votes)
+
+	// the voter moves its balance to another candidate
+	assert.NoError(t, removeVoter(p.am, addresses[2], common.BigToAddress(big.NewInt(0x300))))
+	assert.NoError(t, addVoter(p.am, addresses[0], common.BigToAddress(big.NewInt(0x300))))
+	elected, err = ElectDeputyNodes(p.am, deputynode.SnapshotBlockInterval)
+	assert.NoError(t, err)
+	assert.Empty(t, elected)
+	voters, err := getVoters(p.am, addresses[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []common.Address{common.BigToAddress(big.NewInt(0x100)), common.BigToAddress(big.NewInt(0x300))}, voters)
+}
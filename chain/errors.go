@@ -14,4 +14,5 @@ var (
 	ErrSetConfirmInfoToDB            = errors.New("set confirm info to db error")
 	ErrSetStableBlockToDB            = errors.New("set stable block to db error")
 	ErrStableHeightLargerThanCurrent = errors.New("stable block's height is larger than current block")
+	ErrRewindState                   = errors.New("the rewound account state doesn't match the version root of new fork")
 )
@@ -0,0 +1,337 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"math/big"
+	"sync"
+	"time"
+)
+
+var (
+	ErrEvidenceTxAmount = errors.New("evidence transaction can't transfer value")
+	ErrNotDeputyNode    = errors.New("the offender is not a deputy node at the height of evidence")
+	ErrAlreadySlashed   = errors.New("the deputy node has been slashed")
+	ErrEvidenceReported = errors.New("the offender has been reported by another transaction in pool")
+	ErrEvidenceExpired  = errors.New("the evidence is too old")
+)
+
+// EvidenceAddress is the system account which records the slashed deputy nodes and emits the events of slashing
+var EvidenceAddress = common.HexToAddress("0x1003")
+
+// SlashEventTopic is the first topic of slash event. The second topic is the miner address of offender, and the data is
+// its node id
+var SlashEventTopic = crypto.Keccak256Hash([]byte("Slash(address,bytes)"))
+
+// maxSignRecordAge is how many heights of signatures are kept to find the equivocation. The older evidences are not accepted
+const maxSignRecordAge = 1024
+
+// slashedNodeKey is the storage key in EvidenceAddress to record a slashed node id
+func slashedNodeKey(nodeID []byte) common.Hash {
+	return crypto.Keccak256Hash(nodeID)
+}
+
+// slashedMinerKey is the storage key in EvidenceAddress to record the miner address of a slashed node
+func slashedMinerKey(miner common.Address) common.Hash {
+	return crypto.Keccak256Hash(miner[:], []byte("miner"))
+}
+
+// isSlashed reports whether the key is recorded in evidenceAccount
+func isSlashed(evidenceAccount types.AccountAccessor, key common.Hash) (bool, error) {
+	value, err := evidenceAccount.GetStorageState(key)
+	if err != nil {
+		return false, err
+	}
+	return len(value) > 0, nil
+}
+
+// VerifyEvidenceTx returns the offender of the evidence transaction which is packed at height. The offender must be a
+// deputy node at the height of evidence, and the evidence must be in recent maxSignRecordAge heights
+func VerifyEvidenceTx(tx *types.Transaction, height uint32) (*deputynode.DeputyNode, error) {
+	if tx.Amount().Sign() != 0 {
+		return nil, ErrEvidenceTxAmount
+	}
+	evidence := new(types.Evidence)
+	if err := rlp.DecodeBytes(tx.Data(), evidence); err != nil {
+		return nil, types.ErrInvalidEvidence
+	}
+	nodeID, err := evidence.Offender()
+	if err != nil {
+		return nil, err
+	}
+	if evidence.Height() > height {
+		return nil, types.ErrInvalidEvidence
+	}
+	if evidence.Height()+maxSignRecordAge < height {
+		return nil, ErrEvidenceExpired
+	}
+	node := deputynode.Instance().GetDeputyByNodeID(evidence.Height(), nodeID)
+	if node == nil {
+		return nil, ErrNotDeputyNode
+	}
+	return node, nil
+}
+
+// checkEvidenceTx returns the offender of the evidence transaction which is packed at height. The evidence transaction
+// is free, so it is invalid unless the offender is a deputy node which has not been slashed in evidenceAccount
+func checkEvidenceTx(tx *types.Transaction, height uint32, evidenceAccount types.AccountAccessor) (*deputynode.DeputyNode, error) {
+	node, err := VerifyEvidenceTx(tx, height)
+	if err != nil {
+		return nil, err
+	}
+	slashed, err := isSlashed(evidenceAccount, slashedNodeKey(node.NodeID))
+	if err != nil {
+		return nil, err
+	}
+	if slashed {
+		return nil, ErrAlreadySlashed
+	}
+	return node, nil
+}
+
+// slash records the deputy node as slashed. It cuts the offender's votes and salary, and removes it from the election
+// of next term
+func slash(am *account.Manager, node *deputynode.DeputyNode, height uint32) error {
+	slashed, err := isSlashed(am.GetAccount(EvidenceAddress), slashedNodeKey(node.NodeID))
+	if err != nil {
+		return err
+	}
+	if slashed {
+		return ErrAlreadySlashed
+	}
+	value, err := rlp.EncodeToBytes(height)
+	if err != nil {
+		return err
+	}
+	evidenceAccount := am.GetAccount(EvidenceAddress)
+	if err := evidenceAccount.SetStorageState(slashedNodeKey(node.NodeID), value); err != nil {
+		return err
+	}
+	if err := evidenceAccount.SetStorageState(slashedMinerKey(node.MinerAddress), value); err != nil {
+		return err
+	}
+	candidates, err := getCandidateList(am)
+	if err != nil {
+		return err
+	}
+	for _, addr := range candidates {
+		candidate := am.GetAccount(addr)
+		profile := candidate.GetCandidateProfile()
+		if profile == nil || !bytes.Equal(profile.NodeID, node.NodeID) {
+			continue
+		}
+		profile = profile.Copy()
+		profile.IsCandidate = false
+		candidate.SetCandidateProfile(profile)
+		candidate.SetVotes(new(big.Int))
+	}
+	am.AddEvent(&types.Event{
+		Address:     EvidenceAddress,
+		Topics:      []common.Hash{SlashEventTopic, node.MinerAddress.Hash()},
+		Data:        common.CopyBytes(node.NodeID),
+		BlockHeight: height,
+	})
+	log.Warnf("Slash deputy node. height: %d, miner: %s, node id: %s", height, node.MinerAddress.String(), common.ToHex(node.NodeID))
+	return nil
+}
+
+// NewEvidenceTx creates a transaction signed by key to report the evidence. It costs no gas fee, so any deputy node
+// can report without balance
+func NewEvidenceTx(chainID uint16, nonce uint64, evidence *types.Evidence, key *ecdsa.PrivateKey) (*types.Transaction, error) {
+	data, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		return nil, err
+	}
+	// the gas before Istanbul fork is enough at any height
+	gas, err := IntrinsicGas(data, false, false)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := types.NewEvidenceTransaction(nonce, evidence, gas, new(big.Int), chainID, uint64(time.Now().Unix())+types.DefaultTTTL, "")
+	if err != nil {
+		return nil, err
+	}
+	return types.SignTx(tx, types.MakeSigner(), key)
+}
+
+// signRecord is a signature of deputy node on block hash
+type signRecord struct {
+	header *types.Header
+	sign   types.SignData
+}
+
+// EvidencePool records the signatures of deputy nodes on recent blocks, so that the deputy node who signs two different
+// blocks at the same height is found
+type EvidencePool struct {
+	signs     map[uint32]map[string]*signRecord // height -> node id -> the first signature
+	selfSigns map[uint32]common.Hash            // height -> the block hash signed by self node
+	evidences map[common.Hash]*types.Evidence   // the recent evidences found or received, so that they are not reported again
+	lock      sync.Mutex
+}
+
+func NewEvidencePool() *EvidencePool {
+	return &EvidencePool{
+		signs:     make(map[uint32]map[string]*signRecord),
+		selfSigns: make(map[uint32]common.Hash),
+		evidences: make(map[common.Hash]*types.Evidence),
+	}
+}
+
+// record saves the signature of node. It returns the evidence if the node has signed another block at the same height
+func (pool *EvidencePool) record(nodeID []byte, header *types.Header, sign types.SignData) *types.Evidence {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	records, ok := pool.signs[header.Height]
+	if !ok {
+		records = make(map[string]*signRecord)
+		pool.signs[header.Height] = records
+		pool.prune(header.Height)
+	}
+	first, ok := records[string(nodeID)]
+	if !ok {
+		records[string(nodeID)] = &signRecord{header: header, sign: sign}
+		return nil
+	}
+	if first.header.Hash() == header.Hash() {
+		return nil
+	}
+	return types.NewEvidence(first.header, first.sign, header, sign)
+}
+
+// trySign records the block hash which is going to be signed by self node. It returns false if self node has signed
+// another block at the same height
+func (pool *EvidencePool) trySign(height uint32, hash common.Hash) bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if signed, ok := pool.selfSigns[height]; ok {
+		return signed == hash
+	}
+	pool.selfSigns[height] = hash
+	pool.prune(height)
+	return true
+}
+
+// prune removes the signatures which are too old
+func (pool *EvidencePool) prune(height uint32) {
+	if height < maxSignRecordAge {
+		return
+	}
+	for h := range pool.signs {
+		if h < height-maxSignRecordAge {
+			delete(pool.signs, h)
+		}
+	}
+	for h := range pool.selfSigns {
+		if h < height-maxSignRecordAge {
+			delete(pool.selfSigns, h)
+		}
+	}
+}
+
+// add saves the evidence. It returns false if the evidence exists
+func (pool *EvidencePool) add(evidence *types.Evidence) bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	hash := evidence.Hash()
+	for h, e := range pool.evidences {
+		if e.Height()+maxSignRecordAge < evidence.Height() {
+			delete(pool.evidences, h)
+		}
+	}
+	if _, ok := pool.evidences[hash]; ok {
+		return false
+	}
+	pool.evidences[hash] = evidence
+	return true
+}
+
+// recordSign records the signature of deputy node, and adds the evidence if the node has signed another block at the
+// same height
+func (bc *BlockChain) recordSign(header *types.Header, sign types.SignData) {
+	hash := header.Hash()
+	pubKey, err := crypto.Ecrecover(hash[:], sign[:])
+	if err != nil {
+		return
+	}
+	if deputynode.Instance().GetDeputyByNodeID(header.Height, pubKey[1:]) == nil {
+		return
+	}
+	if evidence := bc.evidencePool.record(pubKey[1:], header, sign); evidence != nil {
+		if err := bc.AddEvidence(evidence); err != nil {
+			log.Debugf("Add evidence failed: %v", err)
+		}
+	}
+}
+
+// RecordSignedBlock records the signature of the miner and the confirms in block
+func (bc *BlockChain) RecordSignedBlock(block *types.Block) {
+	var sign types.SignData
+	copy(sign[:], block.Header.SignData)
+	bc.recordSign(block.Header, sign)
+	for _, confirm := range block.Confirms {
+		bc.recordSign(block.Header, confirm)
+	}
+}
+
+// AddEvidence verifies the evidence found or received from other nodes, and sends it to EvidenceFeed if it is new
+func (bc *BlockChain) AddEvidence(evidence *types.Evidence) error {
+	nodeID, err := evidence.Offender()
+	if err != nil {
+		return err
+	}
+	if evidence.Height()+maxSignRecordAge < bc.CurrentBlock().Height() {
+		return ErrEvidenceExpired
+	}
+	node := deputynode.Instance().GetDeputyByNodeID(evidence.Height(), nodeID)
+	if node == nil {
+		return ErrNotDeputyNode
+	}
+	if !bc.evidencePool.add(evidence) {
+		return nil
+	}
+	log.Warnf("Found equivocation of deputy node. height: %d, miner: %s", evidence.Height(), node.MinerAddress.String())
+	bc.EvidenceFeed.Send(evidence)
+	return nil
+}
+
+// TrySign must be called before self node signs a block. It returns false if self node has signed another block at the
+// same height, so that self node never equivocates
+func (bc *BlockChain) TrySign(height uint32, hash common.Hash) bool {
+	if !bc.evidencePool.trySign(height, hash) {
+		return false
+	}
+	// the record is saved before signing, so that self node doesn't equivocate after restart
+	if err := bc.db.SetSelfSign(height, hash); err != nil {
+		log.Errorf("Can't save self signature. height: %d, err: %v", height, err)
+		return false
+	}
+	if height > maxSignRecordAge {
+		if err := bc.db.DelSelfSign(height - maxSignRecordAge - 1); err != nil {
+			log.Debugf("Can't delete old self signature. height: %d, err: %v", height-maxSignRecordAge-1, err)
+		}
+	}
+	return true
+}
+
+// loadSelfSigns loads the recent block hashes signed by self node before restart
+func (bc *BlockChain) loadSelfSigns() {
+	current := bc.CurrentBlock().Height()
+	from := uint32(0)
+	if current > maxSignRecordAge {
+		from = current - maxSignRecordAge
+	}
+	// the mined block may be not saved
+	for height := from; height <= current+maxSignRecordAge; height++ {
+		if hash, err := bc.db.GetSelfSign(height); err == nil {
+			bc.evidencePool.trySign(height, hash)
+		}
+	}
+}
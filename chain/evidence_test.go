@@ -0,0 +1,180 @@
+package chain
+
+import (
+	"crypto/ecdsa"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/flag"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+)
+
+// makeTestEvidence creates an evidence that the key signed two blocks at the height
+func makeTestEvidence(key *ecdsa.PrivateKey, height uint32) *types.Evidence {
+	headerA := &types.Header{Height: height, Time: 1}
+	headerB := &types.Header{Height: height, Time: 2}
+	return types.NewEvidence(headerA, signTestHeader(headerA, key), headerB, signTestHeader(headerB, key))
+}
+
+func signTestHeader(header *types.Header, key *ecdsa.PrivateKey) types.SignData {
+	hash := header.Hash()
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		panic(err)
+	}
+	var sign types.SignData
+	copy(sign[:], sig)
+	return sign
+}
+
+func TestTxProcessor_applyEvidenceTx(t *testing.T) {
+	store.ClearData()
+	assert.NoError(t, initDeputyNode(2, 0))
+	p := NewTxProcessor(newChain())
+	deputyKey, _ := crypto.ToECDSA(common.FromHex(deputy01Privkey))
+	profile := &types.CandidateProfile{
+		IsCandidate:  true,
+		MinerAddress: common.HexToAddress(block01MinerAddress),
+		NodeID:       crypto.FromECDSAPub(&deputyKey.PublicKey)[1:],
+		Host:         "127.0.0.1",
+		Port:         7001,
+	}
+	evidenceTx, err := NewEvidenceTx(chainID, 4, makeTestEvidence(deputyKey, 1), testPrivate)
+	assert.NoError(t, err)
+	againTx, err := NewEvidenceTx(chainID, 5, makeTestEvidence(deputyKey, 1), testPrivate)
+	assert.NoError(t, err)
+	otherKey, _ := crypto.GenerateKey()
+	notDeputyTx, err := NewEvidenceTx(chainID, 5, makeTestEvidence(otherKey, 1), testPrivate)
+	assert.NoError(t, err)
+	txs := types.Transactions{
+		makeRegisterTx(2, profile),
+		makeVoteTx(3, testAddr),
+		evidenceTx,
+		// slash the same node again
+		againTx,
+		notDeputyTx,
+		// register again after slashed. The invalid transactions don't use the nonce
+		makeRegisterTx(5, profile),
+	}
+	header := &types.Header{
+		ParentHash:   defaultBlocks[1].Hash(),
+		MinerAddress: defaultBlocks[1].MinerAddress(),
+		Height:       2,
+		GasLimit:     defaultBlocks[1].GasLimit(),
+		Time:         defaultBlocks[1].Time(),
+	}
+	_, selectedTxs, invalidTxs, receipts, err := p.ApplyTxs(header, txs)
+	assert.NoError(t, err)
+	// the free evidence transaction is invalid unless it slashes an offender
+	assert.Equal(t, types.Transactions{againTx, notDeputyTx}, invalidTxs)
+	assert.Equal(t, len(txs)-len(invalidTxs), len(selectedTxs))
+	for i, receipt := range receipts {
+		assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Status, "index=%d", i)
+	}
+
+	// the votes are cut
+	candidate := p.am.GetAccount(testAddr)
+	assert.Equal(t, new(big.Int), candidate.GetVotes())
+	assert.Equal(t, true, candidate.GetCandidateProfile().IsCandidate)
+	slashEvents := 0
+	for _, event := range p.am.GetEvents() {
+		if event.Address == EvidenceAddress {
+			assert.Equal(t, SlashEventTopic, event.Topics[0])
+			assert.Equal(t, profile.MinerAddress.Hash(), event.Topics[1])
+			slashEvents++
+		}
+	}
+	assert.Equal(t, 1, slashEvents)
+	// removed from the election even if it registers again and has enough votes
+	deputies := deputynode.Instance().DeputyNodesList
+	deputynode.Instance().Clear()
+	deputynode.Instance().Add(0, DefaultDeputyNodes[:1])
+	candidate.SetBalance(MinDeputyVotes)
+	nodes, err := ElectDeputyNodes(p.am, deputynode.SnapshotBlockInterval)
+	deputynode.Instance().DeputyNodesList = deputies
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(nodes))
+
+	// the evidence expires
+	_, err = VerifyEvidenceTx(evidenceTx, 1+maxSignRecordAge)
+	assert.NoError(t, err)
+	_, err = VerifyEvidenceTx(evidenceTx, 2+maxSignRecordAge)
+	assert.Equal(t, ErrEvidenceExpired, err)
+
+	// the salary is cut
+	oneLemo := new(big.Int).SetUint64(1000000000000000000)
+	assert.NoError(t, setRewardSchedule(p.am, &deputynode.RewardSchedule{TermReward: oneLemo, TreasuryRate: 100, Treasury: common.HexToAddress("0x99")}))
+	before := len(p.am.GetEvents())
+	assert.NoError(t, handOutRewards(1001, p.am))
+	total := new(big.Int)
+	for _, event := range p.am.GetEvents()[before:] {
+		assert.NotEqual(t, profile.MinerAddress.Hash(), event.Topics[1])
+		total.Add(total, new(big.Int).SetBytes(event.Data))
+	}
+	assert.Equal(t, 2, len(p.am.GetEvents())-before)
+	// the cut salary is given to treasury
+	assert.Equal(t, oneLemo, total)
+}
+
+func TestBlockChain_evidence(t *testing.T) {
+	store.ClearData()
+	assert.NoError(t, initDeputyNode(2, 0))
+	bc := newChain()
+	evidenceCh := make(chan *types.Evidence, 1)
+	sub := bc.EvidenceFeed.Subscribe(evidenceCh)
+	defer sub.Unsubscribe()
+
+	deputyKey, _ := crypto.ToECDSA(common.FromHex(deputy01Privkey))
+	blockA := &types.Block{Header: &types.Header{Height: 5, Time: 1}}
+	signA := signTestHeader(blockA.Header, deputyKey)
+	blockA.Header.SignData = signA[:]
+	blockB := &types.Block{Header: &types.Header{Height: 5, Time: 2}}
+	signB := signTestHeader(blockB.Header, deputyKey)
+
+	bc.RecordSignedBlock(blockA)
+	bc.RecordSignedBlock(blockA)
+	select {
+	case <-evidenceCh:
+		t.Error("no evidence should be found")
+	default:
+	}
+	// confirm another block at the same height
+	blockB.Confirms = []types.SignData{signB}
+	bc.RecordSignedBlock(blockB)
+	evidence := <-evidenceCh
+	offender, err := evidence.Offender()
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.FromECDSAPub(&deputyKey.PublicKey)[1:], offender)
+
+	// the same evidence from other nodes
+	assert.NoError(t, bc.AddEvidence(makeTestEvidence(deputyKey, 5)))
+	select {
+	case <-evidenceCh:
+		t.Error("the same evidence should not be sent again")
+	default:
+	}
+	otherKey, _ := crypto.GenerateKey()
+	assert.Equal(t, ErrNotDeputyNode, bc.AddEvidence(makeTestEvidence(otherKey, 5)))
+	assert.Equal(t, types.ErrInvalidEvidence, bc.AddEvidence(&types.Evidence{HeaderA: blockA.Header, SignA: signA, HeaderB: blockA.Header, SignB: signA}))
+
+	// self node never signs two blocks at the same height
+	assert.Equal(t, true, bc.TrySign(5, blockA.Hash()))
+	assert.Equal(t, true, bc.TrySign(5, blockA.Hash()))
+	assert.Equal(t, false, bc.TrySign(5, blockB.Hash()))
+	assert.Equal(t, true, bc.TrySign(6, blockB.Hash()))
+
+	// the signatures are loaded after restart
+	restarted, err := NewBlockChain(chainID, NewDpovp(10*1000, bc.db), bc.db, flag.CmdFlags{})
+	assert.NoError(t, err)
+	assert.Equal(t, false, restarted.TrySign(5, blockB.Hash()))
+	assert.Equal(t, true, restarted.TrySign(5, blockA.Hash()))
+	assert.Equal(t, false, restarted.TrySign(6, blockA.Hash()))
+	// the old signatures are removed
+	assert.Equal(t, true, restarted.TrySign(5+maxSignRecordAge+1, blockA.Hash()))
+	_, err = bc.db.GetSelfSign(5)
+	assert.Equal(t, store.ErrNotExist, err)
+}
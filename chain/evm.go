@@ -34,6 +34,7 @@ func NewEVMContext(tx *types.Transaction, header *types.Header, txIndex uint, tx
 		Time:         header.Time,
 		GasLimit:     header.GasLimit,
 		GasPrice:     new(big.Int).Set(tx.GasPrice()),
+		ChainID:      tx.ChainId(),
 	}
 }
 
@@ -0,0 +1,132 @@
+package chain
+
+import (
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"io"
+)
+
+var (
+	ErrExportRange         = errors.New("the export range is out of stable chain")
+	ErrImportDiscontinuous = errors.New("the parent of imported block is not found")
+	ErrImportGenesis       = errors.New("the genesis block of imported chain is different")
+)
+
+// ExportChain writes the RLP encoded stable blocks in [from, to] with their confirms into w. It returns the count of exported blocks
+func (bc *BlockChain) ExportChain(w io.Writer, from, to uint32) (int, error) {
+	if from > to || to > bc.StableBlock().Height() {
+		return 0, ErrExportRange
+	}
+	count := 0
+	for height := from; height <= to; height++ {
+		block, err := bc.db.GetBlockByHeight(height)
+		if err != nil {
+			return count, err
+		}
+		if err := rlp.Encode(w, block); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ImportChain reads the RLP encoded blocks exported by ExportChain from r, and inserts them into chain. The blocks are fully
+// verified and executed by default, and they become stable by their confirms. If trusted is true, the transactions are not executed, and the
+// account state is rebuilt from the change logs in blocks instead, which is much faster but no receipt is saved.
+// It returns the count of imported blocks. The existing blocks are skipped
+func (bc *BlockChain) ImportChain(r io.Reader, trusted bool) (int, error) {
+	// the node is offline
+	if bc.BroadcastConfirmInfo == nil {
+		bc.BroadcastConfirmInfo = func(hash common.Hash, height uint32) {}
+	}
+	stream := rlp.NewStream(r, 0)
+	count := 0
+	for {
+		block := new(types.Block)
+		if err := stream.Decode(block); err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+
+		if bc.HasBlock(block.Hash()) {
+			continue
+		}
+		if block.Height() == 0 {
+			return count, ErrImportGenesis
+		}
+		if !bc.HasBlock(block.ParentHash()) {
+			return count, ErrImportDiscontinuous
+		}
+
+		var err error
+		if trusted {
+			err = bc.insertTrustedBlock(block)
+		} else {
+			err = bc.insertImportedBlock(block)
+		}
+		if err != nil {
+			log.Errorf("import block failed. height: %d, hash: %s, err: %v", block.Height(), block.Hash().Hex(), err)
+			return count, err
+		}
+		count++
+		if count%1000 == 0 {
+			log.Infof("Imported %d blocks. height: %d", count, block.Height())
+		}
+	}
+}
+
+// insertImportedBlock verifies the confirms and the block, then inserts it. It is set stable if it is confirmed by enough
+// distinct deputy nodes, otherwise InsertChain decides the stability as the blocks from network
+func (bc *BlockChain) insertImportedBlock(block *types.Block) error {
+	confirmErr := bc.verifyStableConfirms(block)
+	if confirmErr != nil && confirmErr != ErrNotEnoughConfirms {
+		return confirmErr
+	}
+	if err := bc.InsertChain(block, true); err != nil {
+		return err
+	}
+	if confirmErr == ErrNotEnoughConfirms {
+		return nil
+	}
+	return bc.SetStableBlock(block.Hash(), block.Height(), true)
+}
+
+// insertTrustedBlock rebuilds the account state from the change logs in block without executing transactions,
+// then saves the block as stable block
+func (bc *BlockChain) insertTrustedBlock(block *types.Block) error {
+	if err := bc.verifyBody(block); err != nil {
+		return err
+	}
+	am, err := bc.parentStateManager(block)
+	if err != nil {
+		return err
+	}
+	if err := am.Redo(block.ChangeLogs); err != nil {
+		return err
+	}
+	if err := am.Finalise(); err != nil {
+		return err
+	}
+	if am.GetVersionRoot() != block.VersionRoot() {
+		return ErrVerifyBlockFailed
+	}
+
+	hash := block.Hash()
+	if err := bc.db.SetBlock(hash, block); err != nil {
+		return ErrSaveBlock
+	}
+	if err := am.Save(hash); err != nil {
+		return err
+	}
+	bc.chainForksLock.Lock()
+	delete(bc.chainForksHead, block.ParentHash())
+	bc.chainForksHead[hash] = block
+	bc.chainForksLock.Unlock()
+	bc.currentBlock.Store(block)
+	return bc.SetStableBlock(hash, block.Height(), true)
+}
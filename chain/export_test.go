@@ -0,0 +1,93 @@
+package chain
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+// makeExportChain creates a chain with 3 stable blocks after genesis
+func makeExportChain(t *testing.T) (*BlockChain, []*types.Block) {
+	store.ClearData()
+	bc, _, err := newBlockChainForTestAt(filepath.Join(store.GetStorePath(), "src"))
+	assert.NoError(t, err)
+	blocks := []*types.Block{bc.Genesis()}
+	for height := uint32(1); height <= 3; height++ {
+		block := makeBlock(bc.db, blockInfo{parentHash: blocks[height-1].Hash(), height: height}, false)
+		block.SetConfirms(makeTestConfirms(t, block.Hash(), 4))
+		assert.NoError(t, bc.InsertChain(block, true))
+		blocks = append(blocks, block)
+	}
+	assert.NoError(t, bc.SetStableBlock(blocks[3].Hash(), 3, true))
+	return bc, blocks
+}
+
+func TestBlockChain_ExportChain(t *testing.T) {
+	bc, blocks := makeExportChain(t)
+
+	buf := new(bytes.Buffer)
+	_, err := bc.ExportChain(buf, 0, 4)
+	assert.Equal(t, ErrExportRange, err)
+	_, err = bc.ExportChain(buf, 2, 1)
+	assert.Equal(t, ErrExportRange, err)
+	count, err := bc.ExportChain(buf, 0, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	// import with full verification
+	data := buf.Bytes()
+	dst, _, err := newBlockChainForTestAt(filepath.Join(store.GetStorePath(), "dst"))
+	assert.NoError(t, err)
+	count, err = dst.ImportChain(bytes.NewReader(data), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, blocks[3].Hash(), dst.StableBlock().Hash())
+	assert.Equal(t, blocks[3].Hash(), dst.CurrentBlock().Hash())
+	assert.Equal(t, blocks[2].Hash(), dst.GetBlockByHeight(2).Hash())
+	// the existing blocks are skipped
+	count, err = dst.ImportChain(bytes.NewReader(data), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// import through trusted path
+	trusted, _, err := newBlockChainForTestAt(filepath.Join(store.GetStorePath(), "trusted"))
+	assert.NoError(t, err)
+	count, err = trusted.ImportChain(bytes.NewReader(data), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, blocks[3].Hash(), trusted.StableBlock().Hash())
+
+	// the block is not stable without enough distinct confirms
+	for i, confirms := range [][]types.SignData{
+		makeTestConfirms(t, blocks[1].Hash(), 3),
+		append(makeTestConfirms(t, blocks[1].Hash(), 3), makeTestConfirms(t, blocks[1].Hash(), 1)...),
+	} {
+		block := *blocks[1]
+		block.SetConfirms(confirms)
+		buf.Reset()
+		assert.NoError(t, rlp.Encode(buf, &block))
+		unconfirmed, _, err := newBlockChainForTestAt(filepath.Join(store.GetStorePath(), fmt.Sprintf("unconfirmed%d", i)))
+		assert.NoError(t, err)
+		count, err = unconfirmed.ImportChain(buf, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.Equal(t, blocks[1].Hash(), unconfirmed.CurrentBlock().Hash())
+		assert.Equal(t, blocks[0].Hash(), unconfirmed.StableBlock().Hash())
+	}
+
+	// the parent is missing
+	buf.Reset()
+	_, err = bc.ExportChain(buf, 2, 3)
+	assert.NoError(t, err)
+	other, _, err := newBlockChainForTestAt(filepath.Join(store.GetStorePath(), "other"))
+	assert.NoError(t, err)
+	count, err = other.ImportChain(buf, false)
+	assert.Equal(t, ErrImportDiscontinuous, err)
+	assert.Equal(t, 0, count)
+	store.ClearData()
+}
@@ -0,0 +1,173 @@
+package filters
+
+import (
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+)
+
+var (
+	ErrInvalidHeightRange = errors.New("the begin height is larger than the end height")
+	ErrTooManyBlocks      = errors.New("too many blocks to search in one query")
+)
+
+// MaxFilterBlocks is the max count of blocks could be searched in one query
+var MaxFilterBlocks uint32 = 10000
+
+// Filter retrieves the events in a range of blocks which match the addresses and topics
+type Filter struct {
+	bc        *chain.BlockChain
+	begin     uint32
+	end       uint32
+	addresses []common.Address
+	topics    [][]common.Hash
+}
+
+// New creates a filter to search events from begin height to end height. The end height will be limited to the current block height.
+// An event matches if it is generated by one of the addresses, and its topic in each position is one of the topics[position].
+// An empty addresses or topics[position] list matches anything.
+func New(bc *chain.BlockChain, begin, end uint32, addresses []common.Address, topics [][]common.Hash) *Filter {
+	return &Filter{
+		bc:        bc,
+		begin:     begin,
+		end:       end,
+		addresses: addresses,
+		topics:    topics,
+	}
+}
+
+// Events searches the blocks in current chain and returns the matched events
+func (f *Filter) Events() ([]*types.Event, error) {
+	if f.begin > f.end {
+		return nil, ErrInvalidHeightRange
+	}
+	end := f.end
+	if current := f.bc.CurrentBlock().Height(); end > current {
+		end = current
+	}
+	result := make([]*types.Event, 0)
+	if f.begin > end {
+		return result, nil
+	}
+	if end-f.begin >= MaxFilterBlocks {
+		return nil, ErrTooManyBlocks
+	}
+	for height := f.begin; height <= end; height++ {
+		block := f.bc.GetBlockByHeight(height)
+		if block == nil {
+			break
+		}
+		events, err := f.blockEvents(block)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, events...)
+	}
+	return result, nil
+}
+
+// blockEvents returns the matched events in block. The block's bloom is used to skip the blocks without matched event
+func (f *Filter) blockEvents(block *types.Block) ([]*types.Event, error) {
+	if !bloomFilter(block.Bloom(), f.addresses, f.topics) {
+		return nil, nil
+	}
+	events, err := BlockEvents(f.bc, block)
+	if err != nil {
+		return nil, err
+	}
+	return filterEvents(events, f.addresses, f.topics), nil
+}
+
+// BlockEvents loads all events in block from its receipts. The events not emitted by transactions are in the last receipt
+func BlockEvents(bc *chain.BlockChain, block *types.Block) ([]*types.Event, error) {
+	receipts, err := bc.Db().GetReceipts(block.Hash())
+	if err == store.ErrNotExist {
+		// the block has no receipts, e.g. genesis block
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	events := make([]*types.Event, 0)
+	for _, receipt := range receipts {
+		events = append(events, receipt.Events...)
+	}
+	return events, nil
+}
+
+// filterEvents returns the events which match the addresses and topics
+func filterEvents(events []*types.Event, addresses []common.Address, topics [][]common.Hash) []*types.Event {
+	result := make([]*types.Event, 0)
+	for _, event := range events {
+		if len(addresses) > 0 && !includes(addresses, event.Address) {
+			continue
+		}
+		if len(topics) > len(event.Topics) {
+			continue
+		}
+		if matchTopics(event.Topics, topics) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// matchTopics checks if the topic in each position is one of the expected topics in the same position
+func matchTopics(eventTopics []common.Hash, topics [][]common.Hash) bool {
+	for i, sub := range topics {
+		if len(sub) == 0 {
+			continue // wildcard
+		}
+		match := false
+		for _, topic := range sub {
+			if eventTopics[i] == topic {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomFilter checks if the bloom may contain the events which match the addresses and topics
+func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		included := false
+		for _, addr := range addresses {
+			if types.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+
+	for _, sub := range topics {
+		included := len(sub) == 0 // wildcard
+		for _, topic := range sub {
+			if types.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+func includes(addresses []common.Address, a common.Address) bool {
+	for _, addr := range addresses {
+		if addr == a {
+			return true
+		}
+	}
+	return false
+}
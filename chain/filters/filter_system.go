@@ -0,0 +1,178 @@
+package filters
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"github.com/LemoFoundationLtd/lemochain-go/common/subscribe"
+	"sync"
+)
+
+// Subscription is created by EventSystem. The matched events are sent to its channel
+type Subscription struct {
+	es        *EventSystem
+	unstable  bool // receives the events in current chain instead of stable blocks
+	addresses []common.Address
+	topics    [][]common.Hash
+	eventsCh  chan<- []*types.Event
+	installed chan struct{} // closed when the subscription is installed
+	quitCh    chan struct{}
+	unsubOnce sync.Once
+}
+
+// Unsubscribe uninstalls the subscription from the event system
+func (sub *Subscription) Unsubscribe() {
+	sub.unsubOnce.Do(func() {
+		<-sub.installed
+		// stop the pending dispatching first, so that the event loop could receive uninstall request
+		close(sub.quitCh)
+		select {
+		case sub.es.uninstallCh <- sub:
+		case <-sub.es.quitCh:
+		}
+	})
+}
+
+// EventSystem dispatches the events in new stable blocks to subscribers. The stable blocks are never abandoned, so there is
+// no removed event to dispatch. The unstable subscribers receive the events once the block become current, and receive them
+// again with removed flag if the block is abandoned by fork switching
+type EventSystem struct {
+	bc         *chain.BlockChain
+	lastHeight uint32 // the height of the last stable block which has been dispatched
+
+	stableBlockCh    chan *types.Block
+	addedEventsCh    chan []*types.Event
+	removedEventsCh  chan []*types.Event
+	stableBlockSub   subscribe.Subscription
+	addedEventsSub   subscribe.Subscription
+	removedEventsSub subscribe.Subscription
+
+	installCh   chan *Subscription
+	uninstallCh chan *Subscription
+	quitCh      chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewEventSystem creates an event system and starts dispatching
+func NewEventSystem(bc *chain.BlockChain) *EventSystem {
+	es := &EventSystem{
+		bc:              bc,
+		lastHeight:      bc.StableBlock().Height(),
+		stableBlockCh:   make(chan *types.Block, 16),
+		addedEventsCh:   make(chan []*types.Event, 16),
+		removedEventsCh: make(chan []*types.Event, 16),
+		installCh:       make(chan *Subscription),
+		uninstallCh:     make(chan *Subscription),
+		quitCh:          make(chan struct{}),
+	}
+	es.stableBlockSub = bc.StableBlockFeed.Subscribe(es.stableBlockCh)
+	es.addedEventsSub = bc.AddedEventsFeed.Subscribe(es.addedEventsCh)
+	es.removedEventsSub = bc.RemovedEventsFeed.Subscribe(es.removedEventsCh)
+	go es.loop()
+	return es
+}
+
+// SubscribeEvents creates a subscription which sends the matched events in new stable blocks to eventsCh
+func (es *EventSystem) SubscribeEvents(addresses []common.Address, topics [][]common.Hash, eventsCh chan<- []*types.Event) *Subscription {
+	return es.subscribe(false, addresses, topics, eventsCh)
+}
+
+// SubscribeUnstableEvents creates a subscription which sends the matched events in new current blocks to eventsCh. The
+// events in abandoned blocks are sent again with removed flag
+func (es *EventSystem) SubscribeUnstableEvents(addresses []common.Address, topics [][]common.Hash, eventsCh chan<- []*types.Event) *Subscription {
+	return es.subscribe(true, addresses, topics, eventsCh)
+}
+
+func (es *EventSystem) subscribe(unstable bool, addresses []common.Address, topics [][]common.Hash, eventsCh chan<- []*types.Event) *Subscription {
+	sub := &Subscription{
+		es:        es,
+		unstable:  unstable,
+		addresses: addresses,
+		topics:    topics,
+		eventsCh:  eventsCh,
+		installed: make(chan struct{}),
+		quitCh:    make(chan struct{}),
+	}
+	select {
+	case es.installCh <- sub:
+	case <-es.quitCh:
+	}
+	close(sub.installed)
+	return sub
+}
+
+// Stop stops dispatching events
+func (es *EventSystem) Stop() {
+	es.stopOnce.Do(func() {
+		es.stableBlockSub.Unsubscribe()
+		es.addedEventsSub.Unsubscribe()
+		es.removedEventsSub.Unsubscribe()
+		close(es.quitCh)
+	})
+}
+
+func (es *EventSystem) loop() {
+	subs := make(map[*Subscription]struct{})
+	for {
+		select {
+		case block := <-es.stableBlockCh:
+			for _, b := range es.newStableBlocks(block) {
+				events, err := BlockEvents(es.bc, b)
+				if err != nil {
+					log.Warnf("can't load events of stable block. height: %d, err: %v", b.Height(), err)
+					continue
+				}
+				es.dispatch(subs, events, false)
+			}
+		case events := <-es.addedEventsCh:
+			es.dispatch(subs, events, true)
+		case events := <-es.removedEventsCh:
+			es.dispatch(subs, events, true)
+		case sub := <-es.installCh:
+			subs[sub] = struct{}{}
+		case sub := <-es.uninstallCh:
+			delete(subs, sub)
+		case <-es.quitCh:
+			return
+		}
+	}
+}
+
+// newStableBlocks returns the blocks which become stable since last dispatching, because the stable block may skip some heights
+func (es *EventSystem) newStableBlocks(block *types.Block) []*types.Block {
+	if block.Height() <= es.lastHeight {
+		return nil
+	}
+	blocks := []*types.Block{block}
+	for block.Height() > es.lastHeight+1 {
+		block = es.bc.GetBlockByHash(block.ParentHash())
+		if block == nil {
+			break
+		}
+		blocks = append([]*types.Block{block}, blocks...)
+	}
+	es.lastHeight = blocks[len(blocks)-1].Height()
+	return blocks
+}
+
+// dispatch sends the events to the stable or unstable subscribers who are interested in them
+func (es *EventSystem) dispatch(subs map[*Subscription]struct{}, events []*types.Event, unstable bool) {
+	if len(events) == 0 {
+		return
+	}
+	for sub := range subs {
+		if sub.unstable != unstable {
+			continue
+		}
+		matched := filterEvents(events, sub.addresses, sub.topics)
+		if len(matched) == 0 {
+			continue
+		}
+		select {
+		case sub.eventsCh <- matched:
+		case <-sub.quitCh:
+		case <-es.quitCh:
+		}
+	}
+}
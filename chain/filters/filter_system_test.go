@@ -0,0 +1,112 @@
+package filters
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func receiveEvents(t *testing.T, ch chan []*types.Event) []*types.Event {
+	select {
+	case events := <-ch:
+		return events
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	return nil
+}
+
+func setStable(bc *chain.BlockChain, block *types.Block) {
+	if err := bc.SetStableBlock(block.Hash(), block.Height(), true); err != nil {
+		panic(err)
+	}
+}
+
+func TestEventSystem_SubscribeEvents(t *testing.T) {
+	bc := newTestChain()
+	es := NewEventSystem(bc)
+	defer es.Stop()
+
+	eventsCh := make(chan []*types.Event, 1)
+	sub := es.SubscribeEvents([]common.Address{testAddr1}, nil, eventsCh)
+
+	// the events are dispatched after the block become stable
+	event1 := &types.Event{Address: testAddr1, Topics: []common.Hash{testTopic1}}
+	event2 := &types.Event{Address: testAddr2, Topics: []common.Hash{testTopic1}}
+	setStable(bc, appendBlock(bc, event1, event2))
+	assert.Equal(t, []*types.Event{event1}, receiveEvents(t, eventsCh))
+
+	// the removed events in abandoned forks are not dispatched, because they have never been dispatched before
+	removed := &types.Event{Address: testAddr1, Topics: []common.Hash{testTopic2}, Removed: true}
+	bc.RemovedEventsFeed.Send([]*types.Event{removed})
+	setStable(bc, appendBlock(bc, event1))
+	assert.Equal(t, []*types.Event{event1}, receiveEvents(t, eventsCh))
+
+	// no matched events
+	setStable(bc, appendBlock(bc, event2))
+	// after unsubscribe
+	sub.Unsubscribe()
+	setStable(bc, appendBlock(bc, event1))
+	select {
+	case events := <-eventsCh:
+		t.Errorf("unexpected events: %v", events)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEventSystem_SubscribeUnstableEvents(t *testing.T) {
+	bc := newTestChain()
+	es := NewEventSystem(bc)
+	defer es.Stop()
+
+	eventsCh := make(chan []*types.Event, 1)
+	stableCh := make(chan []*types.Event, 1)
+	sub := es.SubscribeUnstableEvents([]common.Address{testAddr1}, nil, eventsCh)
+	stableSub := es.SubscribeEvents([]common.Address{testAddr1}, nil, stableCh)
+	defer stableSub.Unsubscribe()
+
+	// the events are dispatched once the block become current
+	event1 := &types.Event{Address: testAddr1, Topics: []common.Hash{testTopic1}}
+	event2 := &types.Event{Address: testAddr2, Topics: []common.Hash{testTopic1}}
+	block := appendBlock(bc, event1, event2)
+	events := receiveEvents(t, eventsCh)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, event1.Topics, events[0].Topics)
+	assert.Equal(t, block.Hash(), events[0].BlockHash)
+	assert.Equal(t, false, events[0].Removed)
+
+	// the removed events in abandoned forks are dispatched to unstable subscribers only
+	removed := &types.Event{Address: testAddr1, Topics: []common.Hash{testTopic2}, Removed: true}
+	bc.RemovedEventsFeed.Send([]*types.Event{removed})
+	assert.Equal(t, []*types.Event{removed}, receiveEvents(t, eventsCh))
+
+	// the events in stable blocks are dispatched to stable subscribers only
+	setStable(bc, block)
+	assert.Equal(t, []*types.Event{event1}, receiveEvents(t, stableCh))
+	// after unsubscribe
+	sub.Unsubscribe()
+	appendBlock(bc, event1)
+	select {
+	case events := <-eventsCh:
+		t.Errorf("unexpected events: %v", events)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEventSystem_newStableBlocks(t *testing.T) {
+	bc := newTestChain()
+	es := NewEventSystem(bc)
+	es.Stop()
+	block1 := appendBlock(bc)
+	block2 := appendBlock(bc)
+	block3 := appendBlock(bc)
+
+	// the skipped blocks are included
+	assert.Equal(t, []*types.Block{block1, block2}, es.newStableBlocks(block2))
+	assert.Equal(t, uint32(2), es.lastHeight)
+	assert.Equal(t, 0, len(es.newStableBlocks(block2)))
+	assert.Equal(t, []*types.Block{block3}, es.newStableBlocks(block3))
+}
@@ -0,0 +1,139 @@
+package filters
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type testEngine struct{}
+
+func (engine *testEngine) VerifyHeader(block *types.Block) error { return nil }
+func (engine *testEngine) Seal(header *types.Header, txs []*types.Transaction, changeLog []*types.ChangeLog, events []*types.Event) (*types.Block, error) {
+	return nil, nil
+}
+func (engine *testEngine) Finalize(header *types.Header, am *account.Manager) error { return nil }
+
+var (
+	testAddr1  = common.HexToAddress("0x01")
+	testAddr2  = common.HexToAddress("0x02")
+	testTopic1 = common.HexToHash("0x11")
+	testTopic2 = common.HexToHash("0x12")
+)
+
+func newTestChain() *chain.BlockChain {
+	store.ClearData()
+	db, err := store.NewCacheChain(store.GetStorePath())
+	if err != nil {
+		panic(err)
+	}
+	if _, err = chain.SetupGenesisBlock(db, chain.DefaultGenesisBlock()); err != nil {
+		panic(err)
+	}
+	bc, err := chain.NewBlockChain(99, new(testEngine), db, nil)
+	if err != nil {
+		panic(err)
+	}
+	bc.BroadcastConfirmInfo = func(hash common.Hash, height uint32) {}
+	deputynode.Instance().Add(0, chain.DefaultDeputyNodes)
+	return bc
+}
+
+// appendBlock saves a block with the events on top of current block
+func appendBlock(bc *chain.BlockChain, events ...*types.Event) *types.Block {
+	parent := bc.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Height:     parent.Height() + 1,
+		Bloom:      types.CreateBloom(events),
+		Time:       uint32(time.Now().Unix()),
+		Extra:      []byte{byte(len(events))},
+	}
+	tx := types.NewTransaction(uint64(header.Height), testAddr1, common.Big1, 21000, common.Big1, nil, 99, uint64(time.Now().Unix()+300), "", "")
+	receipt := types.NewReceipt(tx, header.Height, 0, 21000, false)
+	receipt.Events = events
+	block := types.NewBlock(header, types.Transactions{tx}, nil, events, nil)
+	for i, event := range events {
+		event.BlockHeight = header.Height
+		event.TxHash = tx.Hash()
+		event.Index = uint(i)
+	}
+	if err := bc.SetMinedBlock(block, types.Receipts{receipt}); err != nil {
+		panic(err)
+	}
+	return block
+}
+
+func TestFilter_Events(t *testing.T) {
+	bc := newTestChain()
+	event1 := &types.Event{Address: testAddr1, Topics: []common.Hash{testTopic1}}
+	event2 := &types.Event{Address: testAddr2, Topics: []common.Hash{testTopic1, testTopic2}}
+	event3 := &types.Event{Address: testAddr1, Topics: []common.Hash{testTopic2}}
+	appendBlock(bc, event1)
+	appendBlock(bc)
+	block3 := appendBlock(bc, event2, event3)
+
+	// all events
+	events, err := New(bc, 0, 100, nil, nil).Events()
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.Event{event1, event2, event3}, events)
+	assert.Equal(t, block3.Hash(), events[1].BlockHash)
+
+	// by address
+	events, err = New(bc, 0, 3, []common.Address{testAddr1}, nil).Events()
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.Event{event1, event3}, events)
+
+	// by topics
+	events, err = New(bc, 0, 3, nil, [][]common.Hash{{}, {testTopic2}}).Events()
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.Event{event2}, events)
+	events, err = New(bc, 0, 3, nil, [][]common.Hash{{testTopic1, testTopic2}}).Events()
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.Event{event1, event2, event3}, events)
+
+	// by height
+	events, err = New(bc, 2, 3, []common.Address{testAddr1}, nil).Events()
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.Event{event3}, events)
+	events, err = New(bc, 4, 5, nil, nil).Events()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(events))
+
+	// invalid range
+	_, err = New(bc, 3, 2, nil, nil).Events()
+	assert.Equal(t, ErrInvalidHeightRange, err)
+	defer func(max uint32) { MaxFilterBlocks = max }(MaxFilterBlocks)
+	MaxFilterBlocks = 2
+	_, err = New(bc, 0, 3, nil, nil).Events()
+	assert.Equal(t, ErrTooManyBlocks, err)
+}
+
+func TestBloomFilter(t *testing.T) {
+	bloom := types.CreateBloom([]*types.Event{{Address: testAddr1, Topics: []common.Hash{testTopic1}}})
+
+	assert.Equal(t, true, bloomFilter(bloom, nil, nil))
+	assert.Equal(t, true, bloomFilter(bloom, []common.Address{testAddr2, testAddr1}, nil))
+	assert.Equal(t, false, bloomFilter(bloom, []common.Address{testAddr2}, nil))
+	assert.Equal(t, true, bloomFilter(bloom, nil, [][]common.Hash{{}, {testTopic1}}))
+	assert.Equal(t, false, bloomFilter(bloom, nil, [][]common.Hash{{testTopic2}}))
+}
+
+func TestFilterEvents(t *testing.T) {
+	event1 := &types.Event{Address: testAddr1, Topics: []common.Hash{testTopic1}}
+	event2 := &types.Event{Address: testAddr2, Topics: []common.Hash{testTopic1, testTopic2}}
+	events := []*types.Event{event1, event2}
+
+	assert.Equal(t, events, filterEvents(events, nil, nil))
+	assert.Equal(t, []*types.Event{event2}, filterEvents(events, []common.Address{testAddr2}, nil))
+	assert.Equal(t, events, filterEvents(events, nil, [][]common.Hash{{testTopic1}}))
+	// more topics than the event has
+	assert.Equal(t, []*types.Event{event2}, filterEvents(events, nil, [][]common.Hash{{}, {}}))
+	assert.Equal(t, 0, len(filterEvents(events, nil, [][]common.Hash{{testTopic2}})))
+}
@@ -0,0 +1,123 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+)
+
+var (
+	ErrFinalityRootNotMatch  = errors.New("the finality certificate doesn't match the finality root in header")
+	ErrInvalidFinalityTarget = errors.New("the finality certificate is not for an ancestor block")
+	ErrFinalityNotActivated  = errors.New("the finality certificate is not activated at the height")
+)
+
+// filterConfirms returns the confirms signed by different deputy nodes of the block, and drops the invalid ones
+func filterConfirms(hash common.Hash, height uint32, signs []types.SignData) []types.SignData {
+	nodes := deputynode.Instance().GetDeputiesByHeight(height)
+	signers := make(map[string]bool, len(signs))
+	result := make([]types.SignData, 0, len(signs))
+	for _, sign := range signs {
+		nodeID, err := types.VerifyConfirm(hash, sign, nodes)
+		if err != nil || signers[string(nodeID)] {
+			continue
+		}
+		signers[string(nodeID)] = true
+		result = append(result, sign)
+	}
+	return result
+}
+
+// GetFinality returns the finality certificate of the block. It fails if the confirms of the block are not enough
+func (bc *BlockChain) GetFinality(hash common.Hash) (*types.FinalityCertificate, error) {
+	block, err := bc.db.GetBlockByHash(hash)
+	if err != nil {
+		return nil, ErrBlockNotExist
+	}
+	certificate := &types.FinalityCertificate{
+		Hash:   hash,
+		Height: block.Height(),
+		Signs:  filterConfirms(hash, block.Height(), block.Confirms),
+	}
+	if err := certificate.Verify(deputynode.Instance().GetDeputiesByHeight(block.Height())); err != nil {
+		return nil, err
+	}
+	return certificate, nil
+}
+
+// committedFinality returns the latest finality certificate committed by the unstable ancestors of block
+func (bc *BlockChain) committedFinality(block *types.Block) *types.FinalityCertificate {
+	stableHeight := bc.StableBlock().Height()
+	for block != nil && block.Height() > stableHeight {
+		if block.Finality != nil {
+			return block.Finality
+		}
+		block = bc.GetBlockByHash(block.ParentHash())
+	}
+	return nil
+}
+
+// NextFinality returns the finality certificate which should be committed by the child block of parent. It is the
+// certificate of current stable block if it hasn't been committed by the ancestors, and nil before the Finality fork
+func (bc *BlockChain) NextFinality(parent *types.Block) *types.FinalityCertificate {
+	if !bc.Config().IsFinality(parent.Height() + 1) {
+		return nil
+	}
+	stable := bc.StableBlock()
+	if committed := bc.committedFinality(parent); committed != nil && committed.Height >= stable.Height() {
+		return nil
+	}
+	certificate, err := bc.GetFinality(stable.Hash())
+	if err != nil {
+		return nil
+	}
+	return certificate
+}
+
+// verifyFinality checks the finality certificate committed by block is for one of its ancestors
+func (bc *BlockChain) verifyFinality(block *types.Block) error {
+	certificate := block.Finality
+	if len(block.Header.FinalityRoot) == 0 {
+		if certificate != nil {
+			return ErrFinalityRootNotMatch
+		}
+		return nil
+	}
+	if !bc.Config().IsFinality(block.Height()) {
+		return ErrFinalityNotActivated
+	}
+	if certificate == nil || !bytes.Equal(certificate.Root().Bytes(), block.Header.FinalityRoot) {
+		return ErrFinalityRootNotMatch
+	}
+	if certificate.Height >= block.Height() {
+		return ErrInvalidFinalityTarget
+	}
+	ancestor := bc.GetBlockByHash(block.ParentHash())
+	for ancestor != nil && ancestor.Height() > certificate.Height {
+		ancestor = bc.GetBlockByHash(ancestor.ParentHash())
+	}
+	if ancestor == nil || ancestor.Hash() != certificate.Hash {
+		return ErrInvalidFinalityTarget
+	}
+	return certificate.Verify(deputynode.Instance().GetDeputiesByHeight(certificate.Height))
+}
+
+// applyFinality saves the confirms in the finality certificate committed by block, and makes the certified block stable
+func (bc *BlockChain) applyFinality(block *types.Block, logLess bool) {
+	certificate := block.Finality
+	if certificate == nil {
+		return
+	}
+	confirms, err := bc.db.GetConfirms(certificate.Hash)
+	if err == nil && len(filterConfirms(certificate.Hash, certificate.Height, confirms)) < len(certificate.Signs) {
+		if err := bc.db.SetConfirms(certificate.Hash, certificate.Signs); err != nil {
+			log.Warnf("save confirms of finality certificate failed: %v", err)
+		}
+	}
+	if certificate.Height > bc.StableBlock().Height() {
+		bc.SetStableBlock(certificate.Hash, certificate.Height, logLess)
+	}
+}
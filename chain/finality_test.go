@@ -0,0 +1,106 @@
+package chain
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/network/synchronise/protocol"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var deputyPrivkeys = []string{deputy01Privkey, deputy02Privkey, deputy03Privkey, deputy04Privkey, deputy05Privkey}
+
+// makeTestConfirms signs the block hash by the first count deputy nodes
+func makeTestConfirms(t *testing.T, hash common.Hash, count int) []types.SignData {
+	signs := make([]types.SignData, count)
+	for i := 0; i < count; i++ {
+		confirm, err := buildConfirm(hash, deputyPrivkeys[i][2:])
+		assert.NoError(t, err)
+		signs[i] = confirm.SignInfo
+	}
+	return signs
+}
+
+func TestBlockChain_finality(t *testing.T) {
+	nodes := deputynode.Instance().DeputyNodesList
+	defer func() { deputynode.Instance().DeputyNodesList = nodes }()
+	deputynode.Instance().Clear()
+	store.ClearData()
+	blockChain, _, err := NewBlockChainForTest()
+	assert.NoError(t, err)
+	genesis := blockChain.GetBlockByHeight(0)
+
+	info := blockInfo{parentHash: genesis.Hash(), height: 1, time: 1540893799}
+	block1 := makeBlock(blockChain.db, info, false)
+	assert.NoError(t, blockChain.InsertChain(block1, true))
+	info = blockInfo{parentHash: block1.Hash(), height: 2, time: 1540893800}
+	block2 := makeBlock(blockChain.db, info, false)
+	assert.NoError(t, blockChain.InsertChain(block2, true))
+
+	// the invalid and duplicate confirms are dropped
+	signs := makeTestConfirms(t, block1.Hash(), 4)
+	pack := append([]types.SignData{{}, signs[0]}, makeTestConfirms(t, block2.Hash(), 1)...)
+	blockChain.ReceiveConfirms(protocol.BlockConfirms{Hash: block1.Hash(), Height: 1, Pack: append(pack, signs[:3]...)})
+	confirms, err := blockChain.db.GetConfirms(block1.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, signs[:3], confirms)
+	_, err = blockChain.GetFinality(block1.Hash())
+	assert.Equal(t, types.ErrNotEnoughConfirms, err)
+	blockChain.ReceiveConfirms(protocol.BlockConfirms{Hash: block1.Hash(), Height: 1, Pack: signs[3:]})
+	certificate, err := blockChain.GetFinality(block1.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, &types.FinalityCertificate{Hash: block1.Hash(), Height: 1, Signs: signs}, certificate)
+	_, err = blockChain.GetFinality(common.HexToHash("0x1234"))
+	assert.Equal(t, ErrBlockNotExist, err)
+	// the next block commits to the certificate of stable block
+	assert.Nil(t, blockChain.NextFinality(block2))
+	assert.NoError(t, blockChain.SetStableBlock(block1.Hash(), 1, true))
+	// not activated before the Finality fork
+	assert.Nil(t, blockChain.NextFinality(block2))
+	notActivated := makeBlock(blockChain.db, blockInfo{parentHash: block2.Hash(), height: 3, time: 1540893801}, false)
+	notActivated.SetFinality(certificate)
+	notActivated.Header.FinalityRoot = certificate.Root().Bytes()
+	assert.Equal(t, ErrFinalityNotActivated, blockChain.verifyFinality(notActivated))
+	finalityHeight := uint32(3)
+	config := *blockChain.Config()
+	config.FinalityHeight = &finalityHeight
+	blockChain.config = &config
+	assert.Equal(t, certificate, blockChain.NextFinality(block2))
+
+	// block3 commits to the certificate of block2
+	certificate = &types.FinalityCertificate{Hash: block2.Hash(), Height: 2, Signs: makeTestConfirms(t, block2.Hash(), 4)}
+	info = blockInfo{parentHash: block2.Hash(), height: 3, time: 1540893801}
+	block3 := makeBlock(blockChain.db, info, false)
+	block3.Header.FinalityRoot = certificate.Root().Bytes()
+	block3.SetFinality(certificate)
+	assert.NoError(t, blockChain.verifyFinality(block3))
+	// not match
+	block3.SetFinality(&types.FinalityCertificate{Hash: block2.Hash(), Height: 2, Signs: certificate.Signs[:3]})
+	assert.Equal(t, ErrFinalityRootNotMatch, blockChain.verifyFinality(block3))
+	block3.SetFinality(nil)
+	assert.Equal(t, ErrFinalityRootNotMatch, blockChain.verifyFinality(block3))
+	// not enough confirms
+	bad := makeBlock(blockChain.db, info, false)
+	bad.SetFinality(&types.FinalityCertificate{Hash: block2.Hash(), Height: 2, Signs: certificate.Signs[:3]})
+	bad.Header.FinalityRoot = bad.Finality.Root().Bytes()
+	assert.Equal(t, types.ErrNotEnoughConfirms, blockChain.verifyFinality(bad))
+	// not an ancestor
+	bad.SetFinality(&types.FinalityCertificate{Hash: common.HexToHash("0x1234"), Height: 2, Signs: certificate.Signs})
+	bad.Header.FinalityRoot = bad.Finality.Root().Bytes()
+	assert.Equal(t, ErrInvalidFinalityTarget, blockChain.verifyFinality(bad))
+	bad.SetFinality(&types.FinalityCertificate{Hash: block2.Hash(), Height: 3, Signs: certificate.Signs})
+	bad.Header.FinalityRoot = bad.Finality.Root().Bytes()
+	assert.Equal(t, ErrInvalidFinalityTarget, blockChain.verifyFinality(bad))
+
+	// block2 becomes stable after block3 is inserted
+	block3.SetFinality(certificate)
+	assert.NoError(t, blockChain.InsertChain(block3, true))
+	assert.Equal(t, block2.Hash(), blockChain.StableBlock().Hash())
+	confirms, err = blockChain.db.GetConfirms(block2.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, certificate.Signs, confirms)
+	// the certificate has been committed
+	assert.Nil(t, blockChain.NextFinality(block3))
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
 )
@@ -16,11 +17,13 @@ var _ = (*genesisSpecMarshaling)(nil)
 // MarshalJSON marshals as JSON.
 func (g Genesis) MarshalJSON() ([]byte, error) {
 	type Genesis struct {
-		Time        hexutil.Uint32           `json:"timestamp"     gencodec:"required"`
-		ExtraData   hexutil.Bytes            `json:"extraData"`
-		GasLimit    hexutil.Uint64           `json:"gasLimit"      gencodec:"required"`
-		Founder     common.Address           `json:"founder"       gencodec:"required"`
-		DeputyNodes []*deputynode.DeputyNode `json:"deputyNodes"   gencodec:"required"`
+		Time        hexutil.Uint32             `json:"timestamp"     gencodec:"required"`
+		ExtraData   hexutil.Bytes              `json:"extraData"`
+		GasLimit    hexutil.Uint64             `json:"gasLimit"      gencodec:"required"`
+		Founder     common.Address             `json:"founder"       gencodec:"required"`
+		DeputyNodes []*deputynode.DeputyNode   `json:"deputyNodes"   gencodec:"required"`
+		Reward      *deputynode.RewardSchedule `json:"reward"`
+		Config      *params.ChainConfig        `json:"config"`
 	}
 	var enc Genesis
 	enc.Time = hexutil.Uint32(g.Time)
@@ -28,17 +31,21 @@ func (g Genesis) MarshalJSON() ([]byte, error) {
 	enc.GasLimit = hexutil.Uint64(g.GasLimit)
 	enc.Founder = g.Founder
 	enc.DeputyNodes = g.DeputyNodes
+	enc.Reward = g.Reward
+	enc.Config = g.Config
 	return json.Marshal(&enc)
 }
 
 // UnmarshalJSON unmarshals from JSON.
 func (g *Genesis) UnmarshalJSON(input []byte) error {
 	type Genesis struct {
-		Time        *hexutil.Uint32          `json:"timestamp"     gencodec:"required"`
-		ExtraData   *hexutil.Bytes           `json:"extraData"`
-		GasLimit    *hexutil.Uint64          `json:"gasLimit"      gencodec:"required"`
-		Founder     *common.Address          `json:"founder"       gencodec:"required"`
-		DeputyNodes []*deputynode.DeputyNode `json:"deputyNodes"   gencodec:"required"`
+		Time        *hexutil.Uint32            `json:"timestamp"     gencodec:"required"`
+		ExtraData   *hexutil.Bytes             `json:"extraData"`
+		GasLimit    *hexutil.Uint64            `json:"gasLimit"      gencodec:"required"`
+		Founder     *common.Address            `json:"founder"       gencodec:"required"`
+		DeputyNodes []*deputynode.DeputyNode   `json:"deputyNodes"   gencodec:"required"`
+		Reward      *deputynode.RewardSchedule `json:"reward"`
+		Config      *params.ChainConfig        `json:"config"`
 	}
 	var dec Genesis
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -63,5 +70,11 @@ func (g *Genesis) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'deputyNodes' for Genesis")
 	}
 	g.DeputyNodes = dec.DeputyNodes
+	if dec.Reward != nil {
+		g.Reward = dec.Reward
+	}
+	if dec.Config != nil {
+		g.Config = dec.Config
+	}
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
@@ -73,6 +74,10 @@ type Genesis struct {
 	GasLimit    uint64                 `json:"gasLimit"      gencodec:"required"`
 	Founder     common.Address         `json:"founder"       gencodec:"required"`
 	DeputyNodes deputynode.DeputyNodes `json:"deputyNodes"   gencodec:"required"`
+	// Reward is the schedule of deputy nodes' rewards. The default schedule is used if it is nil
+	Reward *deputynode.RewardSchedule `json:"reward"`
+	// Config is the chain config with the fork heights. The default config is used if it is nil
+	Config *params.ChainConfig `json:"config"`
 }
 
 type genesisSpecMarshaling struct {
@@ -119,10 +124,28 @@ func SetupGenesisBlock(db protocol.ChainDB, genesis *Genesis) (common.Hash, erro
 			panic("genesis deputy nodes check error")
 		}
 	}
+	if genesis.Reward != nil {
+		if err := genesis.Reward.Check(); err != nil {
+			panic("genesis reward schedule check error")
+		}
+	}
 
 	am := account.NewManager(common.Hash{}, db)
 	block := genesis.ToBlock()
 	genesis.setBalance(am)
+	if err := setGenesisCandidates(am, genesis.DeputyNodes); err != nil {
+		return common.Hash{}, fmt.Errorf("setup genesis block failed: %v", err)
+	}
+	if genesis.Reward != nil {
+		if err := setRewardSchedule(am, genesis.Reward); err != nil {
+			return common.Hash{}, fmt.Errorf("setup genesis block failed: %v", err)
+		}
+	}
+	if genesis.Config != nil {
+		if err := setChainConfig(am, genesis.Config); err != nil {
+			return common.Hash{}, fmt.Errorf("setup genesis block failed: %v", err)
+		}
+	}
 	if err := am.Finalise(); err != nil {
 		return common.Hash{}, fmt.Errorf("setup genesis block failed: %v", err)
 	}
@@ -0,0 +1,220 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"math"
+)
+
+const (
+	version = 3
+
+	keyHeaderKDF = "scrypt"
+	cipherName   = "aes-128-ctr"
+	scryptR      = 8
+	scryptDKLen  = 32
+
+	// StandardScryptN and StandardScryptP use 256MB memory and take about 1 second on a modern CPU
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+	// LightScryptN and LightScryptP use 4MB memory and take about 100ms on a modern CPU
+	LightScryptN = 1 << 12
+	LightScryptP = 6
+
+	// maxScryptP bounds the kdf params in key file. The memory and time cost are no more than the standard params
+	maxScryptP = 16
+)
+
+var (
+	ErrDecrypt            = errors.New("could not decrypt key with given passphrase")
+	ErrUnsupportedVersion = errors.New("unsupported key file version")
+	ErrUnsupportedCipher  = errors.New("unsupported cipher or kdf")
+	ErrInvalidScryptParam = errors.New("invalid scrypt params in key file")
+)
+
+// Key is the decrypted private key of an account
+type Key struct {
+	Id         string
+	Address    common.Address
+	PrivateKey *ecdsa.PrivateKey
+}
+
+type encryptedKeyJSON struct {
+	Address string     `json:"address"`
+	Crypto  cryptoJSON `json:"crypto"`
+	Id      string     `json:"id"`
+	Version int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams cipherParamsJSON       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type cipherParamsJSON struct {
+	IV string `json:"iv"`
+}
+
+// newKey creates a key with a random id
+func newKey(privateKey *ecdsa.PrivateKey) (*Key, error) {
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, err
+	}
+	return &Key{
+		Id:         hex.EncodeToString(id),
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}, nil
+}
+
+// EncryptECDSA encrypts the private key by the passphrase into a json key file content
+func EncryptECDSA(privateKey *ecdsa.PrivateKey, passphrase string, scryptN, scryptP int) ([]byte, error) {
+	key, err := newKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptKey(key, passphrase, scryptN, scryptP)
+}
+
+// EncryptKey encrypts the key by the passphrase into a json key file content
+func EncryptKey(key *Key, passphrase string, scryptN, scryptP int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	cipherText, err := aesCTRXOR(derivedKey[:16], crypto.FromECDSA(key.PrivateKey), iv)
+	if err != nil {
+		return nil, err
+	}
+	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+
+	return json.Marshal(encryptedKeyJSON{
+		Address: key.Address.Hex(),
+		Crypto: cryptoJSON{
+			Cipher:       cipherName,
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherParamsJSON{IV: hex.EncodeToString(iv)},
+			KDF:          keyHeaderKDF,
+			KDFParams: map[string]interface{}{
+				"n":     scryptN,
+				"r":     scryptR,
+				"p":     scryptP,
+				"dklen": scryptDKLen,
+				"salt":  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(mac),
+		},
+		Id:      key.Id,
+		Version: version,
+	})
+}
+
+// DecryptKey decrypts the json key file content by the passphrase
+func DecryptKey(keyJSON []byte, passphrase string) (*Key, error) {
+	var k encryptedKeyJSON
+	if err := json.Unmarshal(keyJSON, &k); err != nil {
+		return nil, err
+	}
+	if k.Version != version {
+		return nil, ErrUnsupportedVersion
+	}
+	if k.Crypto.Cipher != cipherName || k.Crypto.KDF != keyHeaderKDF {
+		return nil, ErrUnsupportedCipher
+	}
+	mac, err := hex.DecodeString(k.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := scryptKey(k.Crypto.KDFParams, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrDecrypt
+	}
+	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := crypto.ToECDSA(plainText)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		Id:         k.Id,
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}, nil
+}
+
+// scryptKey derives the key from passphrase by the kdf params in key file
+func scryptKey(params map[string]interface{}, passphrase string) ([]byte, error) {
+	saltHex, ok := params["salt"].(string)
+	if !ok {
+		return nil, ErrUnsupportedCipher
+	}
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return nil, err
+	}
+	// the params are bounded, so that a crafted key file can't exhaust the memory or CPU
+	n, ok1 := intParam(params, "n", StandardScryptN)
+	r, ok2 := intParam(params, "r", scryptR)
+	p, ok3 := intParam(params, "p", maxScryptP)
+	dkLen, ok4 := intParam(params, "dklen", scryptDKLen)
+	if !ok1 || !ok2 || !ok3 || !ok4 || n < 2 || n&(n-1) != 0 || n*p > StandardScryptN*StandardScryptP || dkLen < scryptDKLen {
+		return nil, ErrInvalidScryptParam
+	}
+	return scrypt.Key([]byte(passphrase), salt, n, r, p, dkLen)
+}
+
+// intParam reads the positive integer param which is no more than max
+func intParam(params map[string]interface{}, name string, max int) (int, bool) {
+	value, ok := params[name].(float64)
+	if !ok || value < 1 || value > float64(max) || value != math.Trunc(value) {
+		return 0, false
+	}
+	return int(value), true
+}
+
+func aesCTRXOR(key, inText, iv []byte) ([]byte, error) {
+	aesBlock, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	stream := cipher.NewCTR(aesBlock, iv)
+	outText := make([]byte, len(inText))
+	stream.XORKeyStream(outText, inText)
+	return outText, nil
+}
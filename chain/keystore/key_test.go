@@ -0,0 +1,78 @@
+package keystore
+
+import (
+	"encoding/json"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncryptKey_DecryptKey(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	key, err := newKey(privateKey)
+	assert.NoError(t, err)
+	content, err := EncryptKey(key, "123", LightScryptN, LightScryptP)
+	assert.NoError(t, err)
+
+	decrypted, err := DecryptKey(content, "123")
+	assert.NoError(t, err)
+	assert.Equal(t, key.Id, decrypted.Id)
+	assert.Equal(t, key.Address, decrypted.Address)
+	assert.Equal(t, crypto.FromECDSA(privateKey), crypto.FromECDSA(decrypted.PrivateKey))
+
+	// wrong passphrase
+	_, err = DecryptKey(content, "1234")
+	assert.Equal(t, ErrDecrypt, err)
+	// unsupported version
+	_, err = DecryptKey([]byte(`{"version":1}`), "123")
+	assert.Equal(t, ErrUnsupportedVersion, err)
+	_, err = DecryptKey([]byte(`{"version":3,"crypto":{"cipher":"aes-128-ctr","kdf":"pbkdf2"}}`), "123")
+	assert.Equal(t, ErrUnsupportedCipher, err)
+}
+
+func TestDecryptKey_scryptParams(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	content, err := EncryptECDSA(privateKey, "123", LightScryptN, LightScryptP)
+	assert.NoError(t, err)
+	decrypted, err := DecryptKey(content, "123")
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.FromECDSA(privateKey), crypto.FromECDSA(decrypted.PrivateKey))
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"n", float64(StandardScryptN * 2)},
+		{"n", float64(LightScryptN + 1)},
+		{"n", float64(1)},
+		{"n", "4096"},
+		{"r", float64(scryptR * 2)},
+		{"r", float64(0)},
+		{"p", float64(maxScryptP + 1)},
+		{"p", 1.5},
+		{"dklen", float64(scryptDKLen - 1)},
+		{"dklen", float64(scryptDKLen + 1)},
+	}
+	for _, test := range tests {
+		var keyJSON map[string]interface{}
+		assert.NoError(t, json.Unmarshal(content, &keyJSON))
+		keyJSON["crypto"].(map[string]interface{})["kdfparams"].(map[string]interface{})[test.name] = test.value
+		crafted, err := json.Marshal(keyJSON)
+		assert.NoError(t, err)
+		_, err = DecryptKey(crafted, "123")
+		assert.Equal(t, ErrInvalidScryptParam, err, "%s: %v", test.name, test.value)
+	}
+
+	// too much cost with the max n and a bigger p
+	var keyJSON map[string]interface{}
+	assert.NoError(t, json.Unmarshal(content, &keyJSON))
+	kdfParams := keyJSON["crypto"].(map[string]interface{})["kdfparams"].(map[string]interface{})
+	kdfParams["n"] = float64(StandardScryptN)
+	kdfParams["p"] = float64(StandardScryptP + 1)
+	crafted, err := json.Marshal(keyJSON)
+	assert.NoError(t, err)
+	_, err = DecryptKey(crafted, "123")
+	assert.Equal(t, ErrInvalidScryptParam, err)
+}
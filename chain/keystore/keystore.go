@@ -0,0 +1,275 @@
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoMatch             = errors.New("no key for given address")
+	ErrLocked              = errors.New("account is locked")
+	ErrAccountAlreadyExist = errors.New("account already exists")
+)
+
+// KeyStore manages the encrypted key files in a directory. An account must be unlocked by its passphrase before signing
+type KeyStore struct {
+	dir     string
+	scryptN int
+	scryptP int
+
+	unlocked map[common.Address]*unlocked
+	mu       sync.RWMutex
+}
+
+type unlocked struct {
+	*Key
+	abort chan struct{} // closed to stop the timed lock
+}
+
+// NewKeyStore creates a key store in the directory. scryptN and scryptP are used to encrypt new key files
+func NewKeyStore(dir string, scryptN, scryptP int) *KeyStore {
+	return &KeyStore{
+		dir:      dir,
+		scryptN:  scryptN,
+		scryptP:  scryptP,
+		unlocked: make(map[common.Address]*unlocked),
+	}
+}
+
+// NewAccount generates a new key and stores it encrypted by the passphrase
+func (ks *KeyStore) NewAccount(passphrase string) (common.Address, error) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return ks.ImportECDSA(privateKey, passphrase)
+}
+
+// ImportECDSA stores the private key encrypted by the passphrase
+func (ks *KeyStore) ImportECDSA(privateKey *ecdsa.PrivateKey, passphrase string) (common.Address, error) {
+	key, err := newKey(privateKey)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if err := ks.storeKey(key, passphrase); err != nil {
+		return common.Address{}, err
+	}
+	return key.Address, nil
+}
+
+// Import decrypts the key file content and stores it encrypted by the new passphrase
+func (ks *KeyStore) Import(keyJSON []byte, passphrase, newPassphrase string) (common.Address, error) {
+	key, err := DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if err := ks.storeKey(key, newPassphrase); err != nil {
+		return common.Address{}, err
+	}
+	return key.Address, nil
+}
+
+// Export returns the key file content of the account, which is encrypted by the new passphrase
+func (ks *KeyStore) Export(address common.Address, passphrase, newPassphrase string) ([]byte, error) {
+	key, err := ks.getDecryptedKey(address, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptKey(key, newPassphrase, ks.scryptN, ks.scryptP)
+}
+
+// Accounts returns the addresses of all key files in the directory
+func (ks *KeyStore) Accounts() ([]common.Address, error) {
+	files, err := ioutil.ReadDir(ks.dir)
+	if os.IsNotExist(err) {
+		return []common.Address{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	result := make([]common.Address, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		address, err := readAddress(filepath.Join(ks.dir, file.Name()))
+		if err != nil {
+			// skip the files which are not key file
+			continue
+		}
+		result = append(result, address)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Big().Cmp(result[j].Big()) < 0
+	})
+	return result, nil
+}
+
+// HasAddress reports whether a key file of the address exists
+func (ks *KeyStore) HasAddress(address common.Address) bool {
+	_, err := ks.find(address)
+	return err == nil
+}
+
+// Unlock decrypts the key and keeps it in memory. The account is locked again after the duration, or is unlocked until Lock
+// is called if the duration is 0
+func (ks *KeyStore) Unlock(address common.Address, passphrase string, duration time.Duration) error {
+	key, err := ks.getDecryptedKey(address, passphrase)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if old, ok := ks.unlocked[address]; ok {
+		close(old.abort)
+	}
+	u := &unlocked{Key: key, abort: make(chan struct{})}
+	ks.unlocked[address] = u
+	if duration > 0 {
+		go ks.expire(address, u, duration)
+	}
+	return nil
+}
+
+// Lock removes the decrypted key from memory
+func (ks *KeyStore) Lock(address common.Address) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if u, ok := ks.unlocked[address]; ok {
+		close(u.abort)
+		delete(ks.unlocked, address)
+	}
+	return nil
+}
+
+// IsUnlocked reports whether the account is unlocked
+func (ks *KeyStore) IsUnlocked(address common.Address) bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	_, ok := ks.unlocked[address]
+	return ok
+}
+
+// SignTx signs the transaction by the unlocked account
+func (ks *KeyStore) SignTx(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	u, ok := ks.unlocked[address]
+	if !ok {
+		return nil, ErrLocked
+	}
+	return types.SignTx(tx, types.MakeSigner(), u.PrivateKey)
+}
+
+func (ks *KeyStore) expire(address common.Address, u *unlocked, duration time.Duration) {
+	t := time.NewTimer(duration)
+	defer t.Stop()
+	select {
+	case <-u.abort:
+	case <-t.C:
+		ks.mu.Lock()
+		// the account may be unlocked again, so only remove the same key
+		if ks.unlocked[address] == u {
+			delete(ks.unlocked, address)
+		}
+		ks.mu.Unlock()
+	}
+}
+
+// storeKey encrypts the key and writes it into a new file
+func (ks *KeyStore) storeKey(key *Key, passphrase string) error {
+	if ks.HasAddress(key.Address) {
+		return ErrAccountAlreadyExist
+	}
+	content, err := EncryptKey(key, passphrase, ks.scryptN, ks.scryptP)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ks.dir, 0700); err != nil {
+		return err
+	}
+	// write to a temp file first, so that the key file is never broken
+	file, err := ioutil.TempFile(ks.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	file.Close()
+	return os.Rename(file.Name(), filepath.Join(ks.dir, keyFileName(key.Address)))
+}
+
+func (ks *KeyStore) getDecryptedKey(address common.Address, passphrase string) (*Key, error) {
+	path, err := ks.find(address)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := DecryptKey(content, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if key.Address != address {
+		return nil, fmt.Errorf("key content mismatch: have account %s, want %s", key.Address.String(), address.String())
+	}
+	return key, nil
+}
+
+// find returns the path of the account's key file
+func (ks *KeyStore) find(address common.Address) (string, error) {
+	files, err := ioutil.ReadDir(ks.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(ks.dir, file.Name())
+		if a, err := readAddress(path); err == nil && a == address {
+			return path, nil
+		}
+	}
+	return "", ErrNoMatch
+}
+
+// readAddress reads the address field of key file without decrypting
+func readAddress(path string) (common.Address, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var k struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(content, &k); err != nil {
+		return common.Address{}, err
+	}
+	if k.Address == "" {
+		return common.Address{}, ErrNoMatch
+	}
+	return common.HexToAddress(k.Address), nil
+}
+
+// keyFileName returns the file name like "UTC--2018-10-01T08-07-41.581Z--0x0107134b9cdd7d89f83efa6175f9b3552f29094c"
+func keyFileName(address common.Address) string {
+	ts := time.Now().UTC()
+	return fmt.Sprintf("UTC--%s--%s", ts.Format("2006-01-02T15-04-05.000Z"), address.Hex())
+}
@@ -0,0 +1,114 @@
+package keystore
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestKeyStore(t *testing.T) (*KeyStore, func()) {
+	dir, err := ioutil.TempDir("", "keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewKeyStore(dir, LightScryptN, LightScryptP), func() { os.RemoveAll(dir) }
+}
+
+func TestKeyStore_NewAccount_Accounts(t *testing.T) {
+	ks, clean := newTestKeyStore(t)
+	defer clean()
+
+	accounts, err := ks.Accounts()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(accounts))
+
+	addr1, err := ks.NewAccount("123")
+	assert.NoError(t, err)
+	addr2, err := ks.NewAccount("456")
+	assert.NoError(t, err)
+	assert.Equal(t, true, ks.HasAddress(addr1))
+	accounts, err = ks.Accounts()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(accounts))
+	assert.Contains(t, accounts, addr1)
+	assert.Contains(t, accounts, addr2)
+
+	// the key file is encrypted
+	files, err := ioutil.ReadDir(ks.dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(files))
+	assert.Equal(t, os.FileMode(0600), files[0].Mode().Perm())
+}
+
+func TestKeyStore_Import_Export(t *testing.T) {
+	ks, clean := newTestKeyStore(t)
+	defer clean()
+
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	address, err := ks.ImportECDSA(privateKey, "123")
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), address)
+	_, err = ks.ImportECDSA(privateKey, "123")
+	assert.Equal(t, ErrAccountAlreadyExist, err)
+
+	// export with new passphrase
+	_, err = ks.Export(address, "1234", "456")
+	assert.Equal(t, ErrDecrypt, err)
+	_, err = ks.Export(common.HexToAddress("0x1"), "123", "456")
+	assert.Equal(t, ErrNoMatch, err)
+	content, err := ks.Export(address, "123", "456")
+	assert.NoError(t, err)
+
+	// import to another key store
+	ks2, clean2 := newTestKeyStore(t)
+	defer clean2()
+	_, err = ks2.Import(content, "123", "789")
+	assert.Equal(t, ErrDecrypt, err)
+	imported, err := ks2.Import(content, "456", "789")
+	assert.NoError(t, err)
+	assert.Equal(t, address, imported)
+	assert.NoError(t, ks2.Unlock(address, "789", 0))
+}
+
+func TestKeyStore_Unlock_Lock_SignTx(t *testing.T) {
+	ks, clean := newTestKeyStore(t)
+	defer clean()
+	address, err := ks.NewAccount("123")
+	assert.NoError(t, err)
+	tx := types.NewTransaction(0, common.HexToAddress("0x1"), common.Big1, 21000, common.Big1, nil, 1, uint64(time.Now().Unix()+300), "", "")
+
+	_, err = ks.SignTx(address, tx)
+	assert.Equal(t, ErrLocked, err)
+	assert.Equal(t, ErrDecrypt, ks.Unlock(address, "1234", 0))
+
+	// unlock until lock
+	assert.NoError(t, ks.Unlock(address, "123", 0))
+	assert.Equal(t, true, ks.IsUnlocked(address))
+	signed, err := ks.SignTx(address, tx)
+	assert.NoError(t, err)
+	from, err := signed.From()
+	assert.NoError(t, err)
+	assert.Equal(t, address, from)
+	assert.NoError(t, ks.Lock(address))
+	assert.Equal(t, false, ks.IsUnlocked(address))
+	_, err = ks.SignTx(address, tx)
+	assert.Equal(t, ErrLocked, err)
+
+	// timed unlock
+	assert.NoError(t, ks.Unlock(address, "123", 50*time.Millisecond))
+	assert.Equal(t, true, ks.IsUnlocked(address))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, false, ks.IsUnlocked(address))
+
+	// unlock again before expiring
+	assert.NoError(t, ks.Unlock(address, "123", 50*time.Millisecond))
+	assert.NoError(t, ks.Unlock(address, "123", 0))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, true, ks.IsUnlocked(address))
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
 	"github.com/LemoFoundationLtd/lemochain-go/common/log"
 	"github.com/LemoFoundationLtd/lemochain-go/common/subscribe"
+	"math/big"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,6 +22,23 @@ type MineConfig struct {
 	Timeout   int64
 }
 
+// PackStats is the statistics of packing transactions into a mined block
+type PackStats struct {
+	Height     uint32   `json:"height"`
+	Candidates int      `json:"candidates"` // count of transactions selected by strategy
+	Packed     int      `json:"packed"`
+	Invalid    int      `json:"invalid"` // count of transactions dropped from pool
+	GasUsed    uint64   `json:"gasUsed"`
+	GasLimit   uint64   `json:"gasLimit"`
+	Fee        *big.Int `json:"fee"`
+	Elapsed    int64    `json:"elapsed"` // millisecond
+	Budget     int64    `json:"budget"`  // millisecond
+}
+
+func (s *PackStats) String() string {
+	return fmt.Sprintf("txs: %d/%d, invalid: %d, gas: %d/%d, fee: %s, elapsed: %dms/%dms", s.Packed, s.Candidates, s.Invalid, s.GasUsed, s.GasLimit, s.Fee, s.Elapsed, s.Budget)
+}
+
 type Miner struct {
 	blockInterval int64
 	timeoutTime   int64
@@ -31,6 +49,8 @@ type Miner struct {
 	engine        chain.Engine
 	chain         *chain.BlockChain
 	txProcessor   *chain.TxProcessor
+	strategy      Strategy
+	packStats     atomic.Value // *PackStats of the last mined block
 	mux           sync.Mutex
 	currentBlock  func() *types.Block
 	extra         []byte // 扩展数据 暂保留 最大256byte
@@ -55,6 +75,7 @@ func New(cfg *MineConfig, chain *chain.BlockChain, txPool *chain.TxPool, engine
 		engine:         engine,
 		currentBlock:   chain.CurrentBlock,
 		txProcessor:    chain.TxProcessor(),
+		strategy:       NewFeeStrategy(cfg),
 		recvNewBlockCh: make(chan *types.Block, 1),
 		timeToMineCh:   make(chan struct{}),
 		startCh:        make(chan struct{}),
@@ -120,6 +141,17 @@ func (m *Miner) GetMinerAddress() common.Address {
 	return m.minerAddress
 }
 
+// SetStrategy replaces the strategy of assembling transactions into block. It should be called before mining
+func (m *Miner) SetStrategy(strategy Strategy) {
+	m.strategy = strategy
+}
+
+// PackStats returns the statistics of packing transactions in the last mined block
+func (m *Miner) PackStats() *PackStats {
+	stats, _ := m.packStats.Load().(*PackStats)
+	return stats
+}
+
 // 获取最新区块的时间戳离当前时间的距离 单位：ms
 func (m *Miner) getTimespan() int64 {
 	lstSpan := m.currentBlock().Header.Time
@@ -131,6 +163,15 @@ func (m *Miner) getTimespan() int64 {
 	return now - int64(lstSpan)*1000
 }
 
+// slotLeft 获取本节点出块时间段的剩余时间
+func (m *Miner) slotLeft() time.Duration {
+	if deputynode.Instance().GetDeputiesCount() == 1 || m.timeoutTime <= 0 {
+		return time.Duration(m.blockInterval) * time.Millisecond
+	}
+	passed := m.getTimespan() % m.timeoutTime
+	return time.Duration(m.timeoutTime-passed) * time.Millisecond
+}
+
 // isSelfDeputyNode 本节点是否为代理节点
 func (m *Miner) isSelfDeputyNode() bool {
 	return deputynode.Instance().IsSelfDeputyNode(m.currentBlock().Height() + 1)
@@ -299,14 +340,27 @@ func (m *Miner) sealBlock() {
 		return
 	}
 	header := m.sealHead()
-	txs := m.txPool.Pending(10000000)
-	newHeader, packagedTxs, invalidTxs, err := m.txProcessor.ApplyTxs(header, txs)
+	// commit to the finality certificate of latest stable block
+	finality := m.chain.NextFinality(m.currentBlock())
+	if finality != nil {
+		header.FinalityRoot = finality.Root().Bytes()
+	}
+	start := time.Now()
+	budget := m.strategy.Budget(m.slotLeft())
+	txs := m.strategy.SelectTxs(m.txPool.Pending(10000000), header)
+	newHeader, packagedTxs, invalidTxs, receipts, err := m.txProcessor.ApplyTxsUntil(header, txs, start.Add(budget))
 	if err != nil {
 		log.Errorf("apply transactions for block failed! %v", err)
 		return
 	}
+	// the invalid transactions can't be packed in any block
+	m.removeTxs(invalidTxs)
 
 	hash := newHeader.Hash()
+	if !m.chain.TrySign(newHeader.Height, hash) {
+		log.Warnf("refuse to sign another block at height %d", newHeader.Height)
+		return
+	}
 	signData, err := crypto.Sign(hash[:], m.privKey)
 	if err != nil {
 		log.Errorf("sign for block failed! block hash:%s", hash.Hex())
@@ -318,17 +372,33 @@ func (m *Miner) sealBlock() {
 		log.Error("seal block error!!")
 		return
 	}
-	log.Infof("Mine a new block. height: %d hash: %s", block.Height(), block.Hash().String())
-	m.chain.SetMinedBlock(block)
-	// remove txs from pool
-	txsKeys := make([]common.Hash, len(packagedTxs)+len(invalidTxs))
-	for i, tx := range packagedTxs {
-		txsKeys[i] = tx.Hash()
+	if len(newHeader.DeputyRoot) > 0 {
+		nodes, err := chain.ElectDeputyNodes(m.chain.AccountManager(), newHeader.Height)
+		if err != nil {
+			log.Errorf("elect deputy nodes failed: %v", err)
+			return
+		}
+		block.SetDeputyNodes(nodes)
+	}
+	block.SetFinality(finality)
+	stats := &PackStats{
+		Height:     block.Height(),
+		Candidates: len(txs),
+		Packed:     len(packagedTxs),
+		Invalid:    len(invalidTxs),
+		GasUsed:    newHeader.GasUsed,
+		GasLimit:   newHeader.GasLimit,
+		Fee:        new(big.Int),
+		Elapsed:    int64(time.Since(start) / time.Millisecond),
+		Budget:     int64(budget / time.Millisecond),
 	}
-	for i, tx := range invalidTxs {
-		txsKeys[i+len(packagedTxs)] = tx.Hash()
+	for i, tx := range packagedTxs {
+		stats.Fee.Add(stats.Fee, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), tx.GasPrice()))
 	}
-	m.txPool.Remove(txsKeys)
+	m.packStats.Store(stats)
+	log.Infof("Mine a new block. height: %d hash: %s %s", block.Height(), block.Hash().String(), stats)
+	m.chain.SetMinedBlock(block, receipts)
+	m.removeTxs(packagedTxs)
 	nodeCount := deputynode.Instance().GetDeputiesCount()
 	var timeDur int64
 	if nodeCount == 1 {
@@ -339,6 +409,18 @@ func (m *Miner) sealBlock() {
 	m.resetMinerTimer(timeDur)
 }
 
+// removeTxs removes the transactions from tx pool
+func (m *Miner) removeTxs(txs types.Transactions) {
+	if len(txs) == 0 {
+		return
+	}
+	keys := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		keys[i] = tx.Hash()
+	}
+	m.txPool.Remove(keys)
+}
+
 // sealHead 生成区块头
 func (m *Miner) sealHead() *types.Header {
 	// check is need to change minerAddress
@@ -240,7 +240,9 @@ func (engine *EngineTestForMiner) Seal(header *types.Header, txs []*types.Transa
 	return nil, nil
 }
 
-func (engine *EngineTestForMiner) Finalize(header *types.Header, am *account.Manager) {}
+func (engine *EngineTestForMiner) Finalize(header *types.Header, am *account.Manager) error {
+	return nil
+}
 
 func broadcastStableBlock(block *types.Block) {}
 
@@ -286,7 +288,7 @@ func newMiner(key string) (*Miner, error) {
 		return nil, err
 	}
 
-	txPool := chain.NewTxPool(blockChain.AccountManager())
+	txPool := chain.NewTxPool(blockChain)
 	return New(Cnf, blockChain, txPool, new(EngineTestForMiner)), nil
 }
 
@@ -0,0 +1,125 @@
+package miner
+
+import (
+	"container/heap"
+	"github.com/LemoFoundationLtd/lemochain-go/chain"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"time"
+)
+
+// Strategy decides how the pending transactions are assembled into a new block
+type Strategy interface {
+	// SelectTxs returns the transactions to be applied in the new block, in the order of applying
+	SelectTxs(pending types.Transactions, header *types.Header) types.Transactions
+	// Budget returns how long the miner can spend on applying transactions. slotLeft is the rest time of the mining
+	// slot, a part of it should be reserved to broadcast the block
+	Budget(slotLeft time.Duration) time.Duration
+}
+
+// maxEvidenceTxsPerBlock is the max count of evidence transactions packed in a block, so that the free transactions
+// can't fill the block
+const maxEvidenceTxsPerBlock = 8
+
+// FeeStrategy packs the transactions with higher gas price first. The valid evidence transactions are free and packed
+// first, so that the offender is slashed in time
+type FeeStrategy struct {
+	maxBudget time.Duration
+}
+
+// NewFeeStrategy creates a FeeStrategy which spends at most 1/3 of the mining slot on applying transactions
+func NewFeeStrategy(cfg *MineConfig) *FeeStrategy {
+	return &FeeStrategy{maxBudget: time.Duration(cfg.Timeout) * time.Millisecond / 3}
+}
+
+// SelectTxs orders the transactions by effective gas price. The transactions of a sender keep their nonce order, and
+// the one which exceeds the block gas limit is dropped with the later ones of the sender. So is the evidence
+// transaction which is invalid, reports an offender reported already, or exceeds maxEvidenceTxsPerBlock
+func (s *FeeStrategy) SelectTxs(pending types.Transactions, header *types.Header) types.Transactions {
+	queues := make(map[common.Address]types.Transactions)
+	dropped := make(map[common.Address]bool)
+	offenders := make(map[string]bool)
+	for _, tx := range pending {
+		from, err := tx.From()
+		if err != nil || dropped[from] {
+			continue
+		}
+		if tx.Type() == types.EvidenceTx && !acceptEvidence(tx, header.Height, offenders) {
+			dropped[from] = true
+			continue
+		}
+		queues[from] = append(queues[from], tx)
+	}
+	heads := make(txsByFee, 0, len(queues))
+	for _, queue := range queues {
+		heads = append(heads, queue)
+	}
+	heap.Init(&heads)
+
+	result := make(types.Transactions, 0, len(pending))
+	for len(heads) > 0 {
+		queue := heads[0]
+		if queue[0].GasLimit() > header.GasLimit {
+			heap.Pop(&heads)
+			continue
+		}
+		result = append(result, queue[0])
+		if len(queue) > 1 {
+			heads[0] = queue[1:]
+			heap.Fix(&heads, 0)
+		} else {
+			heap.Pop(&heads)
+		}
+	}
+	return result
+}
+
+// Budget returns half of the rest slot time, but no more than the max budget
+func (s *FeeStrategy) Budget(slotLeft time.Duration) time.Duration {
+	if budget := slotLeft / 2; budget < s.maxBudget {
+		return budget
+	}
+	return s.maxBudget
+}
+
+// acceptEvidence reports whether the evidence transaction can be packed at height. The accepted offender is recorded in
+// offenders
+func acceptEvidence(tx *types.Transaction, height uint32, offenders map[string]bool) bool {
+	if len(offenders) >= maxEvidenceTxsPerBlock {
+		return false
+	}
+	node, err := chain.VerifyEvidenceTx(tx, height)
+	if err != nil || offenders[string(node.NodeID)] {
+		return false
+	}
+	offenders[string(node.NodeID)] = true
+	return true
+}
+
+// higherFee reports whether tx a should be packed before tx b
+func higherFee(a, b *types.Transaction) bool {
+	aEvidence, bEvidence := a.Type() == types.EvidenceTx, b.Type() == types.EvidenceTx
+	if aEvidence != bEvidence {
+		return aEvidence
+	}
+	return a.GasPrice().Cmp(b.GasPrice()) > 0
+}
+
+// txsByFee is a heap of the first transaction in each sender's queue
+type txsByFee []types.Transactions
+
+func (h txsByFee) Len() int           { return len(h) }
+func (h txsByFee) Less(i, j int) bool { return higherFee(h[i][0], h[j][0]) }
+func (h txsByFee) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *txsByFee) Push(x interface{}) {
+	*h = append(*h, x.(types.Transactions))
+}
+
+func (h *txsByFee) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
@@ -0,0 +1,112 @@
+package miner
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func makeStrategyTestTx(t *testing.T, privateKey string, nonce uint64, gasLimit uint64, gasPrice int64) *types.Transaction {
+	key, err := crypto.HexToECDSA(privateKey)
+	assert.NoError(t, err)
+	tx := types.NewTransaction(nonce, common.HexToAddress("0x1"), big.NewInt(1), gasLimit, big.NewInt(gasPrice), nil, 200, uint64(time.Now().Unix()+300), "", "")
+	tx, err = types.SignTx(tx, types.MakeSigner(), key)
+	assert.NoError(t, err)
+	return tx
+}
+
+// makeStrategyEvidenceTx creates an evidence transaction sent by privateKey, which reports that offenderKey signed two
+// blocks at the height
+func makeStrategyEvidenceTx(t *testing.T, privateKey string, nonce uint64, offenderKey string, height uint32) *types.Transaction {
+	key, err := crypto.HexToECDSA(privateKey)
+	assert.NoError(t, err)
+	offender, err := crypto.HexToECDSA(offenderKey)
+	assert.NoError(t, err)
+	sign := func(header *types.Header) types.SignData {
+		hash := header.Hash()
+		sig, err := crypto.Sign(hash[:], offender)
+		assert.NoError(t, err)
+		var data types.SignData
+		copy(data[:], sig)
+		return data
+	}
+	headerA := &types.Header{Height: height, Time: 1}
+	headerB := &types.Header{Height: height, Time: 2}
+	tx, err := chain.NewEvidenceTx(200, nonce, types.NewEvidence(headerA, sign(headerA), headerB, sign(headerB)), key)
+	assert.NoError(t, err)
+	return tx
+}
+
+func TestFeeStrategy_SelectTxs(t *testing.T) {
+	deputynode.Instance().Clear()
+	deputynode.Instance().Add(0, chain.DefaultDeputyNodes)
+	defer deputynode.Instance().Clear()
+	strategy := NewFeeStrategy(Cnf)
+	header := &types.Header{GasLimit: 100000}
+	a1 := makeStrategyTestTx(t, Nodes[0].privateKey, 1, 21000, 1)
+	a2 := makeStrategyTestTx(t, Nodes[0].privateKey, 2, 21000, 5)
+	b1 := makeStrategyTestTx(t, Nodes[1].privateKey, 1, 21000, 3)
+	c1 := makeStrategyTestTx(t, Nodes[2].privateKey, 1, 21000, 2)
+
+	// higher gas price first, but keep the nonce order of sender
+	assert.Equal(t, types.Transactions{b1, c1, a1, a2}, strategy.SelectTxs(types.Transactions{a1, a2, b1, c1}, header))
+
+	// the transaction exceeds block gas limit is dropped with the later ones of sender
+	b2 := makeStrategyTestTx(t, Nodes[1].privateKey, 2, 200000, 10)
+	b3 := makeStrategyTestTx(t, Nodes[1].privateKey, 3, 21000, 10)
+	assert.Equal(t, types.Transactions{b1, c1, a1, a2}, strategy.SelectTxs(types.Transactions{a1, a2, b1, b2, b3, c1}, header))
+
+	// evidence is packed first even though it is free
+	d1 := makeStrategyEvidenceTx(t, Nodes[3].privateKey, 1, Nodes[4].privateKey, 0)
+	assert.Equal(t, types.Transactions{d1, b1, c1}, strategy.SelectTxs(types.Transactions{b1, c1, d1}, header))
+
+	// invalid evidence is dropped with the later ones of sender
+	key, err := crypto.HexToECDSA(Nodes[3].privateKey)
+	assert.NoError(t, err)
+	evidence := &types.Evidence{HeaderA: &types.Header{}, HeaderB: &types.Header{}}
+	invalid, err := types.NewEvidenceTransaction(1, evidence, 50000, new(big.Int), 200, uint64(time.Now().Unix()+300), "")
+	assert.NoError(t, err)
+	invalid, err = types.SignTx(invalid, types.MakeSigner(), key)
+	assert.NoError(t, err)
+	d2 := makeStrategyTestTx(t, Nodes[3].privateKey, 2, 21000, 10)
+	assert.Equal(t, types.Transactions{b1, c1}, strategy.SelectTxs(types.Transactions{b1, c1, invalid, d2}, header))
+	// the offender is not a deputy node
+	otherKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	notDeputy := makeStrategyEvidenceTx(t, Nodes[3].privateKey, 1, common.ToHex(crypto.FromECDSA(otherKey))[2:], 0)
+	assert.Equal(t, types.Transactions{b1}, strategy.SelectTxs(types.Transactions{b1, notDeputy}, header))
+	// the evidence height is higher than block
+	future := makeStrategyEvidenceTx(t, Nodes[3].privateKey, 1, Nodes[4].privateKey, 1)
+	assert.Equal(t, types.Transactions{b1}, strategy.SelectTxs(types.Transactions{b1, future}, header))
+
+	// the same offender is reported only once
+	e1 := makeStrategyEvidenceTx(t, Nodes[2].privateKey, 2, Nodes[4].privateKey, 0)
+	assert.Equal(t, types.Transactions{d1, b1, c1}, strategy.SelectTxs(types.Transactions{d1, b1, c1, e1}, header))
+
+	// no more evidence than maxEvidenceTxsPerBlock
+	pending := make(types.Transactions, 0)
+	for i := uint64(0); i <= maxEvidenceTxsPerBlock; i++ {
+		offender, err := crypto.GenerateKey()
+		assert.NoError(t, err)
+		node := &deputynode.DeputyNode{MinerAddress: common.HexToAddress("0x1"), NodeID: crypto.FromECDSAPub(&offender.PublicKey)[1:], Rank: uint32(i), Votes: 1}
+		deputynode.Instance().Add(0, append(deputynode.Instance().GetDeputiesByHeight(0), node))
+		pending = append(pending, makeStrategyEvidenceTx(t, Nodes[3].privateKey, 1+i, common.ToHex(crypto.FromECDSA(offender))[2:], 0))
+	}
+	assert.Equal(t, pending[:maxEvidenceTxsPerBlock], strategy.SelectTxs(pending, header))
+
+	// unsigned
+	assert.Empty(t, strategy.SelectTxs(types.Transactions{types.NewTransaction(1, common.HexToAddress("0x1"), big.NewInt(1), 21000, big.NewInt(1), nil, 200, 0, "", "")}, header))
+}
+
+func TestFeeStrategy_Budget(t *testing.T) {
+	strategy := NewFeeStrategy(&MineConfig{SleepTime: 3000, Timeout: 9000})
+	assert.Equal(t, 3*time.Second, strategy.Budget(9*time.Second))
+	assert.Equal(t, 2*time.Second, strategy.Budget(4*time.Second))
+	assert.Equal(t, time.Duration(0), strategy.Budget(0))
+}
@@ -1,7 +1,37 @@
 package params
 
+// DefaultChainConfig is used if genesis doesn't set the chain config. The forks of main net are scheduled here
+var DefaultChainConfig = &ChainConfig{
+	ChainID: 1,
+}
+
+// ChainConfig is the chain parameters set in genesis. The fork heights schedule the protocol changes, and the fork is
+// not activated if its height is nil
 type ChainConfig struct {
 	ChainID   int64 `json:"chainid"`
 	Timeout   int64 `json:"timeout"`   // Number of timeout between blocks to produce millsecond
 	SleepTime int64 `json:"sleeptime"` // Time of one block is produced and before ohter node begin produce another block millsecond
+
+	IstanbulHeight   *uint32 `json:"istanbulHeight,omitempty"`   // CHAINID and SELFBALANCE instructions, cheaper non-zero tx data
+	StrictTimeHeight *uint32 `json:"strictTimeHeight,omitempty"` // the block time must not be earlier than its parent
+	FinalityHeight   *uint32 `json:"finalityHeight,omitempty"`   // the block commits to the finality certificate of an ancestor
+}
+
+// IsIstanbul returns whether the Istanbul fork is activated at height
+func (c *ChainConfig) IsIstanbul(height uint32) bool {
+	return isForked(c.IstanbulHeight, height)
+}
+
+// IsStrictTime returns whether the StrictTime fork is activated at height
+func (c *ChainConfig) IsStrictTime(height uint32) bool {
+	return isForked(c.StrictTimeHeight, height)
+}
+
+// IsFinality returns whether the Finality fork is activated at height
+func (c *ChainConfig) IsFinality(height uint32) bool {
+	return isForked(c.FinalityHeight, height)
+}
+
+func isForked(forkHeight *uint32, height uint32) bool {
+	return forkHeight != nil && *forkHeight <= height
 }
@@ -0,0 +1,126 @@
+package proof
+
+import (
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
+	"github.com/LemoFoundationLtd/lemochain-go/common/merkle"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/LemoFoundationLtd/lemochain-go/store/trie"
+	"math/big"
+)
+
+var (
+	ErrIndexOutOfRange = errors.New("the index is out of range")
+	ErrLeafMismatch    = errors.New("the proof is not for this data")
+	ErrRootMismatch    = errors.New("the proof doesn't match the root in header")
+	ErrInvalidProof    = errors.New("invalid proof")
+)
+
+// MerkleProof proves a leaf is in the merkle tree of transactions, change logs or events in a block
+type MerkleProof struct {
+	Leaf     common.Hash         `json:"leaf"     gencodec:"required"`
+	Siblings []merkle.MerkleNode `json:"siblings" gencodec:"required"`
+}
+
+// NewMerkleProof creates the proof of the leaf at index
+func NewMerkleProof(leaves []common.Hash, index int) (*MerkleProof, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, ErrIndexOutOfRange
+	}
+	siblings, err := merkle.FindSiblingNodes(leaves[index], merkle.New(leaves).HashNodes())
+	if err != nil {
+		return nil, err
+	}
+	return &MerkleProof{Leaf: leaves[index], Siblings: siblings}, nil
+}
+
+// Root calculates the merkle root from the leaf and its siblings
+func (p *MerkleProof) Root() common.Hash {
+	hash := p.Leaf
+	for _, node := range p.Siblings {
+		switch node.NodeType {
+		case merkle.LeftNode:
+			hash = crypto.Keccak256Hash(append(node.Hash[:], hash[:]...))
+		case merkle.RightNode:
+			hash = crypto.Keccak256Hash(append(hash[:], node.Hash[:]...))
+		}
+	}
+	return hash
+}
+
+// verify checks if the proof is for the leaf and matches the root
+func (p *MerkleProof) verify(leaf common.Hash, root common.Hash) error {
+	if p == nil {
+		return ErrInvalidProof
+	}
+	if p.Leaf != leaf {
+		return ErrLeafMismatch
+	}
+	if p.Root() != root {
+		return ErrRootMismatch
+	}
+	return nil
+}
+
+// VerifyTx checks if the transaction is in the block of header
+func VerifyTx(header *types.Header, tx *types.Transaction, p *MerkleProof) error {
+	return p.verify(tx.Hash(), header.TxRoot)
+}
+
+// VerifyChangeLog checks if the change log is in the block of header
+func VerifyChangeLog(header *types.Header, log *types.ChangeLog, p *MerkleProof) error {
+	return p.verify(log.Hash(), header.LogRoot)
+}
+
+// VerifyEvent checks if the event is in the block of header
+func VerifyEvent(header *types.Header, event *types.Event, p *MerkleProof) error {
+	return p.verify(event.Hash(), header.EventRoot)
+}
+
+// NodeList collects the encoded trie nodes in the order of being put
+type NodeList []hexutil.Bytes
+
+// Put implements store.Putter
+func (n *NodeList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+// VersionProof proves the newest change log version of an account in the version trie of a block
+type VersionProof struct {
+	Address common.Address      `json:"address" gencodec:"required"`
+	LogType types.ChangeLogType `json:"logType" gencodec:"required"`
+	Nodes   NodeList            `json:"nodes"   gencodec:"required"` // the encoded trie nodes on the path from root
+}
+
+// VersionTrieKey is the key of account version in version trie. It must be same as the key used by account manager
+func VersionTrieKey(address common.Address, logType types.ChangeLogType) []byte {
+	return append(address.Bytes(), big.NewInt(int64(logType)).Bytes()...)
+}
+
+// VerifyVersion checks the proof with the version root in header, and returns the proved version. The version is 0 if the
+// account has never been changed by the type of change log
+func VerifyVersion(header *types.Header, p *VersionProof) (uint32, error) {
+	if p == nil || len(p.Nodes) == 0 {
+		return 0, ErrInvalidProof
+	}
+	db, err := store.NewMemDatabase()
+	if err != nil {
+		return 0, err
+	}
+	for _, node := range p.Nodes {
+		if err := db.Put(crypto.Keccak256(node), node); err != nil {
+			return 0, err
+		}
+	}
+	// the version trie is a secure trie, so the key is hashed
+	key := crypto.Keccak256(VersionTrieKey(p.Address, p.LogType))
+	value, err, _ := trie.VerifyProof(header.VersionRoot, key, db)
+	if err != nil {
+		return 0, ErrRootMismatch
+	}
+	return uint32(new(big.Int).SetBytes(value).Uint64()), nil
+}
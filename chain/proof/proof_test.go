@@ -0,0 +1,93 @@
+package proof
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/merkle"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/LemoFoundationLtd/lemochain-go/store/trie"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+)
+
+func TestNewMerkleProof(t *testing.T) {
+	for count := 1; count <= 6; count++ {
+		leaves := make([]common.Hash, 0, count)
+		for i := 0; i < count; i++ {
+			leaves = append(leaves, common.BigToHash(big.NewInt(int64(i+1))))
+		}
+		root := merkle.New(leaves).Root()
+		for i := range leaves {
+			p, err := NewMerkleProof(leaves, i)
+			assert.NoError(t, err)
+			assert.Equal(t, leaves[i], p.Leaf)
+			assert.Equal(t, root, p.Root())
+		}
+		_, err := NewMerkleProof(leaves, count)
+		assert.Equal(t, ErrIndexOutOfRange, err)
+	}
+}
+
+func TestVerifyTx(t *testing.T) {
+	txs := make([]*types.Transaction, 0)
+	for i := 0; i < 3; i++ {
+		txs = append(txs, types.NewTransaction(uint64(i), common.HexToAddress("0x01"), common.Big1, 21000, common.Big1, nil, 1, 1538210391, "", ""))
+	}
+	header := &types.Header{TxRoot: types.DeriveTxsSha(txs)}
+	leaves := []common.Hash{txs[0].Hash(), txs[1].Hash(), txs[2].Hash()}
+	p, err := NewMerkleProof(leaves, 1)
+	assert.NoError(t, err)
+
+	assert.NoError(t, VerifyTx(header, txs[1], p))
+	assert.Equal(t, ErrLeafMismatch, VerifyTx(header, txs[0], p))
+	assert.Equal(t, ErrRootMismatch, VerifyTx(&types.Header{TxRoot: common.HexToHash("0x01")}, txs[1], p))
+	assert.Equal(t, ErrInvalidProof, VerifyTx(header, txs[1], nil))
+	// tampered sibling
+	p.Siblings[0].Hash = common.HexToHash("0x01")
+	assert.Equal(t, ErrRootMismatch, VerifyTx(header, txs[1], p))
+}
+
+func TestVerifyEvent(t *testing.T) {
+	events := []*types.Event{
+		{Address: common.HexToAddress("0x01"), Topics: []common.Hash{common.HexToHash("0x11")}},
+		{Address: common.HexToAddress("0x02"), Data: []byte{1}},
+	}
+	header := &types.Header{EventRoot: types.DeriveEventsSha(events)}
+	p, err := NewMerkleProof([]common.Hash{events[0].Hash(), events[1].Hash()}, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyEvent(header, events[0], p))
+	assert.Equal(t, ErrLeafMismatch, VerifyEvent(header, events[1], p))
+}
+
+func TestVerifyVersion(t *testing.T) {
+	db, _ := store.NewMemDatabase()
+	tr, err := trie.NewSecure(common.Hash{}, store.NewTrieDatabase(db), 120)
+	assert.NoError(t, err)
+	addr1 := common.HexToAddress("0x01")
+	addr2 := common.HexToAddress("0x02")
+	assert.NoError(t, tr.TryUpdate(VersionTrieKey(addr1, 1), big.NewInt(5).Bytes()))
+	assert.NoError(t, tr.TryUpdate(VersionTrieKey(addr2, 1), big.NewInt(300).Bytes()))
+	assert.NoError(t, tr.TryUpdate(VersionTrieKey(addr2, 2), big.NewInt(1).Bytes()))
+	header := &types.Header{VersionRoot: tr.Hash()}
+
+	prove := func(address common.Address, logType types.ChangeLogType) *VersionProof {
+		p := &VersionProof{Address: address, LogType: logType}
+		assert.NoError(t, tr.Prove(crypto.Keccak256(VersionTrieKey(address, logType)), 0, &p.Nodes))
+		return p
+	}
+	version, err := VerifyVersion(header, prove(addr2, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(300), version)
+	// not exist
+	version, err = VerifyVersion(header, prove(addr1, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), version)
+
+	// wrong root
+	_, err = VerifyVersion(&types.Header{VersionRoot: common.HexToHash("0x01")}, prove(addr1, 1))
+	assert.Equal(t, ErrRootMismatch, err)
+	_, err = VerifyVersion(header, &VersionProof{})
+	assert.Equal(t, ErrInvalidProof, err)
+}
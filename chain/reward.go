@@ -0,0 +1,91 @@
+package chain
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"math/big"
+)
+
+// RewardAddress is the system account which stores the reward schedule and emits the events of reward payouts
+var RewardAddress = common.HexToAddress("0x1002")
+
+// RewardEventTopic is the first topic of reward event. The second topic is the receiver, and the data is the amount
+var RewardEventTopic = crypto.Keccak256Hash([]byte("Reward(address,uint256)"))
+
+// rewardScheduleKey is the storage key of reward schedule in RewardAddress
+var rewardScheduleKey = common.Hash{}
+
+// GetRewardSchedule returns the reward schedule set in genesis. It is the default schedule if genesis doesn't set it
+func GetRewardSchedule(am *account.Manager) (*deputynode.RewardSchedule, error) {
+	value, err := am.GetAccount(RewardAddress).GetStorageState(rewardScheduleKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) == 0 {
+		return deputynode.DefaultRewardSchedule, nil
+	}
+	schedule := new(deputynode.RewardSchedule)
+	if err := rlp.DecodeBytes(value, schedule); err != nil {
+		return nil, deputynode.ErrInvalidRewardSchedule
+	}
+	return schedule, nil
+}
+
+func setRewardSchedule(am *account.Manager, schedule *deputynode.RewardSchedule) error {
+	value, err := rlp.EncodeToBytes(schedule)
+	if err != nil {
+		return err
+	}
+	return am.GetAccount(RewardAddress).SetStorageState(rewardScheduleKey, value)
+}
+
+// handOutRewards pays the rewards of last term, and records every payout as an event of RewardAddress
+func handOutRewards(height uint32, am *account.Manager) error {
+	schedule, err := GetRewardSchedule(am)
+	if err != nil {
+		return err
+	}
+	// the salary of slashed deputy node is cut and given to treasury, so that the payouts still sum to the total reward
+	treasuryReward := new(big.Int)
+	for _, item := range deputynode.CalcSalary(height, schedule) {
+		if item.Address == schedule.Treasury {
+			treasuryReward.Add(treasuryReward, item.Salary)
+			continue
+		}
+		if item.Salary.Sign() == 0 {
+			continue
+		}
+		slashed, err := isSlashed(am.GetAccount(EvidenceAddress), slashedMinerKey(item.Address))
+		if err != nil {
+			return err
+		}
+		if slashed {
+			treasuryReward.Add(treasuryReward, item.Salary)
+			continue
+		}
+		payReward(am, height, item.Address, item.Salary)
+	}
+	// the cut salary is burnt if no treasury is set
+	if schedule.Treasury != (common.Address{}) && treasuryReward.Sign() > 0 {
+		payReward(am, height, schedule.Treasury, treasuryReward)
+	}
+	return nil
+}
+
+// payReward adds the reward to receiver's balance and records it as an event of RewardAddress
+func payReward(am *account.Manager, height uint32, receiver common.Address, reward *big.Int) {
+	account := am.GetAccount(receiver)
+	balance := account.GetBalance()
+	balance.Add(balance, reward)
+	account.SetBalance(balance)
+	am.AddEvent(&types.Event{
+		Address:     RewardAddress,
+		Topics:      []common.Hash{RewardEventTopic, receiver.Hash()},
+		Data:        reward.Bytes(),
+		BlockHeight: height,
+	})
+}
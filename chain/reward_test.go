@@ -0,0 +1,57 @@
+package chain
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+)
+
+func TestGetRewardSchedule(t *testing.T) {
+	dpovp := loadDpovp()
+	defer dpovp.db.Close()
+
+	// default schedule
+	am := account.NewManager(common.Hash{}, dpovp.db)
+	schedule, err := GetRewardSchedule(am)
+	assert.NoError(t, err)
+	assert.Equal(t, deputynode.DefaultRewardSchedule, schedule)
+
+	// the schedule in genesis
+	genesis := DefaultGenesisBlock()
+	genesis.Reward = &deputynode.RewardSchedule{TermReward: big.NewInt(100), ReductionInterval: 1000, ReductionRate: 500, TreasuryRate: 10, Treasury: common.HexToAddress("0x99")}
+	hash, err := SetupGenesisBlock(dpovp.db, genesis)
+	assert.NoError(t, err)
+	schedule, err = GetRewardSchedule(account.NewManager(hash, dpovp.db))
+	assert.NoError(t, err)
+	assert.Equal(t, genesis.Reward, schedule)
+}
+
+func TestHandOutRewards(t *testing.T) {
+	err := initDeputyNode(2, 0)
+	assert.NoError(t, err)
+	dpovp := loadDpovp()
+	defer dpovp.db.Close()
+	am := account.NewManager(common.Hash{}, dpovp.db)
+	treasury := common.HexToAddress("0x99")
+	oneLemo := new(big.Int).SetUint64(1000000000000000000)
+	assert.NoError(t, setRewardSchedule(am, &deputynode.RewardSchedule{TermReward: oneLemo, TreasuryRate: 100, Treasury: treasury}))
+
+	assert.NoError(t, handOutRewards(1001, am))
+	events := am.GetEvents()
+	assert.Equal(t, 3, len(events))
+	total := new(big.Int)
+	for _, event := range events {
+		assert.Equal(t, RewardAddress, event.Address)
+		assert.Equal(t, RewardEventTopic, event.Topics[0])
+		receiver := common.BytesToAddress(event.Topics[1].Bytes())
+		amount := new(big.Int).SetBytes(event.Data)
+		assert.Equal(t, amount, am.GetAccount(receiver).GetBalance())
+		total.Add(total, amount)
+	}
+	// all rewards are paid
+	assert.Equal(t, oneLemo, total)
+	assert.Equal(t, treasury, common.BytesToAddress(events[2].Topics[1].Bytes()))
+}
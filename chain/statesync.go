@@ -0,0 +1,438 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/proof"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/LemoFoundationLtd/lemochain-go/store/trie"
+	"math/big"
+)
+
+var (
+	ErrStableStateChanged = errors.New("the stable block is changed while reading its state")
+	ErrMissingPreimage    = errors.New("can't find the account address of version trie key")
+	ErrStateSyncPivot     = errors.New("the pivot block is not higher than local chain")
+	ErrStateSyncBlock     = errors.New("the block is not on the chain of pivot block")
+	ErrNotEnoughConfirms  = errors.New("the block is not confirmed by enough deputy nodes")
+	ErrStateSyncAccounts  = errors.New("the accounts don't match the version root of pivot block")
+	ErrStateSyncData      = errors.New("the state data is not requested")
+	ErrStateSyncNotDone   = errors.New("the state sync is not finished")
+)
+
+// StableState returns the stable block and a page of accounts in its state. The accounts in db are always the state of
+// stable block, so they are found by the keys in version trie from start. The page ends before the accounts exceed maxBytes,
+// and the key of next page is returned. It is empty if there is no more account. The latest stable block is used if the
+// pivot is empty, or else the pivot must be the stable block
+func (bc *BlockChain) StableState(pivot, start common.Hash, maxBytes int) (*types.Block, []*types.AccountData, common.Hash, error) {
+	for retry := 0; retry < 3; retry++ {
+		block := bc.StableBlock()
+		if pivot != (common.Hash{}) && pivot != block.Hash() {
+			return nil, nil, common.Hash{}, ErrStableStateChanged
+		}
+		accounts, next, err := bc.stableAccounts(block, start, maxBytes)
+		if err != nil {
+			return nil, nil, common.Hash{}, err
+		}
+		if bc.StableBlock().Hash() == block.Hash() {
+			return block, accounts, next, nil
+		}
+	}
+	return nil, nil, common.Hash{}, ErrStableStateChanged
+}
+
+func (bc *BlockChain) stableAccounts(block *types.Block, start common.Hash, maxBytes int) ([]*types.AccountData, common.Hash, error) {
+	versionTrie, err := trie.NewSecure(block.VersionRoot(), bc.db.GetTrieDatabase(), account.MaxTrieCacheGen)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	accounts := make([]*types.AccountData, 0)
+	found := make(map[common.Address]bool)
+	size := 0
+	it := trie.NewIterator(versionTrie.NodeIterator(start[:]))
+	for it.Next() {
+		if size >= maxBytes {
+			return accounts, common.BytesToHash(it.Key), nil
+		}
+		key := versionTrie.GetKey(it.Key)
+		if len(key) < common.AddressLength {
+			return nil, common.Hash{}, ErrMissingPreimage
+		}
+		address := common.BytesToAddress(key[:common.AddressLength])
+		if found[address] {
+			continue
+		}
+		found[address] = true
+		data, err := bc.db.GetCanonicalAccount(address)
+		if err != nil {
+			return nil, common.Hash{}, err
+		}
+		encoded, err := rlp.EncodeToBytes(data)
+		if err != nil {
+			return nil, common.Hash{}, err
+		}
+		size += len(encoded)
+		accounts = append(accounts, data)
+	}
+	if it.Err != nil {
+		return nil, common.Hash{}, it.Err
+	}
+	return accounts, common.Hash{}, nil
+}
+
+// GetNodeData returns the trie nodes or contract codes by their hashes. The missing ones are skipped
+func (bc *BlockChain) GetNodeData(hashes []common.Hash) [][]byte {
+	trieDb := bc.db.GetTrieDatabase()
+	result := make([][]byte, 0, len(hashes))
+	for _, hash := range hashes {
+		if data, err := trieDb.Node(hash); err == nil && len(data) > 0 {
+			result = append(result, data)
+		}
+	}
+	return result
+}
+
+// verifyStableConfirms checks the confirms of block are signed by deputy nodes, and the count is enough to make it stable
+func (bc *BlockChain) verifyStableConfirms(block *types.Block) error {
+	hash := block.Hash()
+	signers := make(map[string]bool)
+	for _, sig := range block.Confirms {
+		pubKey, err := crypto.Ecrecover(hash[:], sig[:])
+		if err != nil || bc.getSignerIndex(pubKey[1:], block.Height()) < 0 {
+			return ErrInvalidSignedConfirmInfo
+		}
+		signers[string(pubKey)] = true
+	}
+	// same as InsertChain, the blocks are stable without confirms if deputy nodes less than 3
+	nodeCount := len(deputynode.Instance().GetDeputiesByHeight(block.Height()))
+	if nodeCount >= 3 && len(signers) < types.MinConfirmCount(nodeCount) {
+		return ErrNotEnoughConfirms
+	}
+	return nil
+}
+
+// StateSync downloads the state of a stable block (pivot) from other nodes, so that the blocks before pivot are saved
+// without executing their transactions. The account set comes with pivot block, and the version trie, the storage tries
+// and the contract codes are downloaded by hash. All of them are verified by the version root of pivot, and the account
+// data is verified by the change logs of blocks.
+// NOTE: the receipts of blocks before pivot are not available
+type StateSync struct {
+	bc       *BlockChain
+	pivot    *types.Block
+	accounts []*types.AccountData
+	last     *types.Block           // the last saved block
+	hashes   map[uint32]common.Hash // the hashes of saved blocks which are not stable in local
+
+	trieDb   *store.TrieDatabase
+	sched    *trie.TrieSync
+	verified bool // whether the accounts are verified by the version trie
+}
+
+// NewStateSync creates a state sync to the pivot block with its accounts
+func (bc *BlockChain) NewStateSync(pivot *types.Block, accounts []*types.AccountData) (*StateSync, error) {
+	if pivot == nil || pivot.Height() <= bc.CurrentBlock().Height() {
+		return nil, ErrStateSyncPivot
+	}
+	trieDb := bc.db.GetTrieDatabase()
+	s := &StateSync{
+		bc:       bc,
+		pivot:    pivot,
+		accounts: accounts,
+		last:     bc.StableBlock(),
+		hashes:   make(map[uint32]common.Hash),
+		trieDb:   trieDb,
+		sched:    trie.NewTrieSync(pivot.VersionRoot(), trieDb.DiskDB(), nil),
+	}
+	if err := s.checkProgress(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Pivot returns the block whose state is being downloaded
+func (s *StateSync) Pivot() *types.Block {
+	return s.pivot
+}
+
+// NextHeight returns the height of next block to insert
+func (s *StateSync) NextHeight() uint32 {
+	return s.last.Height() + 1
+}
+
+// InsertBlock verifies the header and body of a block before pivot, and saves it without executing the transactions. The
+// blocks must be inserted one by one from local stable block to pivot. The confirms of snapshot blocks and pivot are
+// verified too, so the deputy nodes can be trusted
+func (s *StateSync) InsertBlock(block *types.Block) error {
+	hash := block.Hash()
+	if block.ParentHash() != s.last.Hash() || block.Height() > s.pivot.Height() {
+		return ErrStateSyncBlock
+	}
+	isPivot := block.Height() == s.pivot.Height()
+	if isPivot {
+		if hash != s.pivot.Hash() {
+			return ErrStateSyncBlock
+		}
+		block = s.pivot
+	}
+	if err := s.bc.engine.VerifyHeader(block); err != nil {
+		return err
+	}
+	if err := s.bc.verifyBody(block); err != nil {
+		return err
+	}
+	// the change logs are used to verify the accounts of pivot
+	if types.DeriveChangeLogsSha(block.ChangeLogs) != block.Header.LogRoot {
+		return ErrVerifyBlockFailed
+	}
+	isSnapshot := block.Height()%deputynode.SnapshotBlockInterval == 0
+	if isPivot || isSnapshot {
+		if err := s.bc.verifyStableConfirms(block); err != nil {
+			return err
+		}
+	}
+	if err := s.bc.db.SetBlock(hash, block); err != nil && err != store.ErrExist {
+		return ErrSaveBlock
+	}
+	if isSnapshot && len(block.DeputyNodes) > 0 {
+		deputynode.Instance().Add(block.Height(), block.DeputyNodes)
+	}
+	s.hashes[block.Height()] = hash
+	s.last = block
+	return nil
+}
+
+// Missing returns the hashes of trie nodes and contract codes to download
+func (s *StateSync) Missing(max int) []common.Hash {
+	return s.sched.Missing(max)
+}
+
+// Process saves the downloaded trie nodes and contract codes
+func (s *StateSync) Process(data [][]byte) error {
+	results := make([]trie.SyncResult, len(data))
+	for i, item := range data {
+		results[i] = trie.SyncResult{Hash: crypto.Keccak256Hash(item), Data: item}
+	}
+	if _, index, err := s.sched.Process(results); err != nil {
+		log.Debugf("process state data failed. hash: %s, err: %v", results[index].Hash.Hex(), err)
+		return ErrStateSyncData
+	}
+	if _, err := s.sched.Commit(s.trieDb.DiskWriter()); err != nil {
+		return err
+	}
+	return s.checkProgress()
+}
+
+// checkProgress verifies the accounts after the version trie is downloaded, then schedules their storage tries and codes
+func (s *StateSync) checkProgress() error {
+	if s.verified || s.sched.Pending() != 0 {
+		return nil
+	}
+	if err := s.verifyAccounts(); err != nil {
+		return err
+	}
+	s.verified = true
+	for _, data := range s.accounts {
+		if data.StorageRoot != (common.Hash{}) {
+			s.sched.AddSubTrie(data.StorageRoot, 0, common.Hash{}, nil)
+		}
+		if data.CodeHash != (common.Hash{}) {
+			s.sched.AddRawEntry(data.CodeHash, 0, common.Hash{})
+		}
+	}
+	return nil
+}
+
+// verifyAccounts checks the newest versions of every account are recorded in version trie, and nothing else is in the trie
+func (s *StateSync) verifyAccounts() error {
+	versionTrie, err := trie.NewSecure(s.pivot.VersionRoot(), s.trieDb, account.MaxTrieCacheGen)
+	if err != nil {
+		return err
+	}
+	versions := make(map[common.Hash][]byte)
+	it := trie.NewIterator(versionTrie.NodeIterator(nil))
+	for it.Next() {
+		versions[common.BytesToHash(it.Key)] = it.Value
+	}
+	if it.Err != nil {
+		return it.Err
+	}
+
+	found := make(map[common.Address]bool, len(s.accounts))
+	count := 0
+	for _, data := range s.accounts {
+		if data == nil || found[data.Address] {
+			return ErrStateSyncAccounts
+		}
+		found[data.Address] = true
+		for logType, record := range data.NewestRecords {
+			if record.Version == 0 {
+				continue
+			}
+			key := proof.VersionTrieKey(data.Address, logType)
+			hash := crypto.Keccak256Hash(key)
+			version, ok := versions[hash]
+			if !ok || !bytes.Equal(version, big.NewInt(int64(record.Version)).Bytes()) {
+				return ErrStateSyncAccounts
+			}
+			// the preimages are required to serve the stable state for other nodes
+			s.trieDb.Lock()
+			s.trieDb.InsertPreimage(hash, key)
+			s.trieDb.UnLock()
+			count++
+		}
+	}
+	if count != len(versions) {
+		return ErrStateSyncAccounts
+	}
+	return nil
+}
+
+// verifyAccountValues checks the account data are same as the newest change logs. The change log of every newest version is
+// in the block at the recorded height, which is committed by the LogRoot of that block. It requires all blocks and state data
+// to be downloaded
+func (s *StateSync) verifyAccountValues() error {
+	for _, data := range s.accounts {
+		for logType, record := range data.NewestRecords {
+			if record.Version == 0 || logType == account.AddEventLog || logType == account.SuicideLog || logType == account.CandidateProfileLog {
+				continue
+			}
+			c, err := s.findChangeLog(data.Address, logType, record)
+			if err != nil {
+				return err
+			}
+			if !s.matchChangeLog(data, c) {
+				log.Debugf("account data doesn't match the change log. address: %s, log: %v", data.Address.String(), c)
+				return ErrStateSyncAccounts
+			}
+		}
+	}
+	return nil
+}
+
+// findChangeLog finds the change log of the version record
+func (s *StateSync) findChangeLog(address common.Address, logType types.ChangeLogType, record types.VersionRecord) (*types.ChangeLog, error) {
+	heights := []uint32{record.Height}
+	// the accounts set in genesis are recorded at height 1
+	if record.Height == 1 {
+		heights = append(heights, 0)
+	}
+	for _, height := range heights {
+		var block *types.Block
+		var err error
+		if hash, ok := s.hashes[height]; ok {
+			block, err = s.bc.db.GetBlockByHash(hash)
+		} else if height <= s.bc.StableBlock().Height() {
+			block, err = s.bc.db.GetBlockByHeight(height)
+		} else {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range block.ChangeLogs {
+			if c.Address == address && c.LogType == logType && c.Version == record.Version {
+				return c, nil
+			}
+		}
+	}
+	return nil, ErrStateSyncAccounts
+}
+
+// matchChangeLog checks the account data is same as the NewVal of change log
+func (s *StateSync) matchChangeLog(data *types.AccountData, c *types.ChangeLog) bool {
+	switch c.LogType {
+	case account.BalanceLog:
+		balance, ok := c.NewVal.(big.Int)
+		return ok && data.Balance != nil && data.Balance.Cmp(&balance) == 0
+	case account.NonceLog:
+		nonce, ok := c.NewVal.(uint64)
+		return ok && data.Nonce == nonce
+	case account.CodeLog:
+		code, ok := c.NewVal.(types.Code)
+		if len(code) == 0 {
+			return ok && (data.CodeHash == common.Hash{} || data.CodeHash == crypto.Keccak256Hash(nil))
+		}
+		return ok && data.CodeHash == crypto.Keccak256Hash(code)
+	case account.VoteForLog:
+		voteFor, ok := c.NewVal.(common.Address)
+		if data.VoteFor == nil {
+			return ok && voteFor == common.Address{}
+		}
+		return ok && *data.VoteFor == voteFor
+	case account.VotesLog:
+		votes, ok := c.NewVal.(big.Int)
+		if data.Votes == nil {
+			return ok && votes.Sign() == 0
+		}
+		return ok && data.Votes.Cmp(&votes) == 0
+	case account.StorageLog:
+		// the storage logs of different keys are merged in a block, so only the last changed key is checked
+		value, ok := c.NewVal.([]byte)
+		key, hasKey := storageLogKey(c)
+		if !ok || !hasKey {
+			return false
+		}
+		storage, err := trie.NewSecure(data.StorageRoot, s.trieDb, account.MaxTrieCacheGen)
+		if err != nil {
+			return false
+		}
+		stored, err := storage.TryGet(key[:])
+		// the leading zeros are trimmed in storage trie
+		return (err == nil || err == store.ErrNotExist) && bytes.Equal(stored, bytes.TrimLeft(value, "\x00"))
+	}
+	return true
+}
+
+// storageLogKey returns the storage key of the storage change log. The key is decoded as bytes from db
+func storageLogKey(c *types.ChangeLog) (common.Hash, bool) {
+	switch extra := c.Extra.(type) {
+	case common.Hash:
+		return extra, true
+	case []byte:
+		return common.BytesToHash(extra), true
+	}
+	return common.Hash{}, false
+}
+
+// Done reports whether all blocks and state data are downloaded
+func (s *StateSync) Done() bool {
+	return s.last.Hash() == s.pivot.Hash() && s.verified && s.sched.Pending() == 0
+}
+
+// Commit saves the accounts of pivot block, then switches the chain to pivot as the new stable block
+func (s *StateSync) Commit() error {
+	if !s.Done() {
+		return ErrStateSyncNotDone
+	}
+	if err := s.verifyAccountValues(); err != nil {
+		return err
+	}
+	bc := s.bc
+	hash := s.pivot.Hash()
+	if err := s.trieDb.Commit(s.pivot.VersionRoot(), false); err != nil {
+		return err
+	}
+	if err := bc.db.SetAccounts(hash, s.accounts); err != nil {
+		return ErrSaveAccount
+	}
+	if err := bc.db.SetStableBlock(hash); err != nil {
+		log.Errorf("SetStableBlock error. height:%d hash:%s", s.pivot.Height(), hash.Hex())
+		return ErrSetStableBlockToDB
+	}
+	bc.chainForksLock.Lock()
+	bc.chainForksHead = map[common.Hash]*types.Block{hash: s.pivot}
+	bc.currentBlock.Store(s.pivot)
+	bc.stableBlock.Store(s.pivot)
+	bc.chainForksLock.Unlock()
+	bc.am.Reset(hash)
+	log.Infof("State sync finished. height: %d, hash: %s, accounts: %d", s.pivot.Height(), hash.Hex(), len(s.accounts))
+	bc.StableBlockFeed.Send(s.pivot)
+	return nil
+}
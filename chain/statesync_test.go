@@ -0,0 +1,177 @@
+package chain
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	syncContractAddr = common.HexToAddress("0x10000")
+	syncStorageKey   = common.HexToHash("0x1")
+)
+
+// makeContractBlock creates a stable block which deploys a contract with storage
+func makeContractBlock(t *testing.T, bc *BlockChain, parent *types.Block) *types.Block {
+	am := account.NewManager(parent.Hash(), bc.db)
+	contract := am.GetAccount(syncContractAddr)
+	contract.SetCode(types.Code{0x60, 0x80, 0x60, 0x40})
+	assert.NoError(t, contract.SetStorageState(syncStorageKey, []byte{0x2a}))
+	assert.NoError(t, am.Finalise())
+	header := &types.Header{
+		ParentHash:  parent.Hash(),
+		VersionRoot: am.GetVersionRoot(),
+		TxRoot:      types.DeriveTxsSha(nil),
+		LogRoot:     types.DeriveChangeLogsSha(am.GetChangeLogs()),
+		Bloom:       types.CreateBloom(nil),
+		Height:      parent.Height() + 1,
+		GasLimit:    1000000,
+		Time:        uint32(time.Now().Unix()),
+		Extra:       []byte{},
+	}
+	block := &types.Block{ChangeLogs: am.GetChangeLogs()}
+	block.SetHeader(header)
+	assert.NoError(t, bc.insertTrustedBlock(block))
+	return block
+}
+
+// fetchStableState reads the stable state page by page, and returns the pivot, the distinct accounts and the count of pages
+func fetchStableState(t *testing.T, bc *BlockChain, maxBytes int) (*types.Block, []*types.AccountData, int) {
+	pivot, accounts, next, err := bc.StableState(common.Hash{}, common.Hash{}, maxBytes)
+	assert.NoError(t, err)
+	found := make(map[common.Address]bool)
+	for _, data := range accounts {
+		found[data.Address] = true
+	}
+	pages := 1
+	for next != (common.Hash{}) {
+		var page []*types.AccountData
+		_, page, next, err = bc.StableState(pivot.Hash(), next, maxBytes)
+		assert.NoError(t, err)
+		for _, data := range page {
+			if !found[data.Address] {
+				found[data.Address] = true
+				accounts = append(accounts, data)
+			}
+		}
+		pages++
+	}
+	return pivot, accounts, pages
+}
+
+// downloadState feeds the state data from src to s until it's done
+func downloadState(s *StateSync, src *BlockChain) error {
+	for !s.Done() {
+		hashes := s.Missing(2)
+		if len(hashes) == 0 {
+			return ErrStateSyncNotDone
+		}
+		if err := s.Process(src.GetNodeData(hashes)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestBlockChain_StateSync(t *testing.T) {
+	src, blocks := makeExportChain(t)
+	blocks = append(blocks, makeContractBlock(t, src, blocks[3]))
+	pivot, accounts, pages := fetchStableState(t, src, 1024*1024)
+	assert.Equal(t, 1, pages)
+	assert.Equal(t, blocks[4].Hash(), pivot.Hash())
+	// every page has one account at least
+	_, paged, pages := fetchStableState(t, src, 1)
+	assert.Equal(t, len(accounts), len(paged))
+	assert.True(t, pages >= len(accounts))
+	// the pivot must be the stable block
+	_, _, _, err := src.StableState(blocks[3].Hash(), common.Hash{}, 1)
+	assert.Equal(t, ErrStableStateChanged, err)
+	found := false
+	for _, data := range accounts {
+		if data.Address == syncContractAddr {
+			found = true
+			assert.NotEqual(t, common.Hash{}, data.StorageRoot)
+		}
+	}
+	assert.True(t, found)
+
+	nodes := deputynode.Instance().DeputyNodesList
+	defer func() { deputynode.Instance().DeputyNodesList = nodes }()
+	deputynode.Instance().Clear()
+	deputynode.Instance().Add(0, DefaultDeputyNodes)
+
+	dst, _, err := newBlockChainForTestAt(filepath.Join(store.GetStorePath(), "dst"))
+	assert.NoError(t, err)
+	_, err = dst.NewStateSync(dst.Genesis(), accounts)
+	assert.Equal(t, ErrStateSyncPivot, err)
+	s, err := dst.NewStateSync(pivot, accounts)
+	assert.NoError(t, err)
+	assert.Equal(t, pivot, s.Pivot())
+	assert.Equal(t, ErrStateSyncBlock, s.InsertBlock(blocks[2]))
+	for height := 1; height < 4; height++ {
+		assert.NoError(t, s.InsertBlock(blocks[height]))
+	}
+	assert.Equal(t, uint32(4), s.NextHeight())
+	// the pivot has no confirm
+	assert.Equal(t, ErrNotEnoughConfirms, s.InsertBlock(blocks[4]))
+	deputynode.Instance().Clear()
+	deputynode.Instance().Add(0, DefaultDeputyNodes[:1])
+	assert.NoError(t, s.InsertBlock(blocks[4]))
+
+	assert.Equal(t, ErrStateSyncNotDone, s.Commit())
+	assert.Equal(t, ErrStateSyncData, s.Process([][]byte{{1, 2, 3}}))
+	assert.NoError(t, downloadState(s, src))
+	assert.NoError(t, s.Commit())
+	assert.Equal(t, pivot.Hash(), dst.StableBlock().Hash())
+	assert.Equal(t, pivot.Hash(), dst.CurrentBlock().Hash())
+	assert.Equal(t, blocks[2].Hash(), dst.GetBlockByHeight(2).Hash())
+	contract := dst.AccountManager().GetAccount(syncContractAddr)
+	code, err := contract.GetCode()
+	assert.NoError(t, err)
+	assert.Equal(t, types.Code{0x60, 0x80, 0x60, 0x40}, code)
+	value, err := contract.GetStorageState(syncStorageKey)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x2a}, value)
+	// the synced node can serve the state too
+	_, dstAccounts, _ := fetchStableState(t, dst, 1)
+	assert.Equal(t, len(accounts), len(dstAccounts))
+
+	// the account data doesn't match the change logs
+	tampered := make([]*types.AccountData, len(accounts))
+	cheat := false
+	for i, data := range accounts {
+		tampered[i] = data.Copy()
+		if !cheat && data.NewestRecords[account.BalanceLog].Version > 0 {
+			tampered[i].Balance = new(big.Int).Add(data.Balance, big.NewInt(1))
+			cheat = true
+		}
+	}
+	assert.True(t, cheat)
+	cheated, _, err := newBlockChainForTestAt(filepath.Join(store.GetStorePath(), "cheated"))
+	assert.NoError(t, err)
+	deputynode.Instance().Clear()
+	deputynode.Instance().Add(0, DefaultDeputyNodes[:1])
+	s, err = cheated.NewStateSync(pivot, tampered)
+	assert.NoError(t, err)
+	for height := 1; height <= 4; height++ {
+		assert.NoError(t, s.InsertBlock(blocks[height]))
+	}
+	assert.NoError(t, downloadState(s, src))
+	assert.Equal(t, ErrStateSyncAccounts, s.Commit())
+	assert.Equal(t, cheated.Genesis().Hash(), cheated.StableBlock().Hash())
+
+	// the account set doesn't match the version trie
+	other, _, err := newBlockChainForTestAt(filepath.Join(store.GetStorePath(), "other"))
+	assert.NoError(t, err)
+	s, err = other.NewStateSync(pivot, accounts[1:])
+	assert.NoError(t, err)
+	assert.Equal(t, ErrStateSyncAccounts, downloadState(s, src))
+	store.ClearData()
+}
@@ -0,0 +1,118 @@
+package chain
+
+import (
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"io"
+	"os"
+)
+
+// errNoActiveJournal is returned if a transaction is attempted to be inserted
+// into the journal, but no such file is currently open.
+var errNoActiveJournal = errors.New("no active journal")
+
+// txJournal is a rotating log of transactions with the aim of storing the transactions in pool across node restarts
+type txJournal struct {
+	path   string         // Filesystem path to store the transactions at
+	writer io.WriteCloser // Output stream to write new transactions into
+}
+
+// newTxJournal creates a new transaction journal
+func newTxJournal(path string) *txJournal {
+	return &txJournal{
+		path: path,
+	}
+}
+
+// load parses a transaction journal dump from disk, loading its contents into the specified pool
+func (journal *txJournal) load(add func(txs types.Transactions)) error {
+	input, err := os.Open(journal.path)
+	if os.IsNotExist(err) {
+		// Skip the parsing if the journal file doesn't exist at all
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// load the transactions in batches, so that the huge journal won't take too much memory
+	stream := rlp.NewStream(input, 0)
+	total := 0
+	batch := make(types.Transactions, 0, 1024)
+	for {
+		tx := new(types.Transaction)
+		if err = stream.Decode(tx); err != nil {
+			break
+		}
+		total++
+		batch = append(batch, tx)
+		if len(batch) == cap(batch) {
+			add(batch)
+			batch = make(types.Transactions, 0, 1024)
+		}
+	}
+	if len(batch) > 0 {
+		add(batch)
+	}
+	log.Infof("Loaded local transaction journal. transactions: %d", total)
+	if err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// insert adds the specified transaction to the journal
+func (journal *txJournal) insert(tx *types.Transaction) error {
+	if journal.writer == nil {
+		return errNoActiveJournal
+	}
+	return rlp.Encode(journal.writer, tx)
+}
+
+// rotate regenerates the transaction journal with the transactions in pool
+func (journal *txJournal) rotate(all types.Transactions) error {
+	// Close the current journal (if any is open)
+	if journal.writer != nil {
+		if err := journal.writer.Close(); err != nil {
+			return err
+		}
+		journal.writer = nil
+	}
+	// Generate a new journal with the contents of the current pool
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	for _, tx := range all {
+		if err = rlp.Encode(replacement, tx); err != nil {
+			replacement.Close()
+			return err
+		}
+	}
+	replacement.Close()
+
+	// Replace the live journal with the newly generated one
+	if err = os.Rename(journal.path+".new", journal.path); err != nil {
+		return err
+	}
+	sink, err := os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	journal.writer = sink
+	log.Debugf("Regenerated local transaction journal. transactions: %d", len(all))
+	return nil
+}
+
+// close flushes the transaction journal contents to disk and closes the file
+func (journal *txJournal) close() error {
+	var err error
+	if journal.writer != nil {
+		err = journal.writer.Close()
+		journal.writer = nil
+	}
+	return err
+}
@@ -1,13 +1,18 @@
 package chain
 
 import (
+	"bytes"
+	"container/heap"
 	"errors"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
 	"github.com/LemoFoundationLtd/lemochain-go/common/subscribe"
 	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,112 +23,31 @@ var (
 	// ErrInsufficientFunds is returned if the total cost of executing a transaction
 	// is higher than the balance of the user's account.
 	ErrInsufficientFunds = errors.New("insufficient funds for gas * price + value")
-)
-
-var TransactionTimeOut = int64(10)
-
-type TransactionWithTime struct {
-	Tx      *types.Transaction
-	RecTime int64
-	DelFlg  bool
-}
-
-type TxsSort interface {
-	push(tx *types.Transaction)
-
-	pop(size int) []*types.Transaction
-
-	removeBatch(keys []common.Hash)
-
-	remove(key common.Hash)
-
-	len() int
-}
-
-type TxsSortByTime struct {
-	txs   []*TransactionWithTime
-	index map[common.Hash]int
-	cap   int
-	cnt   int
-}
 
-func NewTxsSortByTime() TxsSort {
-	cache := &TxsSortByTime{}
-	cache.cap = 2
-	cache.cnt = 0
-	cache.txs = make([]*TransactionWithTime, cache.cap)
-	cache.index = make(map[common.Hash]int)
-	return cache
-}
-
-func (cache *TxsSortByTime) push(tx *types.Transaction) {
-
-	if cache.cap-cache.cnt < 1 {
-		cache.cap = 2 * cache.cap
-		tmp := make([]*TransactionWithTime, cache.cap)
-		copy(tmp, cache.txs)
-		cache.txs = tmp
-	}
+	// ErrInvalidChainID is returned if the chain id of transaction is not same as the chain's.
+	ErrInvalidChainID = errors.New("invalid chain id")
 
-	t := time.Now()
-	cache.txs[cache.cnt] = &TransactionWithTime{
-		Tx:      tx,
-		RecTime: t.Unix(),
-		DelFlg:  false,
-	}
-	cache.index[tx.Hash()] = cache.cnt
+	// ErrTxExpired is returned if the expiration time of transaction has passed.
+	ErrTxExpired = errors.New("transaction is expired")
 
-	cache.cnt = cache.cnt + 1
-}
+	// ErrIntrinsicGas is returned if the gas limit of transaction is lower than the intrinsic gas.
+	ErrIntrinsicGas = errors.New("intrinsic gas too low")
 
-func (cache *TxsSortByTime) pop(size int) []*types.Transaction {
-	txs := make([]*types.Transaction, 0)
-	if (size <= 0) || (len(cache.txs) <= 0) {
-		return txs
-	} else {
-		cnt := 0
-		for index := 0; (index < cache.cnt) && (cnt < size); index++ {
-			if cache.txs[index].DelFlg {
-				// delete(cache.index, cache.txs[index].Tx.Hash())
-				// cache.txs = append(cache.txs[:index], cache.txs[index+1:]...)
-				// cache.cnt = cache.cnt - 1
-				// index = index - 1
-			} else {
-				txs = append(txs, cache.txs[index].Tx)
-				cnt = cnt + 1
-			}
-		}
+	// ErrReplaceUnderpriced is returned if a transaction is attempted to be replaced
+	// with a different one without the required price bump.
+	ErrReplaceUnderpriced = errors.New("replacement transaction underpriced")
 
-		if len(txs) <= 0 {
-			cache.txs = make([]*TransactionWithTime, cache.cap)
-			cache.index = make(map[common.Hash]int)
-			cache.cnt = 0
-		}
-
-		return txs
-	}
-}
-
-func (cache *TxsSortByTime) removeBatch(keys []common.Hash) {
-	if len(keys) <= 0 {
-		return
-	} else {
-		for index := 0; index < len(keys); index++ {
-			cache.remove(keys[index])
-		}
-	}
-}
-
-func (cache *TxsSortByTime) remove(key common.Hash) {
-	pos, ok := cache.index[key]
-	if ok && pos >= 0 {
-		cache.txs[pos].DelFlg = true
-	}
-}
+	// ErrTxPoolFull is returned if the pool is full and the gas price of transaction is not higher than the cheapest one in pool.
+	ErrTxPoolFull = errors.New("transaction pool is full")
+)
 
-func (cache *TxsSortByTime) len() int {
-	return cache.cnt
-}
+var (
+	TransactionTimeOut = int64(10)
+	// TxPoolCapacity is the max count of transactions in pool
+	TxPoolCapacity = 10240
+	// TxJournalRotate is the time interval to regenerate the transaction journal
+	TxJournalRotate = time.Hour
+)
 
 type TxsRecent struct {
 	lastTime int64
@@ -164,23 +88,95 @@ func (recent *TxsRecent) put(hash common.Hash) {
 	recent.recent[recent.index.Cur()][hash] = true
 }
 
+// txQueue holds the transactions from one sender, sorted by nonce
+type txQueue []*types.Transaction
+
+// search returns the position of the transaction with the nonce, or the position to insert it
+func (q txQueue) search(nonce uint64) int {
+	return sort.Search(len(q), func(i int) bool {
+		return q[i].Nonce() >= nonce
+	})
+}
+
+// put inserts the transaction into queue. The transaction with same nonce will be replaced and returned
+func (q *txQueue) put(tx *types.Transaction) *types.Transaction {
+	i := q.search(tx.Nonce())
+	if i < len(*q) && (*q)[i].Nonce() == tx.Nonce() {
+		old := (*q)[i]
+		(*q)[i] = tx
+		return old
+	}
+	*q = append(*q, nil)
+	copy((*q)[i+1:], (*q)[i:])
+	(*q)[i] = tx
+	return nil
+}
+
+// get returns the transaction with the nonce
+func (q txQueue) get(nonce uint64) *types.Transaction {
+	i := q.search(nonce)
+	if i < len(q) && q[i].Nonce() == nonce {
+		return q[i]
+	}
+	return nil
+}
+
+// remove deletes the transaction from queue
+func (q *txQueue) remove(tx *types.Transaction) bool {
+	i := q.search(tx.Nonce())
+	if i < len(*q) && (*q)[i].Hash() == tx.Hash() {
+		*q = append((*q)[:i], (*q)[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// txsByPrice is a heap of the first transaction in each sender's queue. The transaction with higher gas price comes first
+type txsByPrice []txQueue
+
+func (h txsByPrice) Len() int           { return len(h) }
+func (h txsByPrice) Less(i, j int) bool { return h[i][0].GasPrice().Cmp(h[j][0].GasPrice()) > 0 }
+func (h txsByPrice) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *txsByPrice) Push(x interface{}) {
+	*h = append(*h, x.(txQueue))
+}
+
+func (h *txsByPrice) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
 type TxPool struct {
-	am *account.Manager
+	chain *BlockChain
 
-	txsCache TxsSort
+	queues  map[common.Address]txQueue         // transactions group by sender, sorted by nonce
+	all     map[common.Hash]*types.Transaction // all transactions in pool
+	senders map[common.Hash]common.Address     // senders of transactions in pool
 
-	recent *TxsRecent
-	mux    sync.Mutex
+	recent  *TxsRecent
+	journal *txJournal // journal of transactions to back up to disk
+	mux     sync.Mutex
 
 	NewTxsFeed subscribe.Feed
+
+	running int32
+	quitCh  chan struct{}
 }
 
-func NewTxPool(am *account.Manager) *TxPool {
+func NewTxPool(bc *BlockChain) *TxPool {
 	pool := &TxPool{
-		am:     am,
-		recent: NewRecent(),
+		chain:   bc,
+		queues:  make(map[common.Address]txQueue),
+		all:     make(map[common.Hash]*types.Transaction),
+		senders: make(map[common.Hash]common.Address),
+		recent:  NewRecent(),
+		quitCh:  make(chan struct{}),
 	}
-	pool.txsCache = NewTxsSortByTime()
+	bc.TxsReorg = pool.Reorg
 
 	return pool
 }
@@ -193,30 +189,26 @@ func (pool *TxPool) AddTx(tx *types.Transaction) error {
 	if isExist {
 		return nil
 	} else {
-		// err := pool.validateTx(tx)
-		// if err != nil {
-		// 	return err
-		// }
+		if err := pool.add(tx); err != nil {
+			return err
+		}
 		pool.recent.put(hash)
-		pool.txsCache.push(tx)
+		pool.journalTx(tx)
 		pool.NewTxsFeed.Send(types.Transactions{tx})
 		return nil
 	}
 }
 
+// AddTxs adds all the valid transactions, and returns the first error if any transaction is invalid
 func (pool *TxPool) AddTxs(txs []*types.Transaction) error {
-	if len(txs) <= 0 {
-		return nil
-	} else {
-		for index := 0; index < len(txs); index++ {
-			err := pool.AddTx(txs[index])
-			if err != nil {
-				return err
-			}
+	var firstErr error
+	for index := 0; index < len(txs); index++ {
+		err := pool.AddTx(txs[index])
+		if err != nil && firstErr == nil {
+			firstErr = err
 		}
-
-		return nil
 	}
+	return firstErr
 }
 
 func (pool *TxPool) AddKey(hash common.Hash) {
@@ -225,31 +217,337 @@ func (pool *TxPool) AddKey(hash common.Hash) {
 	pool.recent.put(hash)
 }
 
+// Pending returns the transactions which could be packaged. The transactions from one sender are sorted by nonce, and the senders are ordered by gas price
 func (pool *TxPool) Pending(size int) []*types.Transaction {
 	pool.mux.Lock()
 	defer pool.mux.Unlock()
 
-	return pool.txsCache.pop(size)
+	pool.prune()
+	return pool.pending(size)
+}
+
+// pending picks the transaction with highest gas price from the first of each sender's queue one by one
+func (pool *TxPool) pending(size int) []*types.Transaction {
+	txs := make([]*types.Transaction, 0)
+	if size <= 0 {
+		return txs
+	}
+	heads := make(txsByPrice, 0, len(pool.queues))
+	for _, queue := range pool.queues {
+		heads = append(heads, queue)
+	}
+	heap.Init(&heads)
+	for len(heads) > 0 && len(txs) < size {
+		queue := heads[0]
+		txs = append(txs, queue[0])
+		if len(queue) > 1 {
+			heads[0] = queue[1:]
+			heap.Fix(&heads, 0)
+		} else {
+			heap.Pop(&heads)
+		}
+	}
+	return txs
 }
 
 func (pool *TxPool) Remove(keys []common.Hash) {
 	pool.mux.Lock()
 	defer pool.mux.Unlock()
 
-	pool.txsCache.removeBatch(keys)
+	for _, hash := range keys {
+		if tx, ok := pool.all[hash]; ok {
+			pool.remove(tx)
+		}
+	}
 }
 
+// Reorg is called when the chain switches fork. It puts the transactions dropped from old fork back to pool, and removes the transactions packaged in new fork
+func (pool *TxPool) Reorg(dropped, included types.Transactions) {
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	for _, tx := range included {
+		if old, ok := pool.all[tx.Hash()]; ok {
+			pool.remove(old)
+		}
+	}
+	for _, tx := range dropped {
+		if err := pool.add(tx); err != nil {
+			log.Debugf("Discard transaction from old fork. hash: %s, err: %v", tx.Hash().Hex(), err)
+			continue
+		}
+		pool.recent.put(tx.Hash())
+		pool.journalTx(tx)
+	}
+}
+
+// StartJournal loads the unexpired transactions from journal file, and keeps saving the new transactions to it
+func (pool *TxPool) StartJournal(path string) error {
+	if pool.journal != nil {
+		return nil
+	}
+	journal := newTxJournal(path)
+	if err := journal.load(pool.addJournalTxs); err != nil {
+		log.Warnf("Failed to load transaction journal: %v", err)
+	}
+
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+	if err := journal.rotate(pool.allTxs()); err != nil {
+		return err
+	}
+	pool.journal = journal
+	go pool.loop()
+	return nil
+}
+
+// Stop stops the journal rotation and closes the journal file
+func (pool *TxPool) Stop() {
+	if !atomic.CompareAndSwapInt32(&pool.running, 0, 1) {
+		return
+	}
+	close(pool.quitCh)
+
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+	if pool.journal != nil {
+		if err := pool.journal.close(); err != nil {
+			log.Warnf("Failed to close transaction journal: %v", err)
+		}
+	}
+	log.Info("TxPool stop")
+}
+
+// loop regenerates the journal periodically, so that the removed transactions are dropped from it
+func (pool *TxPool) loop() {
+	ticker := time.NewTicker(TxJournalRotate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			pool.mux.Lock()
+			if err := pool.journal.rotate(pool.allTxs()); err != nil {
+				log.Warnf("Failed to rotate transaction journal: %v", err)
+			}
+			pool.mux.Unlock()
+		case <-pool.quitCh:
+			return
+		}
+	}
+}
+
+// addJournalTxs revalidates the transactions loaded from journal and puts them into pool
+func (pool *TxPool) addJournalTxs(txs types.Transactions) {
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	for _, tx := range txs {
+		if err := pool.add(tx); err != nil {
+			log.Debugf("Discard transaction from journal. hash: %s, err: %v", tx.Hash().Hex(), err)
+			continue
+		}
+		pool.recent.put(tx.Hash())
+	}
+}
+
+// journalTx saves the transaction to journal if it is enabled
+func (pool *TxPool) journalTx(tx *types.Transaction) {
+	if pool.journal == nil {
+		return
+	}
+	if err := pool.journal.insert(tx); err != nil {
+		log.Warnf("Failed to journal transaction. hash: %s, err: %v", tx.Hash().Hex(), err)
+	}
+}
+
+// allTxs returns all transactions in pool. The transactions from one sender are sorted by nonce
+func (pool *TxPool) allTxs() types.Transactions {
+	txs := make(types.Transactions, 0, len(pool.all))
+	for _, queue := range pool.queues {
+		txs = append(txs, queue...)
+	}
+	return txs
+}
+
+// Get returns the transaction in pool by hash
+func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	return pool.all[hash]
+}
+
+// PendingNonce returns the next nonce of the sender, counting the transactions in current chain and in pool
+func (pool *TxPool) PendingNonce(address common.Address) uint64 {
+	am := account.NewManager(pool.chain.CurrentBlock().Hash(), pool.chain.db)
+	nonce := am.GetAccount(address).GetNonce()
+
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+	if queue := pool.queues[address]; len(queue) > 0 {
+		if next := queue[len(queue)-1].Nonce() + 1; next > nonce {
+			nonce = next
+		}
+	}
+	return nonce
+}
+
+// Len returns the count of transactions in pool
+func (pool *TxPool) Len() int {
+	pool.mux.Lock()
+	defer pool.mux.Unlock()
+
+	return len(pool.all)
+}
+
+// add validates the transaction and puts it into sender's queue
+func (pool *TxPool) add(tx *types.Transaction) error {
+	hash := tx.Hash()
+	if _, ok := pool.all[hash]; ok {
+		return nil
+	}
+	if err := pool.validateTx(tx); err != nil {
+		return err
+	}
+	// ignore the error because it is checked in validateTx
+	from, _ := tx.From()
+	queue := pool.queues[from]
+	if old := queue.get(tx.Nonce()); old != nil {
+		// a transaction with same nonce is in pool, replace it only if the new one is more expensive
+		if old.GasPrice().Cmp(tx.GasPrice()) >= 0 {
+			return ErrReplaceUnderpriced
+		}
+	} else if len(pool.all) >= TxPoolCapacity {
+		if err := pool.evict(tx); err != nil {
+			return err
+		}
+		queue = pool.queues[from]
+	}
+	if old := queue.put(tx); old != nil {
+		delete(pool.all, old.Hash())
+		delete(pool.senders, old.Hash())
+	}
+	pool.queues[from] = queue
+	pool.all[hash] = tx
+	pool.senders[hash] = from
+	return nil
+}
+
+// remove deletes the transaction from pool
+func (pool *TxPool) remove(tx *types.Transaction) {
+	hash := tx.Hash()
+	from := pool.senders[hash]
+	queue := pool.queues[from]
+	queue.remove(tx)
+	if len(queue) == 0 {
+		delete(pool.queues, from)
+	} else {
+		pool.queues[from] = queue
+	}
+	delete(pool.all, hash)
+	delete(pool.senders, hash)
+}
+
+// evict drops the cheapest transaction to make room for the new one. Only the last transaction of each sender could be dropped, so that the others are still continuous
+func (pool *TxPool) evict(tx *types.Transaction) error {
+	var cheapest *types.Transaction
+	for _, queue := range pool.queues {
+		last := queue[len(queue)-1]
+		if cheapest == nil || last.GasPrice().Cmp(cheapest.GasPrice()) < 0 {
+			cheapest = last
+		}
+	}
+	if cheapest == nil || cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0 {
+		return ErrTxPoolFull
+	}
+	log.Debugf("Evict transaction from pool. hash: %s", cheapest.Hash().Hex())
+	pool.remove(cheapest)
+	return nil
+}
+
+// prune drops the transactions which are expired, underfunded or have been applied in stable blocks. The evidence
+// transaction is dropped once the offender is slashed in stable blocks
+func (pool *TxPool) prune() {
+	am := pool.chain.AccountManager()
+	now := uint64(time.Now().Unix())
+	height := pool.chain.CurrentBlock().Height() + 1
+	evidenceAccount := am.GetCanonicalAccount(EvidenceAddress)
+	stale := make([]*types.Transaction, 0)
+	for from, queue := range pool.queues {
+		sender := am.GetCanonicalAccount(from)
+		for _, tx := range queue {
+			if tx.Expiration() < now || tx.Nonce() < sender.GetNonce() || sender.GetBalance().Cmp(tx.Cost()) < 0 {
+				stale = append(stale, tx)
+			} else if tx.Type() == types.EvidenceTx {
+				if _, err := checkEvidenceTx(tx, height, evidenceAccount); err != nil {
+					stale = append(stale, tx)
+				}
+			}
+		}
+	}
+	for _, tx := range stale {
+		pool.remove(tx)
+	}
+}
+
+// validateEvidenceTx checks the evidence by the stable state. Every deputy node reports the same offender, so only one
+// transaction is kept in pool for an offender
+func (pool *TxPool) validateEvidenceTx(tx *types.Transaction, from common.Address) error {
+	height := pool.chain.CurrentBlock().Height() + 1
+	node, err := checkEvidenceTx(tx, height, pool.chain.AccountManager().GetCanonicalAccount(EvidenceAddress))
+	if err != nil {
+		return err
+	}
+	for hash, other := range pool.all {
+		if other.Type() != types.EvidenceTx || (pool.senders[hash] == from && other.Nonce() == tx.Nonce()) {
+			continue
+		}
+		if reported, err := VerifyEvidenceTx(other, height); err == nil && bytes.Equal(reported.NodeID, node.NodeID) {
+			return ErrEvidenceReported
+		}
+	}
+	return nil
+}
+
+// validateTx checks whether a transaction is valid according to the consensus rules and the stable state
 func (pool *TxPool) validateTx(tx *types.Transaction) error {
+	if tx.ChainId() != pool.chain.ChainID() {
+		return ErrInvalidChainID
+	}
+	if tx.Expiration() < uint64(time.Now().Unix()) {
+		return ErrTxExpired
+	}
 	from, err := tx.From()
 	if err != nil {
 		return ErrInvalidSender
 	}
+	if tx.Type() > types.EvidenceTx {
+		return ErrUnknownTxType
+	}
+	if tx.Type() == types.VoteTx && tx.To() == nil {
+		return ErrNoCandidate
+	}
+	if tx.Type() == types.EvidenceTx {
+		if err := pool.validateEvidenceTx(tx, from); err != nil {
+			return err
+		}
+	}
+	istanbul := pool.chain.Config().IsIstanbul(pool.chain.CurrentBlock().Height() + 1)
+	gas, err := IntrinsicGas(tx.Data(), tx.IsContractCreation(), istanbul)
+	if err != nil {
+		return err
+	}
+	if tx.GasLimit() < gas {
+		return ErrIntrinsicGas
+	}
 
-	fromAccount := pool.am.GetAccount(from)
-	balance := fromAccount.GetBalance()
-	if balance.Cmp(tx.Cost()) < 0 {
+	// the transactions applied in unstable blocks are still accepted, because the fork may be switched
+	fromAccount := pool.chain.AccountManager().GetCanonicalAccount(from)
+	if tx.Nonce() < fromAccount.GetNonce() {
+		return ErrNonceTooLow
+	}
+	if fromAccount.GetBalance().Cmp(tx.Cost()) < 0 {
 		return ErrInsufficientFunds
-	} else {
-		return nil
 	}
+	return nil
 }
@@ -1,156 +1,287 @@
 package chain
 
 import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
-func CreateTx(to string, amount int64, gasPrice int64, expiration uint64) *types.Transaction {
-	address := common.HexToAddress(to)
-
-	bigAmount := new(big.Int)
-	bigAmount.SetInt64(amount)
-
-	gasLimit := uint64(2500000)
-
-	bigGasPrice := new(big.Int)
-	bigGasPrice.SetInt64(gasPrice)
-
-	return types.NewTransaction(address, bigAmount, gasLimit, bigGasPrice, nil, 0, expiration, "paul xie", "")
-}
+// the nonce of testAddr in stable block is 2
+const testStableNonce = 2
 
 func TestTxPool_AddTx(t *testing.T) {
-	pool := NewTxPool(nil)
-	tx := CreateTx("0x1d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 1000, 2000, 3000)
+	store.ClearData()
+	pool := NewTxPool(newChain())
+	expiration := uint64(time.Now().Unix() + 300)
 
+	tx := makeTx(testPrivate, testStableNonce, defaultAccounts[0], common.Big1)
 	err := pool.AddTx(tx)
 	assert.NoError(t, err)
 
+	// exist
 	err = pool.AddTx(tx)
 	assert.NoError(t, err)
+	assert.Equal(t, 1, pool.Len())
 
-	pending := pool.Pending(100)
-	assert.Equal(t, 1, len(pending))
-}
+	// invalid chain id
+	tx = signTransaction(types.NewTransaction(testStableNonce, defaultAccounts[0], common.Big1, 1000000, common.Big1, nil, chainID+1, expiration, "", ""), testPrivate)
+	assert.Equal(t, ErrInvalidChainID, pool.AddTx(tx))
 
-func TestTxPool_Pending(t *testing.T) {
-	// txCh := make(chan types.Transactions, 100)
-	pool := NewTxPool(nil)
+	// expired
+	tx = makeTransaction(testPrivate, testStableNonce, defaultAccounts[0], common.Big1, common.Big1, uint64(time.Now().Unix()-1), 1000000)
+	assert.Equal(t, ErrTxExpired, pool.AddTx(tx))
 
-	// is not exist
-	tx := CreateTx("0x1d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 1000, 2000, 3000)
-	err := pool.AddTx(tx)
-	assert.NoError(t, err)
+	// not signed
+	tx = types.NewTransaction(testStableNonce, defaultAccounts[0], common.Big1, 1000000, common.Big1, nil, chainID, expiration, "", "")
+	assert.Equal(t, ErrInvalidSender, pool.AddTx(tx))
 
-	// is not exist
-	tx = CreateTx("0x2d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 4000, 5000, 6000)
-	err = pool.AddTx(tx)
-	assert.NoError(t, err)
+	// vote without candidate
+	assert.Equal(t, ErrNoCandidate, pool.AddTx(makeNoCandidateVoteTx(testStableNonce)))
 
-	//time.Sleep(time.Duration(10) * time.Second)
+	// intrinsic gas
+	tx = makeTransaction(testPrivate, testStableNonce, defaultAccounts[0], common.Big1, common.Big1, expiration, 100)
+	assert.Equal(t, ErrIntrinsicGas, pool.AddTx(tx))
 
-	// is not exist
-	tx = CreateTx("0x3d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 7000, 8000, 9000)
-	err = pool.AddTx(tx)
-	assert.NoError(t, err)
+	// nonce too low
+	tx = makeTx(testPrivate, testStableNonce-1, defaultAccounts[0], common.Big1)
+	assert.Equal(t, ErrNonceTooLow, pool.AddTx(tx))
 
-	// exist
-	tx = CreateTx("0x1d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 1000, 2000, 3000)
-	err = pool.AddTx(tx)
-	assert.NoError(t, err)
+	// balance not enough
+	private, _ := crypto.GenerateKey()
+	tx = makeTx(private, 0, defaultAccounts[0], common.Big1)
+	assert.Equal(t, ErrInsufficientFunds, pool.AddTx(tx))
 
-	// exist
-	tx = CreateTx("0x2d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 4000, 5000, 6000)
-	err = pool.AddTx(tx)
-	assert.NoError(t, err)
+	// replace with same gas price
+	tx = makeTransaction(testPrivate, testStableNonce, defaultAccounts[0], common.Big2, common.Big1, expiration, 1000000)
+	assert.Equal(t, ErrReplaceUnderpriced, pool.AddTx(tx))
 
-	//time.Sleep(time.Duration(10) * time.Second)
+	// replace with higher gas price
+	tx = makeTransaction(testPrivate, testStableNonce, defaultAccounts[0], common.Big2, common.Big2, expiration, 1000000)
+	assert.NoError(t, pool.AddTx(tx))
+	assert.Equal(t, []*types.Transaction{tx}, pool.Pending(10))
+}
 
-	tx = CreateTx("0x3d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 7000, 8000, 9000)
-	err = pool.AddTx(tx)
+func TestTxPool_evidence(t *testing.T) {
+	store.ClearData()
+	assert.NoError(t, initDeputyNode(2, 0))
+	bc := newChain()
+	pool := NewTxPool(bc)
+	deputyKey, _ := crypto.ToECDSA(common.FromHex(deputy01Privkey))
+	reporter1, _ := crypto.GenerateKey()
+	reporter2, _ := crypto.GenerateKey()
+
+	// the evidence is free
+	tx, err := NewEvidenceTx(chainID, 0, makeTestEvidence(deputyKey, 1), reporter1)
 	assert.NoError(t, err)
+	assert.NoError(t, pool.AddTx(tx))
 
-	result := pool.Pending(10)
-	assert.Equal(t, 3, len(result))
-
-	tx = CreateTx("0x1d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 1000, 2000, 3000)
-	err = pool.AddTx(tx)
+	// invalid evidence
+	invalidTx, err := types.NewEvidenceTransaction(0, &types.Evidence{HeaderA: &types.Header{}, HeaderB: &types.Header{}}, 1000000, new(big.Int), chainID, uint64(time.Now().Unix()+300), "")
 	assert.NoError(t, err)
-
-	tx = CreateTx("0x2d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 4000, 5000, 6000)
-	err = pool.AddTx(tx)
+	assert.Equal(t, types.ErrInvalidEvidence, pool.AddTx(signTransaction(invalidTx, reporter2)))
+	// not deputy node
+	otherKey, _ := crypto.GenerateKey()
+	notDeputyTx, err := NewEvidenceTx(chainID, 0, makeTestEvidence(otherKey, 1), reporter2)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrNotDeputyNode, pool.AddTx(notDeputyTx))
+	// the offender is reported by another node
+	againTx, err := NewEvidenceTx(chainID, 0, makeTestEvidence(deputyKey, 2), reporter2)
 	assert.NoError(t, err)
+	assert.Equal(t, ErrEvidenceReported, pool.AddTx(againTx))
+	assert.Equal(t, []*types.Transaction{tx}, pool.Pending(10))
+
+	// the offender is slashed in stable block
+	manager := account.NewManager(defaultBlocks[1].Hash(), bc.db)
+	node := deputynode.Instance().GetDeputyByNodeID(1, crypto.FromECDSAPub(&deputyKey.PublicKey)[1:])
+	assert.NoError(t, manager.GetAccount(EvidenceAddress).SetStorageState(slashedNodeKey(node.NodeID), []byte{2}))
+	assert.NoError(t, manager.Finalise())
+	block := &types.Block{Header: &types.Header{ParentHash: defaultBlocks[1].Hash(), Height: 2, VersionRoot: manager.GetVersionRoot(), Time: 1538209759}}
+	assert.NoError(t, bc.db.SetBlock(block.Hash(), block))
+	assert.NoError(t, manager.Save(block.Hash()))
+	assert.NoError(t, bc.db.SetStableBlock(block.Hash()))
+	assert.Empty(t, pool.Pending(10))
+	assert.Equal(t, ErrAlreadySlashed, pool.AddTx(againTx))
+}
 
-	// tx = CreateTx("0x3d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 7000, 8000, 9000)
-	// err = pool.AddTx(tx)
-	// assert.NoError(t, err)
+func TestTxPool_Pending(t *testing.T) {
+	store.ClearData()
+	pool := NewTxPool(newChain())
 
-	keys := []common.Hash{tx.Hash()}
-	pool.Remove(keys)
+	tx4 := makeTx(testPrivate, testStableNonce+2, defaultAccounts[0], common.Big1)
+	tx3 := makeTx(testPrivate, testStableNonce+1, defaultAccounts[0], common.Big1)
+	tx2 := makeTx(testPrivate, testStableNonce, defaultAccounts[0], common.Big1)
+	err := pool.AddTxs([]*types.Transaction{tx4, tx3, tx2})
+	assert.NoError(t, err)
 
-	result = pool.Pending(10)
-	assert.Equal(t, 2, len(result))
+	// sorted by nonce
+	result := pool.Pending(10)
+	assert.Equal(t, []*types.Transaction{tx2, tx3, tx4}, result)
+	result = pool.Pending(2)
+	assert.Equal(t, []*types.Transaction{tx2, tx3}, result)
+	result = pool.Pending(0)
+	assert.Equal(t, 0, len(result))
+	// pending doesn't remove transactions
+	assert.Equal(t, 3, pool.Len())
+}
+
+func TestTxPool_pending(t *testing.T) {
+	store.ClearData()
+	pool := NewTxPool(newChain())
+	expiration := uint64(time.Now().Unix() + 300)
+
+	// put transactions from different senders without validation
+	private1, _ := crypto.GenerateKey()
+	private2, _ := crypto.GenerateKey()
+	tx10 := makeTransaction(private1, 0, defaultAccounts[0], common.Big1, big.NewInt(1), expiration, 1000000)
+	tx11 := makeTransaction(private1, 1, defaultAccounts[0], common.Big1, big.NewInt(5), expiration, 1000000)
+	tx20 := makeTransaction(private2, 0, defaultAccounts[0], common.Big1, big.NewInt(3), expiration, 1000000)
+	tx21 := makeTransaction(private2, 1, defaultAccounts[0], common.Big1, big.NewInt(2), expiration, 1000000)
+	for _, tx := range []*types.Transaction{tx11, tx21, tx10, tx20} {
+		from, _ := tx.From()
+		queue := pool.queues[from]
+		queue.put(tx)
+		pool.queues[from] = queue
+	}
+
+	// the first transaction of each sender is ordered by gas price
+	result := pool.pending(10)
+	assert.Equal(t, []*types.Transaction{tx20, tx21, tx10, tx11}, result)
+	result = pool.pending(1)
+	assert.Equal(t, []*types.Transaction{tx20}, result)
 }
 
 func TestTxPool_Remove(t *testing.T) {
-	pool := NewTxPool(nil)
+	store.ClearData()
+	pool := NewTxPool(newChain())
 
-	tx1 := CreateTx("0x1d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 1000, 2000, 3000)
-	err := pool.AddTx(tx1)
+	tx2 := makeTx(testPrivate, testStableNonce, defaultAccounts[0], common.Big1)
+	tx3 := makeTx(testPrivate, testStableNonce+1, defaultAccounts[0], common.Big1)
+	tx4 := makeTx(testPrivate, testStableNonce+2, defaultAccounts[0], common.Big1)
+	err := pool.AddTxs([]*types.Transaction{tx2, tx3, tx4})
 	assert.NoError(t, err)
 
-	tx2 := CreateTx("0x2d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 2000, 5000, 6000)
-	err = pool.AddTx(tx2)
-	assert.NoError(t, err)
+	pool.Remove([]common.Hash{tx3.Hash()})
+	assert.Equal(t, 2, pool.Len())
+	assert.Equal(t, []*types.Transaction{tx2, tx4}, pool.Pending(10))
 
-	tx3 := CreateTx("0x3d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 3000, 8000, 9000)
-	err = pool.AddTx(tx3)
-	assert.NoError(t, err)
+	// not exist
+	pool.Remove([]common.Hash{tx3.Hash()})
+	assert.Equal(t, 2, pool.Len())
 
-	tx4 := CreateTx("0x1d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 4000, 2000, 3000)
-	err = pool.AddTx(tx4)
-	assert.NoError(t, err)
+	pool.Remove([]common.Hash{tx2.Hash(), tx4.Hash()})
+	assert.Equal(t, 0, pool.Len())
+	assert.Equal(t, 0, len(pool.Pending(10)))
 
-	tx5 := CreateTx("0x2d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 5000, 5000, 6000)
-	err = pool.AddTx(tx5)
-	assert.NoError(t, err)
+	// the removed transaction can't be added again
+	assert.NoError(t, pool.AddTx(tx2))
+	assert.Equal(t, 0, pool.Len())
+}
 
-	tx6 := CreateTx("0x3d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 6001, 8000, 9000)
-	err = pool.AddTx(tx6)
-	assert.NoError(t, err)
+func TestTxPool_Evict(t *testing.T) {
+	store.ClearData()
+	pool := NewTxPool(newChain())
+	expiration := uint64(time.Now().Unix() + 300)
+	defer func(capacity int) { TxPoolCapacity = capacity }(TxPoolCapacity)
+	TxPoolCapacity = 2
 
-	tx7 := CreateTx("0x1d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 7002, 2000, 3000)
-	err = pool.AddTx(tx7)
+	tx2 := makeTransaction(testPrivate, testStableNonce, defaultAccounts[0], common.Big1, common.Big2, expiration, 1000000)
+	tx3 := makeTransaction(testPrivate, testStableNonce+1, defaultAccounts[0], common.Big1, common.Big2, expiration, 1000000)
+	err := pool.AddTxs([]*types.Transaction{tx2, tx3})
 	assert.NoError(t, err)
 
-	tx8 := CreateTx("0x2d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e", 8003, 5000, 6000)
-	err = pool.AddTx(tx8)
-	assert.NoError(t, err)
+	// not more expensive than the cheapest one
+	tx4 := makeTransaction(testPrivate, testStableNonce+2, defaultAccounts[0], common.Big1, common.Big2, expiration, 1000000)
+	assert.Equal(t, ErrTxPoolFull, pool.AddTx(tx4))
 
-	keys := []common.Hash{tx2.Hash()}
-	pool.Remove(keys)
-	assert.Equal(t, 8, pool.txsCache.len())
+	// the last transaction of sender is evicted
+	tx4 = makeTransaction(testPrivate, testStableNonce+2, defaultAccounts[0], common.Big1, common.Big3, expiration, 1000000)
+	assert.NoError(t, pool.AddTx(tx4))
+	assert.Equal(t, []*types.Transaction{tx2, tx4}, pool.Pending(10))
+}
 
-	result := pool.Pending(3)
-	assert.Equal(t, 3, len(result))
-	assert.Equal(t, 8, pool.txsCache.len())
+func TestTxPool_Reorg(t *testing.T) {
+	store.ClearData()
+	pool := NewTxPool(newChain())
 
-	keys = []common.Hash{tx1.Hash(), tx3.Hash(), tx4.Hash()}
-	pool.Remove(keys)
-	assert.Equal(t, 8, pool.txsCache.len())
+	tx2 := makeTx(testPrivate, testStableNonce, defaultAccounts[0], common.Big1)
+	tx3 := makeTx(testPrivate, testStableNonce+1, defaultAccounts[0], common.Big1)
+	tx4 := makeTx(testPrivate, testStableNonce+2, defaultAccounts[0], common.Big1)
+	err := pool.AddTx(tx2)
+	assert.NoError(t, err)
+	pool.Remove([]common.Hash{tx2.Hash()})
+
+	// the dropped transactions are put back even if they have been seen, and the included transactions are removed
+	pool.Reorg(types.Transactions{tx2, tx3}, types.Transactions{})
+	assert.Equal(t, []*types.Transaction{tx2, tx3}, pool.Pending(10))
+	pool.Reorg(types.Transactions{tx4}, types.Transactions{tx2})
+	assert.Equal(t, []*types.Transaction{tx3, tx4}, pool.Pending(10))
+
+	// invalid transactions are discarded
+	tx1 := makeTx(testPrivate, testStableNonce-1, defaultAccounts[0], common.Big1)
+	pool.Reorg(types.Transactions{tx1}, types.Transactions{})
+	assert.Equal(t, 2, pool.Len())
+}
 
-	result = pool.Pending(10)
-	assert.Equal(t, 4, len(result))
-	assert.Equal(t, 8, pool.txsCache.len())
+func TestTxPool_PendingNonce(t *testing.T) {
+	store.ClearData()
+	pool := NewTxPool(newChain())
 
-	keys = []common.Hash{tx1.Hash(), tx2.Hash(), tx3.Hash(), tx4.Hash(), tx5.Hash(), tx6.Hash(), tx7.Hash(), tx8.Hash()}
-	pool.Remove(keys)
-	assert.Equal(t, 8, pool.txsCache.len())
+	assert.Equal(t, uint64(testStableNonce), pool.PendingNonce(testAddr))
+	assert.NoError(t, pool.AddTx(makeTx(testPrivate, testStableNonce, defaultAccounts[0], common.Big1)))
+	assert.NoError(t, pool.AddTx(makeTx(testPrivate, testStableNonce+1, defaultAccounts[0], common.Big1)))
+	assert.Equal(t, uint64(testStableNonce+2), pool.PendingNonce(testAddr))
+	assert.Equal(t, uint64(0), pool.PendingNonce(common.HexToAddress("0x1")))
+}
 
-	result = pool.Pending(10)
-	assert.Equal(t, 0, len(result))
-	assert.Equal(t, 0, pool.txsCache.len())
+func TestTxPool_Journal(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+	dir, err := ioutil.TempDir("", "lemo-txpool")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "transactions.rlp")
+
+	pool := NewTxPool(bc)
+	assert.NoError(t, pool.StartJournal(path))
+	tx2 := makeTx(testPrivate, testStableNonce, defaultAccounts[0], common.Big1)
+	tx3 := makeTx(testPrivate, testStableNonce+1, defaultAccounts[0], common.Big1)
+	tx4 := makeTx(testPrivate, testStableNonce+2, defaultAccounts[0], common.Big1)
+	err = pool.AddTxs([]*types.Transaction{tx2, tx3, tx4})
+	assert.NoError(t, err)
+	pool.Stop()
+
+	// reload after restart
+	pool = NewTxPool(bc)
+	assert.NoError(t, pool.StartJournal(path))
+	assert.Equal(t, txHashes([]*types.Transaction{tx2, tx3, tx4}), txHashes(pool.Pending(10)))
+
+	// the removed transactions are dropped after rotation
+	pool.Remove([]common.Hash{tx3.Hash()})
+	pool.mux.Lock()
+	err = pool.journal.rotate(pool.allTxs())
+	pool.mux.Unlock()
+	assert.NoError(t, err)
+	pool.Stop()
+	pool = NewTxPool(bc)
+	assert.NoError(t, pool.StartJournal(path))
+	assert.Equal(t, txHashes([]*types.Transaction{tx2, tx4}), txHashes(pool.Pending(10)))
+	pool.Stop()
+}
+
+func txHashes(txs []*types.Transaction) []common.Hash {
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	return hashes
 }
@@ -3,19 +3,35 @@ package chain
 import (
 	"errors"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/vm"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
 	"github.com/LemoFoundationLtd/lemochain-go/common/log"
 	"math"
 	"math/big"
 	"sync"
+	"time"
 )
 
 var (
 	ErrInsufficientBalanceForGas = errors.New("insufficient balance to pay for gas")
 	ErrInvalidTxInBlock          = errors.New("block contains invalid transaction")
+	ErrFinalizeBlock             = errors.New("finalize block failed")
+	// ErrNonceTooLow is returned if the nonce of a transaction is lower than the one present in the sender's account.
+	ErrNonceTooLow = errors.New("nonce too low")
+	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the next one expected by the sender's account.
+	ErrNonceTooHigh = errors.New("nonce too high")
+	// ErrTxExecutionFailed is returned if the contract execution is failed in pre-execution
+	ErrTxExecutionFailed = errors.New("transaction execution failed")
+	// ErrGasRequiredExceeds is returned if the transaction can't be executed successfully with the max gas limit
+	ErrGasRequiredExceeds = errors.New("gas required exceeds allowance or always failing transaction")
+	// ErrTxIndexOutOfRange is returned if the transaction to trace is not in the block
+	ErrTxIndexOutOfRange = errors.New("transaction index out of range")
+	// ErrUnknownTxType is returned if the type of transaction is not supported
+	ErrUnknownTxType = errors.New("unknown transaction type")
 )
 
 type TxProcessor struct {
@@ -46,47 +62,62 @@ func NewTxProcessor(bc *BlockChain) *TxProcessor {
 }
 
 // Process processes all transactions in a block. Change accounts' data and execute contract codes.
-func (p *TxProcessor) Process(block *types.Block) (*types.Header, error) {
+func (p *TxProcessor) Process(block *types.Block) (*types.Header, types.Receipts, error) {
 	p.lock.Lock()
-	p.lock.Unlock()
+	defer p.lock.Unlock()
 	var (
 		gp          = new(types.GasPool).AddGas(block.GasLimit())
 		gasUsed     = uint64(0)
 		totalGasFee = new(big.Int)
 		header      = block.Header
 		txs         = block.Txs
+		receipts    = make(types.Receipts, 0, len(block.Txs))
 	)
 	p.am.Reset(header.ParentHash)
 	// genesis
 	if header.Height == 0 {
 		log.Warn("It is not necessary to process genesis block.")
-		return header, nil
+		return header, receipts, nil
 	}
 	// Iterate over and process the individual transactions
 	for i, tx := range txs {
-		gas, err := p.applyTx(gp, header, tx, uint(i), block.Hash())
+		receipt, _, err := p.applyTx(gp, header, tx, uint(i), block.Hash())
 		if err != nil {
 			log.Info("Invalid transaction", "hash", tx.Hash(), "err", err)
-			return nil, ErrInvalidTxInBlock
+			return nil, nil, ErrInvalidTxInBlock
 		}
-		gasUsed = gasUsed + gas
-		fee := new(big.Int).Mul(new(big.Int).SetUint64(gas), tx.GasPrice())
+		receipts = append(receipts, receipt)
+		gasUsed = gasUsed + receipt.GasUsed
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), tx.GasPrice())
 		totalGasFee.Add(totalGasFee, fee)
 	}
 	p.chargeForGas(totalGasFee, header.MinerAddress)
 
-	return p.FillHeader(header.Copy(), txs, gasUsed)
+	newHeader, err := p.FillHeader(header.Copy(), txs, gasUsed)
+	if err != nil {
+		return nil, nil, err
+	}
+	if receipt := p.blockReceipt(header.Height, uint(len(txs)), block.Hash()); receipt != nil {
+		receipts = append(receipts, receipt)
+	}
+	return newHeader, receipts, nil
 }
 
-// ApplyTxs picks and processes transactions from miner's tx pool.
-func (p *TxProcessor) ApplyTxs(header *types.Header, txs types.Transactions) (*types.Header, types.Transactions, types.Transactions, error) {
+// ApplyTxs picks and processes transactions from miner's tx pool. It returns the new header, the packaged transactions, the invalid transactions and the receipts of packaged transactions
+func (p *TxProcessor) ApplyTxs(header *types.Header, txs types.Transactions) (*types.Header, types.Transactions, types.Transactions, types.Receipts, error) {
+	return p.ApplyTxsUntil(header, txs, time.Time{})
+}
+
+// ApplyTxsUntil is like ApplyTxs, but it stops picking transactions after the deadline. There is no deadline if it is zero
+func (p *TxProcessor) ApplyTxsUntil(header *types.Header, txs types.Transactions, deadline time.Time) (*types.Header, types.Transactions, types.Transactions, types.Receipts, error) {
 	p.lock.Lock()
-	p.lock.Unlock()
+	defer p.lock.Unlock()
 	gp := new(types.GasPool).AddGas(header.GasLimit)
 	gasUsed := uint64(0)
 	totalGasFee := new(big.Int)
 	selectedTxs := make(types.Transactions, 0)
 	invalidTxs := make(types.Transactions, 0)
+	receipts := make(types.Receipts, 0)
 
 	p.am.Reset(header.ParentHash)
 
@@ -96,15 +127,23 @@ func (p *TxProcessor) ApplyTxs(header *types.Header, txs types.Transactions) (*t
 			log.Info("Not enough gas for further transactions", "gp", gp)
 			break
 		}
+		// The time budget of miner is used up
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			log.Info("Not enough time for further transactions", "applied", len(selectedTxs))
+			break
+		}
 		// Start executing the transaction
 		snap := p.am.Snapshot()
 
-		gas, err := p.applyTx(gp, header, tx, uint(len(selectedTxs)), common.Hash{})
+		receipt, _, err := p.applyTx(gp, header, tx, uint(len(selectedTxs)), common.Hash{})
 		if err != nil {
 			p.am.RevertToSnapshot(snap)
 			if err == types.ErrGasLimitReached {
 				// block is full
 				log.Info("Not enough gas for further transactions", "gp", gp, "lastTxGasLimit", tx.GasLimit())
+			} else if err == ErrNonceTooHigh {
+				// the previous transaction of the sender is missing. Keep it in pool for next block
+				log.Info("Skipped future transaction", "hash", tx.Hash(), "nonce", tx.Nonce())
 			} else {
 				// Strange error, discard the transaction and get the next in line.
 				log.Info("Skipped invalid transaction", "hash", tx.Hash(), "err", err)
@@ -113,41 +152,79 @@ func (p *TxProcessor) ApplyTxs(header *types.Header, txs types.Transactions) (*t
 			continue
 		}
 		selectedTxs = append(selectedTxs, tx)
+		receipts = append(receipts, receipt)
 
-		gasUsed = gasUsed + gas
-		fee := new(big.Int).Mul(new(big.Int).SetUint64(gas), tx.GasPrice())
+		gasUsed = gasUsed + receipt.GasUsed
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), tx.GasPrice())
 		totalGasFee.Add(totalGasFee, fee)
 	}
 	p.chargeForGas(totalGasFee, header.MinerAddress)
 
 	newHeader, err := p.FillHeader(header.Copy(), selectedTxs, gasUsed)
-	return newHeader, selectedTxs, invalidTxs, err
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if receipt := p.blockReceipt(header.Height, uint(len(selectedTxs)), common.Hash{}); receipt != nil {
+		receipts = append(receipts, receipt)
+	}
+	return newHeader, selectedTxs, invalidTxs, receipts, nil
 }
 
-// applyTx processes transaction. Change accounts' data and execute contract codes.
-func (p *TxProcessor) applyTx(gp *types.GasPool, header *types.Header, tx *types.Transaction, txIndex uint, blockHash common.Hash) (uint64, error) {
+// blockReceipt packs the events which are not emitted by transactions, e.g. reward payouts and slashes, into a receipt
+// without transaction. It follows the receipts of transactions, so that the events can be found by filters
+func (p *TxProcessor) blockReceipt(height uint32, index uint, blockHash common.Hash) *types.Receipt {
+	events := p.am.GetEventsByTx(common.Hash{})
+	if len(events) == 0 {
+		return nil
+	}
+	return &types.Receipt{
+		BlockHash:   blockHash,
+		BlockHeight: height,
+		TxIndex:     index,
+		Status:      types.ReceiptStatusSuccessful,
+		Events:      events,
+	}
+}
+
+// applyTx processes transaction. Change accounts' data and execute contract codes. It returns the receipt and the output of contract
+func (p *TxProcessor) applyTx(gp *types.GasPool, header *types.Header, tx *types.Transaction, txIndex uint, blockHash common.Hash) (*types.Receipt, []byte, error) {
 	senderAddr, err := tx.From()
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
 	var (
 		// Create a new context to be used in the EVM environment
 		context = NewEVMContext(tx, header, txIndex, tx.Hash(), blockHash, p.chain)
 		// Create a new environment which holds all relevant information
 		// about the transaction and calling mechanisms.
-		vmEnv            = vm.NewEVM(context, p.am, *p.cfg)
+		vmEnv            = vm.NewEVM(context, p.am, p.chain.Config(), *p.cfg)
 		sender           = p.am.GetAccount(senderAddr)
-		contractCreation = tx.To() == nil
+		contractCreation = tx.IsContractCreation()
 		restGas          = tx.GasLimit()
 		mergeFrom        = len(p.am.GetChangeLogs())
 	)
+	if tx.Type() > types.EvidenceTx {
+		return nil, nil, ErrUnknownTxType
+	}
+	err = checkNonce(sender, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var offender *deputynode.DeputyNode
+	if tx.Type() == types.EvidenceTx {
+		offender, err = checkEvidenceTx(tx, header.Height, p.am.GetAccount(EvidenceAddress))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 	err = p.buyGas(gp, tx)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
-	restGas, err = p.payIntrinsicGas(tx, restGas)
+	sender.SetNonce(tx.Nonce() + 1)
+	restGas, err = p.payIntrinsicGas(tx, header.Height, restGas)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
 
 	// vm errors do not effect consensus and are therefor not assigned to err,
@@ -155,12 +232,20 @@ func (p *TxProcessor) applyTx(gp *types.GasPool, header *types.Header, tx *types
 	var (
 		vmErr         error
 		recipientAddr common.Address
+		ret           []byte
 	)
-	if contractCreation {
-		_, recipientAddr, restGas, vmErr = vmEnv.Create(sender, tx.Data(), restGas, tx.Amount())
-	} else {
+	switch {
+	case tx.Type() == types.VoteTx:
+		recipientAddr, vmErr = p.applyVoteTx(sender, tx)
+	case tx.Type() == types.RegisterTx:
+		recipientAddr, vmErr = CandidateListAddress, p.applyRegisterTx(sender, tx)
+	case tx.Type() == types.EvidenceTx:
+		recipientAddr, vmErr = EvidenceAddress, slash(p.am, offender, header.Height)
+	case contractCreation:
+		ret, recipientAddr, restGas, vmErr = vmEnv.Create(sender, tx.Data(), restGas, tx.Amount())
+	default:
 		recipientAddr = *tx.To()
-		_, restGas, vmErr = vmEnv.Call(sender, recipientAddr, tx.Data(), restGas, tx.Amount())
+		ret, restGas, vmErr = vmEnv.Call(sender, recipientAddr, tx.Data(), restGas, tx.Amount())
 	}
 	if vmErr != nil {
 		log.Info("VM returned with error", "err", vmErr)
@@ -168,7 +253,7 @@ func (p *TxProcessor) applyTx(gp *types.GasPool, header *types.Header, tx *types
 		// sufficient balance to make the transfer happen. The first
 		// balance transfer may never fail.
 		if vmErr == vm.ErrInsufficientBalance {
-			return 0, vmErr
+			return nil, nil, vmErr
 		}
 	}
 	p.refundGas(gp, tx, restGas)
@@ -177,7 +262,219 @@ func (p *TxProcessor) applyTx(gp *types.GasPool, header *types.Header, tx *types
 	p.am.MergeChangeLogs(mergeFrom)
 	mergeFrom = len(p.am.GetChangeLogs())
 
-	return tx.GasLimit() - restGas, nil
+	receipt := types.NewReceipt(tx, header.Height, txIndex, tx.GasLimit()-restGas, vmErr != nil)
+	receipt.BlockHash = blockHash
+	if contractCreation {
+		receipt.ContractAddress = recipientAddr
+	}
+	receipt.Events = p.am.GetEventsByTx(tx.Hash())
+	return receipt, ret, nil
+}
+
+// CallMsg is the arguments to pre-execute a transaction without signature
+type CallMsg struct {
+	From     common.Address
+	To       *common.Address // nil means contract creation
+	Amount   *big.Int
+	GasLimit uint64 // 0 means the gas limit of block
+	GasPrice *big.Int
+	Data     []byte
+}
+
+// CallTx executes the message based on the state after the block, and returns the output of contract. Nothing is saved
+func (p *TxProcessor) CallTx(block *types.Block, msg *CallMsg) ([]byte, error) {
+	gasLimit := msg.GasLimit
+	if gasLimit == 0 {
+		gasLimit = block.GasLimit()
+	}
+	receipt, ret, err := p.preExecute(block, msg, gasLimit)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return ret, ErrTxExecutionFailed
+	}
+	return ret, nil
+}
+
+// EstimateGas finds the minimal gas limit which the message needs to be executed successfully based on the state after the block
+func (p *TxProcessor) EstimateGas(block *types.Block, msg *CallMsg) (uint64, error) {
+	intrinsicGas, err := IntrinsicGas(msg.Data, msg.To == nil, p.chain.Config().IsIstanbul(block.Height()+1))
+	if err != nil {
+		return 0, err
+	}
+	hi := msg.GasLimit
+	if hi == 0 {
+		hi = block.GasLimit()
+	}
+	if hi < intrinsicGas {
+		return 0, ErrGasRequiredExceeds
+	}
+	executable := func(gasLimit uint64) (bool, error) {
+		receipt, _, err := p.preExecute(block, msg, gasLimit)
+		if err == vm.ErrOutOfGas {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return receipt.Status == types.ReceiptStatusSuccessful, nil
+	}
+	if ok, err := executable(hi); err != nil {
+		return 0, err
+	} else if !ok {
+		return 0, ErrGasRequiredExceeds
+	}
+	// binary search in (lo, hi]
+	lo := intrinsicGas - 1
+	for lo+1 < hi {
+		mid := (lo + hi) / 2
+		ok, err := executable(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, nil
+}
+
+// preExecute applies the message on a throwaway account manager which works on the state of the block
+func (p *TxProcessor) preExecute(block *types.Block, msg *CallMsg, gasLimit uint64) (*types.Receipt, []byte, error) {
+	am, err := p.chain.stateManager(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	processor := &TxProcessor{
+		chain: p.chain,
+		am:    am,
+		cfg:   p.cfg,
+	}
+	nonce := processor.am.GetAccount(msg.From).GetNonce()
+	tx := types.NewCallTransaction(msg.From, nonce, msg.To, msg.Amount, gasLimit, msg.GasPrice, msg.Data, p.chain.ChainID())
+	header := &types.Header{
+		ParentHash:   block.Hash(),
+		MinerAddress: block.MinerAddress(),
+		Height:       block.Height() + 1,
+		GasLimit:     block.GasLimit(),
+		Time:         uint32(time.Now().Unix()),
+	}
+	gp := new(types.GasPool).AddGas(gasLimit)
+	return processor.applyTx(gp, header, tx, 0, common.Hash{})
+}
+
+// TxTrace is the execution trace of a transaction
+type TxTrace struct {
+	TxHash      common.Hash    `json:"txHash"`
+	Failed      bool           `json:"failed"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	ReturnValue hexutil.Bytes  `json:"returnValue"`
+	StructLogs  []vm.StructLog `json:"structLogs"` // the opcode level steps
+	Calls       *vm.CallFrame  `json:"calls"`      // the call tree of internal CALL/CREATE frames
+}
+
+// TraceTx re-executes the transaction at index of the block on its parent state, and returns the trace of it
+func (p *TxProcessor) TraceTx(block *types.Block, index int, cfg *vm.LogConfig) (*TxTrace, error) {
+	if index < 0 || index >= len(block.Txs) {
+		return nil, ErrTxIndexOutOfRange
+	}
+	traces, err := p.traceTxs(block, index, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return traces[0], nil
+}
+
+// TraceBlock re-executes all transactions of the block on its parent state, and returns the traces of them
+func (p *TxProcessor) TraceBlock(block *types.Block, cfg *vm.LogConfig) ([]*TxTrace, error) {
+	return p.traceTxs(block, -1, cfg)
+}
+
+// traceTxs applies the transactions in block one by one, and traces the transaction at index. All transactions are traced if index is -1
+func (p *TxProcessor) traceTxs(block *types.Block, index int, cfg *vm.LogConfig) ([]*TxTrace, error) {
+	am, err := p.chain.parentStateManager(block)
+	if err != nil {
+		return nil, err
+	}
+	processor := &TxProcessor{
+		chain: p.chain,
+		am:    am,
+	}
+	gp := new(types.GasPool).AddGas(block.GasLimit())
+	traces := make([]*TxTrace, 0, len(block.Txs))
+	for i, tx := range block.Txs {
+		if index >= 0 && i > index {
+			break
+		}
+		traced := index < 0 || i == index
+		logger := vm.NewStructLogger(cfg)
+		callTracer := vm.NewCallTracer()
+		processor.cfg = &vm.Config{}
+		if traced {
+			processor.cfg = &vm.Config{Debug: true, Tracer: vm.MultiTracer{logger, callTracer}}
+		}
+		receipt, ret, err := processor.applyTx(gp, block.Header, tx, uint(i), block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if !traced {
+			continue
+		}
+		calls := callTracer.Result()
+		if calls == nil {
+			// there is no code to run, e.g. transfer to a normal account
+			if calls, err = newTransferFrame(tx, p.chain.Config().IsIstanbul(block.Height())); err != nil {
+				return nil, err
+			}
+		}
+		traces = append(traces, &TxTrace{
+			TxHash:      tx.Hash(),
+			Failed:      receipt.Status == types.ReceiptStatusFailed,
+			GasUsed:     hexutil.Uint64(receipt.GasUsed),
+			ReturnValue: ret,
+			StructLogs:  logger.StructLogs(),
+			Calls:       calls,
+		})
+	}
+	return traces, nil
+}
+
+// newTransferFrame creates the root call frame of the transaction which runs no code
+func newTransferFrame(tx *types.Transaction, istanbul bool) (*vm.CallFrame, error) {
+	from, err := tx.From()
+	if err != nil {
+		return nil, err
+	}
+	intrinsicGas, err := IntrinsicGas(tx.Data(), tx.IsContractCreation(), istanbul)
+	if err != nil {
+		return nil, err
+	}
+	frame := &vm.CallFrame{
+		Type:  vm.CALL.String(),
+		From:  from,
+		Value: (*hexutil.Big10)(tx.Amount()),
+		Input: tx.Data(),
+	}
+	if tx.To() != nil {
+		frame.To = *tx.To()
+	}
+	if tx.GasLimit() > intrinsicGas {
+		frame.Gas = hexutil.Uint64(tx.GasLimit() - intrinsicGas)
+	}
+	return frame, nil
+}
+
+// checkNonce makes sure the transactions from the same sender are applied one by one in strict order
+func checkNonce(sender types.AccountAccessor, tx *types.Transaction) error {
+	nonce := sender.GetNonce()
+	if tx.Nonce() < nonce {
+		return ErrNonceTooLow
+	} else if tx.Nonce() > nonce {
+		return ErrNonceTooHigh
+	}
+	return nil
 }
 
 func (p *TxProcessor) buyGas(gp *types.GasPool, tx *types.Transaction) error {
@@ -196,8 +493,8 @@ func (p *TxProcessor) buyGas(gp *types.GasPool, tx *types.Transaction) error {
 	return nil
 }
 
-func (p *TxProcessor) payIntrinsicGas(tx *types.Transaction, restGas uint64) (uint64, error) {
-	gas, err := IntrinsicGas(tx.Data(), tx.To() == nil)
+func (p *TxProcessor) payIntrinsicGas(tx *types.Transaction, height uint32, restGas uint64) (uint64, error) {
+	gas, err := IntrinsicGas(tx.Data(), tx.IsContractCreation(), p.chain.Config().IsIstanbul(height))
 	if err != nil {
 		return restGas, err
 	}
@@ -208,7 +505,7 @@ func (p *TxProcessor) payIntrinsicGas(tx *types.Transaction, restGas uint64) (ui
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func IntrinsicGas(data []byte, contractCreation bool) (uint64, error) {
+func IntrinsicGas(data []byte, contractCreation, istanbul bool) (uint64, error) {
 	// Set the starting gas for the raw transaction
 	var gas uint64
 	if contractCreation {
@@ -225,11 +522,15 @@ func IntrinsicGas(data []byte, contractCreation bool) (uint64, error) {
 				nz++
 			}
 		}
+		nonZeroGas := params.TxDataNonZeroGas
+		if istanbul {
+			nonZeroGas = params.TxDataNonZeroGasIstanbul
+		}
 		// Make sure we don't exceed uint64 for all data combinations
-		if (math.MaxUint64-gas)/params.TxDataNonZeroGas < nz {
+		if (math.MaxUint64-gas)/nonZeroGas < nz {
 			return 0, vm.ErrOutOfGas
 		}
-		gas += nz * params.TxDataNonZeroGas
+		gas += nz * nonZeroGas
 
 		z := uint64(len(data)) - nz
 		if (math.MaxUint64-gas)/params.TxDataZeroGas < z {
@@ -267,13 +568,16 @@ func (p *TxProcessor) FillHeader(header *types.Header, txs types.Transactions, g
 	if len(txs) > 0 {
 		log.Infof("process %d transactions", len(txs))
 	}
+	// Pay miners at the end of their tenure. This method increases miners' balance and records the payouts as events.
+	if err := p.chain.engine.Finalize(header, p.am); err != nil {
+		log.Errorf("finalize block failed. height: %d, err: %v", header.Height, err)
+		return nil, ErrFinalizeBlock
+	}
 	events := p.am.GetEvents()
 	header.Bloom = types.CreateBloom(events)
 	header.EventRoot = types.DeriveEventsSha(events)
 	header.GasUsed = gasUsed
 	header.TxRoot = types.DeriveTxsSha(txs)
-	// Pay miners at the end of their tenure. This method increases miners' balance.
-	p.chain.engine.Finalize(header, p.am)
 	// Update version trie, storage trie.
 	err := p.am.Finalise()
 	if err != nil {
@@ -1,6 +1,8 @@
 package chain
 
 import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/vm"
@@ -12,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"math/big"
 	"testing"
+	"time"
 )
 
 func TestNewTxProcessor(t *testing.T) {
@@ -33,7 +36,7 @@ func TestTxProcessor_Process(t *testing.T) {
 
 	// last not stable block
 	block := defaultBlocks[2]
-	newHeader, err := p.Process(block)
+	newHeader, _, err := p.Process(block)
 	assert.NoError(t, err)
 	assert.Equal(t, block.Header.Bloom, newHeader.Bloom)
 	assert.Equal(t, block.Header.EventRoot, newHeader.EventRoot)
@@ -48,8 +51,20 @@ func TestTxProcessor_Process(t *testing.T) {
 
 	// block not in db
 	block = defaultBlocks[3]
-	newHeader, err = p.Process(block)
+	newHeader, receipts, err := p.Process(block)
 	assert.NoError(t, err)
+	assert.Equal(t, len(block.Txs), len(receipts))
+	gasUsed := uint64(0)
+	for i, receipt := range receipts {
+		assert.Equal(t, block.Txs[i].Hash(), receipt.TxHash)
+		assert.Equal(t, block.Hash(), receipt.BlockHash)
+		assert.Equal(t, block.Height(), receipt.BlockHeight)
+		assert.Equal(t, uint(i), receipt.TxIndex)
+		assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+		assert.Equal(t, common.Address{}, receipt.ContractAddress)
+		gasUsed += receipt.GasUsed
+	}
+	assert.Equal(t, block.GasUsed(), gasUsed)
 	assert.Equal(t, block.Header.Bloom, newHeader.Bloom)
 	assert.Equal(t, block.Header.EventRoot, newHeader.EventRoot)
 	assert.Equal(t, block.Header.GasUsed, newHeader.GasUsed)
@@ -63,7 +78,7 @@ func TestTxProcessor_Process(t *testing.T) {
 
 	// genesis block
 	block = defaultBlocks[0]
-	newHeader, err = p.Process(block)
+	newHeader, _, err = p.Process(block)
 	assert.NoError(t, err)
 	assert.Equal(t, block.Header.Bloom, newHeader.Bloom)
 	assert.Equal(t, block.Header.EventRoot, newHeader.EventRoot)
@@ -75,7 +90,7 @@ func TestTxProcessor_Process(t *testing.T) {
 
 	// block on fork branch
 	block = createNewBlock()
-	newHeader, err = p.Process(block)
+	newHeader, _, err = p.Process(block)
 	assert.NoError(t, err)
 	assert.Equal(t, block.Header.Bloom, newHeader.Bloom)
 	assert.Equal(t, block.Header.EventRoot, newHeader.EventRoot)
@@ -94,24 +109,24 @@ func TestTxProcessor_Process2(t *testing.T) {
 	// tamper with amount
 	block := createNewBlock()
 	rawTx, _ := rlp.EncodeToBytes(block.Txs[0])
-	rawTx[29]++ // amount++
+	rawTx[30]++ // amount++
 	cpy := new(types.Transaction)
 	err := rlp.DecodeBytes(rawTx, cpy)
 	assert.NoError(t, err)
 	assert.Equal(t, new(big.Int).Add(block.Txs[0].Amount(), big.NewInt(1)), cpy.Amount())
 	block.Txs[0] = cpy
-	_, err = p.Process(block)
+	_, _, err = p.Process(block)
 	assert.Equal(t, ErrInvalidTxInBlock, err)
 
 	// invalid signature
 	block = createNewBlock()
 	rawTx, _ = rlp.EncodeToBytes(block.Txs[0])
-	rawTx[43] = 0 // invalid S
+	rawTx[44] = 0 // invalid S
 	cpy = new(types.Transaction)
 	err = rlp.DecodeBytes(rawTx, cpy)
 	assert.NoError(t, err)
 	block.Txs[0] = cpy
-	_, err = p.Process(block)
+	_, _, err = p.Process(block)
 	assert.Equal(t, ErrInvalidTxInBlock, err)
 
 	// not enough gas (resign by another address)
@@ -122,28 +137,28 @@ func TestTxProcessor_Process2(t *testing.T) {
 	newFrom, _ := block.Txs[0].From()
 	assert.NotEqual(t, origFrom, newFrom)
 	block.Header.TxRoot = types.DeriveTxsSha(block.Txs)
-	_, err = p.Process(block)
+	_, _, err = p.Process(block)
 	assert.Equal(t, ErrInvalidTxInBlock, err)
 
 	// exceed block gas limit
 	block = createNewBlock()
 	block.Header.GasLimit = 1
-	_, err = p.Process(block)
+	_, _, err = p.Process(block)
 	assert.Equal(t, ErrInvalidTxInBlock, err)
 
 	// used gas reach limit in some tx
 	block = createNewBlock()
-	block.Txs[0] = makeTransaction(testPrivate, defaultAccounts[1], big.NewInt(100), common.Big1, 0, 1)
+	block.Txs[0] = makeTransaction(testPrivate, 2, defaultAccounts[1], big.NewInt(100), common.Big1, 0, 1)
 	block.Header.TxRoot = types.DeriveTxsSha(block.Txs)
-	_, err = p.Process(block)
+	_, _, err = p.Process(block)
 	assert.Equal(t, ErrInvalidTxInBlock, err)
 
 	// balance not enough
 	block = createNewBlock()
 	balance := p.am.GetAccount(testAddr).GetBalance()
-	block.Txs[0] = makeTx(testPrivate, defaultAccounts[1], new(big.Int).Add(balance, big.NewInt(1)))
+	block.Txs[0] = makeTx(testPrivate, 2, defaultAccounts[1], new(big.Int).Add(balance, big.NewInt(1)))
 	block.Header.TxRoot = types.DeriveTxsSha(block.Txs)
-	_, err = p.Process(block)
+	_, _, err = p.Process(block)
 	assert.Equal(t, ErrInvalidTxInBlock, err)
 
 	// TODO test create or call contract fail
@@ -156,7 +171,7 @@ func createNewBlock() *types.Block {
 		parentHash: defaultBlocks[1].Hash(),
 		author:     testAddr,
 		txList: []*types.Transaction{
-			makeTx(testPrivate, defaultAccounts[1], big.NewInt(100)),
+			makeTx(testPrivate, 2, defaultAccounts[1], big.NewInt(100)),
 		}}, false)
 }
 
@@ -176,8 +191,12 @@ func TestTxProcessor_ApplyTxs(t *testing.T) {
 		GasUsed:      header.GasUsed,
 		Time:         header.Time,
 	}
-	newHeader, selectedTxs, invalidTxs, err := p.ApplyTxs(emptyHeader, txs)
+	newHeader, selectedTxs, invalidTxs, receipts, err := p.ApplyTxs(emptyHeader, txs)
 	assert.NoError(t, err)
+	assert.Equal(t, len(selectedTxs), len(receipts))
+	assert.Equal(t, txs[0].Hash(), receipts[0].TxHash)
+	assert.Equal(t, common.Hash{}, receipts[0].BlockHash)
+	assert.Equal(t, header.GasUsed, receipts[0].GasUsed)
 	assert.Equal(t, header.Bloom, newHeader.Bloom)
 	assert.Equal(t, header.EventRoot, newHeader.EventRoot)
 	assert.Equal(t, header.GasUsed, newHeader.GasUsed)
@@ -200,7 +219,7 @@ func TestTxProcessor_ApplyTxs(t *testing.T) {
 		GasUsed:      header.GasUsed,
 		Time:         header.Time,
 	}
-	newHeader, selectedTxs, invalidTxs, err = p.ApplyTxs(emptyHeader, txs)
+	newHeader, selectedTxs, invalidTxs, _, err = p.ApplyTxs(emptyHeader, txs)
 	assert.NoError(t, err)
 	assert.Equal(t, header.Bloom, newHeader.Bloom)
 	assert.Equal(t, header.EventRoot, newHeader.EventRoot)
@@ -225,7 +244,7 @@ func TestTxProcessor_ApplyTxs(t *testing.T) {
 	p.am.Reset(emptyHeader.ParentHash)
 	author := p.am.GetAccount(header.MinerAddress)
 	origBalance := author.GetBalance()
-	newHeader, selectedTxs, invalidTxs, err = p.ApplyTxs(emptyHeader, nil)
+	newHeader, selectedTxs, invalidTxs, _, err = p.ApplyTxs(emptyHeader, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, types.Bloom{}, newHeader.Bloom)
 	emptyTrieHash := common.HexToHash("0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470")
@@ -247,7 +266,7 @@ func TestTxProcessor_ApplyTxs(t *testing.T) {
 		GasUsed:      header.GasUsed,
 		Time:         header.Time,
 	}
-	newHeader, selectedTxs, invalidTxs, err = p.ApplyTxs(emptyHeader, txs)
+	newHeader, selectedTxs, invalidTxs, _, err = p.ApplyTxs(emptyHeader, txs)
 	assert.NoError(t, err)
 	assert.Equal(t, header.Bloom, newHeader.Bloom)
 	assert.Equal(t, header.EventRoot, newHeader.EventRoot)
@@ -274,10 +293,10 @@ func TestTxProcessor_ApplyTxs(t *testing.T) {
 	balance := p.am.GetAccount(testAddr).GetBalance()
 	txs = types.Transactions{
 		txs[0],
-		makeTx(testPrivate, defaultAccounts[1], new(big.Int).Add(balance, big.NewInt(1))),
+		makeTx(testPrivate, 4, defaultAccounts[1], new(big.Int).Add(balance, big.NewInt(1))),
 		txs[1],
 	}
-	newHeader, selectedTxs, invalidTxs, err = p.ApplyTxs(emptyHeader, txs)
+	newHeader, selectedTxs, invalidTxs, _, err = p.ApplyTxs(emptyHeader, txs)
 	assert.NoError(t, err)
 	assert.Equal(t, header.Bloom, newHeader.Bloom)
 	assert.Equal(t, header.EventRoot, newHeader.EventRoot)
@@ -288,6 +307,18 @@ func TestTxProcessor_ApplyTxs(t *testing.T) {
 	assert.Equal(t, header.Hash(), newHeader.Hash())
 	assert.Equal(t, len(txs)-1, len(selectedTxs))
 	assert.Equal(t, 1, len(invalidTxs))
+
+	// deadline is passed
+	txs = defaultBlocks[3].Txs
+	newHeader, selectedTxs, invalidTxs, _, err = p.ApplyTxsUntil(emptyHeader, txs, time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(selectedTxs))
+	assert.Equal(t, 0, len(invalidTxs))
+	assert.Equal(t, uint64(0), newHeader.GasUsed)
+	newHeader, selectedTxs, _, _, err = p.ApplyTxsUntil(emptyHeader, txs, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, len(txs), len(selectedTxs))
+	assert.Equal(t, header.Hash(), newHeader.Hash())
 }
 
 // TODO move these cases to evm
@@ -311,9 +342,9 @@ func TestTxProcessor_ApplyTxs2(t *testing.T) {
 	minerBalance := p.am.GetAccount(defaultAccounts[0]).GetBalance()
 	recipientBalance := p.am.GetAccount(defaultAccounts[1]).GetBalance()
 	txs := types.Transactions{
-		makeTx(testPrivate, defaultAccounts[1], common.Big1),
+		makeTx(testPrivate, 3, defaultAccounts[1], common.Big1),
 	}
-	newHeader, _, _, err := p.ApplyTxs(emptyHeader, txs)
+	newHeader, _, _, _, err := p.ApplyTxs(emptyHeader, txs)
 	assert.NoError(t, err)
 	assert.Equal(t, params.TxGas, newHeader.GasUsed)
 	newSenderBalance := p.am.GetAccount(testAddr).GetBalance()
@@ -339,12 +370,155 @@ func TestTxProcessor_ApplyTxs2(t *testing.T) {
 	p.am.Reset(emptyHeader.ParentHash)
 	senderBalance = p.am.GetAccount(testAddr).GetBalance()
 	txs = types.Transactions{
-		makeTx(testPrivate, testAddr, common.Big1),
+		makeTx(testPrivate, 3, testAddr, common.Big1),
 	}
-	newHeader, _, _, err = p.ApplyTxs(emptyHeader, txs)
+	newHeader, _, _, _, err = p.ApplyTxs(emptyHeader, txs)
 	assert.NoError(t, err)
 	assert.Equal(t, params.TxGas, newHeader.GasUsed)
 	newSenderBalance = p.am.GetAccount(testAddr).GetBalance()
 	cost = txs[0].GasPrice().Mul(txs[0].GasPrice(), big.NewInt(int64(params.TxGas)))
 	assert.Equal(t, senderBalance.Sub(senderBalance, cost), newSenderBalance)
 }
+
+func TestTxProcessor_blockReceipt(t *testing.T) {
+	store.ClearData()
+	p := NewTxProcessor(newChain())
+	p.am.Reset(defaultBlocks[2].Hash())
+	assert.Nil(t, p.blockReceipt(3, 0, common.Hash{}))
+
+	txEvent := &types.Event{Address: testAddr, TxHash: common.HexToHash("0x1"), BlockHeight: 3}
+	p.am.AddEvent(txEvent)
+	assert.Nil(t, p.blockReceipt(3, 1, common.Hash{}))
+
+	// the payouts are not emitted by any transaction
+	assert.NoError(t, setRewardSchedule(p.am, &deputynode.RewardSchedule{TermReward: big.NewInt(100), TreasuryRate: 100, Treasury: common.HexToAddress("0x99")}))
+	assert.NoError(t, handOutRewards(1001, p.am))
+	receipt := p.blockReceipt(3, 1, common.HexToHash("0x2"))
+	assert.Equal(t, common.Hash{}, receipt.TxHash)
+	assert.Equal(t, common.HexToHash("0x2"), receipt.BlockHash)
+	assert.Equal(t, uint32(3), receipt.BlockHeight)
+	assert.Equal(t, uint(1), receipt.TxIndex)
+	assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	assert.Equal(t, len(p.am.GetEvents())-1, len(receipt.Events))
+	for _, event := range receipt.Events {
+		assert.Equal(t, RewardAddress, event.Address)
+	}
+}
+
+// init code which deploys a contract returning 42
+var testContractInitCode = common.FromHex("0x69602a60005260206000f3600052600a6016f3")
+
+func TestTxProcessor_CallTx(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+	p := bc.TxProcessor()
+	block := bc.GetBlockByHeight(1)
+
+	// contract creation returns the runtime code
+	ret, err := p.CallTx(block, &CallMsg{From: testAddr, Data: testContractInitCode})
+	assert.NoError(t, err)
+	assert.Equal(t, common.FromHex("0x602a60005260206000f3"), ret)
+
+	// transfer to normal account returns nothing, and the state is not changed
+	ret, err = p.CallTx(block, &CallMsg{From: testAddr, To: &defaultAccounts[0], Amount: common.Big1, GasPrice: common.Big1})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(ret))
+	manager := account.NewManager(block.Hash(), bc.db)
+	assert.Equal(t, testStableNonce, int(manager.GetAccount(testAddr).GetNonce()))
+
+	// execution failed
+	_, err = p.CallTx(block, &CallMsg{From: testAddr, Data: []byte{0xfe}})
+	assert.Equal(t, ErrTxExecutionFailed, err)
+
+	// insufficient balance
+	private, _ := crypto.GenerateKey()
+	_, err = p.CallTx(block, &CallMsg{From: crypto.PubkeyToAddress(private.PublicKey), To: &defaultAccounts[0], GasPrice: common.Big1})
+	assert.Equal(t, ErrInsufficientBalanceForGas, err)
+}
+
+func TestTxProcessor_CallTx_history(t *testing.T) {
+	store.ClearData()
+	db := newDB()
+	assert.NoError(t, db.SetStableBlock(defaultBlocks[2].Hash()))
+	bc, err := NewBlockChain(chainID, NewDpovp(10*1000, db), db, flag.CmdFlags{})
+	assert.NoError(t, err)
+	p := bc.TxProcessor()
+	block := bc.GetBlockByHeight(1)
+
+	// the accounts in db are changed by the stable block 2
+	manager := account.NewManager(block.Hash(), bc.db)
+	assert.Equal(t, testStableNonce+1, int(manager.GetAccount(testAddr).GetNonce()))
+	manager, err = bc.stateManager(block)
+	assert.NoError(t, err)
+	assert.Equal(t, testStableNonce, int(manager.GetAccount(testAddr).GetNonce()))
+
+	// the balance of block 1 is enough for the transfer
+	balance := manager.GetAccount(testAddr).GetBalance()
+	ret, err := p.CallTx(block, &CallMsg{From: testAddr, To: &defaultAccounts[0], Amount: balance})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(ret))
+	_, err = p.CallTx(defaultBlocks[2], &CallMsg{From: testAddr, To: &defaultAccounts[0], Amount: balance})
+	assert.Equal(t, vm.ErrInsufficientBalance, err)
+}
+
+func TestTxProcessor_EstimateGas(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+	p := bc.TxProcessor()
+	block := bc.GetBlockByHeight(1)
+
+	// transfer
+	gas, err := p.EstimateGas(block, &CallMsg{From: testAddr, To: &defaultAccounts[0], Amount: common.Big1, GasPrice: common.Big1})
+	assert.NoError(t, err)
+	assert.Equal(t, params.TxGas, gas)
+
+	// contract creation costs more than intrinsic gas, and the estimated gas is just enough
+	gas, err = p.EstimateGas(block, &CallMsg{From: testAddr, Data: testContractInitCode})
+	assert.NoError(t, err)
+	intrinsicGas, _ := IntrinsicGas(testContractInitCode, true, false)
+	assert.Equal(t, true, gas > intrinsicGas)
+	_, err = p.CallTx(block, &CallMsg{From: testAddr, Data: testContractInitCode, GasLimit: gas})
+	assert.NoError(t, err)
+	_, err = p.CallTx(block, &CallMsg{From: testAddr, Data: testContractInitCode, GasLimit: gas - 1})
+	assert.Equal(t, ErrTxExecutionFailed, err)
+
+	// always failing
+	_, err = p.EstimateGas(block, &CallMsg{From: testAddr, Data: []byte{0xfe}})
+	assert.Equal(t, ErrGasRequiredExceeds, err)
+	// gas limit is less than intrinsic gas
+	_, err = p.EstimateGas(block, &CallMsg{From: testAddr, To: &defaultAccounts[0], GasLimit: params.TxGas - 1})
+	assert.Equal(t, ErrGasRequiredExceeds, err)
+}
+
+func TestTxProcessor_TraceBlock(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+	p := bc.TxProcessor()
+	block := bc.GetBlockByHeight(1)
+
+	traces, err := p.TraceBlock(block, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, len(block.Txs), len(traces))
+	for i, trace := range traces {
+		// transfer to normal account only costs intrinsic gas
+		intrinsicGas, _ := IntrinsicGas(block.Txs[i].Data(), false, false)
+		assert.Equal(t, block.Txs[i].Hash(), trace.TxHash)
+		assert.Equal(t, false, trace.Failed)
+		assert.Equal(t, intrinsicGas, uint64(trace.GasUsed))
+		assert.Equal(t, "CALL", trace.Calls.Type)
+		assert.Equal(t, testAddr, trace.Calls.From)
+		assert.Equal(t, *block.Txs[i].To(), trace.Calls.To)
+		assert.Equal(t, 0, len(trace.StructLogs))
+	}
+
+	// trace single transaction
+	trace, err := p.TraceTx(block, 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, traces[1], trace)
+	_, err = p.TraceTx(block, len(block.Txs), nil)
+	assert.Equal(t, ErrTxIndexOutOfRange, err)
+
+	// the state of chain is not changed
+	manager := account.NewManager(block.Hash(), bc.db)
+	assert.Equal(t, testStableNonce, int(manager.GetAccount(testAddr).GetNonce()))
+}
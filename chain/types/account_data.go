@@ -29,16 +29,23 @@ type versionRecordMarshaling struct {
 type AccountData struct {
 	Address     common.Address `json:"address" gencodec:"required"`
 	Balance     *big.Int       `json:"balance" gencodec:"required"`
+	Nonce       uint64         `json:"nonce"` // count of transactions sent from this account, used against replay
 	CodeHash    common.Hash    `json:"codeHash" gencodec:"required"`
 	StorageRoot common.Hash    `json:"root" gencodec:"required"` // MPT root of the storage trie
 	// It records the block height which contains any type of newest change log.
 	NewestRecords map[ChangeLogType]VersionRecord `json:"records" gencodec:"required"`
+	// election data. They are nil if never been set
+	VoteFor   *common.Address   `json:"voteFor,omitempty"`   // the candidate which the account votes for
+	Votes     *big.Int          `json:"votes,omitempty"`     // the votes received by the candidate
+	Candidate *CandidateProfile `json:"candidate,omitempty"` // the registration information of candidate
 	// related transactions include income and outcome
 	TxHashList []common.Hash `json:"-"`
 }
 
 type accountDataMarshaling struct {
 	Balance *hexutil.Big10
+	Nonce   hexutil.Uint64
+	Votes   *hexutil.Big10
 }
 
 // rlpVersionRecord defines the fields which would be encode/decode by rlp
@@ -52,11 +59,16 @@ type rlpVersionRecord struct {
 type rlpAccountData struct {
 	Address     common.Address
 	Balance     *big.Int
+	Nonce       uint64
 	CodeHash    common.Hash
 	StorageRoot common.Hash
 	TxHashList  []common.Hash
 
 	NewestRecords []rlpVersionRecord
+
+	VoteFor   common.Address
+	Votes     *big.Int
+	Candidate CandidateProfile
 }
 
 // EncodeRLP implements rlp.Encoder.
@@ -65,14 +77,26 @@ func (a *AccountData) EncodeRLP(w io.Writer) error {
 	for logType, record := range a.NewestRecords {
 		NewestRecords = append(NewestRecords, rlpVersionRecord{logType, record.Version, record.Height})
 	}
-	return rlp.Encode(w, rlpAccountData{
+	enc := rlpAccountData{
 		Address:       a.Address,
 		Balance:       a.Balance,
+		Nonce:         a.Nonce,
 		CodeHash:      a.CodeHash,
 		StorageRoot:   a.StorageRoot,
 		TxHashList:    a.TxHashList,
 		NewestRecords: NewestRecords,
-	})
+		Votes:         new(big.Int),
+	}
+	if a.VoteFor != nil {
+		enc.VoteFor = *a.VoteFor
+	}
+	if a.Votes != nil {
+		enc.Votes = a.Votes
+	}
+	if a.Candidate != nil {
+		enc.Candidate = *a.Candidate
+	}
+	return rlp.Encode(w, enc)
 }
 
 // DecodeRLP implements rlp.Decoder.
@@ -80,12 +104,23 @@ func (a *AccountData) DecodeRLP(s *rlp.Stream) error {
 	var dec rlpAccountData
 	err := s.Decode(&dec)
 	if err == nil {
-		a.Address, a.Balance, a.CodeHash, a.StorageRoot, a.TxHashList = dec.Address, dec.Balance, dec.CodeHash, dec.StorageRoot, dec.TxHashList
+		a.Address, a.Balance, a.Nonce, a.CodeHash, a.StorageRoot, a.TxHashList = dec.Address, dec.Balance, dec.Nonce, dec.CodeHash, dec.StorageRoot, dec.TxHashList
 		a.NewestRecords = make(map[ChangeLogType]VersionRecord)
 
 		for _, record := range dec.NewestRecords {
 			a.NewestRecords[ChangeLogType(record.LogType)] = VersionRecord{Version: record.Version, Height: record.Height}
 		}
+		// the empty election data is decoded as nil
+		a.VoteFor, a.Votes, a.Candidate = nil, nil, nil
+		if dec.VoteFor != (common.Address{}) {
+			a.VoteFor = &dec.VoteFor
+		}
+		if dec.Votes != nil && dec.Votes.Sign() != 0 {
+			a.Votes = dec.Votes
+		}
+		if !dec.Candidate.Equal(&CandidateProfile{}) {
+			a.Candidate = &dec.Candidate
+		}
 	}
 	return err
 }
@@ -99,6 +134,16 @@ func (a *AccountData) Copy() *AccountData {
 			cpy.NewestRecords[logType] = record
 		}
 	}
+	if a.VoteFor != nil {
+		voteFor := *a.VoteFor
+		cpy.VoteFor = &voteFor
+	}
+	if a.Votes != nil {
+		cpy.Votes = new(big.Int).Set(a.Votes)
+	}
+	if a.Candidate != nil {
+		cpy.Candidate = a.Candidate.Copy()
+	}
 	if len(a.TxHashList) > 0 {
 		cpy.TxHashList = make([]common.Hash, 0, len(a.TxHashList))
 		for _, hash := range a.TxHashList {
@@ -113,12 +158,24 @@ func (a *AccountData) String() string {
 		fmt.Sprintf("Address: %s", a.Address.String()),
 		fmt.Sprintf("Balance: %s", a.Balance.String()),
 	}
+	if a.Nonce != 0 {
+		set = append(set, fmt.Sprintf("Nonce: %d", a.Nonce))
+	}
 	if a.CodeHash != (common.Hash{}) {
 		set = append(set, fmt.Sprintf("CodeHash: %s", a.CodeHash.Hex()))
 	}
 	if a.StorageRoot != (common.Hash{}) {
 		set = append(set, fmt.Sprintf("StorageRoot: %s", a.StorageRoot.Hex()))
 	}
+	if a.VoteFor != nil {
+		set = append(set, fmt.Sprintf("VoteFor: %s", a.VoteFor.String()))
+	}
+	if a.Votes != nil {
+		set = append(set, fmt.Sprintf("Votes: %s", a.Votes.String()))
+	}
+	if a.Candidate != nil {
+		set = append(set, fmt.Sprintf("Candidate: %s", a.Candidate.String()))
+	}
 	if len(a.TxHashList) > 0 {
 		set = append(set, fmt.Sprintf("TxHashList: %v", a.TxHashList))
 	}
@@ -143,6 +200,8 @@ type AccountAccessor interface {
 	GetAddress() common.Address
 	GetBalance() *big.Int
 	SetBalance(balance *big.Int)
+	GetNonce() uint64
+	SetNonce(nonce uint64)
 	GetVersion(logType ChangeLogType) uint32
 	SetVersion(logType ChangeLogType, version uint32)
 	GetCodeHash() common.Hash
@@ -158,5 +217,11 @@ type AccountAccessor interface {
 	IsEmpty() bool
 	GetSuicide() bool
 	SetSuicide(suicided bool)
+	GetVoteFor() common.Address
+	SetVoteFor(candidate common.Address)
+	GetVotes() *big.Int
+	SetVotes(votes *big.Int)
+	GetCandidateProfile() *CandidateProfile
+	SetCandidateProfile(profile *CandidateProfile)
 	MarshalJSON() ([]byte, error)
 }
@@ -46,6 +46,23 @@ func TestAccountData_EncodeRLP_DecodeRLP(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestAccountData_EncodeRLP_DecodeRLP_election(t *testing.T) {
+	account := getAccountData()
+	voteFor := common.HexToAddress("0x20000")
+	account.VoteFor = &voteFor
+	account.Votes = big.NewInt(5)
+	account.Candidate = &CandidateProfile{IsCandidate: true, MinerAddress: voteFor, NodeID: []byte{0x12}, Host: "127.0.0.1", Port: 7001}
+
+	data, err := rlp.EncodeToBytes(account)
+	assert.NoError(t, err)
+	decoded := new(AccountData)
+	err = rlp.DecodeBytes(data, decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, voteFor, *decoded.VoteFor)
+	assert.Equal(t, big.NewInt(5), decoded.Votes)
+	assert.Equal(t, true, account.Candidate.Equal(decoded.Candidate))
+}
+
 func TestAccountData_Copy(t *testing.T) {
 	account := getAccountData()
 
@@ -64,7 +81,7 @@ func TestAccountData_MarshalJSON_UnmarshalJSON(t *testing.T) {
 
 	data, err := account.MarshalJSON()
 	assert.NoError(t, err)
-	assert.Equal(t, `{"address":"Lemo8888888888888888888888888888883CPHBJ","balance":"100","codeHash":"0x1d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e","root":"0xcbeb7c7e36b846713bc99b8fa527e8d552e31bfaa1ac0f2b773958cda3aba3ed","records":{"1":{"version":"100","height":"10"},"2":{"version":"101","height":"11"}}}`, string(data))
+	assert.Equal(t, `{"address":"Lemo8888888888888888888888888888883CPHBJ","balance":"100","nonce":"0","codeHash":"0x1d5f11eaa13e02cdca886181dc38ab4cb8cf9092e86c000fb42d12c8b504500e","root":"0xcbeb7c7e36b846713bc99b8fa527e8d552e31bfaa1ac0f2b773958cda3aba3ed","records":{"1":{"version":"100","height":"10"},"2":{"version":"101","height":"11"}}}`, string(data))
 
 	decode := new(AccountData)
 	err = decode.UnmarshalJSON(data)
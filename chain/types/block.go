@@ -1,15 +1,19 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"github.com/LemoFoundationLtd/lemochain-go/common/crypto/sha3"
 	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
 	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"io"
 	"strings"
 )
 
+var ErrInvalidHeaderRLP = errors.New("invalid optional fields in header rlp")
+
 //go:generate gencodec -type Header -field-override headerMarshaling -out gen_header_json.go
 //go:generate gencodec -type Block -out gen_block_json.go
 
@@ -27,18 +31,82 @@ type Header struct {
 	Time         uint32         `json:"timestamp"        gencodec:"required"`
 	SignData     []byte         `json:"signData"         gencodec:"required"`
 	DeputyRoot   []byte         `json:"deputyRoot"`
-	Extra        []byte         `json:"extraData"` // max length is 256 bytes
+	Extra        []byte         `json:"extraData"`    // max length is 256 bytes
+	FinalityRoot []byte         `json:"finalityRoot"` // hash of the finality certificate of an ancestor block
+}
+
+// rlpHeader is the rlp layout of header. FinalityRoot is an optional trailing field, so the headers without it are encoded
+// same as the old nodes
+type rlpHeader struct {
+	ParentHash   common.Hash
+	MinerAddress common.Address
+	VersionRoot  common.Hash
+	TxRoot       common.Hash
+	LogRoot      common.Hash
+	EventRoot    common.Hash
+	Bloom        Bloom
+	Height       uint32
+	GasLimit     uint64
+	GasUsed      uint64
+	Time         uint32
+	SignData     []byte
+	DeputyRoot   []byte
+	Extra        []byte
+	Optional     [][]byte `rlp:"tail"`
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (h *Header) EncodeRLP(w io.Writer) error {
+	enc := rlpHeader{
+		ParentHash:   h.ParentHash,
+		MinerAddress: h.MinerAddress,
+		VersionRoot:  h.VersionRoot,
+		TxRoot:       h.TxRoot,
+		LogRoot:      h.LogRoot,
+		EventRoot:    h.EventRoot,
+		Bloom:        h.Bloom,
+		Height:       h.Height,
+		GasLimit:     h.GasLimit,
+		GasUsed:      h.GasUsed,
+		Time:         h.Time,
+		SignData:     h.SignData,
+		DeputyRoot:   h.DeputyRoot,
+		Extra:        h.Extra,
+	}
+	if len(h.FinalityRoot) > 0 {
+		enc.Optional = [][]byte{h.FinalityRoot}
+	}
+	return rlp.Encode(w, &enc)
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (h *Header) DecodeRLP(s *rlp.Stream) error {
+	var dec rlpHeader
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	if len(dec.Optional) > 1 {
+		return ErrInvalidHeaderRLP
+	}
+	h.ParentHash, h.MinerAddress, h.VersionRoot, h.TxRoot, h.LogRoot, h.EventRoot = dec.ParentHash, dec.MinerAddress, dec.VersionRoot, dec.TxRoot, dec.LogRoot, dec.EventRoot
+	h.Bloom, h.Height, h.GasLimit, h.GasUsed, h.Time = dec.Bloom, dec.Height, dec.GasLimit, dec.GasUsed, dec.Time
+	h.SignData, h.DeputyRoot, h.Extra, h.FinalityRoot = dec.SignData, dec.DeputyRoot, dec.Extra, nil
+	if len(dec.Optional) == 1 {
+		h.FinalityRoot = dec.Optional[0]
+	}
+	return nil
 }
 
 type headerMarshaling struct {
-	Height     hexutil.Uint32
-	GasLimit   hexutil.Uint64
-	GasUsed    hexutil.Uint64
-	Time       hexutil.Uint32
-	SignData   hexutil.Bytes
-	DeputyRoot hexutil.Bytes
-	Extra      hexutil.Bytes
-	Hash       common.Hash `json:"hash"`
+	Height       hexutil.Uint32
+	GasLimit     hexutil.Uint64
+	GasUsed      hexutil.Uint64
+	Time         hexutil.Uint32
+	SignData     hexutil.Bytes
+	DeputyRoot   hexutil.Bytes
+	FinalityRoot hexutil.Bytes
+	Extra        hexutil.Bytes
+	Hash         common.Hash `json:"hash"`
 }
 
 // 签名信息
@@ -61,6 +129,7 @@ type Block struct {
 	Events      []*Event               `json:"events"        gencodec:"required"`
 	Confirms    []SignData             `json:"confirms"`
 	DeputyNodes deputynode.DeputyNodes `json:"deputyNodes"`
+	Finality    *FinalityCertificate   `json:"finality"      rlp:"nil"`
 }
 
 func NewBlock(header *Header, txs []*Transaction, changeLog []*ChangeLog, events []*Event, confirms []SignData) *Block {
@@ -77,7 +146,7 @@ type Blocks []*Block
 
 // Hash 块hash 排除 SignData字段
 func (h *Header) Hash() common.Hash {
-	return rlpHash([]interface{}{
+	fields := []interface{}{
 		h.ParentHash,
 		h.MinerAddress,
 		h.VersionRoot,
@@ -91,7 +160,12 @@ func (h *Header) Hash() common.Hash {
 		h.Time,
 		h.DeputyRoot,
 		h.Extra,
-	})
+	}
+	// FinalityRoot is only set after the Finality fork, so the hashes of the blocks before the fork are not changed
+	if len(h.FinalityRoot) > 0 {
+		fields = append(fields, h.FinalityRoot)
+	}
+	return rlpHash(fields)
 }
 
 // Copy 拷贝一份头
@@ -105,6 +179,10 @@ func (h *Header) Copy() *Header {
 		cpy.DeputyRoot = make([]byte, len(h.DeputyRoot))
 		copy(cpy.DeputyRoot, h.DeputyRoot)
 	}
+	if len(h.FinalityRoot) > 0 {
+		cpy.FinalityRoot = make([]byte, len(h.FinalityRoot))
+		copy(cpy.FinalityRoot, h.FinalityRoot)
+	}
 	if len(h.Extra) > 0 {
 		cpy.Extra = make([]byte, len(h.Extra))
 		copy(cpy.Extra, h.Extra)
@@ -136,6 +214,9 @@ func (h *Header) String() string {
 		fmt.Sprintf("SignData: %s", common.ToHex(h.SignData[:])),
 		fmt.Sprintf("DeputyNodes: %s", common.ToHex(h.DeputyRoot)),
 	}
+	if len(h.FinalityRoot) > 0 {
+		set = append(set, fmt.Sprintf("FinalityRoot: %s", common.ToHex(h.FinalityRoot)))
+	}
 	if len(h.Extra) > 0 {
 		set = append(set, fmt.Sprintf("Extra: %s", common.ToHex(h.Extra[:])))
 	}
@@ -164,6 +245,7 @@ func (b *Block) SetConfirms(confirms []SignData)                   { b.Confirms
 func (b *Block) SetChangeLogs(logs []*ChangeLog)                   { b.ChangeLogs = logs }
 func (b *Block) SetEvents(events []*Event)                         { b.Events = events }
 func (b *Block) SetDeputyNodes(deputyNodes deputynode.DeputyNodes) { b.DeputyNodes = deputyNodes }
+func (b *Block) SetFinality(finality *FinalityCertificate)         { b.Finality = finality }
 
 func (b *Block) String() string {
 	set := []string{
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
+	"net"
+)
+
+var (
+	ErrInvalidCandidateProfile = errors.New("invalid candidate profile")
+)
+
+// CandidateProfile is the registration information of deputy node candidate
+type CandidateProfile struct {
+	IsCandidate  bool           `json:"isCandidate"`  // false means the candidate quits the election
+	MinerAddress common.Address `json:"minerAddress"` // the account to receive block rewards
+	NodeID       hexutil.Bytes  `json:"nodeID"`
+	Host         string         `json:"host"` // ip
+	Port         uint32         `json:"port"`
+}
+
+// Check checks if the profile can be used to create a deputy node
+func (p *CandidateProfile) Check() error {
+	if len(p.NodeID) != 64 || net.ParseIP(p.Host) == nil || p.Port == 0 || p.Port > 65535 {
+		return ErrInvalidCandidateProfile
+	}
+	return nil
+}
+
+// Copy returns a deep copy of the profile
+func (p *CandidateProfile) Copy() *CandidateProfile {
+	if p == nil {
+		return nil
+	}
+	cpy := *p
+	cpy.NodeID = common.CopyBytes(p.NodeID)
+	return &cpy
+}
+
+// Equal reports whether two profiles are same
+func (p *CandidateProfile) Equal(other *CandidateProfile) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	return p.IsCandidate == other.IsCandidate && p.MinerAddress == other.MinerAddress && bytes.Equal(p.NodeID, other.NodeID) && p.Host == other.Host && p.Port == other.Port
+}
+
+func (p *CandidateProfile) String() string {
+	return fmt.Sprintf("{IsCandidate: %t, MinerAddress: %s, NodeID: %s, Host: %s, Port: %d}", p.IsCandidate, p.MinerAddress.String(), common.ToHex(p.NodeID), p.Host, p.Port)
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCandidateProfile_Check(t *testing.T) {
+	profile := &CandidateProfile{
+		IsCandidate: true,
+		NodeID:      common.FromHex("0x5e3600755f9b512a65603b38e30885c98cbac70259c3235c9b3f42ee563b480edea351ba0ff5748a638fe0aeff5d845bf37a3b437831871b48fd32f33cd9a3c0"),
+		Host:        "127.0.0.1",
+		Port:        7001,
+	}
+	assert.NoError(t, profile.Check())
+
+	invalid := profile.Copy()
+	invalid.NodeID = invalid.NodeID[1:]
+	assert.Equal(t, ErrInvalidCandidateProfile, invalid.Check())
+	invalid = profile.Copy()
+	invalid.Host = "localhost"
+	assert.Equal(t, ErrInvalidCandidateProfile, invalid.Check())
+	invalid = profile.Copy()
+	invalid.Port = 65536
+	assert.Equal(t, ErrInvalidCandidateProfile, invalid.Check())
+}
+
+func TestCandidateProfile_Copy_Equal(t *testing.T) {
+	profile := &CandidateProfile{IsCandidate: true, NodeID: []byte{0x12}, Host: "127.0.0.1", Port: 7001}
+	cpy := profile.Copy()
+	assert.Equal(t, true, profile.Equal(cpy))
+	cpy.NodeID[0] = 0x34
+	assert.Equal(t, false, profile.Equal(cpy))
+
+	var empty *CandidateProfile
+	assert.Nil(t, empty.Copy())
+	assert.Equal(t, true, empty.Equal(nil))
+	assert.Equal(t, false, profile.Equal(nil))
+}
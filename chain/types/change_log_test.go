@@ -18,14 +18,24 @@ type testAccount struct {
 func (f *testAccount) GetAddress() common.Address  { return f.AccountData.Address }
 func (f *testAccount) GetBalance() *big.Int        { return f.AccountData.Balance }
 func (f *testAccount) SetBalance(balance *big.Int) { f.AccountData.Balance = balance }
+func (f *testAccount) GetNonce() uint64            { return f.AccountData.Nonce }
+func (f *testAccount) SetNonce(nonce uint64)       { f.AccountData.Nonce = nonce }
 func (f *testAccount) GetVersion(logType ChangeLogType) uint32 {
 	return f.AccountData.NewestRecords[logType].Version
 }
 func (f *testAccount) SetVersion(logType ChangeLogType, version uint32) {
 	f.AccountData.NewestRecords[logType] = VersionRecord{Version: version, Height: f.baseHeight + 1}
 }
-func (f *testAccount) GetSuicide() bool                                    { return false }
-func (f *testAccount) SetSuicide(suicided bool)                            {}
+func (f *testAccount) GetSuicide() bool                       { return false }
+func (f *testAccount) SetSuicide(suicided bool)               {}
+func (f *testAccount) GetVoteFor() common.Address             { return common.Address{} }
+func (f *testAccount) SetVoteFor(candidate common.Address)    {}
+func (f *testAccount) GetVotes() *big.Int                     { return f.AccountData.Votes }
+func (f *testAccount) SetVotes(votes *big.Int)                { f.AccountData.Votes = votes }
+func (f *testAccount) GetCandidateProfile() *CandidateProfile { return f.AccountData.Candidate }
+func (f *testAccount) SetCandidateProfile(profile *CandidateProfile) {
+	f.AccountData.Candidate = profile
+}
 func (f *testAccount) GetCodeHash() common.Hash                            { return f.AccountData.CodeHash }
 func (f *testAccount) SetCodeHash(codeHash common.Hash)                    { f.AccountData.CodeHash = codeHash }
 func (f *testAccount) GetCode() (Code, error)                              { return nil, nil }
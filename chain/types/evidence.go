@@ -0,0 +1,61 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+)
+
+var (
+	ErrInvalidEvidence = errors.New("invalid evidence")
+)
+
+// Evidence proves that a deputy node signed two different blocks at the same height. A signature is either the SignData
+// of a mined block or a confirm, both of them are signed on the block hash
+type Evidence struct {
+	HeaderA *Header
+	SignA   SignData
+	HeaderB *Header
+	SignB   SignData
+}
+
+// NewEvidence creates an evidence from two signed headers. The headers are sorted by hash, so that the same conflict
+// always makes the same evidence
+func NewEvidence(headerA *Header, signA SignData, headerB *Header, signB SignData) *Evidence {
+	hashA, hashB := headerA.Hash(), headerB.Hash()
+	if bytes.Compare(hashA[:], hashB[:]) > 0 {
+		headerA, signA, headerB, signB = headerB, signB, headerA, signA
+	}
+	return &Evidence{HeaderA: headerA, SignA: signA, HeaderB: headerB, SignB: signB}
+}
+
+// Hash returns the hash of the conflicting block hashes
+func (e *Evidence) Hash() common.Hash {
+	return rlpHash([]interface{}{e.HeaderA.Hash(), e.HeaderB.Hash()})
+}
+
+// Height returns the height of the conflicting blocks
+func (e *Evidence) Height() uint32 {
+	return e.HeaderA.Height
+}
+
+// Offender checks the evidence, and returns the node id of the deputy node who signed both blocks
+func (e *Evidence) Offender() ([]byte, error) {
+	if e.HeaderA == nil || e.HeaderB == nil || e.HeaderA.Height != e.HeaderB.Height {
+		return nil, ErrInvalidEvidence
+	}
+	hashA, hashB := e.HeaderA.Hash(), e.HeaderB.Hash()
+	if hashA == hashB {
+		return nil, ErrInvalidEvidence
+	}
+	pubKeyA, err := crypto.Ecrecover(hashA[:], e.SignA[:])
+	if err != nil {
+		return nil, ErrInvalidEvidence
+	}
+	pubKeyB, err := crypto.Ecrecover(hashB[:], e.SignB[:])
+	if err != nil || !bytes.Equal(pubKeyA, pubKeyB) {
+		return nil, ErrInvalidEvidence
+	}
+	return pubKeyA[1:], nil
+}
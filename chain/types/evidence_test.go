@@ -0,0 +1,59 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func signHeader(header *Header, key *ecdsa.PrivateKey) SignData {
+	hash := header.Hash()
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		panic(err)
+	}
+	var sign SignData
+	copy(sign[:], sig)
+	return sign
+}
+
+func TestEvidence_Offender(t *testing.T) {
+	headerA := &Header{Height: 10, Time: 1}
+	headerB := &Header{Height: 10, Time: 2}
+	signA, signB := signHeader(headerA, testPrivate), signHeader(headerB, testPrivate)
+	evidence := NewEvidence(headerA, signA, headerB, signB)
+	nodeID, err := evidence.Offender()
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.FromECDSAPub(&testPrivate.PublicKey)[1:], nodeID)
+	assert.Equal(t, uint32(10), evidence.Height())
+	// the order of headers doesn't change the evidence
+	assert.Equal(t, evidence.Hash(), NewEvidence(headerB, signB, headerA, signA).Hash())
+
+	// rlp
+	data, err := rlp.EncodeToBytes(evidence)
+	assert.NoError(t, err)
+	decoded := new(Evidence)
+	assert.NoError(t, rlp.DecodeBytes(data, decoded))
+	assert.Equal(t, evidence.Hash(), decoded.Hash())
+	_, err = decoded.Offender()
+	assert.NoError(t, err)
+
+	// same block
+	_, err = NewEvidence(headerA, signA, headerA, signA).Offender()
+	assert.Equal(t, ErrInvalidEvidence, err)
+	// different heights
+	headerC := &Header{Height: 11}
+	_, err = NewEvidence(headerA, signA, headerC, signHeader(headerC, testPrivate)).Offender()
+	assert.Equal(t, ErrInvalidEvidence, err)
+	// signed by different nodes
+	otherKey, _ := crypto.GenerateKey()
+	_, err = NewEvidence(headerA, signA, headerB, signHeader(headerB, otherKey)).Offender()
+	assert.Equal(t, ErrInvalidEvidence, err)
+	// invalid signature
+	_, err = NewEvidence(headerA, signA, headerB, SignData{}).Offender()
+	assert.Equal(t, ErrInvalidEvidence, err)
+	_, err = (&Evidence{HeaderA: headerA, SignA: signA}).Offender()
+	assert.Equal(t, ErrInvalidEvidence, err)
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
+	"math"
+)
+
+var (
+	ErrInvalidConfirm    = errors.New("the confirm is not signed by a deputy node")
+	ErrDuplicateConfirm  = errors.New("the deputy node confirmed the block twice")
+	ErrNotEnoughConfirms = errors.New("the confirms are less than 2/3 of deputy nodes")
+)
+
+//go:generate gencodec -type FinalityCertificate -field-override finalityMarshaling -out gen_finality_json.go
+
+// FinalityCertificate proves that a block is stable. It contains the confirms signed by at least 2/3 of the deputy
+// nodes at the height of the block
+type FinalityCertificate struct {
+	Hash   common.Hash `json:"hash"     gencodec:"required"`
+	Height uint32      `json:"height"   gencodec:"required"`
+	Signs  []SignData  `json:"signs"    gencodec:"required"`
+}
+
+type finalityMarshaling struct {
+	Height hexutil.Uint32
+}
+
+// MinConfirmCount returns how many confirms make a block stable
+func MinConfirmCount(nodeCount int) int {
+	return int(math.Ceil(float64(nodeCount) * 2.0 / 3.0))
+}
+
+// Root returns the hash which is committed by the FinalityRoot in header of later blocks
+func (c *FinalityCertificate) Root() common.Hash {
+	return rlpHash(c)
+}
+
+// VerifyConfirm checks the confirm is signed on hash by one of the nodes, and returns the node id of signer
+func VerifyConfirm(hash common.Hash, sign SignData, nodes deputynode.DeputyNodes) ([]byte, error) {
+	pubKey, err := crypto.Ecrecover(hash[:], sign[:])
+	if err != nil {
+		return nil, ErrInvalidConfirm
+	}
+	for _, node := range nodes {
+		if bytes.Equal(node.NodeID, pubKey[1:]) {
+			return node.NodeID, nil
+		}
+	}
+	return nil, ErrInvalidConfirm
+}
+
+// Verify checks every confirm is signed by a different deputy node, and the confirms are enough
+func (c *FinalityCertificate) Verify(nodes deputynode.DeputyNodes) error {
+	signers := make(map[string]bool, len(c.Signs))
+	for _, sign := range c.Signs {
+		nodeID, err := VerifyConfirm(c.Hash, sign, nodes)
+		if err != nil {
+			return err
+		}
+		if signers[string(nodeID)] {
+			return ErrDuplicateConfirm
+		}
+		signers[string(nodeID)] = true
+	}
+	if len(signers) == 0 || len(signers) < MinConfirmCount(len(nodes)) {
+		return ErrNotEnoughConfirms
+	}
+	return nil
+}
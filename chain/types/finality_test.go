@@ -0,0 +1,140 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func makeTestDeputies(count int) ([]*ecdsa.PrivateKey, deputynode.DeputyNodes) {
+	keys := make([]*ecdsa.PrivateKey, count)
+	nodes := make(deputynode.DeputyNodes, count)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		nodes[i] = &deputynode.DeputyNode{
+			MinerAddress: crypto.PubkeyToAddress(keys[i].PublicKey),
+			NodeID:       crypto.FromECDSAPub(&keys[i].PublicKey)[1:],
+			Rank:         uint32(i),
+		}
+	}
+	return keys, nodes
+}
+
+func signConfirm(hash common.Hash, key *ecdsa.PrivateKey) SignData {
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		panic(err)
+	}
+	var sign SignData
+	copy(sign[:], sig)
+	return sign
+}
+
+func TestHeader_Hash(t *testing.T) {
+	header := &Header{ParentHash: common.HexToHash("0x1"), Height: 10, Time: 123, DeputyRoot: []byte{1}, Extra: []byte{2}}
+	// the hash of the header without finality root is same as before the Finality fork
+	expected := rlpHash([]interface{}{header.ParentHash, header.MinerAddress, header.VersionRoot, header.TxRoot, header.LogRoot, header.EventRoot, header.Bloom, header.Height, header.GasLimit, header.GasUsed, header.Time, header.DeputyRoot, header.Extra})
+	assert.Equal(t, expected, header.Hash())
+
+	header.FinalityRoot = common.HexToHash("0x2").Bytes()
+	assert.NotEqual(t, expected, header.Hash())
+	other := header.Copy()
+	other.FinalityRoot = common.HexToHash("0x3").Bytes()
+	assert.NotEqual(t, header.Hash(), other.Hash())
+}
+
+// oldHeader is the header layout before the Finality fork
+type oldHeader struct {
+	ParentHash   common.Hash
+	MinerAddress common.Address
+	VersionRoot  common.Hash
+	TxRoot       common.Hash
+	LogRoot      common.Hash
+	EventRoot    common.Hash
+	Bloom        Bloom
+	Height       uint32
+	GasLimit     uint64
+	GasUsed      uint64
+	Time         uint32
+	SignData     []byte
+	DeputyRoot   []byte
+	Extra        []byte
+}
+
+func TestHeader_EncodeRLP(t *testing.T) {
+	header := &Header{ParentHash: common.HexToHash("0x1"), Height: 10, Time: 123, SignData: []byte{3}, DeputyRoot: []byte{1}, Extra: []byte{2}}
+	old := &oldHeader{ParentHash: header.ParentHash, Height: header.Height, Time: header.Time, SignData: header.SignData, DeputyRoot: header.DeputyRoot, Extra: header.Extra}
+	// the header without finality root is encoded same as the old nodes
+	encoded, err := rlp.EncodeToBytes(header)
+	assert.NoError(t, err)
+	oldEncoded, err := rlp.EncodeToBytes(old)
+	assert.NoError(t, err)
+	assert.Equal(t, oldEncoded, encoded)
+	decoded := new(Header)
+	assert.NoError(t, rlp.DecodeBytes(oldEncoded, decoded))
+	assert.Equal(t, header.Hash(), decoded.Hash())
+	assert.Empty(t, decoded.FinalityRoot)
+
+	// the finality root is a trailing field
+	header.FinalityRoot = common.HexToHash("0x2").Bytes()
+	encoded, err = rlp.EncodeToBytes(header)
+	assert.NoError(t, err)
+	decoded = new(Header)
+	assert.NoError(t, rlp.DecodeBytes(encoded, decoded))
+	assert.Equal(t, header.FinalityRoot, decoded.FinalityRoot)
+	assert.Equal(t, header.Hash(), decoded.Hash())
+	assert.Error(t, rlp.DecodeBytes(encoded, new(oldHeader)))
+
+	// unknown optional fields
+	raw, err := rlp.EncodeToBytes([]interface{}{old.ParentHash, old.MinerAddress, old.VersionRoot, old.TxRoot, old.LogRoot, old.EventRoot, old.Bloom, old.Height, old.GasLimit, old.GasUsed, old.Time, old.SignData, old.DeputyRoot, old.Extra, []byte{1}, []byte{2}})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrInvalidHeaderRLP, rlp.DecodeBytes(raw, new(Header)))
+}
+
+func TestMinConfirmCount(t *testing.T) {
+	assert.Equal(t, 1, MinConfirmCount(1))
+	assert.Equal(t, 2, MinConfirmCount(2))
+	assert.Equal(t, 2, MinConfirmCount(3))
+	assert.Equal(t, 3, MinConfirmCount(4))
+	assert.Equal(t, 4, MinConfirmCount(5))
+}
+
+func TestFinalityCertificate_Verify(t *testing.T) {
+	keys, nodes := makeTestDeputies(5)
+	hash := common.HexToHash("0x1234")
+	signs := make([]SignData, len(keys))
+	for i, key := range keys {
+		signs[i] = signConfirm(hash, key)
+	}
+
+	certificate := &FinalityCertificate{Hash: hash, Height: 10, Signs: signs[:4]}
+	assert.NoError(t, certificate.Verify(nodes))
+	nodeID, err := VerifyConfirm(hash, signs[2], nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, nodes[2].NodeID, nodeID)
+	// the root commits to the signs
+	root := certificate.Root()
+	certificate.Signs = signs
+	assert.NoError(t, certificate.Verify(nodes))
+	assert.NotEqual(t, root, certificate.Root())
+
+	// not enough
+	certificate.Signs = signs[:3]
+	assert.Equal(t, ErrNotEnoughConfirms, certificate.Verify(nodes))
+	// duplicate
+	certificate.Signs = []SignData{signs[0], signs[1], signs[2], signs[2]}
+	assert.Equal(t, ErrDuplicateConfirm, certificate.Verify(nodes))
+	// signed by other node
+	otherKey, _ := crypto.GenerateKey()
+	certificate.Signs = []SignData{signs[0], signs[1], signs[2], signConfirm(hash, otherKey)}
+	assert.Equal(t, ErrInvalidConfirm, certificate.Verify(nodes))
+	// signed on other block
+	certificate.Signs = []SignData{signs[0], signs[1], signs[2], signConfirm(common.HexToHash("0x5678"), keys[3])}
+	assert.Equal(t, ErrInvalidConfirm, certificate.Verify(nodes))
+	certificate.Signs = []SignData{signs[0], signs[1], signs[2], {}}
+	assert.Equal(t, ErrInvalidConfirm, certificate.Verify(nodes))
+}
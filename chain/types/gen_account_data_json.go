@@ -18,17 +18,25 @@ func (a AccountData) MarshalJSON() ([]byte, error) {
 	type AccountData struct {
 		Address       common.Address                  `json:"address" gencodec:"required"`
 		Balance       *hexutil.Big10                  `json:"balance" gencodec:"required"`
+		Nonce         hexutil.Uint64                  `json:"nonce"`
 		CodeHash      common.Hash                     `json:"codeHash" gencodec:"required"`
 		StorageRoot   common.Hash                     `json:"root" gencodec:"required"`
 		NewestRecords map[ChangeLogType]VersionRecord `json:"records" gencodec:"required"`
+		VoteFor       *common.Address                 `json:"voteFor,omitempty"`
+		Votes         *hexutil.Big10                  `json:"votes,omitempty"`
+		Candidate     *CandidateProfile               `json:"candidate,omitempty"`
 		TxHashList    []common.Hash                   `json:"-"`
 	}
 	var enc AccountData
 	enc.Address = a.Address
 	enc.Balance = (*hexutil.Big10)(a.Balance)
+	enc.Nonce = hexutil.Uint64(a.Nonce)
 	enc.CodeHash = a.CodeHash
 	enc.StorageRoot = a.StorageRoot
 	enc.NewestRecords = a.NewestRecords
+	enc.VoteFor = a.VoteFor
+	enc.Votes = (*hexutil.Big10)(a.Votes)
+	enc.Candidate = a.Candidate
 	enc.TxHashList = a.TxHashList
 	return json.Marshal(&enc)
 }
@@ -38,9 +46,13 @@ func (a *AccountData) UnmarshalJSON(input []byte) error {
 	type AccountData struct {
 		Address       *common.Address                 `json:"address" gencodec:"required"`
 		Balance       *hexutil.Big10                  `json:"balance" gencodec:"required"`
+		Nonce         *hexutil.Uint64                 `json:"nonce"`
 		CodeHash      *common.Hash                    `json:"codeHash" gencodec:"required"`
 		StorageRoot   *common.Hash                    `json:"root" gencodec:"required"`
 		NewestRecords map[ChangeLogType]VersionRecord `json:"records" gencodec:"required"`
+		VoteFor       *common.Address                 `json:"voteFor,omitempty"`
+		Votes         *hexutil.Big10                  `json:"votes,omitempty"`
+		Candidate     *CandidateProfile               `json:"candidate,omitempty"`
 		TxHashList    []common.Hash                   `json:"-"`
 	}
 	var dec AccountData
@@ -55,6 +67,9 @@ func (a *AccountData) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'balance' for AccountData")
 	}
 	a.Balance = (*big.Int)(dec.Balance)
+	if dec.Nonce != nil {
+		a.Nonce = uint64(*dec.Nonce)
+	}
 	if dec.CodeHash == nil {
 		return errors.New("missing required field 'codeHash' for AccountData")
 	}
@@ -67,6 +82,15 @@ func (a *AccountData) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'records' for AccountData")
 	}
 	a.NewestRecords = dec.NewestRecords
+	if dec.VoteFor != nil {
+		a.VoteFor = dec.VoteFor
+	}
+	if dec.Votes != nil {
+		a.Votes = (*big.Int)(dec.Votes)
+	}
+	if dec.Candidate != nil {
+		a.Candidate = dec.Candidate
+	}
 	if dec.TxHashList != nil {
 		a.TxHashList = dec.TxHashList
 	}
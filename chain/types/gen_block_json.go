@@ -18,6 +18,7 @@ func (b Block) MarshalJSON() ([]byte, error) {
 		Events      []*Event               `json:"events"        gencodec:"required"`
 		Confirms    []SignData             `json:"confirms"`
 		DeputyNodes deputynode.DeputyNodes `json:"deputyNodes"`
+		Finality    *FinalityCertificate   `json:"finality"      rlp:"nil"`
 	}
 	var enc Block
 	enc.Header = b.Header
@@ -26,6 +27,7 @@ func (b Block) MarshalJSON() ([]byte, error) {
 	enc.Events = b.Events
 	enc.Confirms = b.Confirms
 	enc.DeputyNodes = b.DeputyNodes
+	enc.Finality = b.Finality
 	return json.Marshal(&enc)
 }
 
@@ -38,6 +40,7 @@ func (b *Block) UnmarshalJSON(input []byte) error {
 		Events      []*Event                `json:"events"        gencodec:"required"`
 		Confirms    []SignData              `json:"confirms"`
 		DeputyNodes *deputynode.DeputyNodes `json:"deputyNodes"`
+		Finality    *FinalityCertificate    `json:"finality"      rlp:"nil"`
 	}
 	var dec Block
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -65,5 +68,8 @@ func (b *Block) UnmarshalJSON(input []byte) error {
 	if dec.DeputyNodes != nil {
 		b.DeputyNodes = *dec.DeputyNodes
 	}
+	if dec.Finality != nil {
+		b.Finality = dec.Finality
+	}
 	return nil
 }
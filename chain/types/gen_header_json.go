@@ -29,6 +29,7 @@ func (h Header) MarshalJSON() ([]byte, error) {
 		SignData     hexutil.Bytes  `json:"signData"         gencodec:"required"`
 		DeputyRoot   hexutil.Bytes  `json:"deputyRoot"`
 		Extra        hexutil.Bytes  `json:"extraData"`
+		FinalityRoot hexutil.Bytes  `json:"finalityRoot"`
 		Hash         common.Hash    `json:"hash"`
 	}
 	var enc Header
@@ -46,6 +47,7 @@ func (h Header) MarshalJSON() ([]byte, error) {
 	enc.SignData = h.SignData
 	enc.DeputyRoot = h.DeputyRoot
 	enc.Extra = h.Extra
+	enc.FinalityRoot = h.FinalityRoot
 	enc.Hash = h.Hash()
 	return json.Marshal(&enc)
 }
@@ -67,6 +69,7 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		SignData     *hexutil.Bytes  `json:"signData"         gencodec:"required"`
 		DeputyRoot   *hexutil.Bytes  `json:"deputyRoot"`
 		Extra        *hexutil.Bytes  `json:"extraData"`
+		FinalityRoot *hexutil.Bytes  `json:"finalityRoot"`
 	}
 	var dec Header
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -126,5 +129,8 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 	if dec.Extra != nil {
 		h.Extra = *dec.Extra
 	}
+	if dec.FinalityRoot != nil {
+		h.FinalityRoot = *dec.FinalityRoot
+	}
 	return nil
 }
@@ -16,6 +16,7 @@ var _ = (*txdataMarshaling)(nil)
 // MarshalJSON marshals as JSON.
 func (t txdata) MarshalJSON() ([]byte, error) {
 	type txdata struct {
+		AccountNonce  hexutil.Uint64  `json:"nonce" gencodec:"required"`
 		Recipient     *common.Address `json:"to" rlp:"nil"`
 		RecipientName string          `json:"toName"`
 		GasPrice      *hexutil.Big10  `json:"gasPrice" gencodec:"required"`
@@ -30,6 +31,7 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 		Hash          *common.Hash    `json:"hash" rlp:"-"`
 	}
 	var enc txdata
+	enc.AccountNonce = hexutil.Uint64(t.AccountNonce)
 	enc.Recipient = t.Recipient
 	enc.RecipientName = t.RecipientName
 	enc.GasPrice = (*hexutil.Big10)(t.GasPrice)
@@ -48,6 +50,7 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON unmarshals from JSON.
 func (t *txdata) UnmarshalJSON(input []byte) error {
 	type txdata struct {
+		AccountNonce  *hexutil.Uint64 `json:"nonce" gencodec:"required"`
 		Recipient     *common.Address `json:"to" rlp:"nil"`
 		RecipientName *string         `json:"toName"`
 		GasPrice      *hexutil.Big10  `json:"gasPrice" gencodec:"required"`
@@ -65,6 +68,10 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 	if err := json.Unmarshal(input, &dec); err != nil {
 		return err
 	}
+	if dec.AccountNonce == nil {
+		return errors.New("missing required field 'nonce' for txdata")
+	}
+	t.AccountNonce = uint64(*dec.AccountNonce)
 	if dec.Recipient != nil {
 		t.Recipient = dec.Recipient
 	}
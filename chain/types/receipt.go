@@ -0,0 +1,143 @@
+package types
+
+import (
+	"fmt"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"io"
+	"strings"
+)
+
+//go:generate gencodec -type Receipt -field-override receiptMarshaling -out gen_receipt_json.go
+
+const (
+	// ReceiptStatusFailed is the status code of a transaction if execution failed.
+	ReceiptStatusFailed = uint64(0)
+
+	// ReceiptStatusSuccessful is the status code of a transaction if execution succeeded.
+	ReceiptStatusSuccessful = uint64(1)
+)
+
+// Receipt represents the results of a transaction.
+type Receipt struct {
+	TxHash      common.Hash `json:"transactionHash"  gencodec:"required"`
+	BlockHash   common.Hash `json:"blockHash"`
+	BlockHeight uint32      `json:"blockHeight"      gencodec:"required"`
+	TxIndex     uint        `json:"transactionIndex" gencodec:"required"`
+	GasUsed     uint64      `json:"gasUsed"          gencodec:"required"`
+	Status      uint64      `json:"status"           gencodec:"required"`
+	// ContractAddress is the address of contract created by the transaction. It is empty if the transaction is not a contract creation
+	ContractAddress common.Address `json:"contractAddress"`
+	Events          []*Event       `json:"events"           gencodec:"required"`
+}
+
+type receiptMarshaling struct {
+	BlockHeight hexutil.Uint32
+	TxIndex     hexutil.Uint64
+	GasUsed     hexutil.Uint64
+	Status      hexutil.Uint64
+}
+
+type rlpStorageReceipt struct {
+	TxHash          common.Hash
+	BlockHash       common.Hash
+	BlockHeight     uint32
+	TxIndex         uint
+	GasUsed         uint64
+	Status          uint64
+	ContractAddress common.Address
+	Events          []*EventForStorage
+}
+
+// NewReceipt creates a transaction receipt
+func NewReceipt(tx *Transaction, height uint32, txIndex uint, gasUsed uint64, failed bool) *Receipt {
+	r := &Receipt{
+		TxHash:      tx.Hash(),
+		BlockHeight: height,
+		TxIndex:     txIndex,
+		GasUsed:     gasUsed,
+		Status:      ReceiptStatusSuccessful,
+		Events:      make([]*Event, 0),
+	}
+	if failed {
+		r.Status = ReceiptStatusFailed
+	}
+	return r
+}
+
+func (r *Receipt) String() string {
+	set := []string{
+		fmt.Sprintf("TxHash: %s", r.TxHash.Hex()),
+		fmt.Sprintf("BlockHash: %s", r.BlockHash.Hex()),
+		fmt.Sprintf("BlockHeight: %d", r.BlockHeight),
+		fmt.Sprintf("TxIndex: %d", r.TxIndex),
+		fmt.Sprintf("GasUsed: %d", r.GasUsed),
+		fmt.Sprintf("Status: %d", r.Status),
+	}
+	if (r.ContractAddress != common.Address{}) {
+		set = append(set, fmt.Sprintf("ContractAddress: %s", r.ContractAddress.String()))
+	}
+	if len(r.Events) > 0 {
+		set = append(set, fmt.Sprintf("Events: %v", r.Events))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(set, ", "))
+}
+
+// ReceiptForStorage is a wrapper around a Receipt that flattens and parses the entire content of
+// a receipt including the non-consensus fields of events.
+type ReceiptForStorage Receipt
+
+// EncodeRLP implements rlp.Encoder.
+func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
+	events := make([]*EventForStorage, len(r.Events))
+	for i, event := range r.Events {
+		events[i] = (*EventForStorage)(event)
+	}
+	return rlp.Encode(w, rlpStorageReceipt{
+		TxHash:          r.TxHash,
+		BlockHash:       r.BlockHash,
+		BlockHeight:     r.BlockHeight,
+		TxIndex:         r.TxIndex,
+		GasUsed:         r.GasUsed,
+		Status:          r.Status,
+		ContractAddress: r.ContractAddress,
+		Events:          events,
+	})
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (r *ReceiptForStorage) DecodeRLP(s *rlp.Stream) error {
+	var dec rlpStorageReceipt
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	events := make([]*Event, len(dec.Events))
+	for i, event := range dec.Events {
+		events[i] = (*Event)(event)
+	}
+	*r = ReceiptForStorage{
+		TxHash:          dec.TxHash,
+		BlockHash:       dec.BlockHash,
+		BlockHeight:     dec.BlockHeight,
+		TxIndex:         dec.TxIndex,
+		GasUsed:         dec.GasUsed,
+		Status:          dec.Status,
+		ContractAddress: dec.ContractAddress,
+		Events:          events,
+	}
+	return nil
+}
+
+// Receipts is a wrapper around a Receipt array
+type Receipts []*Receipt
+
+// SetBlockHash fills the hash of block which the receipts belong to. The hash is unknown when the transactions are applied by miner
+func (r Receipts) SetBlockHash(hash common.Hash) {
+	for _, receipt := range r {
+		receipt.BlockHash = hash
+		for _, event := range receipt.Events {
+			event.BlockHash = hash
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"encoding/json"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func getReceipt() *Receipt {
+	receipt := NewReceipt(testTx, 10, 1, 21000, false)
+	receipt.ContractAddress = common.HexToAddress("0x10000")
+	receipt.Events = []*Event{{
+		Address:     common.HexToAddress("0x10000"),
+		Topics:      []common.Hash{TopicContractCreation},
+		Data:        []byte{},
+		BlockHeight: 10,
+		TxHash:      testTx.Hash(),
+		TxIndex:     1,
+		Index:       2,
+	}}
+	Receipts{receipt}.SetBlockHash(common.HexToHash("0x11"))
+	return receipt
+}
+
+func TestNewReceipt(t *testing.T) {
+	receipt := NewReceipt(testTx, 10, 1, 21000, false)
+	assert.Equal(t, testTx.Hash(), receipt.TxHash)
+	assert.Equal(t, uint32(10), receipt.BlockHeight)
+	assert.Equal(t, uint(1), receipt.TxIndex)
+	assert.Equal(t, uint64(21000), receipt.GasUsed)
+	assert.Equal(t, ReceiptStatusSuccessful, receipt.Status)
+	assert.Equal(t, 0, len(receipt.Events))
+
+	receipt = NewReceipt(testTx, 10, 1, 21000, true)
+	assert.Equal(t, ReceiptStatusFailed, receipt.Status)
+}
+
+func TestReceipts_SetBlockHash(t *testing.T) {
+	receipt := getReceipt()
+	assert.Equal(t, common.HexToHash("0x11"), receipt.BlockHash)
+	assert.Equal(t, common.HexToHash("0x11"), receipt.Events[0].BlockHash)
+}
+
+func TestReceiptForStorage_EncodeRLP_DecodeRLP(t *testing.T) {
+	receipt := getReceipt()
+	data, err := rlp.EncodeToBytes((*ReceiptForStorage)(receipt))
+	assert.NoError(t, err)
+
+	decoded := new(ReceiptForStorage)
+	err = rlp.DecodeBytes(data, decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, receipt, (*Receipt)(decoded))
+
+	// decode incorrect data
+	err = rlp.DecodeBytes(data[1:], decoded)
+	assert.Error(t, err)
+}
+
+func TestReceipt_MarshalJSON_UnmarshalJSON(t *testing.T) {
+	receipt := getReceipt()
+	data, err := json.Marshal(receipt)
+	assert.NoError(t, err)
+
+	decoded := new(Receipt)
+	err = json.Unmarshal(data, decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, receipt, decoded)
+
+	// missing required field
+	err = json.Unmarshal([]byte(`{"transactionHash":"0x0000000000000000000000000000000000000000000000000000000000000001"}`), decoded)
+	assert.Equal(t, "missing required field 'blockHeight' for Receipt", err.Error())
+}
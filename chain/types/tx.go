@@ -21,6 +21,14 @@ var (
 	TxVersion     uint8  = 1 // current transaction version. should between 0 and 128
 )
 
+// transaction types
+const (
+	OrdinaryTx uint8 = iota // transfer or contract creation/call
+	VoteTx                  // vote for the candidate in recipient
+	RegisterTx              // register or update the sender as deputy node candidate. The data is the rlp of CandidateProfile
+	EvidenceTx              // report the equivocation of a deputy node to slash it. The data is the rlp of Evidence
+)
+
 type Transactions []*Transaction
 
 type Transaction struct {
@@ -32,7 +40,8 @@ type Transaction struct {
 }
 
 type txdata struct {
-	Recipient     *common.Address `json:"to" rlp:"nil"` // nil means contract creation
+	AccountNonce  uint64          `json:"nonce" gencodec:"required"` // sequence number of the sender's transactions
+	Recipient     *common.Address `json:"to" rlp:"nil"`              // nil means contract creation
 	RecipientName string          `json:"toName"`
 	GasPrice      *big.Int        `json:"gasPrice" gencodec:"required"`
 	GasLimit      uint64          `json:"gasLimit" gencodec:"required"`
@@ -53,29 +62,61 @@ type txdata struct {
 }
 
 type txdataMarshaling struct {
-	GasPrice   *hexutil.Big10
-	GasLimit   hexutil.Uint64
-	Amount     *hexutil.Big10
-	Data       hexutil.Bytes
-	Expiration hexutil.Uint64
-	V          *hexutil.Big
-	R          *hexutil.Big
-	S          *hexutil.Big
+	AccountNonce hexutil.Uint64
+	GasPrice     *hexutil.Big10
+	GasLimit     hexutil.Uint64
+	Amount       *hexutil.Big10
+	Data         hexutil.Bytes
+	Expiration   hexutil.Uint64
+	V            *hexutil.Big
+	R            *hexutil.Big
+	S            *hexutil.Big
+}
+
+func NewTransaction(nonce uint64, to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, chainId uint16, expiration uint64, toName string, message string) *Transaction {
+	return newTransaction(0, TxVersion, chainId, nonce, &to, amount, gasLimit, gasPrice, data, expiration, toName, message)
+}
+
+func NewContractCreation(nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, chainId uint16, expiration uint64, toName string, message string) *Transaction {
+	return newTransaction(0, TxVersion, chainId, nonce, nil, amount, gasLimit, gasPrice, data, expiration, toName, message)
+}
+
+// NewVoteTransaction creates a transaction to vote for the candidate
+func NewVoteTransaction(nonce uint64, candidate common.Address, gasLimit uint64, gasPrice *big.Int, chainId uint16, expiration uint64, message string) *Transaction {
+	return newTransaction(VoteTx, TxVersion, chainId, nonce, &candidate, nil, gasLimit, gasPrice, nil, expiration, "", message)
+}
+
+// NewRegisterTransaction creates a transaction to register the sender as deputy node candidate
+func NewRegisterTransaction(nonce uint64, profile *CandidateProfile, gasLimit uint64, gasPrice *big.Int, chainId uint16, expiration uint64, message string) (*Transaction, error) {
+	data, err := rlp.EncodeToBytes(profile)
+	if err != nil {
+		return nil, err
+	}
+	return newTransaction(RegisterTx, TxVersion, chainId, nonce, nil, nil, gasLimit, gasPrice, data, expiration, "", message), nil
 }
 
-func NewTransaction(to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, chainId uint16, expiration uint64, toName string, message string) *Transaction {
-	return newTransaction(0, TxVersion, chainId, &to, amount, gasLimit, gasPrice, data, expiration, toName, message)
+// NewEvidenceTransaction creates a transaction to report the equivocation of a deputy node
+func NewEvidenceTransaction(nonce uint64, evidence *Evidence, gasLimit uint64, gasPrice *big.Int, chainId uint16, expiration uint64, message string) (*Transaction, error) {
+	data, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		return nil, err
+	}
+	return newTransaction(EvidenceTx, TxVersion, chainId, nonce, nil, nil, gasLimit, gasPrice, data, expiration, "", message), nil
 }
 
-func NewContractCreation(amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, chainId uint16, expiration uint64, toName string, message string) *Transaction {
-	return newTransaction(0, TxVersion, chainId, nil, amount, gasLimit, gasPrice, data, expiration, toName, message)
+// NewCallTransaction creates an unsigned transaction whose sender is specified directly. It is only used to pre-execute transaction without signature, e.g. contract call and gas estimation
+func NewCallTransaction(from common.Address, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, chainId uint16) *Transaction {
+	tx := newTransaction(0, TxVersion, chainId, nonce, to, amount, gasLimit, gasPrice, data, 0, "", "")
+	tx.from.Store(from)
+	return tx
 }
 
-func newTransaction(txType uint8, version uint8, chainId uint16, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, expiration uint64, toName string, message string) *Transaction {
+func newTransaction(txType uint8, version uint8, chainId uint16, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, expiration uint64, toName string, message string) *Transaction {
 	if version >= 128 {
 		panic(fmt.Sprintf("invalid transaction version %d, should < 128", version))
 	}
 	d := txdata{
+		AccountNonce:  nonce,
 		Recipient:     to,
 		RecipientName: toName,
 		GasPrice:      new(big.Int),
@@ -142,6 +183,7 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 func (tx *Transaction) Type() uint8        { txType, _, _, _ := ParseV(tx.data.V); return txType }
 func (tx *Transaction) Version() uint8     { _, version, _, _ := ParseV(tx.data.V); return version }
 func (tx *Transaction) ChainId() uint16    { _, _, _, chainId := ParseV(tx.data.V); return chainId }
+func (tx *Transaction) Nonce() uint64      { return tx.data.AccountNonce }
 func (tx *Transaction) Data() []byte       { return common.CopyBytes(tx.data.Data) }
 func (tx *Transaction) GasLimit() uint64   { return tx.data.GasLimit }
 func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.data.GasPrice) }
@@ -157,6 +199,11 @@ func (tx *Transaction) To() *common.Address {
 	return &to
 }
 
+// IsContractCreation returns true if the transaction deploys a contract
+func (tx *Transaction) IsContractCreation() bool {
+	return tx.Type() == OrdinaryTx && tx.data.Recipient == nil
+}
+
 func (tx *Transaction) From() (common.Address, error) {
 	from := tx.from.Load()
 	if from != nil {
@@ -228,6 +275,7 @@ func (tx *Transaction) String() string {
 		fmt.Sprintf("Type: %d", tx.Type()),
 		fmt.Sprintf("Version: %d", tx.Version()),
 		fmt.Sprintf("ChainId: %d", tx.ChainId()),
+		fmt.Sprintf("Nonce: %d", tx.data.AccountNonce),
 		fmt.Sprintf("From: %s", from),
 		fmt.Sprintf("To: %s", to),
 	}
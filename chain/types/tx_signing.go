@@ -92,6 +92,7 @@ func (s DefaultSigner) Hash(tx *Transaction) common.Hash {
 		tx.Type(),
 		tx.Version(),
 		tx.ChainId(),
+		tx.data.AccountNonce,
 		tx.data.Recipient,
 		tx.data.RecipientName,
 		tx.data.GasPrice,
@@ -51,7 +51,7 @@ func TestDefaultSigner_GetSender(t *testing.T) {
 func TestDefaultSigner_ParseSignature(t *testing.T) {
 	txV, err := SignTx(testTx, testSigner, testPrivate)
 	assert.NoError(t, err)
-	r, s, v, err := testSigner.ParseSignature(testTx, common.FromHex("0x8c0499083cb3d27bead4f21994aeebf8e75fa11df6bfe01c71cad583fc9a3c70778a437607d072540719a866adb630001fabbfb6b032d1a8dfbffac7daed8f0201"))
+	r, s, v, err := testSigner.ParseSignature(testTx, common.FromHex("0x104d56590407d9851ec0c7c0d158679f7cef62a57fcc563b94892eb852395908483ee80cc3ccf34851470603f4e5a8d83e539bedbaeab8f7b1256cc4c0dcb81001"))
 	assert.NoError(t, err)
 	assert.Equal(t, txV.data.R, r)
 	assert.Equal(t, txV.data.S, s)
@@ -64,5 +64,5 @@ func TestDefaultSigner_ParseSignature(t *testing.T) {
 }
 
 func TestDefaultSigner_Hash(t *testing.T) {
-	assert.Equal(t, "0x81f8b8f725a9342a9ad85f31d2a6009afd52e43c5f86199a2089a32ea81913e6", testSigner.Hash(testTx).Hex())
+	assert.Equal(t, "0x6e351f1435d1fea23dbb1f2c66fef1579cd197191eb7afc62c778e340cf4545e", testSigner.Hash(testTx).Hex())
 }
@@ -17,11 +17,11 @@ var (
 	testPrivate, _ = crypto.HexToECDSA("432a86ab8765d82415a803e29864dcfc1ed93dac949abf6f95a583179f27e4bb") // secp256k1.V = 1
 	testAddr       = crypto.PubkeyToAddress(testPrivate.PublicKey)                                         // 0x0107134b9cdd7d89f83efa6175f9b3552f29094c
 
-	testTx = NewTransaction(common.HexToAddress("0x1"), common.Big1, 100, common.Big2, []byte{12}, 200, 1544584596, "aa", "aaa")
+	testTx = NewTransaction(0, common.HexToAddress("0x1"), common.Big1, 100, common.Big2, []byte{12}, 200, 1544584596, "aa", "aaa")
 
 	bigNum, _ = new(big.Int).SetString("111111111111111111111111111111111111111111111111111111111111", 16)
 	bigString = "888888888888888888888888888888888888888888888888888888888888"
-	testTxBig = NewTransaction(common.HexToAddress("0x1000000000000000000000000000000000000000"), bigNum, 100, bigNum, []byte(bigString), 200, 1544584596, bigString, bigString)
+	testTxBig = NewTransaction(1, common.HexToAddress("0x1000000000000000000000000000000000000000"), bigNum, 100, bigNum, []byte(bigString), 200, 1544584596, bigString, bigString)
 )
 
 func ExpirationFromNow() uint64 {
@@ -30,11 +30,12 @@ func ExpirationFromNow() uint64 {
 
 func TestNewTransaction(t *testing.T) {
 	expiration := ExpirationFromNow()
-	tx := NewTransaction(common.HexToAddress("0x1"), common.Big1, 100, common.Big2, []byte{12}, 200, expiration, "aa", "")
+	tx := NewTransaction(3, common.HexToAddress("0x1"), common.Big1, 100, common.Big2, []byte{12}, 200, expiration, "aa", "")
 	assert.Equal(t, uint8(0), tx.Type())
 	assert.Equal(t, TxVersion, tx.Version())
 	assert.Equal(t, uint16(200), tx.ChainId())
 	assert.Equal(t, big.NewInt(0x200c8), tx.data.V)
+	assert.Equal(t, uint64(3), tx.Nonce())
 	assert.Equal(t, common.HexToAddress("0x1"), *tx.To())
 	assert.Equal(t, "aa", tx.ToName())
 	assert.Equal(t, common.Big2, tx.GasPrice())
@@ -47,11 +48,12 @@ func TestNewTransaction(t *testing.T) {
 
 func TestNewContractCreation(t *testing.T) {
 	expiration := ExpirationFromNow()
-	tx := NewContractCreation(common.Big1, 100, common.Big2, []byte{12}, 200, expiration, "aa", "")
+	tx := NewContractCreation(3, common.Big1, 100, common.Big2, []byte{12}, 200, expiration, "aa", "")
 	assert.Equal(t, uint8(0), tx.Type())
 	assert.Equal(t, TxVersion, tx.Version())
 	assert.Equal(t, uint16(200), tx.ChainId())
 	assert.Equal(t, big.NewInt(0x200c8), tx.data.V)
+	assert.Equal(t, uint64(3), tx.Nonce())
 	assert.Empty(t, tx.To())
 	assert.Equal(t, "aa", tx.ToName())
 	assert.Equal(t, common.Big2, tx.GasPrice())
@@ -62,6 +64,27 @@ func TestNewContractCreation(t *testing.T) {
 	assert.Empty(t, tx.Message())
 }
 
+func TestNewVoteTransaction(t *testing.T) {
+	tx := NewVoteTransaction(3, common.HexToAddress("0x1"), 100, common.Big2, 200, ExpirationFromNow(), "")
+	assert.Equal(t, VoteTx, tx.Type())
+	assert.Equal(t, common.HexToAddress("0x1"), *tx.To())
+	assert.Equal(t, new(big.Int), tx.Amount())
+	assert.Empty(t, tx.Data())
+	assert.Equal(t, false, tx.IsContractCreation())
+}
+
+func TestNewRegisterTransaction(t *testing.T) {
+	profile := &CandidateProfile{IsCandidate: true, MinerAddress: common.HexToAddress("0x1"), NodeID: []byte{0x12}, Host: "127.0.0.1", Port: 7001}
+	tx, err := NewRegisterTransaction(3, profile, 100, common.Big2, 200, ExpirationFromNow(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, RegisterTx, tx.Type())
+	assert.Empty(t, tx.To())
+	assert.Equal(t, false, tx.IsContractCreation())
+	decoded := new(CandidateProfile)
+	assert.NoError(t, rlp.DecodeBytes(tx.Data(), decoded))
+	assert.Equal(t, true, profile.Equal(decoded))
+}
+
 func TestTransaction_WithSignature_From_Raw(t *testing.T) {
 	h := testSigner.Hash(testTx)
 	sig, err := crypto.Sign(h[:], testPrivate)
@@ -95,13 +118,14 @@ func TestTransaction_WithSignature_From_Raw_bigTx(t *testing.T) {
 func TestTransaction_EncodeRLP_DecodeRLP(t *testing.T) {
 	txb, err := rlp.EncodeToBytes(testTx)
 	assert.NoError(t, err)
-	assert.Equal(t, "0xeb9400000000000000000000000000000000000000018261610264010c845c107d9483616161830200c88080", common.ToHex(txb))
+	assert.Equal(t, "0xec809400000000000000000000000000000000000000018261610264010c845c107d9483616161830200c88080", common.ToHex(txb))
 	result := Transaction{}
 	err = rlp.DecodeBytes(txb, &result)
 	assert.NoError(t, err)
 	assert.Equal(t, testTx.Type(), result.Type())
 	assert.Equal(t, testTx.Version(), result.Version())
 	assert.Equal(t, testTx.ChainId(), result.ChainId())
+	assert.Equal(t, testTx.Nonce(), result.Nonce())
 	assert.Equal(t, testTx.To(), result.To())
 	assert.Equal(t, testTx.ToName(), result.ToName())
 	assert.Equal(t, testTx.GasPrice(), result.GasPrice())
@@ -119,7 +143,7 @@ func TestTransaction_EncodeRLP_DecodeRLP(t *testing.T) {
 	assert.NoError(t, err)
 	txb, err = rlp.EncodeToBytes(txV)
 	assert.NoError(t, err)
-	assert.Equal(t, "0xf86b9400000000000000000000000000000000000000018261610264010c845c107d9483616161830300c8a08c0499083cb3d27bead4f21994aeebf8e75fa11df6bfe01c71cad583fc9a3c70a0778a437607d072540719a866adb630001fabbfb6b032d1a8dfbffac7daed8f02", common.ToHex(txb))
+	assert.Equal(t, "0xf86c809400000000000000000000000000000000000000018261610264010c845c107d9483616161830300c8a0104d56590407d9851ec0c7c0d158679f7cef62a57fcc563b94892eb852395908a0483ee80cc3ccf34851470603f4e5a8d83e539bedbaeab8f7b1256cc4c0dcb810", common.ToHex(txb))
 	result = Transaction{}
 	err = rlp.DecodeBytes(txb, &result)
 	assert.NoError(t, err)
@@ -132,13 +156,14 @@ func TestTransaction_EncodeRLP_DecodeRLP(t *testing.T) {
 func TestTransaction_EncodeRLP_DecodeRLP_bigTx(t *testing.T) {
 	txb, err := rlp.EncodeToBytes(testTxBig)
 	assert.NoError(t, err)
-	assert.Equal(t, "0xf90119941000000000000000000000000000000000000000b83c3838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838389e111111111111111111111111111111111111111111111111111111111111649e111111111111111111111111111111111111111111111111111111111111b83c383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838845c107d94b83c383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838830200c88080", common.ToHex(txb))
+	assert.Equal(t, "0xf9011a01941000000000000000000000000000000000000000b83c3838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838389e111111111111111111111111111111111111111111111111111111111111649e111111111111111111111111111111111111111111111111111111111111b83c383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838845c107d94b83c383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838830200c88080", common.ToHex(txb))
 	result := Transaction{}
 	err = rlp.DecodeBytes(txb, &result)
 	assert.NoError(t, err)
 	assert.Equal(t, testTxBig.Type(), result.Type())
 	assert.Equal(t, testTxBig.Version(), result.Version())
 	assert.Equal(t, testTxBig.ChainId(), result.ChainId())
+	assert.Equal(t, testTxBig.Nonce(), result.Nonce())
 	assert.Equal(t, testTxBig.To(), result.To())
 	assert.Equal(t, testTxBig.ToName(), result.ToName())
 	assert.Equal(t, testTxBig.GasPrice(), result.GasPrice())
@@ -156,7 +181,7 @@ func TestTransaction_EncodeRLP_DecodeRLP_bigTx(t *testing.T) {
 	assert.NoError(t, err)
 	txb, err = rlp.EncodeToBytes(txV)
 	assert.NoError(t, err)
-	assert.Equal(t, "0xf90159941000000000000000000000000000000000000000b83c3838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838389e111111111111111111111111111111111111111111111111111111111111649e111111111111111111111111111111111111111111111111111111111111b83c383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838845c107d94b83c383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838830300c8a0956da7af3519f957e1281e8f41735aa965e6a712e828856fe95bdbd31c787d76a00f235ae3ed6ff4ea8198d2528b4561b68544c5691de58aa974b7b6cc791f78d5", common.ToHex(txb))
+	assert.Equal(t, "0xf9015a01941000000000000000000000000000000000000000b83c3838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838389e111111111111111111111111111111111111111111111111111111111111649e111111111111111111111111111111111111111111111111111111111111b83c383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838845c107d94b83c383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838830300c8a09a229bdf2ad3bbfe027e151a1659b4bed1824dbc0f63b74c0177535c70ac2c70a022d9e76575c9924fdb17f16d4aa86a828363ee3fdaf8afd5db12d79aa3722021", common.ToHex(txb))
 	result = Transaction{}
 	err = rlp.DecodeBytes(txb, &result)
 	assert.NoError(t, err)
@@ -168,18 +193,18 @@ func TestTransaction_EncodeRLP_DecodeRLP_bigTx(t *testing.T) {
 
 func TestTransaction_Hash(t *testing.T) {
 	// hash without signature
-	assert.Equal(t, common.HexToHash("0xa3cddd511dd5ca88b3f724e82e469d828cdc6dc6bb436e9e40a7ceb8cac94bdd"), testTx.Hash())
+	assert.Equal(t, common.HexToHash("0x0779c64780a6bfdeb4449898e741d84e00e44066a3a61455e1f00bbb0ad4ee08"), testTx.Hash())
 
 	// hash for sign
 	h := testSigner.Hash(testTx)
-	assert.Equal(t, common.HexToHash("0x81f8b8f725a9342a9ad85f31d2a6009afd52e43c5f86199a2089a32ea81913e6"), h)
+	assert.Equal(t, common.HexToHash("0x6e351f1435d1fea23dbb1f2c66fef1579cd197191eb7afc62c778e340cf4545e"), h)
 
 	// hash with signature
 	sig, err := crypto.Sign(h[:], testPrivate)
 	assert.NoError(t, err)
 	txV, err := testTx.WithSignature(testSigner, sig)
 	assert.NoError(t, err)
-	assert.Equal(t, common.HexToHash("0x2b6d49ea4bd64f4c94f7c0677566c867dbae50eb9d2142448a97b145baf4a277"), txV.Hash())
+	assert.Equal(t, common.HexToHash("0x9f29a78311af5efb4e1b4a89d6bb6429550f6b304e15725328e68e84371acafb"), txV.Hash())
 }
 
 func TestTransaction_MarshalJSON_UnmarshalJSON(t *testing.T) {
@@ -187,7 +212,7 @@ func TestTransaction_MarshalJSON_UnmarshalJSON(t *testing.T) {
 	assert.NoError(t, err)
 	data, err := json.Marshal(txV)
 	assert.NoError(t, err)
-	assert.Equal(t, "0x7b22746f223a224c656d6f383838383838383838383838383838383838383838383838383838383838383838384257222c22746f4e616d65223a226161222c226761735072696365223a2232222c226761734c696d6974223a22313030222c22616d6f756e74223a2231222c2264617461223a2230783063222c2265787069726174696f6e54696d65223a2231353434353834353936222c226d657373616765223a22616161222c2276223a2230783330306338222c2272223a22307838633034393930383363623364323762656164346632313939346165656266386537356661313164663662666530316337316361643538336663396133633730222c2273223a22307837373861343337363037643037323534303731396138363661646236333030303166616262666236623033326431613864666266666163376461656438663032222c2268617368223a22307832623664343965613462643634663463393466376330363737353636633836376462616535306562396432313432343438613937623134356261663461323737227d", common.ToHex(data))
+	assert.Equal(t, "0x7b226e6f6e6365223a2230222c22746f223a224c656d6f383838383838383838383838383838383838383838383838383838383838383838384257222c22746f4e616d65223a226161222c226761735072696365223a2232222c226761734c696d6974223a22313030222c22616d6f756e74223a2231222c2264617461223a2230783063222c2265787069726174696f6e54696d65223a2231353434353834353936222c226d657373616765223a22616161222c2276223a2230783330306338222c2272223a22307831303464353635393034303764393835316563306337633064313538363739663763656636326135376663633536336239343839326562383532333935393038222c2273223a22307834383365653830636333636366333438353134373036303366346535613864383365353339626564626165616238663762313235366363346330646362383130222c2268617368223a22307839663239613738333131616635656662346531623461383964366262363432393535306636623330346531353732353332386536386538343337316163616662227d", common.ToHex(data))
 	var parsedTx *Transaction
 	err = json.Unmarshal(data, &parsedTx)
 	assert.NoError(t, err)
@@ -199,7 +224,7 @@ func TestTransaction_MarshalJSON_UnmarshalJSON_bigTx(t *testing.T) {
 	assert.NoError(t, err)
 	data, err := json.Marshal(txV)
 	assert.NoError(t, err)
-	assert.Equal(t, "0x7b22746f223a224c656d6f385036593234535a324a5059374146514434484a5757525136444a365457325939434346222c22746f4e616d65223a22383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838222c226761735072696365223a22313137373839383034333138353538393535333035353533313636373136313934353637373231383332323539373931373037393330353431343430343133343139353037393835222c226761734c696d6974223a22313030222c22616d6f756e74223a22313137373839383034333138353538393535333035353533313636373136313934353637373231383332323539373931373037393330353431343430343133343139353037393835222c2264617461223a223078333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338222c2265787069726174696f6e54696d65223a2231353434353834353936222c226d657373616765223a22383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838222c2276223a2230783330306338222c2272223a22307839353664613761663335313966393537653132383165386634313733356161393635653661373132653832383835366665393562646264333163373837643736222c2273223a223078663233356165336564366666346561383139386432353238623435363162363835343463353639316465353861613937346237623663633739316637386435222c2268617368223a22307862653931653962613837636239333231343463643362656530343536323063343261626632623639633062623565663564633732313764666231613831636331227d", common.ToHex(data))
+	assert.Equal(t, "0x7b226e6f6e6365223a2231222c22746f223a224c656d6f385036593234535a324a5059374146514434484a5757525136444a365457325939434346222c22746f4e616d65223a22383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838222c226761735072696365223a22313137373839383034333138353538393535333035353533313636373136313934353637373231383332323539373931373037393330353431343430343133343139353037393835222c226761734c696d6974223a22313030222c22616d6f756e74223a22313137373839383034333138353538393535333035353533313636373136313934353637373231383332323539373931373037393330353431343430343133343139353037393835222c2264617461223a223078333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338333833383338222c2265787069726174696f6e54696d65223a2231353434353834353936222c226d657373616765223a22383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838383838222c2276223a2230783330306338222c2272223a22307839613232396264663261643362626665303237653135316131363539623462656431383234646263306636336237346330313737353335633730616332633730222c2273223a22307832326439653736353735633939323466646231376631366434616138366138323833363365653366646166386166643564623132643739616133373232303231222c2268617368223a22307833623036376335366161356532636262303265353163376331343830373963366431373036646534353030346337333735613861643565633130333538303133227d", common.ToHex(data))
 	var parsedTx *Transaction
 	err = json.Unmarshal(data, &parsedTx)
 	assert.NoError(t, err)
@@ -0,0 +1,206 @@
+package vm
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
+)
+
+// CallFrame is a CALL/CREATE frame in the call tree of a transaction
+type CallFrame struct {
+	Type    string         `json:"type"` // CALL, CALLCODE, DELEGATECALL, STATICCALL or CREATE
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big10 `json:"value,omitempty"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Input   hexutil.Bytes  `json:"input"`
+	Output  hexutil.Bytes  `json:"output"`
+	Error   string         `json:"error,omitempty"`
+	Calls   []*CallFrame   `json:"calls,omitempty"`
+
+	depth int    // the depth of the caller
+	gasIn uint64 // the gas of caller before the call instruction
+}
+
+// CallTracer is an EVM tracer and implements Tracer. It collects the call tree of CALL/CREATE frames
+type CallTracer struct {
+	root  *CallFrame
+	calls []*CallFrame // the frames which are still running. The root frame is at the bottom
+}
+
+// NewCallTracer returns a new call tracer
+func NewCallTracer() *CallTracer {
+	return &CallTracer{}
+}
+
+func (t *CallTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	callType := CALL.String()
+	if create {
+		callType = CREATE.String()
+	}
+	t.root = &CallFrame{
+		Type:  callType,
+		From:  from,
+		To:    to,
+		Value: (*hexutil.Big10)(new(big.Int).Set(value)),
+		Gas:   hexutil.Uint64(gas),
+		Input: common.CopyBytes(input),
+	}
+	t.calls = []*CallFrame{t.root}
+	return nil
+}
+
+// CaptureState closes the frames which are returned to caller, and opens a new frame if the op is a CALL/CREATE instruction
+func (t *CallTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	if len(t.calls) == 0 {
+		return nil
+	}
+	// the code of callee is running in a deeper depth
+	for len(t.calls) > 1 && t.calls[len(t.calls)-1].depth >= depth {
+		t.exitFrame(gas, stack)
+	}
+	if err != nil {
+		return nil
+	}
+
+	top := t.calls[len(t.calls)-1]
+	switch op {
+	case RETURN, REVERT:
+		if top.depth+1 == depth && stack.len() >= 2 {
+			top.Output = memory.Get(stack.Back(0).Int64(), stack.Back(1).Int64())
+		}
+		return nil
+	case CALL, CALLCODE:
+		if stack.len() < 5 {
+			return nil
+		}
+		t.enterFrame(op, contract, gas, depth, &CallFrame{
+			To:    common.BigToAddress(stack.Back(1)),
+			Value: (*hexutil.Big10)(new(big.Int).Set(stack.Back(2))),
+			Gas:   hexutil.Uint64(env.callGasTemp),
+			Input: memory.Get(stack.Back(3).Int64(), stack.Back(4).Int64()),
+		})
+	case DELEGATECALL, STATICCALL:
+		if stack.len() < 4 {
+			return nil
+		}
+		t.enterFrame(op, contract, gas, depth, &CallFrame{
+			To:    common.BigToAddress(stack.Back(1)),
+			Gas:   hexutil.Uint64(env.callGasTemp),
+			Input: memory.Get(stack.Back(2).Int64(), stack.Back(3).Int64()),
+		})
+	case CREATE:
+		if stack.len() < 3 {
+			return nil
+		}
+		// all but one 64th of the rest gas is passed to the creation
+		rest := gas - cost
+		t.enterFrame(op, contract, gas, depth, &CallFrame{
+			Value: (*hexutil.Big10)(new(big.Int).Set(stack.Back(0))),
+			Gas:   hexutil.Uint64(rest - rest/64),
+			Input: memory.Get(stack.Back(1).Int64(), stack.Back(2).Int64()),
+		})
+	}
+	return nil
+}
+
+func (t *CallTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *CallTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	if t.root == nil {
+		return nil
+	}
+	// the frames which are not closed are aborted by error
+	for len(t.calls) > 1 {
+		frame := t.calls[len(t.calls)-1]
+		frame.Error = "aborted"
+		t.calls = t.calls[:len(t.calls)-1]
+	}
+	t.root.Output = common.CopyBytes(output)
+	t.root.GasUsed = hexutil.Uint64(gasUsed)
+	if err != nil {
+		t.root.Error = err.Error()
+	}
+	return nil
+}
+
+// enterFrame pushes a new frame of the call instruction
+func (t *CallTracer) enterFrame(op OpCode, contract *Contract, gas uint64, depth int, frame *CallFrame) {
+	frame.Type = op.String()
+	frame.From = contract.GetAddress()
+	frame.depth = depth
+	frame.gasIn = gas
+	parent := t.calls[len(t.calls)-1]
+	parent.Calls = append(parent.Calls, frame)
+	t.calls = append(t.calls, frame)
+}
+
+// exitFrame pops the top frame after it returned to caller. The result of call instruction is on the top of caller's stack
+func (t *CallTracer) exitFrame(gas uint64, stack *Stack) {
+	frame := t.calls[len(t.calls)-1]
+	t.calls = t.calls[:len(t.calls)-1]
+	// the gas used by the call instruction, including the cost of the instruction itself
+	if frame.gasIn > gas {
+		frame.GasUsed = hexutil.Uint64(frame.gasIn - gas)
+	}
+	if stack.len() == 0 || stack.Back(0).Sign() == 0 {
+		frame.Error = "failed"
+		return
+	}
+	if frame.Type == CREATE.String() {
+		frame.To = common.BigToAddress(stack.Back(0))
+	}
+}
+
+// Result returns the root frame of the call tree. It is nil if there is no contract code to run
+func (t *CallTracer) Result() *CallFrame {
+	return t.root
+}
+
+// MultiTracer dispatches the tracing events to several tracers. It returns the first error after all tracers are called
+type MultiTracer []Tracer
+
+func (m MultiTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	var result error
+	for _, t := range m {
+		if err := t.CaptureStart(from, to, create, input, gas, value); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+func (m MultiTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	var result error
+	for _, t := range m {
+		if err := t.CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+func (m MultiTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	var result error
+	for _, t := range m {
+		if err := t.CaptureFault(env, pc, op, gas, cost, memory, stack, contract, depth, err); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+func (m MultiTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	var result error
+	for _, t := range m {
+		if err := t.CaptureEnd(output, gasUsed, d, err); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
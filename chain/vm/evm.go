@@ -55,6 +55,7 @@ type Context struct {
 	GasLimit     uint64         // Provides information for GASLIMIT
 	BlockHeight  uint32         // Provides information for HEIGHT
 	Time         uint32         // Provides information for TIME
+	ChainID      uint16         // Provides information for CHAINID
 }
 
 // EVM is the Lemochain Virtual Machine base object and provides
@@ -71,6 +72,8 @@ type EVM struct {
 	Context
 	// am gives access to the underlying state
 	am AccountManager
+	// chainConfig decides the protocol rules by the block height
+	chainConfig *params.ChainConfig
 	// Depth is the current call stack
 	depth int
 
@@ -91,17 +94,23 @@ type EVM struct {
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
 // only ever be used *once*.
-func NewEVM(ctx Context, am AccountManager, vmConfig Config) *EVM {
+func NewEVM(ctx Context, am AccountManager, chainConfig *params.ChainConfig, vmConfig Config) *EVM {
 	evm := &EVM{
-		Context:  ctx,
-		am:       am,
-		vmConfig: vmConfig,
+		Context:     ctx,
+		am:          am,
+		chainConfig: chainConfig,
+		vmConfig:    vmConfig,
 	}
 
 	evm.interpreter = NewInterpreter(evm, vmConfig)
 	return evm
 }
 
+// ChainConfig returns the chain config which decides the protocol rules
+func (evm *EVM) ChainConfig() *params.ChainConfig {
+	return evm.chainConfig
+}
+
 // Cancel cancels any running EVM operation. This may be called concurrently and
 // it's safe to be called multiple times.
 func (evm *EVM) Cancel() {
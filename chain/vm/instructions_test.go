@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 )
 
@@ -15,7 +16,7 @@ type twoOperandTest struct {
 
 func testTwoOperandOp(t *testing.T, tests []twoOperandTest, opFn func(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error)) {
 	var (
-		env   = NewEVM(Context{}, nil, Config{})
+		env   = NewEVM(Context{}, nil, params.DefaultChainConfig, Config{})
 		stack = newstack()
 		pc    = uint64(0)
 	)
@@ -51,7 +52,7 @@ func testTwoOperandOp(t *testing.T, tests []twoOperandTest, opFn func(pc *uint64
 
 func TestByteOp(t *testing.T) {
 	var (
-		env   = NewEVM(Context{}, nil, Config{})
+		env   = NewEVM(Context{}, nil, params.DefaultChainConfig, Config{})
 		stack = newstack()
 	)
 	tests := []struct {
@@ -179,9 +180,29 @@ func TestSLT(t *testing.T) {
 	testTwoOperandOp(t, tests, opSlt)
 }
 
+func TestIstanbulInstructionSet(t *testing.T) {
+	height := uint32(100)
+	config := &params.ChainConfig{IstanbulHeight: &height}
+	env := NewEVM(Context{BlockHeight: height - 1}, nil, config, Config{})
+	if env.interpreter.cfg.JumpTable[CHAINID].valid || env.interpreter.cfg.JumpTable[SELFBALANCE].valid {
+		t.Fatal("Istanbul instructions are valid before fork")
+	}
+
+	env = NewEVM(Context{BlockHeight: height, ChainID: 200}, nil, config, Config{})
+	if !env.interpreter.cfg.JumpTable[CHAINID].valid || !env.interpreter.cfg.JumpTable[SELFBALANCE].valid {
+		t.Fatal("Istanbul instructions are invalid after fork")
+	}
+	stack := newstack()
+	pc := uint64(0)
+	opChainID(&pc, env, nil, nil, stack)
+	if chainID := stack.pop().Uint64(); chainID != 200 {
+		t.Errorf("Expected chain id 200, got %d", chainID)
+	}
+}
+
 func opBenchmark(bench *testing.B, op func(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error), args ...string) {
 	var (
-		env   = NewEVM(Context{}, nil, Config{})
+		env   = NewEVM(Context{}, nil, params.DefaultChainConfig, Config{})
 		stack = newstack()
 	)
 	// convert args
@@ -41,9 +41,13 @@ type Interpreter struct {
 func NewInterpreter(evm *EVM, cfg Config) *Interpreter {
 	// We use the STOP instruction whether to see
 	// the jump table was initialised. If it was not
-	// we'll set the default jump table.
+	// we'll set the jump table by the forks at block height.
 	if !cfg.JumpTable[STOP].valid {
-		cfg.JumpTable = NewInstructionSet()
+		if evm.ChainConfig().IsIstanbul(evm.BlockHeight) {
+			cfg.JumpTable = NewIstanbulInstructionSet()
+		} else {
+			cfg.JumpTable = NewInstructionSet()
+		}
 	}
 
 	return &Interpreter{
@@ -34,6 +34,24 @@ type operation struct {
 	returns bool // determines whether the operations sets the return data content
 }
 
+// NewIstanbulInstructionSet returns the instructions after Istanbul fork, which adds CHAINID and SELFBALANCE.
+func NewIstanbulInstructionSet() [256]operation {
+	instructionSet := NewInstructionSet()
+	instructionSet[CHAINID] = operation{
+		execute:       opChainID,
+		gasCost:       constGasFunc(GasQuickStep),
+		validateStack: makeStackFunc(0, 1),
+		valid:         true,
+	}
+	instructionSet[SELFBALANCE] = operation{
+		execute:       opSelfBalance,
+		gasCost:       constGasFunc(GasFastStep),
+		validateStack: makeStackFunc(0, 1),
+		valid:         true,
+	}
+	return instructionSet
+}
+
 // NewInstructionSet instructions.
 func NewInstructionSet() [256]operation {
 	return [256]operation{
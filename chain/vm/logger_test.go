@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 )
 
@@ -31,7 +32,7 @@ type dummyStateDB struct {
 
 func TestStoreCapture(t *testing.T) {
 	var (
-		env      = NewEVM(Context{}, nil, Config{})
+		env      = NewEVM(Context{}, nil, params.DefaultChainConfig, Config{})
 		logger   = NewStructLogger(nil)
 		mem      = NewMemory()
 		stack    = newstack()
@@ -34,9 +34,7 @@ type Config struct {
 // sets defaults on the config
 func setDefaults(cfg *Config) {
 	if cfg.ChainConfig == nil {
-		cfg.ChainConfig = &params.ChainConfig{
-			ChainID: 1,
-		}
+		cfg.ChainConfig = params.DefaultChainConfig
 	}
 
 	if cfg.Time == 0 {
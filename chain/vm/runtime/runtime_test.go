@@ -126,3 +126,53 @@ func BenchmarkCall(b *testing.B) {
 		}
 	}
 }
+
+func TestCallTracer(t *testing.T) {
+	db, _ := store.NewCacheChain("../../../testdata/vm_runtime")
+	am := account.NewManager(common.Hash{}, db)
+	callee := common.HexToAddress("0x0b")
+	am.GetAccount(callee).SetCode([]byte{
+		byte(vm.PUSH1), 10,
+		byte(vm.PUSH1), 0,
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 32,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	})
+	// call the callee and an address without code, then return the output of callee
+	caller := common.HexToAddress("0x0a")
+	am.GetAccount(caller).SetCode([]byte{
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0x0b, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0x0c, byte(vm.GAS), byte(vm.STATICCALL), byte(vm.POP),
+		byte(vm.PUSH1), 32,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	})
+
+	tracer := vm.NewCallTracer()
+	logger := vm.NewStructLogger(nil)
+	cfg := &Config{AccountManager: am, GasLimit: 100000, EVMConfig: vm.Config{Debug: true, Tracer: vm.MultiTracer{tracer, logger}}}
+	ret, err := Call(caller, nil, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), new(big.Int).SetBytes(ret).Int64())
+
+	root := tracer.Result()
+	assert.Equal(t, "CALL", root.Type)
+	assert.Equal(t, caller, root.To)
+	assert.Equal(t, ret, []byte(root.Output))
+	assert.Equal(t, "", root.Error)
+	assert.Equal(t, 2, len(root.Calls))
+	assert.Equal(t, "CALL", root.Calls[0].Type)
+	assert.Equal(t, caller, root.Calls[0].From)
+	assert.Equal(t, callee, root.Calls[0].To)
+	assert.Equal(t, ret, []byte(root.Calls[0].Output))
+	assert.Equal(t, true, root.Calls[0].GasUsed > 0 && root.Calls[0].GasUsed < root.GasUsed)
+	assert.Equal(t, "STATICCALL", root.Calls[1].Type)
+	assert.Equal(t, common.HexToAddress("0x0c"), root.Calls[1].To)
+	assert.Equal(t, 0, len(root.Calls[1].Output))
+	// the steps in both contracts are logged
+	assert.Equal(t, 26, len(logger.StructLogs()))
+	assert.Equal(t, 2, logger.StructLogs()[13].Depth)
+}
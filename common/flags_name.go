@@ -2,6 +2,9 @@ package common
 
 const (
 	DataDir          = "datadir"
+	DBEngine         = "dbengine"
+	SyncMode         = "syncmode"
+	NodeKeyPassword  = "nodekeypassword"
 	MaxPeers         = "maxpeers"
 	ListenPort       = "port"
 	ExtraData        = "extradata"
@@ -17,6 +20,7 @@ const (
 	WSListenAddr     = "wsaddr"
 	WSPort           = "wsport"
 	WSAllowedOrigins = "wsorigins"
+	WSApi            = "wsapi"
 	Debug            = "debug"
 	JSpath           = "jspath"
 	LogLevel         = "loglevel"
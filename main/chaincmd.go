@@ -0,0 +1,170 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"github.com/LemoFoundationLtd/lemochain-go/main/node"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	trustedFlag = cli.BoolFlag{
+		Name:  "trusted",
+		Usage: "Rebuild the account state from the change logs in blocks without executing transactions. No receipt is saved",
+	}
+
+	exportCommand = cli.Command{
+		Action:    exportChain,
+		Name:      "export",
+		Usage:     "Export stable blocks into file",
+		ArgsUsage: "<filename> [<from> [<to>]]",
+		Flags: []cli.Flag{
+			node.DataDirFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export command writes the RLP encoded stable blocks with their confirms into file.
+The blocks from genesis to the latest stable block are exported by default. The file
+is gzipped if its name ends with ".gz".`,
+	}
+
+	importCommand = cli.Command{
+		Action:    importChain,
+		Name:      "import",
+		Usage:     "Import blocks from file",
+		ArgsUsage: "<filename>",
+		Flags: []cli.Flag{
+			node.DataDirFlag,
+			node.DBEngineFlag,
+			trustedFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The import command inserts the blocks exported by export command into the local chain
+as stable blocks. The blocks are fully verified by default, and --trusted skips the
+transaction execution. The file is gunzipped if its name ends with ".gz".`,
+	}
+)
+
+var (
+	ErrInvalidExportRange = errors.New("invalid block height")
+)
+
+// exportChain export blocks action
+func exportChain(ctx *cli.Context) error {
+	log.Setup(log.LevelInfo, false, false)
+
+	fileName := ctx.Args().First()
+	if len(fileName) == 0 {
+		log.Crit("Must supply the export file path")
+	}
+	bc, err := node.NewOfflineChain(dataDirOf(ctx), ctx.GlobalString(node.DBEngineFlag.Name))
+	if err != nil {
+		log.Crit(err.Error())
+	}
+	defer bc.Db().Close()
+
+	from, to := uint32(0), bc.StableBlock().Height()
+	if ctx.NArg() > 1 {
+		if from, err = parseHeight(ctx.Args().Get(1)); err != nil {
+			log.Crit(err.Error())
+		}
+	}
+	if ctx.NArg() > 2 {
+		if to, err = parseHeight(ctx.Args().Get(2)); err != nil {
+			log.Crit(err.Error())
+		}
+	}
+
+	count, err := exportBlocks(bc, fileName, from, to)
+	if err != nil {
+		log.Crit(err.Error())
+	}
+	log.Infof("export %d blocks succeed", count)
+	return nil
+}
+
+func parseHeight(s string) (uint32, error) {
+	height, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidExportRange
+	}
+	return uint32(height), nil
+}
+
+func exportBlocks(bc *chain.BlockChain, fileName string, from, to uint32) (int, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var writer io.Writer = file
+	var gzWriter *gzip.Writer
+	if strings.HasSuffix(fileName, ".gz") {
+		gzWriter = gzip.NewWriter(file)
+		writer = gzWriter
+	}
+	count, err := bc.ExportChain(writer, from, to)
+	if err != nil {
+		return count, err
+	}
+	// flush the compressed data and the file, so that a truncated export is reported
+	if gzWriter != nil {
+		if err := gzWriter.Close(); err != nil {
+			return count, err
+		}
+	}
+	return count, file.Close()
+}
+
+// importChain import blocks action
+func importChain(ctx *cli.Context) error {
+	log.Setup(log.LevelInfo, false, false)
+
+	fileName := ctx.Args().First()
+	if len(fileName) == 0 {
+		log.Crit("Must supply the import file path")
+	}
+	engine := ctx.GlobalString(node.DBEngineFlag.Name)
+	if ctx.IsSet(node.DBEngineFlag.Name) {
+		engine = ctx.String(node.DBEngineFlag.Name)
+	}
+	bc, err := node.NewOfflineChain(dataDirOf(ctx), engine)
+	if err != nil {
+		log.Crit(err.Error())
+	}
+	defer bc.Db().Close()
+
+	count, err := importBlocks(bc, fileName, ctx.Bool(trustedFlag.Name))
+	if err != nil {
+		log.Critf("import failed after %d blocks: %v", count, err)
+	}
+	log.Infof("import %d blocks succeed. current height: %d", count, bc.StableBlock().Height())
+	return nil
+}
+
+func importBlocks(bc *chain.BlockChain, fileName string, trusted bool) (int, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(fileName, ".gz") {
+		gzReader, err := gzip.NewReader(file)
+		if err != nil {
+			return 0, err
+		}
+		defer gzReader.Close()
+		reader = gzReader
+	}
+	return bc.ImportChain(reader, trusted)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/main/node"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestConfig(datadir string) {
+	os.MkdirAll(datadir, os.ModePerm)
+	config := `{"chainID": "0x64", "sleepTime": "0xbb8", "timeout": "0x2710"}`
+	ioutil.WriteFile(filepath.Join(datadir, "config.json"), []byte(config), os.ModePerm)
+}
+
+func Test_exportImportBlocks(t *testing.T) {
+	srcDir := "lemo-test-export"
+	dstDir := "lemo-test-import"
+	defer deleteDir(srcDir)
+	defer deleteDir(dstDir)
+	writeTestConfig(srcDir)
+	writeTestConfig(dstDir)
+
+	for _, fileName := range []string{"blocks.rlp", "blocks.rlp.gz"} {
+		filePath := filepath.Join(srcDir, fileName)
+		bc, err := node.NewOfflineChain(srcDir, store.DefaultEngine)
+		assert.NoError(t, err)
+		_, err = exportBlocks(bc, filePath, 1, 0)
+		assert.Error(t, err)
+		count, err := exportBlocks(bc, filePath, 0, bc.StableBlock().Height())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		bc.Db().Close()
+
+		// the genesis block is the same, so it is skipped
+		bc, err = node.NewOfflineChain(dstDir, store.DefaultEngine)
+		assert.NoError(t, err)
+		count, err = importBlocks(bc, filePath, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+		bc.Db().Close()
+	}
+
+	bc, err := node.NewOfflineChain(dstDir, store.DefaultEngine)
+	assert.NoError(t, err)
+	defer bc.Db().Close()
+	_, err = importBlocks(bc, filepath.Join(srcDir, "not-exist.rlp"), false)
+	assert.True(t, os.IsNotExist(err))
+	_, err = parseHeight("-1")
+	assert.Equal(t, ErrInvalidExportRange, err)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LemoFoundationLtd/lemochain-go/common/flock"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"github.com/LemoFoundationLtd/lemochain-go/main/node"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path/filepath"
+)
+
+var (
+	dbCommand = cli.Command{
+		Name:     "db",
+		Usage:    "Manage the chain database",
+		Category: "DATABASE COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Action:    migrateDb,
+				Name:      "migrate",
+				Usage:     "Copy the chain database into another engine",
+				ArgsUsage: "<engine>",
+				Flags: []cli.Flag{
+					node.DataDirFlag,
+				},
+				Description: `
+The migrate command copies all records in <datadir>/chaindata into a new database
+of the engine (lmstore or leveldb), and then replaces the old one with it. The old
+database is kept as <datadir>/chaindata.<old engine>.bak. The node must be stopped.`,
+			},
+			{
+				Action: compactDb,
+				Name:   "compact",
+				Usage:  "Reclaim the disk space of stale records in the chain database",
+				Flags: []cli.Flag{
+					node.DataDirFlag,
+					dryRunFlag,
+				},
+				Description: `
+The compact command rewrites the alive records in <datadir>/chaindata into new data
+files, and drops the overwritten or deleted ones. It prints the reclaimable bytes
+only if --dryrun is set. The node must be stopped.`,
+			},
+			{
+				Action: verifyDb,
+				Name:   "verify",
+				Usage:  "Check the integrity of the chain database",
+				Flags: []cli.Flag{
+					node.DataDirFlag,
+				},
+				Description: `
+The verify command checks the checksums and offsets of all records, the hint files,
+the context file, and the block indexes in <datadir>/chaindata. It prints a JSON
+report and exits with error if any issue is found.`,
+			},
+			{
+				Action: repairDb,
+				Name:   "repair",
+				Usage:  "Fix the issues found by verify command",
+				Flags: []cli.Flag{
+					node.DataDirFlag,
+				},
+				Description: `
+The repair command drops the records with wrong checksum or corrupted header,
+truncates the torn tail records, rebuilds the hint files, and resets the current
+block to the highest block in height index. It prints a JSON report. The node
+must be stopped.`,
+			},
+		},
+	}
+)
+
+var (
+	dryRunFlag = cli.BoolFlag{
+		Name:  "dryrun",
+		Usage: "Print the reclaimable bytes without compacting",
+	}
+)
+
+var (
+	ErrSameEngine        = errors.New("the database is using this engine already")
+	ErrDatabaseCorrupted = errors.New("the database is corrupted")
+)
+
+// dataDirOf returns the datadir flag of subcommand or the global one
+func dataDirOf(ctx *cli.Context) string {
+	if ctx.IsSet(node.DataDirFlag.Name) {
+		return ctx.String(node.DataDirFlag.Name)
+	}
+	return ctx.GlobalString(node.DataDirFlag.Name)
+}
+
+// migrateDb copies chain database into another engine
+func migrateDb(ctx *cli.Context) error {
+	log.Setup(log.LevelInfo, false, false)
+
+	engine := ctx.Args().First()
+	if len(engine) == 0 {
+		log.Crit("Must supply the target engine")
+	}
+	if err := migrateChainData(dataDirOf(ctx), engine); err != nil {
+		log.Crit(err.Error())
+	}
+	log.Infof("migrate database to %s succeed", engine)
+	return nil
+}
+
+func migrateChainData(datadir, engine string) error {
+	if engine != store.EngineLmStore && engine != store.EngineLevelDB {
+		return store.ErrUnknownEngine
+	}
+	// make sure the node is not running
+	release, _, err := flock.New(filepath.Join(datadir, "LOCK"))
+	if err != nil {
+		return err
+	}
+	defer release.Release()
+
+	chaindata := filepath.Join(datadir, "chaindata")
+	oldEngine := store.DetectEngine(chaindata)
+	if oldEngine == "" {
+		return store.ErrNotExist
+	}
+	if oldEngine == engine {
+		return ErrSameEngine
+	}
+
+	tmpDir := chaindata + ".migrating"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	if _, err := store.MigrateBackend(chaindata, tmpDir, engine); err != nil {
+		os.RemoveAll(tmpDir)
+		return err
+	}
+	if err := os.Rename(chaindata, chaindata+"."+oldEngine+".bak"); err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, chaindata)
+}
+
+// compactDb reclaims the disk space of chain database
+func compactDb(ctx *cli.Context) error {
+	log.Setup(log.LevelInfo, false, false)
+
+	total, reclaimed, err := compactChainData(dataDirOf(ctx), ctx.Bool(dryRunFlag.Name))
+	if err != nil {
+		log.Crit(err.Error())
+	}
+	if ctx.Bool(dryRunFlag.Name) {
+		log.Infof("data size: %d bytes, reclaimable: %d bytes", total, reclaimed)
+	} else {
+		log.Infof("compact database succeed. data size: %d bytes, reclaimed: %d bytes", total, reclaimed)
+	}
+	return nil
+}
+
+// compactChainData returns the size of data files before compaction and the reclaimable bytes
+func compactChainData(datadir string, dryRun bool) (int64, int64, error) {
+	release, _, err := flock.New(filepath.Join(datadir, "LOCK"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer release.Release()
+
+	chaindata := filepath.Join(datadir, "chaindata")
+	if store.DetectEngine(chaindata) == "" {
+		return 0, 0, store.ErrNotExist
+	}
+	db, err := store.OpenBackend(chaindata, store.DefaultEngine)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer db.Close()
+	compactor, ok := db.(store.Compactor)
+	if !ok {
+		return 0, 0, store.ErrCompactUnsupported
+	}
+
+	total, alive, err := compactor.Stats()
+	if err != nil {
+		return 0, 0, err
+	}
+	if dryRun {
+		return total, total - alive, nil
+	}
+	if err := compactor.Compact(); err != nil {
+		return 0, 0, err
+	}
+	newTotal, _, err := compactor.Stats()
+	if err != nil {
+		return 0, 0, err
+	}
+	return total, total - newTotal, nil
+}
+
+// verifyDb checks the chain database
+func verifyDb(ctx *cli.Context) error {
+	return checkDb(dataDirOf(ctx), false)
+}
+
+// repairDb fixes the chain database
+func repairDb(ctx *cli.Context) error {
+	return checkDb(dataDirOf(ctx), true)
+}
+
+func checkDb(datadir string, repair bool) error {
+	report, err := checkChainData(datadir, repair)
+	if report != nil {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	}
+	if err != nil {
+		return err
+	}
+	if !report.Healthy() {
+		return ErrDatabaseCorrupted
+	}
+	return nil
+}
+
+func checkChainData(datadir string, repair bool) (*store.VerifyReport, error) {
+	release, _, err := flock.New(filepath.Join(datadir, "LOCK"))
+	if err != nil {
+		return nil, err
+	}
+	defer release.Release()
+
+	return store.VerifyDatabase(filepath.Join(datadir, "chaindata"), repair)
+}
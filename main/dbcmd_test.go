@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_migrateChainData(t *testing.T) {
+	datadir := "lemo-test-migrate"
+	defer deleteDir(datadir)
+	os.MkdirAll(datadir, os.ModePerm)
+	chaindata := filepath.Join(datadir, "chaindata")
+
+	assert.Equal(t, store.ErrNotExist, migrateChainData(datadir, store.EngineLevelDB))
+	hash, err := saveBlock(datadir, store.EngineLmStore, chain.DefaultGenesisBlock())
+	assert.NoError(t, err)
+	assert.Equal(t, store.ErrUnknownEngine, migrateChainData(datadir, "mysql"))
+	assert.Equal(t, ErrSameEngine, migrateChainData(datadir, store.EngineLmStore))
+
+	assert.NoError(t, migrateChainData(datadir, store.EngineLevelDB))
+	assert.Equal(t, store.EngineLevelDB, store.DetectEngine(chaindata))
+	assert.Equal(t, store.EngineLmStore, store.DetectEngine(chaindata+".lmstore.bak"))
+	db, err := store.NewCacheChainWithEngine(chaindata, store.EngineLevelDB)
+	assert.NoError(t, err)
+	defer db.Close()
+	block, err := db.GetBlockByHeight(0)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, block.Hash())
+}
+
+func Test_compactChainData(t *testing.T) {
+	datadir := "lemo-test-compact"
+	defer deleteDir(datadir)
+	os.MkdirAll(datadir, os.ModePerm)
+
+	_, _, err := compactChainData(datadir, true)
+	assert.Equal(t, store.ErrNotExist, err)
+	hash, err := saveBlock(datadir, store.EngineLmStore, chain.DefaultGenesisBlock())
+	assert.NoError(t, err)
+
+	total, reclaimable, err := compactChainData(datadir, true)
+	assert.NoError(t, err)
+	assert.True(t, total > 0)
+	total2, reclaimed, err := compactChainData(datadir, false)
+	assert.NoError(t, err)
+	assert.Equal(t, total, total2)
+	assert.Equal(t, reclaimable, reclaimed)
+	_, reclaimable, err = compactChainData(datadir, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), reclaimable)
+
+	db, err := store.NewCacheChain(filepath.Join(datadir, "chaindata"))
+	assert.NoError(t, err)
+	defer db.Close()
+	block, err := db.GetBlockByHeight(0)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, block.Hash())
+}
+
+func Test_checkChainData(t *testing.T) {
+	datadir := "lemo-test-verify"
+	defer deleteDir(datadir)
+	os.MkdirAll(datadir, os.ModePerm)
+
+	_, err := checkChainData(datadir, false)
+	assert.Equal(t, store.ErrNotExist, err)
+	_, err = saveBlock(datadir, store.EngineLmStore, chain.DefaultGenesisBlock())
+	assert.NoError(t, err)
+
+	report, err := checkChainData(datadir, false)
+	assert.NoError(t, err)
+	assert.True(t, report.Healthy())
+	assert.Equal(t, 1, report.Blocks)
+	assert.NoError(t, checkDb(datadir, false))
+
+	// lose the current block
+	assert.NoError(t, os.Remove(filepath.Join(datadir, "chaindata", "data.context")))
+	assert.Equal(t, ErrDatabaseCorrupted, checkDb(datadir, false))
+	assert.NoError(t, checkDb(datadir, true))
+	assert.NoError(t, checkDb(datadir, false))
+}
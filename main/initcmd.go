@@ -21,6 +21,7 @@ var (
 		ArgsUsage: "<genesisPath>",
 		Flags: []cli.Flag{
 			node.DataDirFlag,
+			node.DBEngineFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -44,11 +45,12 @@ func initGenesis(ctx *cli.Context) error {
 	// open special genesis config file
 	genesisFile := ctx.Args().First()
 	dir := ctx.GlobalString(node.DataDirFlag.Name)
+	engine := ctx.GlobalString(node.DBEngineFlag.Name)
 	if len(genesisFile) == 0 {
 		log.Crit("Must supply genesis json file path")
 	}
 
-	hash, err := setupGenesisBlock(genesisFile, dir)
+	hash, err := setupGenesisBlock(genesisFile, dir, engine)
 	if err != nil {
 		log.Crit(err.Error())
 	}
@@ -56,18 +58,18 @@ func initGenesis(ctx *cli.Context) error {
 	return nil
 }
 
-func setupGenesisBlock(genesisFile, datadir string) (common.Hash, error) {
+func setupGenesisBlock(genesisFile, datadir, engine string) (common.Hash, error) {
 	genesis, err := unmarshal(genesisFile)
 	if err != nil {
 		return common.Hash{}, err
 	}
-	return saveBlock(datadir, genesis)
+	return saveBlock(datadir, engine, genesis)
 }
 
 // saveBlock save block to db
-func saveBlock(datadir string, genesis *chain.Genesis) (common.Hash, error) {
+func saveBlock(datadir, engine string, genesis *chain.Genesis) (common.Hash, error) {
 	chaindata := filepath.Join(datadir, "chaindata")
-	db, err := store.NewCacheChain(chaindata)
+	db, err := store.NewCacheChainWithEngine(chaindata, engine)
 	if err != nil {
 		log.Errorf("%v", err)
 		return common.Hash{}, err
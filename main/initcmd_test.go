@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"os"
@@ -55,11 +56,11 @@ func Test_setupGenesisBlock_correct(t *testing.T) {
 			"votes": 16
 		}
 	]
-}`, common.HexToHash("0x8aec56fbe87e6a9faabb62acefdcecc609f17fb08538a7fdf0751ac1a1c7cae9"), nil}
+}`, common.HexToHash("0x14e91f921e15890909cbea92d9b73f7f9fd7e8c6ff4c618518994f5b08219a89"), nil}
 	fileName := "test_genesis.json"
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
-	hash, err := setupGenesisBlock(fileName, datadir)
+	hash, err := setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	assert.Equal(t, test.Output, err)
 	assert.Equal(t, test.Hash, hash)
 	deleteTmpFile(fileName)
@@ -70,7 +71,7 @@ func Test_setupGenesisBlock_correct(t *testing.T) {
 func Test_setupGenesisBlock_no_file(t *testing.T) {
 	fileName := "test_genesis.json"
 	datadir := "lemo-test"
-	_, err := setupGenesisBlock(fileName, datadir)
+	_, err := setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	assert.Equal(t, ErrFileReadFailed, err)
 	deleteDir(datadir)
 }
@@ -86,7 +87,7 @@ func Test_setupGenesisBlock_empty(t *testing.T) {
 	fileName := "test_genesis.json"
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
-	_, err := setupGenesisBlock(fileName, datadir)
+	_, err := setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	assert.Equal(t, test.Output, err)
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -127,7 +128,7 @@ func Test_setupGenesisBlock_error_format(t *testing.T) {
 	fileName := "test_genesis.json"
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
-	_, err := setupGenesisBlock(fileName, datadir)
+	_, err := setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	assert.Equal(t, test.Output, err)
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -152,7 +153,7 @@ func Test_setupGenesisBlock_no_deputy(t *testing.T) {
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
 	assert.PanicsWithValue(t, "default deputy nodes can't be empty", func() {
-		setupGenesisBlock(fileName, datadir)
+		setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	})
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -192,7 +193,7 @@ func Test_setupGenesisBlock_lack_required(t *testing.T) {
 	fileName := "test_genesis.json"
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
-	_, err := setupGenesisBlock(fileName, datadir)
+	_, err := setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	assert.Equal(t, test.Output, err)
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -233,7 +234,7 @@ func Test_setupGenesisBlock_error_field(t *testing.T) {
 	fileName := "test_genesis.json"
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
-	_, err := setupGenesisBlock(fileName, datadir)
+	_, err := setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	assert.Equal(t, test.Output, err)
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -274,7 +275,7 @@ func Test_setupGenesisBlock_error_minerAddress(t *testing.T) {
 	fileName := "test_genesis.json"
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
-	_, err := setupGenesisBlock(fileName, datadir)
+	_, err := setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	assert.Equal(t, test.Output, err)
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -315,7 +316,7 @@ func Test_setupGenesisBlock_error_ip(t *testing.T) {
 	fileName := "test_genesis.json"
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
-	_, err := setupGenesisBlock(fileName, datadir)
+	_, err := setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	assert.Equal(t, test.Output, err)
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -357,7 +358,7 @@ func Test_setupGenesisBlock_error_port(t *testing.T) {
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
 	assert.PanicsWithValue(t, "genesis deputy nodes check error", func() {
-		setupGenesisBlock(fileName, datadir)
+		setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	})
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -399,7 +400,7 @@ func Test_setupGenesisBlock_error_NodeID(t *testing.T) {
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
 	assert.PanicsWithValue(t, "genesis deputy nodes check error", func() {
-		setupGenesisBlock(fileName, datadir)
+		setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	})
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -440,7 +441,7 @@ func Test_setupGenesisBlock_error_votes(t *testing.T) {
 	fileName := "test_genesis.json"
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
-	_, err := setupGenesisBlock(fileName, datadir)
+	_, err := setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	assert.Equal(t, test.Output, err)
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -482,7 +483,7 @@ func Test_setupGenesisBlock_error_extraData(t *testing.T) {
 	datadir := "lemo-test"
 	writeContentToFile(test.Content, fileName)
 	assert.PanicsWithValue(t, "genesis block's extraData length larger than 256", func() {
-		setupGenesisBlock(fileName, datadir)
+		setupGenesisBlock(fileName, datadir, store.DefaultEngine)
 	})
 	deleteTmpFile(fileName)
 	deleteDir(datadir)
@@ -21,6 +21,9 @@ var (
 	// flags to configure the node
 	nodeFlags = []cli.Flag{
 		node.DataDirFlag,
+		node.DBEngineFlag,
+		node.SyncModeFlag,
+		node.NodeKeyPasswordFlag,
 		node.MaxPeersFlag,
 		node.ListenPortFlag,
 		node.ExtraDataFlag,
@@ -40,6 +43,7 @@ var (
 		node.WSListenAddrFlag,
 		node.WSPortFlag,
 		node.WSAllowedOriginsFlag,
+		node.WSApiFlag,
 		node.IPCDisabledFlag,
 		node.IPCPathFlag,
 	}
@@ -53,6 +57,9 @@ func init() {
 		initCommand,
 		consoleCommand,
 		attachCommand,
+		dbCommand,
+		exportCommand,
+		importCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 	app.Flags = append(app.Flags, nodeFlags...)
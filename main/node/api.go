@@ -1,28 +1,41 @@
 package node
 
 import (
+	"context"
 	"github.com/LemoFoundationLtd/lemochain-go/chain"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/filters"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/keystore"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/miner"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/proof"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/vm"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
 	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
 	"github.com/LemoFoundationLtd/lemochain-go/network/p2p"
+	"github.com/LemoFoundationLtd/lemochain-go/network/rpc"
 	"math/big"
 	"runtime"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// defaultUnlockDuration is the duration of unlocking account if it is not set, in seconds
+const defaultUnlockDuration = 300
+
 // Private
 type PrivateAccountAPI struct {
-	manager *account.Manager
+	manager  *account.Manager
+	keystore *keystore.KeyStore
 }
 
 // NewPrivateAccountAPI
-func NewPrivateAccountAPI(m *account.Manager) *PrivateAccountAPI {
-	return &PrivateAccountAPI{m}
+func NewPrivateAccountAPI(m *account.Manager, ks *keystore.KeyStore) *PrivateAccountAPI {
+	return &PrivateAccountAPI{m, ks}
 }
 
 // NewAccount get lemo address api
@@ -34,14 +47,60 @@ func (a *PrivateAccountAPI) NewKeyPair() (*crypto.AccountKey, error) {
 	return accountKey, nil
 }
 
+// NewAccount creates an account in node's key store, which is encrypted by the passphrase
+func (a *PrivateAccountAPI) NewAccount(passphrase string) (common.Address, error) {
+	return a.keystore.NewAccount(passphrase)
+}
+
+// ImportKey imports the hex private key into node's key store
+func (a *PrivateAccountAPI) ImportKey(privateKey string, passphrase string) (common.Address, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(privateKey, "0x"))
+	if err != nil {
+		return common.Address{}, err
+	}
+	return a.keystore.ImportECDSA(key, passphrase)
+}
+
+// ImportKeyFile imports the content of encrypted key file into node's key store, and encrypts it by the new passphrase
+func (a *PrivateAccountAPI) ImportKeyFile(keyJSON string, passphrase string, newPassphrase string) (common.Address, error) {
+	return a.keystore.Import([]byte(keyJSON), passphrase, newPassphrase)
+}
+
+// ExportKeyFile returns the content of account's key file, which is encrypted by the new passphrase
+func (a *PrivateAccountAPI) ExportKeyFile(address common.Address, passphrase string, newPassphrase string) (string, error) {
+	keyJSON, err := a.keystore.Export(address, passphrase, newPassphrase)
+	return string(keyJSON), err
+}
+
+// ListAccounts returns the addresses of all accounts in node's key store
+func (a *PrivateAccountAPI) ListAccounts() ([]common.Address, error) {
+	return a.keystore.Accounts()
+}
+
+// UnlockAccount unlocks the account for the duration in seconds, so that it can sign transactions. The default duration is
+// 300 seconds, and 0 means unlocking until LockAccount is called
+func (a *PrivateAccountAPI) UnlockAccount(address common.Address, passphrase string, duration *uint64) error {
+	seconds := uint64(defaultUnlockDuration)
+	if duration != nil {
+		seconds = *duration
+	}
+	return a.keystore.Unlock(address, passphrase, time.Duration(seconds)*time.Second)
+}
+
+// LockAccount locks the unlocked account
+func (a *PrivateAccountAPI) LockAccount(address common.Address) error {
+	return a.keystore.Lock(address)
+}
+
 // PublicAccountAPI API for access to account information
 type PublicAccountAPI struct {
+	chain   *chain.BlockChain
 	manager *account.Manager
 }
 
 // NewPublicAccountAPI
-func NewPublicAccountAPI(m *account.Manager) *PublicAccountAPI {
-	return &PublicAccountAPI{m}
+func NewPublicAccountAPI(chain *chain.BlockChain, m *account.Manager) *PublicAccountAPI {
+	return &PublicAccountAPI{chain, m}
 }
 
 // GetBalance get balance in mo
@@ -66,6 +125,69 @@ func (a *PublicAccountAPI) GetAccount(LemoAddress string) (types.AccountAccessor
 	return accountData, nil
 }
 
+// historicalManager creates an account manager to read the account data at the block height
+func (a *PublicAccountAPI) historicalManager(height uint32) (*account.Manager, error) {
+	block := a.chain.GetBlockByHeight(height)
+	if block == nil {
+		return nil, ErrBlockNotExist
+	}
+	return account.NewManager(block.Hash(), a.chain.Db()), nil
+}
+
+// GetBalanceAt get balance in mo at the block height
+func (a *PublicAccountAPI) GetBalanceAt(LemoAddress string, height uint32) (string, error) {
+	address, err := common.StringToAddress(LemoAddress)
+	if err != nil {
+		return "", err
+	}
+	manager, err := a.historicalManager(height)
+	if err != nil {
+		return "", err
+	}
+	balance, err := manager.GetHistoricalBalance(address)
+	if err != nil {
+		return "", err
+	}
+	return balance.String(), nil
+}
+
+// GetCodeAt get contract code at the block height
+func (a *PublicAccountAPI) GetCodeAt(LemoAddress string, height uint32) (hexutil.Bytes, error) {
+	address, err := common.StringToAddress(LemoAddress)
+	if err != nil {
+		return nil, err
+	}
+	manager, err := a.historicalManager(height)
+	if err != nil {
+		return nil, err
+	}
+	code, err := manager.GetHistoricalCode(address)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(code), nil
+}
+
+// GetStorageAt get the value of contract storage slot at the block height
+func (a *PublicAccountAPI) GetStorageAt(LemoAddress string, key common.Hash, height uint32) (hexutil.Bytes, error) {
+	address, err := common.StringToAddress(LemoAddress)
+	if err != nil {
+		return nil, err
+	}
+	manager, err := a.historicalManager(height)
+	if err != nil {
+		return nil, err
+	}
+	value, err := manager.GetHistoricalStorageState(address, key)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(value), nil
+}
+
+// defaultGasPrice is the suggested gas price for transactions
+var defaultGasPrice = big.NewInt(100000000)
+
 // ChainAPI
 type PublicChainAPI struct {
 	chain *chain.BlockChain
@@ -166,10 +288,15 @@ func (c *PublicChainAPI) LatestStableHeight() uint32 {
 	return c.chain.StableBlock().Height()
 }
 
+// GetFinality get the finality certificate which proves the block is stable
+func (c *PublicChainAPI) GetFinality(hash string) (*types.FinalityCertificate, error) {
+	return c.chain.GetFinality(common.HexToHash(hash))
+}
+
 // GasPriceAdvice get suggest gas price
 func (c *PublicChainAPI) GasPriceAdvice() *big.Int {
 	// todo
-	return big.NewInt(100000000)
+	return new(big.Int).Set(defaultGasPrice)
 }
 
 // NodeVersion
@@ -177,14 +304,157 @@ func (n *PublicChainAPI) NodeVersion() string {
 	return params.Version
 }
 
+// BlockDataProof is the proof of a transaction, change log or event in block
+type BlockDataProof struct {
+	BlockHash   common.Hash        `json:"blockHash"`
+	BlockHeight uint32             `json:"height"`
+	Proof       *proof.MerkleProof `json:"proof"`
+}
+
+// newBlockDataProof creates the proof of the leaf at index
+func newBlockDataProof(block *types.Block, leaves []common.Hash, index uint32) (*BlockDataProof, error) {
+	p, err := proof.NewMerkleProof(leaves, int(index))
+	if err != nil {
+		return nil, err
+	}
+	return &BlockDataProof{
+		BlockHash:   block.Hash(),
+		BlockHeight: block.Height(),
+		Proof:       p,
+	}, nil
+}
+
+// GetTxProof returns the proof that the transaction is in the block's TxRoot
+func (c *PublicChainAPI) GetTxProof(txHash string) (*BlockDataProof, error) {
+	tx, block, index := c.chain.GetTxByHash(common.HexToHash(txHash))
+	if tx == nil {
+		return nil, ErrTxNotExist
+	}
+	leaves := make([]common.Hash, 0, len(block.Txs))
+	for _, tx := range block.Txs {
+		leaves = append(leaves, tx.Hash())
+	}
+	return newBlockDataProof(block, leaves, index)
+}
+
+// GetChangeLogProof returns the proof that the change log at index is in the block's LogRoot
+func (c *PublicChainAPI) GetChangeLogProof(height uint32, index uint32) (*BlockDataProof, error) {
+	block := c.chain.GetBlockByHeight(height)
+	if block == nil {
+		return nil, ErrBlockNotExist
+	}
+	leaves := make([]common.Hash, 0, len(block.ChangeLogs))
+	for _, log := range block.ChangeLogs {
+		leaves = append(leaves, log.Hash())
+	}
+	return newBlockDataProof(block, leaves, index)
+}
+
+// GetEventProof returns the proof that the event at index is in the block's EventRoot
+func (c *PublicChainAPI) GetEventProof(height uint32, index uint32) (*BlockDataProof, error) {
+	block := c.chain.GetBlockByHeight(height)
+	if block == nil {
+		return nil, ErrBlockNotExist
+	}
+	leaves := make([]common.Hash, 0, len(block.Events))
+	for _, event := range block.Events {
+		leaves = append(leaves, event.Hash())
+	}
+	return newBlockDataProof(block, leaves, index)
+}
+
+// GetVersionProof returns the proof of the account's newest change log version in the stable block's VersionRoot
+func (c *PublicChainAPI) GetVersionProof(LemoAddress string, logType uint32, height uint32) (*proof.VersionProof, error) {
+	address, err := common.StringToAddress(LemoAddress)
+	if err != nil {
+		return nil, err
+	}
+	if height > c.chain.StableBlock().Height() {
+		return nil, ErrBlockNotStable
+	}
+	block := c.chain.GetBlockByHeight(height)
+	if block == nil {
+		return nil, ErrBlockNotExist
+	}
+	manager := account.NewManager(block.Hash(), c.chain.Db())
+	return manager.ProveVersion(address, types.ChangeLogType(logType))
+}
+
+// NewHeads creates a subscription which pushes the header of every new block, including the blocks mined by self and received from network
+func (c *PublicChainAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		blockCh := make(chan *types.Block, 16)
+		minedSub := c.chain.MinedBlockFeed.Subscribe(blockCh)
+		defer minedSub.Unsubscribe()
+		recvSub := c.chain.RecvBlockFeed.Subscribe(blockCh)
+		defer recvSub.Unsubscribe()
+		for {
+			select {
+			case block := <-blockCh:
+				notifier.Notify(rpcSub.ID, block.Header)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// StableBlocks creates a subscription which pushes the new stable block
+func (c *PublicChainAPI) StableBlocks(ctx context.Context, withBody bool) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		blockCh := make(chan *types.Block, 16)
+		sub := c.chain.StableBlockFeed.Subscribe(blockCh)
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case block := <-blockCh:
+				if withBody {
+					notifier.Notify(rpcSub.ID, block)
+				} else {
+					notifier.Notify(rpcSub.ID, &types.Block{Header: block.Header})
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // TXAPI
 type PublicTxAPI struct {
+	chain  *chain.BlockChain
 	txpool *chain.TxPool
 }
 
 // NewTxAPI API for send a transaction
-func NewPublicTxAPI(txpool *chain.TxPool) *PublicTxAPI {
-	return &PublicTxAPI{txpool}
+func NewPublicTxAPI(chain *chain.BlockChain, txpool *chain.TxPool) *PublicTxAPI {
+	return &PublicTxAPI{chain, txpool}
+}
+
+// TxInBlock is a transaction with its position in chain. The block hash is empty if the transaction is still pending
+type TxInBlock struct {
+	Tx          *types.Transaction `json:"tx"`
+	BlockHash   common.Hash        `json:"blockHash"`
+	BlockHeight uint32             `json:"blockHeight"`
+	TxIndex     uint32             `json:"transactionIndex"`
 }
 
 // Send send a transaction
@@ -198,6 +468,225 @@ func (t *PublicTxAPI) PendingTx(size int) []*types.Transaction {
 	return t.txpool.Pending(size)
 }
 
+// GetTxByHash get transaction by hash from chain or tx pool
+func (t *PublicTxAPI) GetTxByHash(txHash string) *TxInBlock {
+	hash := common.HexToHash(txHash)
+	tx, block, index := t.chain.GetTxByHash(hash)
+	if tx != nil {
+		return &TxInBlock{
+			Tx:          tx,
+			BlockHash:   block.Hash(),
+			BlockHeight: block.Height(),
+			TxIndex:     index,
+		}
+	}
+	tx = t.txpool.Get(hash)
+	if tx == nil {
+		return nil
+	}
+	return &TxInBlock{Tx: tx}
+}
+
+// CallArgs is the arguments to call contract or estimate gas
+type CallArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"` // empty means contract creation
+	Amount   *hexutil.Big10  `json:"amount"`
+	GasLimit hexutil.Uint64  `json:"gasLimit"` // 0 means the gas limit of block
+	GasPrice *hexutil.Big10  `json:"gasPrice"`
+	Data     hexutil.Bytes   `json:"data"`
+}
+
+func (args *CallArgs) toMsg() *chain.CallMsg {
+	return &chain.CallMsg{
+		From:     args.From,
+		To:       args.To,
+		Amount:   (*big.Int)(args.Amount),
+		GasLimit: uint64(args.GasLimit),
+		GasPrice: (*big.Int)(args.GasPrice),
+		Data:     args.Data,
+	}
+}
+
+// blockByHeight returns the block in current chain. The current block is returned if height is nil
+func (t *PublicTxAPI) blockByHeight(height *hexutil.Uint32) (*types.Block, error) {
+	if height == nil {
+		return t.chain.CurrentBlock(), nil
+	}
+	block := t.chain.GetBlockByHeight(uint32(*height))
+	if block == nil {
+		return nil, ErrBlockNotExist
+	}
+	return block, nil
+}
+
+// Call executes a contract call based on the state after the block, and returns the output of contract. Nothing is changed in chain
+func (t *PublicTxAPI) Call(args CallArgs, height *hexutil.Uint32) (hexutil.Bytes, error) {
+	block, err := t.blockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return t.chain.TxProcessor().CallTx(block, args.toMsg())
+}
+
+// EstimateGas returns the minimal gas limit which the transaction needs to be executed successfully based on the state after the block
+func (t *PublicTxAPI) EstimateGas(args CallArgs, height *hexutil.Uint32) (hexutil.Uint64, error) {
+	block, err := t.blockByHeight(height)
+	if err != nil {
+		return 0, err
+	}
+	gas, err := t.chain.TxProcessor().EstimateGas(block, args.toMsg())
+	return hexutil.Uint64(gas), err
+}
+
+// defaultTxLifetime is the duration before transaction expired if the expiration time is not set, in seconds
+const defaultTxLifetime = 30 * 60
+
+// PrivateTxAPI sends the transactions signed by the unlocked accounts in node's key store
+type PrivateTxAPI struct {
+	chain     *chain.BlockChain
+	txpool    *chain.TxPool
+	keystore  *keystore.KeyStore
+	nonceLock sync.Mutex // make sure the nonces of concurrent transactions are continuous
+}
+
+// NewPrivateTxAPI
+func NewPrivateTxAPI(chain *chain.BlockChain, txpool *chain.TxPool, ks *keystore.KeyStore) *PrivateTxAPI {
+	return &PrivateTxAPI{chain: chain, txpool: txpool, keystore: ks}
+}
+
+// SendTxArgs is the arguments to send transaction by node's account
+type SendTxArgs struct {
+	From       common.Address  `json:"from"`
+	To         *common.Address `json:"to"` // empty means contract creation
+	ToName     string          `json:"toName"`
+	Amount     *hexutil.Big10  `json:"amount"`
+	GasLimit   hexutil.Uint64  `json:"gasLimit"` // 0 means estimating by current block
+	GasPrice   *hexutil.Big10  `json:"gasPrice"`
+	Data       hexutil.Bytes   `json:"data"`
+	Expiration hexutil.Uint64  `json:"expirationTime"` // 0 means 30 minutes later
+	Message    string          `json:"message"`
+	Nonce      *hexutil.Uint64 `json:"nonce"` // empty means the next nonce counting the transactions in pool
+}
+
+// SendTransaction signs the transaction by the unlocked account and adds it into tx pool
+func (t *PrivateTxAPI) SendTransaction(args SendTxArgs) (common.Hash, error) {
+	if !t.keystore.IsUnlocked(args.From) {
+		return common.Hash{}, keystore.ErrLocked
+	}
+	t.nonceLock.Lock()
+	defer t.nonceLock.Unlock()
+
+	tx, err := t.newTx(&args)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	signed, err := t.keystore.SignTx(args.From, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := t.txpool.AddTx(signed); err != nil {
+		return common.Hash{}, err
+	}
+	return signed.Hash(), nil
+}
+
+// newTx creates the unsigned transaction and fills the default values
+func (t *PrivateTxAPI) newTx(args *SendTxArgs) (*types.Transaction, error) {
+	amount := new(big.Int)
+	if args.Amount != nil {
+		amount = (*big.Int)(args.Amount)
+	}
+	gasPrice := new(big.Int).Set(defaultGasPrice)
+	if args.GasPrice != nil {
+		gasPrice = (*big.Int)(args.GasPrice)
+	}
+	gasLimit := uint64(args.GasLimit)
+	if gasLimit == 0 {
+		call := &CallArgs{From: args.From, To: args.To, Amount: (*hexutil.Big10)(amount), GasPrice: (*hexutil.Big10)(gasPrice), Data: args.Data}
+		gas, err := t.chain.TxProcessor().EstimateGas(t.chain.CurrentBlock(), call.toMsg())
+		if err != nil {
+			return nil, err
+		}
+		gasLimit = gas
+	}
+	var nonce uint64
+	if args.Nonce != nil {
+		nonce = uint64(*args.Nonce)
+	} else {
+		nonce = t.txpool.PendingNonce(args.From)
+	}
+	expiration := uint64(args.Expiration)
+	if expiration == 0 {
+		expiration = uint64(time.Now().Unix()) + defaultTxLifetime
+	}
+	if args.To == nil {
+		return types.NewContractCreation(nonce, amount, gasLimit, gasPrice, args.Data, t.chain.ChainID(), expiration, args.ToName, args.Message), nil
+	}
+	return types.NewTransaction(nonce, *args.To, amount, gasLimit, gasPrice, args.Data, t.chain.ChainID(), expiration, args.ToName, args.Message), nil
+}
+
+// PendingTxs creates a subscription which pushes the hash of every new transaction in pool
+func (t *PublicTxAPI) PendingTxs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		txsCh := make(chan types.Transactions, 128)
+		sub := t.txpool.NewTxsFeed.Subscribe(txsCh)
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case txs := <-txsCh:
+				for _, tx := range txs {
+					notifier.Notify(rpcSub.ID, tx.Hash())
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// GetReceipt get the receipt of a transaction which has been packaged in chain
+func (t *PublicTxAPI) GetReceipt(txHash string) *types.Receipt {
+	return t.chain.GetReceipt(common.HexToHash(txHash))
+}
+
+// PrivateDebugAPI re-executes the transactions in chain for debugging
+type PrivateDebugAPI struct {
+	chain *chain.BlockChain
+}
+
+// NewPrivateDebugAPI
+func NewPrivateDebugAPI(chain *chain.BlockChain) *PrivateDebugAPI {
+	return &PrivateDebugAPI{chain}
+}
+
+// TraceTransaction re-executes the transaction on its parent state, and returns the opcode level steps and the call tree
+func (d *PrivateDebugAPI) TraceTransaction(txHash string, config *vm.LogConfig) (*chain.TxTrace, error) {
+	tx, block, index := d.chain.GetTxByHash(common.HexToHash(txHash))
+	if tx == nil {
+		return nil, ErrTxNotExist
+	}
+	return d.chain.TxProcessor().TraceTx(block, int(index), config)
+}
+
+// TraceBlock re-executes all transactions in the block on its parent state, and returns the traces of them
+func (d *PrivateDebugAPI) TraceBlock(height uint32, config *vm.LogConfig) ([]*chain.TxTrace, error) {
+	block := d.chain.GetBlockByHeight(height)
+	if block == nil {
+		return nil, ErrBlockNotExist
+	}
+	return d.chain.TxProcessor().TraceBlock(block, config)
+}
+
 // PrivateMineAPI
 type PrivateMineAPI struct {
 	miner *miner.Miner
@@ -239,6 +728,11 @@ func (m *PublicMineAPI) Miner() string {
 	return address.String()
 }
 
+// PackStats returns the statistics of packing transactions in the last mined block
+func (m *PublicMineAPI) PackStats() *miner.PackStats {
+	return m.miner.PackStats()
+}
+
 // PrivateNetAPI
 type PrivateNetAPI struct {
 	node *Node
@@ -304,3 +798,72 @@ func (n *PublicNetAPI) Info() *NetInfo {
 		Go:       runtime.Version(),
 	}
 }
+
+// FilterCriteria is the query of events. The height range is ignored in subscription
+type FilterCriteria struct {
+	FromHeight *hexutil.Uint32  `json:"fromHeight"` // default to current block height
+	ToHeight   *hexutil.Uint32  `json:"toHeight"`   // default to current block height
+	Addresses  []common.Address `json:"addresses"`  // empty means any address
+	Topics     [][]common.Hash  `json:"topics"`     // empty list in a position means any topic
+}
+
+// PublicFilterAPI API for searching and subscribing contract events
+type PublicFilterAPI struct {
+	chain  *chain.BlockChain
+	events *filters.EventSystem
+}
+
+// NewPublicFilterAPI
+func NewPublicFilterAPI(chain *chain.BlockChain, events *filters.EventSystem) *PublicFilterAPI {
+	return &PublicFilterAPI{chain, events}
+}
+
+// GetEvents returns the events matching the criteria in current chain
+func (f *PublicFilterAPI) GetEvents(crit FilterCriteria) ([]*types.Event, error) {
+	current := f.chain.CurrentBlock().Height()
+	begin, end := current, current
+	if crit.FromHeight != nil {
+		begin = uint32(*crit.FromHeight)
+	}
+	if crit.ToHeight != nil {
+		end = uint32(*crit.ToHeight)
+	}
+	return filters.New(f.chain, begin, end, crit.Addresses, crit.Topics).Events()
+}
+
+// Events creates a subscription which pushes the matched events in new stable blocks
+func (f *PublicFilterAPI) Events(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+	return f.subscribe(ctx, crit, f.events.SubscribeEvents)
+}
+
+// UnstableEvents creates a subscription which pushes the matched events in new current blocks. The events in abandoned forks are pushed again with removed flag
+func (f *PublicFilterAPI) UnstableEvents(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+	return f.subscribe(ctx, crit, f.events.SubscribeUnstableEvents)
+}
+
+func (f *PublicFilterAPI) subscribe(ctx context.Context, crit FilterCriteria, subscribeFn func([]common.Address, [][]common.Hash, chan<- []*types.Event) *filters.Subscription) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		eventsCh := make(chan []*types.Event, 16)
+		sub := subscribeFn(crit.Addresses, crit.Topics, eventsCh)
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case events := <-eventsCh:
+				for _, event := range events {
+					notifier.Notify(rpcSub.ID, event)
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -1,13 +1,27 @@
 package node
 
 import (
+	"context"
 	"github.com/LemoFoundationLtd/lemochain-go/chain"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/filters"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/keystore"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/params"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/proof"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/vm"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/LemoFoundationLtd/lemochain-go/common/hexutil"
+	"github.com/LemoFoundationLtd/lemochain-go/network/rpc"
 	"github.com/LemoFoundationLtd/lemochain-go/store"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 // TestAccountAPI_api account api test
@@ -15,8 +29,8 @@ func TestAccountAPI_api(t *testing.T) {
 	db := newDB()
 	defer store.ClearData()
 	am := account.NewManager(common.Hash{}, db)
-	acc := NewPublicAccountAPI(am)
-	priAcc := NewPrivateAccountAPI(am)
+	acc := NewPublicAccountAPI(nil, am)
+	priAcc := NewPrivateAccountAPI(am, nil)
 	// Create key pair
 	addressKeyPair, err := priAcc.NewKeyPair()
 	assert.NoError(t, err)
@@ -49,6 +63,39 @@ func TestAccountAPI_api(t *testing.T) {
 
 }
 
+// TestAccountAPI_historical historical account data api test
+func TestAccountAPI_historical(t *testing.T) {
+	bc := newChain()
+	defer store.ClearData()
+	acc := NewPublicAccountAPI(bc, account.NewManager(common.Hash{}, bc.Db()))
+
+	// genesis coin
+	genesisBalance, _ := new(big.Int).SetString("1000000000000000000000000", 10)
+	balance, err := acc.GetBalanceAt(testAddr.String(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, genesisBalance.String(), balance)
+	// 2 transfers in stable block 1, cost 1+(21000+68)*2 and 1+21000*2
+	balance1 := new(big.Int).Sub(genesisBalance, big.NewInt(84138))
+	balance, err = acc.GetBalanceAt(testAddr.String(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, balance1.String(), balance)
+	// the account not exist at the height
+	balance, err = acc.GetBalanceAt(defaultAccounts[1].String(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "0", balance)
+	// block not exist in current chain
+	_, err = acc.GetBalanceAt(testAddr.String(), 2)
+	assert.Equal(t, ErrBlockNotExist, err)
+
+	// code and storage of normal account
+	code, err := acc.GetCodeAt(testAddr.String(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(code))
+	value, err := acc.GetStorageAt(testAddr.String(), k(1), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(value))
+}
+
 // TestChainAPI_api chain api test
 func TestChainAPI_api(t *testing.T) {
 	bc := newChain()
@@ -56,12 +103,12 @@ func TestChainAPI_api(t *testing.T) {
 	c := NewPublicChainAPI(bc)
 
 	// getBlockByHash
-	exBlock1 := c.chain.GetBlockByHash(common.HexToHash("0x3f4c3152fb02a7673bf804b1ddeb75542b6ef9a5a87501d9cfbbcf6c3632a211"))
-	assert.Equal(t, exBlock1, c.GetBlockByHash("0x3f4c3152fb02a7673bf804b1ddeb75542b6ef9a5a87501d9cfbbcf6c3632a211", true))
+	exBlock1 := c.chain.GetBlockByHash(common.HexToHash("0x467cbd96ac4a003bb588bae314919d648703405d59b43de63e74fc0ff33f2d79"))
+	assert.Equal(t, exBlock1, c.GetBlockByHash("0x467cbd96ac4a003bb588bae314919d648703405d59b43de63e74fc0ff33f2d79", true))
 	Block1 := &types.Block{
 		Header: exBlock1.Header,
 	}
-	assert.Equal(t, Block1, c.GetBlockByHash("0x3f4c3152fb02a7673bf804b1ddeb75542b6ef9a5a87501d9cfbbcf6c3632a211", false))
+	assert.Equal(t, Block1, c.GetBlockByHash("0x467cbd96ac4a003bb588bae314919d648703405d59b43de63e74fc0ff33f2d79", false))
 
 	// getBlockByHeight
 	exBlock2 := c.chain.GetBlockByHeight(1)
@@ -99,6 +146,12 @@ func TestChainAPI_api(t *testing.T) {
 	// get latest stable block height
 	assert.Equal(t, c.chain.StableBlock().Height(), c.LatestStableHeight())
 
+	// get finality certificate
+	_, err := c.GetFinality("0x467cbd96ac4a003bb588bae314919d648703405d59b43de63e74fc0ff33f2d79")
+	assert.Equal(t, types.ErrNotEnoughConfirms, err)
+	_, err = c.GetFinality("0x1234")
+	assert.Equal(t, chain.ErrBlockNotExist, err)
+
 	// get suggest gas price
 	// todo
 
@@ -107,17 +160,80 @@ func TestChainAPI_api(t *testing.T) {
 
 }
 
+// TestChainAPI_proof proof api test
+func TestChainAPI_proof(t *testing.T) {
+	bc := newChain()
+	defer store.ClearData()
+	c := NewPublicChainAPI(bc)
+	block := bc.GetBlockByHeight(1)
+
+	// tx proof
+	tx := block.Txs[1]
+	txProof, err := c.GetTxProof(tx.Hash().Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, block.Hash(), txProof.BlockHash)
+	assert.Equal(t, uint32(1), txProof.BlockHeight)
+	assert.NoError(t, proof.VerifyTx(block.Header, tx, txProof.Proof))
+	_, err = c.GetTxProof("0x01")
+	assert.Equal(t, ErrTxNotExist, err)
+
+	// change log proof
+	logProof, err := c.GetChangeLogProof(1, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, proof.VerifyChangeLog(block.Header, block.ChangeLogs[2], logProof.Proof))
+	_, err = c.GetChangeLogProof(1, uint32(len(block.ChangeLogs)))
+	assert.Equal(t, proof.ErrIndexOutOfRange, err)
+	_, err = c.GetChangeLogProof(100, 0)
+	assert.Equal(t, ErrBlockNotExist, err)
+
+	// version proof
+	versionProof, err := c.GetVersionProof(testAddr.String(), uint32(account.BalanceLog), 1)
+	assert.NoError(t, err)
+	version, err := proof.VerifyVersion(block.Header, versionProof)
+	assert.NoError(t, err)
+	expect, err := account.NewManager(block.Hash(), bc.Db()).GetVersion(testAddr, account.BalanceLog)
+	assert.NoError(t, err)
+	assert.Equal(t, expect, version)
+	assert.NotEqual(t, uint32(0), version)
+	_, err = c.GetVersionProof(testAddr.String(), uint32(account.BalanceLog), 2)
+	assert.Equal(t, ErrBlockNotStable, err)
+}
+
 // TestTxAPI_api send tx api test
 func TestTxAPI_api(t *testing.T) {
-	testTx := types.NewTransaction(common.HexToAddress("0x1"), common.Big1, 100, common.Big2, []byte{12}, 200, uint64(1544596), "aa", string("send a Tx"))
-	signTx := signTransaction(testTx, testPrivate)
-	// txCh := make(chan types.Transactions, 100)
-	pool := chain.NewTxPool(nil)
-	txAPI := NewPublicTxAPI(pool)
+	bc := newChain()
+	defer store.ClearData()
+	// the transactions with nonce 0 and 1 are in stable block
+	signTx := makeTx(testPrivate, 2, common.HexToAddress("0x1"), common.Big1)
+	pool := chain.NewTxPool(bc)
+	txAPI := NewPublicTxAPI(bc, pool)
 
 	sendTxHash, err := txAPI.SendTx(signTx)
 	assert.Nil(t, err)
 	assert.Equal(t, signTx.Hash(), sendTxHash)
+	assert.Equal(t, types.Transactions{signTx}, types.Transactions(txAPI.PendingTx(10)))
+
+	// get pending tx
+	txInBlock := txAPI.GetTxByHash(signTx.Hash().Hex())
+	assert.Equal(t, signTx, txInBlock.Tx)
+	assert.Equal(t, common.Hash{}, txInBlock.BlockHash)
+	assert.Nil(t, txAPI.GetReceipt(signTx.Hash().Hex()))
+
+	// get stable tx
+	block1 := bc.GetBlockByHeight(1)
+	txInBlock = txAPI.GetTxByHash(block1.Txs[1].Hash().Hex())
+	assert.Equal(t, block1.Txs[1].Hash(), txInBlock.Tx.Hash())
+	assert.Equal(t, block1.Hash(), txInBlock.BlockHash)
+	assert.Equal(t, uint32(1), txInBlock.BlockHeight)
+	assert.Equal(t, uint32(1), txInBlock.TxIndex)
+
+	// not exist
+	assert.Nil(t, txAPI.GetTxByHash("0x1"))
+
+	// expired
+	testTx := types.NewTransaction(3, common.HexToAddress("0x1"), common.Big1, 100000, common.Big2, []byte{12}, chainID, uint64(1544596), "aa", string("send a Tx"))
+	_, err = txAPI.SendTx(signTransaction(testTx, testPrivate))
+	assert.Equal(t, chain.ErrTxExpired, err)
 }
 
 // // TestMineAPI_api miner api test // todo
@@ -146,3 +262,217 @@ func TestTxAPI_api(t *testing.T) {
 //
 // }
 //
+
+func TestFilterAPI_api(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+	events := filters.NewEventSystem(bc)
+	defer events.Stop()
+	filterAPI := NewPublicFilterAPI(bc, events)
+
+	// default to current block
+	result, err := filterAPI.GetEvents(FilterCriteria{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(result))
+
+	from, to := hexutil.Uint32(2), hexutil.Uint32(1)
+	_, err = filterAPI.GetEvents(FilterCriteria{FromHeight: &from, ToHeight: &to})
+	assert.Equal(t, filters.ErrInvalidHeightRange, err)
+
+	// subscription is not supported without notifier
+	_, err = filterAPI.Events(context.Background(), FilterCriteria{})
+	assert.Equal(t, rpc.ErrNotificationsUnsupported, err)
+}
+
+func TestChainAPI_subscribe(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+	server := rpc.NewServer()
+	defer server.Stop()
+	assert.NoError(t, server.RegisterName("chain", NewPublicChainAPI(bc)))
+	assert.NoError(t, server.RegisterName("tx", NewPublicTxAPI(bc, chain.NewTxPool(bc))))
+	client := rpc.DialInProc(server)
+	defer client.Close()
+	block := bc.CurrentBlock()
+
+	// new heads
+	headCh := make(chan *types.Header)
+	sub, err := client.Subscribe(context.Background(), "chain", headCh, "newHeads")
+	assert.NoError(t, err)
+	head := receiveNotification(t, headCh, func() { bc.MinedBlockFeed.Send(block) }).(*types.Header)
+	assert.Equal(t, block.Hash(), head.Hash())
+	sub.Unsubscribe()
+
+	// stable blocks
+	blockCh := make(chan *types.Block)
+	sub, err = client.Subscribe(context.Background(), "chain", blockCh, "stableBlocks", true)
+	assert.NoError(t, err)
+	stable := receiveNotification(t, blockCh, func() { bc.StableBlockFeed.Send(block) }).(*types.Block)
+	assert.Equal(t, block.Hash(), stable.Hash())
+	assert.Equal(t, len(block.Txs), len(stable.Txs))
+	sub.Unsubscribe()
+	// without body
+	headerOnlyCh := make(chan map[string]interface{})
+	sub, err = client.Subscribe(context.Background(), "chain", headerOnlyCh, "stableBlocks", false)
+	assert.NoError(t, err)
+	headerOnly := receiveNotification(t, headerOnlyCh, func() { bc.StableBlockFeed.Send(block) }).(map[string]interface{})
+	assert.Equal(t, block.Hash().Hex(), headerOnly["header"].(map[string]interface{})["hash"])
+	assert.Nil(t, headerOnly["transactions"])
+	sub.Unsubscribe()
+
+	// subscription is not supported without notifier
+	_, err = NewPublicChainAPI(bc).NewHeads(context.Background())
+	assert.Equal(t, rpc.ErrNotificationsUnsupported, err)
+}
+
+func TestTxAPI_subscribe(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+	pool := chain.NewTxPool(bc)
+	server := rpc.NewServer()
+	defer server.Stop()
+	assert.NoError(t, server.RegisterName("tx", NewPublicTxAPI(bc, pool)))
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	hashCh := make(chan common.Hash)
+	sub, err := client.Subscribe(context.Background(), "tx", hashCh, "pendingTxs")
+	assert.NoError(t, err)
+	defer sub.Unsubscribe()
+	tx := makeTx(testPrivate, 2, common.HexToAddress("0x1"), common.Big1)
+	hash := receiveNotification(t, hashCh, func() { pool.NewTxsFeed.Send(types.Transactions{tx}) }).(common.Hash)
+	assert.Equal(t, tx.Hash(), hash)
+}
+
+// receiveNotification calls send repeatedly until a notification is received, because the notifications are dropped before the subscription is activated
+func receiveNotification(t *testing.T, ch interface{}, send func()) interface{} {
+	timeout := time.After(2 * time.Second)
+	chValue := reflect.ValueOf(ch)
+	for {
+		send()
+		chosen, value, _ := reflect.Select([]reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: chValue},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(time.After(50 * time.Millisecond))},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timeout)},
+		})
+		switch chosen {
+		case 0:
+			return value.Interface()
+		case 2:
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestTxAPI_call(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+	txAPI := NewPublicTxAPI(bc, chain.NewTxPool(bc))
+	to := common.HexToAddress("0x10001")
+	args := CallArgs{From: testAddr, To: &to, Amount: (*hexutil.Big10)(common.Big1)}
+
+	ret, err := txAPI.Call(args, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(ret))
+	gas, err := txAPI.EstimateGas(args, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, hexutil.Uint64(params.TxGas), gas)
+
+	height := hexutil.Uint32(100)
+	_, err = txAPI.Call(args, &height)
+	assert.Equal(t, ErrBlockNotExist, err)
+	_, err = txAPI.EstimateGas(args, &height)
+	assert.Equal(t, ErrBlockNotExist, err)
+}
+
+func TestPrivateDebugAPI_trace(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+	defer store.ClearData()
+	d := NewPrivateDebugAPI(bc)
+	block := bc.GetBlockByHeight(1)
+
+	traces, err := d.TraceBlock(1, &vm.LogConfig{DisableMemory: true})
+	assert.NoError(t, err)
+	assert.Equal(t, len(block.Txs), len(traces))
+	trace, err := d.TraceTransaction(block.Txs[1].Hash().Hex(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, traces[1], trace)
+	assert.Equal(t, block.Txs[1].Hash(), trace.TxHash)
+	assert.Equal(t, *block.Txs[1].To(), trace.Calls.To)
+
+	_, err = d.TraceTransaction("0x01", nil)
+	assert.Equal(t, ErrTxNotExist, err)
+	_, err = d.TraceBlock(100, nil)
+	assert.Equal(t, ErrBlockNotExist, err)
+}
+
+func newTestKeyStore(t *testing.T) (*keystore.KeyStore, func()) {
+	dir, err := ioutil.TempDir("", "keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP), func() { os.RemoveAll(dir) }
+}
+
+func TestPrivateAccountAPI_keystore(t *testing.T) {
+	ks, clean := newTestKeyStore(t)
+	defer clean()
+	priAcc := NewPrivateAccountAPI(nil, ks)
+
+	address, err := priAcc.NewAccount("123")
+	assert.NoError(t, err)
+	imported, err := priAcc.ImportKey(common.ToHex(crypto.FromECDSA(testPrivate)), "456")
+	assert.NoError(t, err)
+	assert.Equal(t, testAddr, imported)
+	accounts, err := priAcc.ListAccounts()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(accounts))
+
+	// export and import again
+	keyJSON, err := priAcc.ExportKeyFile(address, "123", "789")
+	assert.NoError(t, err)
+	_, err = priAcc.ImportKeyFile(keyJSON, "789", "123")
+	assert.Equal(t, keystore.ErrAccountAlreadyExist, err)
+
+	// lock and unlock
+	assert.Equal(t, keystore.ErrDecrypt, priAcc.UnlockAccount(address, "456", nil))
+	assert.NoError(t, priAcc.UnlockAccount(address, "123", nil))
+	assert.Equal(t, true, ks.IsUnlocked(address))
+	assert.NoError(t, priAcc.LockAccount(address))
+	assert.Equal(t, false, ks.IsUnlocked(address))
+}
+
+func TestPrivateTxAPI_SendTransaction(t *testing.T) {
+	store.ClearData()
+	bc := newChain()
+	txpool := chain.NewTxPool(bc)
+	ks, clean := newTestKeyStore(t)
+	defer clean()
+	txAPI := NewPrivateTxAPI(bc, txpool, ks)
+	_, err := ks.ImportECDSA(testPrivate, "123")
+	assert.NoError(t, err)
+	to := common.HexToAddress("0x10001")
+	args := SendTxArgs{From: testAddr, To: &to, Amount: (*hexutil.Big10)(common.Big1)}
+
+	_, err = txAPI.SendTransaction(args)
+	assert.Equal(t, keystore.ErrLocked, err)
+
+	assert.NoError(t, ks.Unlock(testAddr, "123", 0))
+	hash, err := txAPI.SendTransaction(args)
+	assert.NoError(t, err)
+	tx := txpool.Get(hash)
+	assert.NotNil(t, tx)
+	from, err := tx.From()
+	assert.NoError(t, err)
+	assert.Equal(t, testAddr, from)
+	assert.Equal(t, params.TxGas, tx.GasLimit())
+	assert.Equal(t, defaultGasPrice, tx.GasPrice())
+	// the nonce of testAddr in stable block is 2
+	assert.Equal(t, uint64(2), tx.Nonce())
+
+	// the next nonce counts the transaction in pool
+	hash, err = txAPI.SendTransaction(args)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), txpool.Get(hash).Nonce())
+}
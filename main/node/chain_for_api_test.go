@@ -53,49 +53,49 @@ var (
 		},
 		// block 1 is stable block
 		{
-			hash:        common.HexToHash("0x3f4c3152fb02a7673bf804b1ddeb75542b6ef9a5a87501d9cfbbcf6c3632a211"),
+			hash:        common.HexToHash("0x467cbd96ac4a003bb588bae314919d648703405d59b43de63e74fc0ff33f2d79"),
 			height:      1,
 			author:      common.HexToAddress("0x20000"),
-			versionRoot: common.HexToHash("0xc4fa99a20b2db7026f80366e34213861457e240bf0411d57f7a22cbf5b7f2346"),
-			txRoot:      common.HexToHash("0xf044cc436950ef7470aca61053eb3f1ed46b9dcd501a5210f3673dc657c4fc88"),
-			logRoot:     common.HexToHash("0xc287a6c955f3d6d4d240413a585f48009106a6ff5886b194cb506e87323786d7"),
+			versionRoot: common.HexToHash("0x26a453beace8f9ef347a4c976e308a1735ed7dee138d927923bc046569e58cee"),
+			txRoot:      common.HexToHash("0xa89902860f27749a739980f994599f6083cc7add7d42b5d81a4a5d78adeffe15"),
+			logRoot:     common.HexToHash("0x3e0b303ee15760300af77f44ba3c1b851bfe022b7d9cbe4ec2a91251ac250b7a"),
 			txList: []*types.Transaction{
 				// testAddr -> defaultAccounts[0] 1
-				signTransaction(types.NewTransaction(defaultAccounts[0], common.Big1, 2000000, common.Big2, []byte{12}, chainID, uint64(1538210391), "aa", string("aaa")), testPrivate),
+				signTransaction(types.NewTransaction(0, defaultAccounts[0], common.Big1, 2000000, common.Big2, []byte{12}, chainID, uint64(1538210391), "aa", string("aaa")), testPrivate),
 				// testAddr -> defaultAccounts[1] 1
-				makeTransaction(testPrivate, defaultAccounts[1], common.Big1, common.Big2, uint64(1538210491), 2000000),
+				makeTransaction(testPrivate, 1, defaultAccounts[1], common.Big1, common.Big2, uint64(1538210491), 2000000),
 			},
 			gasLimit: 20000000,
 			time:     1538209755,
 		},
 		// block 2 is not stable block
 		{
-			hash:        common.HexToHash("0xacfcede138d710bb1f3514ec0ffe3b65d65e8c627f38d943735190d47dc2d87c"),
+			hash:        common.HexToHash("0x94a214d6fcc5bb10082fcaa9ba5fbe1271a720a41fd15cccc1e688452f34b053"),
 			height:      2,
 			author:      defaultAccounts[0],
-			versionRoot: common.HexToHash("0xb169fb0304ad3c989589b044afbfe6257d125222e5023004e418b4bdb5f154f9"),
-			txRoot:      common.HexToHash("0x2501f471d13f0383a9a09ef7776dc94c6ccdc19ebd36127ac8a39cfef85dc412"),
-			logRoot:     common.HexToHash("0x6c24ab2c37e6bdd12c276361ba7aa1300ab389b4861ca364e1fdd480197803da"),
+			versionRoot: common.HexToHash("0xf46c93fe38b210c0aa9f6622a864fb6726e428c3e2db1485484f7c5a5844b072"),
+			txRoot:      common.HexToHash("0x7f13536f17a721a4c1b73d2caf11047cde9620746f68197b654f548a55141d3c"),
+			logRoot:     common.HexToHash("0xc78114089165d24cd111662138dde7b4d76a91d409694f26b6794412d3257092"),
 			txList: []*types.Transaction{
 				// testAddr -> defaultAccounts[0] 2
-				makeTransaction(testPrivate, defaultAccounts[0], bigNumber, common.Big2, uint64(1538210395), 2000000),
+				makeTransaction(testPrivate, 2, defaultAccounts[0], bigNumber, common.Big2, uint64(1538210395), 2000000),
 			},
 			time:     1538209758,
 			gasLimit: 20000000,
 		},
 		// block 3 is not store in db
 		{
-			hash:        common.HexToHash("0x4d5026ae18fd6e685ca5cfdc7efd5a39ec840910eb223190877e23b4fe622a66"),
+			hash:        common.HexToHash("0xb9183564aa8397f65f8e135d3af57036b0d30eb54ed4f86710fa6a6661fbb90b"),
 			height:      3,
 			author:      defaultAccounts[0],
-			versionRoot: common.HexToHash("0xfe833c7d3aa89fc1031198374e6eda37425de579e02430e46cc2273ee7f94162"),
-			txRoot:      common.HexToHash("0x883e9542653e2ad8bae93fa5567d8f5833543057af94ea0ed59c97a7034acac8"),
-			logRoot:     common.HexToHash("0xeff8eb5827967fe372182d8173b8be788c8367e7c04823c09bf49d40bf070982"),
+			versionRoot: common.HexToHash("0x0cc78ebb86b3206cc92c7e3cb9ed63fa3b049324e6367661c22267411fe7fdec"),
+			txRoot:      common.HexToHash("0x173c1fede14b22565d83e2b8836e2d5a79397c1847432a6d16e6d10bfeeaf60e"),
+			logRoot:     common.HexToHash("0x753d492ac57bca0e219cea916146469d6e3cdedf93aa12b0f2d83e25b5195f07"),
 			txList: []*types.Transaction{
 				// testAddr -> defaultAccounts[0] 2
-				makeTransaction(testPrivate, defaultAccounts[0], common.Big2, common.Big2, uint64(1538210398), 30000),
+				makeTransaction(testPrivate, 3, defaultAccounts[0], common.Big2, common.Big2, uint64(1538210398), 30000),
 				// testAddr -> defaultAccounts[1] 2
-				makeTransaction(testPrivate, defaultAccounts[1], common.Big2, common.Big3, uint64(1538210425), 30000),
+				makeTransaction(testPrivate, 4, defaultAccounts[1], common.Big2, common.Big3, uint64(1538210425), 30000),
 			},
 			time:     1538209761,
 			gasLimit: 20000000,
@@ -178,6 +178,7 @@ func makeBlock(db protocol.ChainDB, info blockInfo, save bool) *types.Block {
 			to.SetBalance(new(big.Int).Add(to.GetBalance(), tx.Amount()))
 			from.SetBalance(new(big.Int).Sub(from.GetBalance(), cost))
 		}
+		from.SetNonce(tx.Nonce() + 1)
 		gasUsed += gas
 		salary.Add(salary, fee)
 	}
@@ -249,12 +250,12 @@ func makeBlock(db protocol.ChainDB, info blockInfo, save bool) *types.Block {
 	return block
 }
 
-func makeTx(fromPrivate *ecdsa.PrivateKey, to common.Address, amount *big.Int) *types.Transaction {
-	return makeTransaction(fromPrivate, to, amount, common.Big1, uint64(time.Now().Unix()+300), 1000000)
+func makeTx(fromPrivate *ecdsa.PrivateKey, nonce uint64, to common.Address, amount *big.Int) *types.Transaction {
+	return makeTransaction(fromPrivate, nonce, to, amount, common.Big1, uint64(time.Now().Unix()+300), 1000000)
 }
 
-func makeTransaction(fromPrivate *ecdsa.PrivateKey, to common.Address, amount *big.Int, gasPrice *big.Int, expiration uint64, gasLimit uint64) *types.Transaction {
-	tx := types.NewTransaction(to, amount, gasLimit, gasPrice, []byte{}, chainID, expiration, "", string("aaa"))
+func makeTransaction(fromPrivate *ecdsa.PrivateKey, nonce uint64, to common.Address, amount *big.Int, gasPrice *big.Int, expiration uint64, gasLimit uint64) *types.Transaction {
+	tx := types.NewTransaction(nonce, to, amount, gasLimit, gasPrice, []byte{}, chainID, expiration, "", string("aaa"))
 	return signTransaction(tx, fromPrivate)
 }
 
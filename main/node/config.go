@@ -3,6 +3,7 @@ package node
 import (
 	"crypto/ecdsa"
 	"fmt"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/keystore"
 	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
 	"github.com/LemoFoundationLtd/lemochain-go/common/log"
 	"github.com/LemoFoundationLtd/lemochain-go/network/p2p"
@@ -25,18 +26,26 @@ const (
 	datadirStaticNodes  = "static-nodes.json"
 	datadirTrustedNodes = "trusted-nodes.json"
 	datadirNodeDatabase = "nodes"
+	datadirKeyStore     = "keystore"
 )
 
 var DefaultHTTPVirtualHosts = []string{"localhost"}
 
+// DefaultWSModules are the API modules offered over the websocket RPC server by default
+var DefaultWSModules = []string{"chain", "account", "tx", "net", "filter"}
+
 type Config struct {
 	Name    string `toml:"-"`
 	Version string `toml:"-"`
 
 	ExtraData []byte `toml:",omitempty"`
 
-	DataDir string
-	P2P     p2p.Config
+	DataDir  string
+	DBEngine string
+	SyncMode string
+	P2P      p2p.Config
+	// NodeKeyPasswordFile is the file containing the password to encrypt the node key. The node key is saved in plaintext if it is empty
+	NodeKeyPasswordFile string `toml:",omitempty"`
 
 	IPCPath          string   `toml:",omitempty"`
 	HTTPHost         string   `toml:",omitempty"`
@@ -46,6 +55,7 @@ type Config struct {
 	WSHost           string   `toml:",omitempty"`
 	WSPort           int      `toml:",omitempty"`
 	WSOrigins        []string `toml:",omitempty"`
+	WSModules        []string `toml:",omitempty"`
 	WSExposeAll      bool     `toml:",omitempty"`
 }
 
@@ -85,8 +95,26 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 		return c.P2P.PrivateKey
 	}
 
+	password := c.nodeKeyPassword()
 	keyFile := filepath.Join(c.DataDir, datadirPrivateKey)
+	if content, err := ioutil.ReadFile(keyFile); err == nil && isEncryptedKey(content) {
+		if password == "" {
+			log.Critf("The node key is encrypted. Please set the password file by --%s", NodeKeyPasswordFlag.Name)
+		}
+		key, err := keystore.DecryptKey(content, password)
+		if err != nil {
+			log.Critf("Failed to decrypt node key: %v", err)
+		}
+		return key.PrivateKey
+	}
 	if key, err := crypto.LoadECDSA(keyFile); err == nil {
+		// migrate the plaintext node key
+		if password != "" {
+			saveNodeKey(keyFile, key, password)
+			log.Info("The node key has been encrypted")
+		} else {
+			log.Warnf("The node key is saved in plaintext. Please set the password file by --%s to encrypt it", NodeKeyPasswordFlag.Name)
+		}
 		return key
 	}
 
@@ -99,11 +127,46 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 		log.Errorf("Failed to persist node key: %v", err)
 		return key
 	}
-	keyFile = filepath.Join(instanceDir, datadirPrivateKey)
-	if err := crypto.SaveECDSA(keyFile, key); err != nil {
+	saveNodeKey(filepath.Join(instanceDir, datadirPrivateKey), key, password)
+	return key
+}
+
+// nodeKeyPassword reads the password from the password file
+func (c *Config) nodeKeyPassword() string {
+	if c.NodeKeyPasswordFile == "" {
+		return ""
+	}
+	content, err := ioutil.ReadFile(c.NodeKeyPasswordFile)
+	if err != nil {
+		log.Critf("Failed to read node key password file: %v", err)
+	}
+	password := strings.TrimRight(string(content), "\r\n")
+	if password == "" {
+		log.Critf("The node key password file %s is empty", c.NodeKeyPasswordFile)
+	}
+	return password
+}
+
+// isEncryptedKey checks if the key file content is in keystore json format
+func isEncryptedKey(content []byte) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(content)), "{")
+}
+
+// saveNodeKey saves the node key in keystore json format if the password is set, or else saves it in plaintext
+func saveNodeKey(keyFile string, key *ecdsa.PrivateKey, password string) {
+	if password == "" {
+		if err := crypto.SaveECDSA(keyFile, key); err != nil {
+			log.Errorf("Failed to persist node key: %v", err)
+		}
+		return
+	}
+	content, err := keystore.EncryptECDSA(key, password, keystore.StandardScryptN, keystore.StandardScryptP)
+	if err == nil {
+		err = ioutil.WriteFile(keyFile, content, 0600)
+	}
+	if err != nil {
 		log.Errorf("Failed to persist node key: %v", err)
 	}
-	return key
 }
 
 func parseNodes(path string) []string {
@@ -131,6 +194,11 @@ func (c *Config) StaticNodes() []string {
 	return parseNodes(filepath.Join(c.DataDir, datadirStaticNodes))
 }
 
+// KeyStoreDir returns the directory of encrypted account key files
+func (c *Config) KeyStoreDir() string {
+	return filepath.Join(c.DataDir, datadirKeyStore)
+}
+
 func DefaultDataDir() string {
 	return filepath.Dir(os.Args[0])
 }
@@ -0,0 +1,48 @@
+package node
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/keystore"
+	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_NodeKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nodekey")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, datadirPrivateKey)
+	passwordFile := filepath.Join(dir, "password")
+	assert.NoError(t, ioutil.WriteFile(passwordFile, []byte("123\n"), 0600))
+
+	// generate plaintext key
+	cfg := &Config{DataDir: dir}
+	key := cfg.NodeKey()
+	loaded, err := crypto.LoadECDSA(keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.FromECDSA(key), crypto.FromECDSA(loaded))
+	assert.Equal(t, crypto.FromECDSA(key), crypto.FromECDSA(cfg.NodeKey()))
+
+	// migrate to encrypted key
+	cfg = &Config{DataDir: dir, NodeKeyPasswordFile: passwordFile}
+	assert.Equal(t, crypto.FromECDSA(key), crypto.FromECDSA(cfg.NodeKey()))
+	content, err := ioutil.ReadFile(keyFile)
+	assert.NoError(t, err)
+	assert.True(t, isEncryptedKey(content))
+	decrypted, err := keystore.DecryptKey(content, "123")
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.FromECDSA(key), crypto.FromECDSA(decrypted.PrivateKey))
+	// load encrypted key
+	assert.Equal(t, crypto.FromECDSA(key), crypto.FromECDSA(cfg.NodeKey()))
+
+	// generate encrypted key
+	assert.NoError(t, os.Remove(keyFile))
+	key = cfg.NodeKey()
+	content, err = ioutil.ReadFile(keyFile)
+	assert.NoError(t, err)
+	assert.True(t, isEncryptedKey(content))
+	assert.Equal(t, crypto.FromECDSA(key), crypto.FromECDSA(cfg.NodeKey()))
+}
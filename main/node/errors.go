@@ -7,4 +7,7 @@ var (
 	ErrOpenFileFailed    = errors.New("open file datadir failed")
 	ErrServerStartFailed = errors.New("start p2p server failed")
 	ErrRpcStartFailed    = errors.New("start rpc failed")
+	ErrBlockNotExist     = errors.New("block not exist")
+	ErrBlockNotStable    = errors.New("block is not stable")
+	ErrTxNotExist        = errors.New("transaction not exist")
 )
@@ -5,6 +5,7 @@ import (
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"github.com/LemoFoundationLtd/lemochain-go/common/flag"
 	"github.com/LemoFoundationLtd/lemochain-go/network/p2p"
+	"github.com/LemoFoundationLtd/lemochain-go/store"
 	"gopkg.in/urfave/cli.v1"
 	"os"
 	"path/filepath"
@@ -26,6 +27,20 @@ var (
 		Usage: "Data directory for the databases",
 		Value: DefaultDataDir(),
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  common.DBEngine,
+		Usage: "Engine of the new chain database (lmstore, leveldb). An existing database is opened by its own engine",
+		Value: store.DefaultEngine,
+	}
+	SyncModeFlag = cli.StringFlag{
+		Name:  common.SyncMode,
+		Usage: "Blockchain sync mode (full, fast). The fast mode downloads the state of a recent stable block instead of executing all blocks",
+		Value: "full",
+	}
+	NodeKeyPasswordFlag = cli.StringFlag{
+		Name:  common.NodeKeyPassword,
+		Usage: "File containing the password to encrypt the node key",
+	}
 	MaxPeersFlag = cli.IntFlag{
 		Name:  common.MaxPeers,
 		Usage: "Maximum number of network peers",
@@ -95,6 +110,11 @@ var (
 		Name:  common.WSAllowedOrigins,
 		Usage: "Origins from which to accept websockets request.",
 	}
+	WSApiFlag = cli.StringFlag{
+		Name:  common.WSApi,
+		Usage: "Comma separated list of API modules offered over the WS-RPC interface. Only the public APIs of these modules are served",
+		Value: strings.Join(DefaultWSModules, ","),
+	}
 	DebugFlag = cli.BoolFlag{
 		Name:  common.Debug,
 		Usage: "Debug for runtime",
@@ -170,6 +190,7 @@ func setWS(flags flag.CmdFlags, cfg *Config) {
 		}
 		cfg.WSPort = flags.Int(WSPortFlag.Name)
 		cfg.WSOrigins = splitAndTrim(flags.String(WSAllowedOriginsFlag.Name))
+		cfg.WSModules = splitAndTrim(flags.String(WSApiFlag.Name))
 	}
 }
 
@@ -182,6 +203,9 @@ func getNodeConfig(flags flag.CmdFlags) *Config {
 			cfg.DataDir = absDataDir
 		}
 	}
+	cfg.DBEngine = flags.String(DBEngineFlag.Name)
+	cfg.SyncMode = flags.String(SyncModeFlag.Name)
+	cfg.NodeKeyPasswordFile = flags.String(NodeKeyPasswordFlag.Name)
 	setP2PConfig(flags, &cfg.P2P)
 	setIPC(flags, cfg)
 	setHttp(flags, cfg)
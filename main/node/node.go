@@ -6,6 +6,8 @@ import (
 	"github.com/LemoFoundationLtd/lemochain-go/chain"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/account"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/deputynode"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/filters"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/keystore"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/miner"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
@@ -19,6 +21,7 @@ import (
 	"github.com/LemoFoundationLtd/lemochain-go/store/protocol"
 	"math/big"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -38,8 +41,10 @@ type Node struct {
 
 	db       protocol.ChainDB
 	accMan   *account.Manager
+	keystore *keystore.KeyStore
 	txPool   *chain.TxPool
 	chain    *chain.BlockChain
+	events   *filters.EventSystem
 	pm       *synchronise.ProtocolManager
 	miner    *miner.Miner
 	gasPrice *big.Int
@@ -98,9 +103,9 @@ func initConfig(flags flag.CmdFlags) (*Config, *ConfigFromFile, *miner.MineConfi
 	return cfg, configFromFile, mineCfg
 }
 
-func initDb(dataDir string) protocol.ChainDB {
+func initDb(dataDir string, engine string) protocol.ChainDB {
 	dir := filepath.Join(dataDir, "chaindata")
-	db, err := store.NewCacheChain(dir)
+	db, err := store.NewCacheChainWithEngine(dir, engine)
 	if err != nil {
 		panic("new cacheChain failed!!!")
 	}
@@ -145,9 +150,27 @@ func initDeputyNodes(db protocol.ChainDB) {
 	}
 }
 
+// NewOfflineChain opens the block chain in data dir without network. It is used by the chain management commands
+func NewOfflineChain(dataDir, dbEngine string) (*chain.BlockChain, error) {
+	configFromFile, err := readConfigFile(filepath.Join(dataDir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	configFromFile.Check()
+	db := initDb(dataDir, dbEngine)
+	getGenesis(db)
+	initDeputyNodes(db)
+	engine := chain.NewDpovp(int64(configFromFile.Timeout), db)
+	return chain.NewBlockChain(uint16(configFromFile.ChainID), engine, db, flag.CmdFlags{})
+}
+
 func New(flags flag.CmdFlags) *Node {
 	cfg, configFromFile, mineCfg := initConfig(flags)
-	db := initDb(cfg.DataDir)
+	syncMode, err := synchronise.ParseSyncMode(cfg.SyncMode)
+	if err != nil {
+		panic(fmt.Sprintf("invalid sync mode: %s", cfg.SyncMode))
+	}
+	db := initDb(cfg.DataDir, cfg.DBEngine)
 	// read genesis block
 	genesisBlock := getGenesis(db)
 	// read all deputy nodes from snapshot block
@@ -162,7 +185,7 @@ func New(flags flag.CmdFlags) *Node {
 
 	// newTxsCh := make(chan types.Transactions)
 	accMan := blockChain.AccountManager()
-	txPool := chain.NewTxPool(accMan)
+	txPool := chain.NewTxPool(blockChain)
 	n := &Node{
 		config:       cfg,
 		ipcEndpoint:  cfg.IPCEndpoint(),
@@ -170,10 +193,12 @@ func New(flags flag.CmdFlags) *Node {
 		wsEndpoint:   cfg.WSEndpoint(),
 		db:           db,
 		accMan:       accMan,
+		keystore:     keystore.NewKeyStore(cfg.KeyStoreDir(), keystore.StandardScryptN, keystore.StandardScryptP),
 		chain:        blockChain,
 		txPool:       txPool,
+		events:       filters.NewEventSystem(blockChain),
 		miner:        miner.New(mineCfg, blockChain, txPool, engine),
-		pm:           synchronise.NewProtocolManager(configFromFile.ChainID, deputynode.GetSelfNodeID(), blockChain, txPool),
+		pm:           synchronise.NewProtocolManager(configFromFile.ChainID, deputynode.GetSelfNodeID(), blockChain, txPool, syncMode),
 		genesisBlock: genesisBlock,
 	}
 	// set Founder for next block
@@ -208,6 +233,9 @@ func (n *Node) Start() error {
 		log.Errorf("%v", err)
 		return ErrServerStartFailed
 	}
+	if err := n.txPool.StartJournal(filepath.Join(n.config.DataDir, "transactions.rlp")); err != nil {
+		log.Warnf("Can't start transaction journal: %v", err)
+	}
 	n.pm.Start()
 	n.server = server
 	n.stop = make(chan struct{})
@@ -234,7 +262,7 @@ func (n *Node) startRPC() error {
 		n.stopInProc()
 		return err
 	}
-	if err := n.startWS(apis); err != nil {
+	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
 		n.stopHTTP()
 		n.stopIPC()
 		n.stopInProc()
@@ -360,12 +388,52 @@ func (n *Node) stopHTTP() {
 	}
 }
 
-func (n *Node) startWS(apis []rpc.API) error {
+// startWS serves the public APIs of whitelisted modules over websocket, so that the subscriptions are available to
+// dApps. All APIs are served if exposeAll is true
+func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool) error {
+	// Short circuit if the WS endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	whitelist := make(map[string]bool)
+	for _, module := range modules {
+		whitelist[module] = true
+	}
+	handler := rpc.NewServer()
+	for _, api := range apis {
+		if exposeAll || (api.Public && whitelist[api.Namespace]) {
+			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+				return err
+			}
+		}
+	}
+	var (
+		listener net.Listener
+		err      error
+	)
+	if listener, err = net.Listen("tcp", endpoint); err != nil {
+		return err
+	}
+	go (&http.Server{Handler: handler.WebsocketHandler(wsOrigins)}).Serve(listener)
+	log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()), "modules", strings.Join(modules, ","), "origins", strings.Join(wsOrigins, ","))
+	n.wsEndpoint = endpoint
+	n.wsListener = listener
+	n.wsHandler = handler
+
 	return nil
 }
 
 func (n *Node) stopWS() {
+	if n.wsListener != nil {
+		n.wsListener.Close()
+		n.wsListener = nil
 
+		log.Info("WebSocket endpoint closed", "url", fmt.Sprintf("ws://%s", n.wsEndpoint))
+	}
+	if n.wsHandler != nil {
+		n.wsHandler.Stop()
+		n.wsHandler = nil
+	}
 }
 
 func (n *Node) stopRPC() {
@@ -416,7 +484,8 @@ func (n *Node) Stop() error {
 func (n *Node) stopChain() error {
 	n.chain.Stop()
 	n.pm.Stop()
-	// n.txPool.Stop()
+	n.txPool.Stop()
+	n.events.Stop()
 	n.miner.Close()
 	if err := n.db.Close(); err != nil {
 		return err
@@ -500,13 +569,13 @@ func (n *Node) apis() []rpc.API {
 		{
 			Namespace: "account",
 			Version:   "1.0",
-			Service:   NewPublicAccountAPI(n.accMan),
+			Service:   NewPublicAccountAPI(n.chain, n.accMan),
 			Public:    true,
 		},
 		{
 			Namespace: "account",
 			Version:   "1.0",
-			Service:   NewPrivateAccountAPI(n.accMan),
+			Service:   NewPrivateAccountAPI(n.accMan, n.keystore),
 			Public:    false,
 		},
 		{
@@ -524,8 +593,26 @@ func (n *Node) apis() []rpc.API {
 		{
 			Namespace: "tx",
 			Version:   "1.0",
-			Service:   NewPublicTxAPI(n.txPool),
+			Service:   NewPublicTxAPI(n.chain, n.txPool),
+			Public:    true,
+		},
+		{
+			Namespace: "tx",
+			Version:   "1.0",
+			Service:   NewPrivateTxAPI(n.chain, n.txPool, n.keystore),
+			Public:    false,
+		},
+		{
+			Namespace: "filter",
+			Version:   "1.0",
+			Service:   NewPublicFilterAPI(n.chain, n.events),
 			Public:    true,
 		},
+		{
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   NewPrivateDebugAPI(n.chain),
+			Public:    false,
+		},
 	}
 }
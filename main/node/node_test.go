@@ -0,0 +1,119 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/LemoFoundationLtd/lemochain-go/network/rpc"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+	"testing"
+	"time"
+)
+
+// EchoAPI is a public api for test
+type EchoAPI struct{}
+
+func (api *EchoAPI) Echo(s string) string {
+	return s
+}
+
+// Ticks sends one notification after subscribed
+func (api *EchoAPI) Ticks(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		notifier.Notify(sub.ID, "tick")
+	}()
+	return sub, nil
+}
+
+// SecretAPI is a private api for test
+type SecretAPI struct{}
+
+func (api *SecretAPI) Secret() string {
+	return "secret"
+}
+
+type wsTestMessage struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Params struct {
+		Result string `json:"result"`
+	} `json:"params"`
+}
+
+func wsCall(t *testing.T, conn *websocket.Conn, method string, params ...interface{}) *wsTestMessage {
+	req := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params}
+	assert.NoError(t, websocket.JSON.Send(conn, req))
+	var resp wsTestMessage
+	assert.NoError(t, websocket.JSON.Receive(conn, &resp))
+	return &resp
+}
+
+func TestNode_startWS(t *testing.T) {
+	apis := []rpc.API{
+		{Namespace: "chain", Version: "1.0", Service: &EchoAPI{}, Public: true},
+		{Namespace: "chain", Version: "1.0", Service: &SecretAPI{}, Public: false},
+		{Namespace: "debug", Version: "1.0", Service: &EchoAPI{}, Public: true},
+	}
+	n := &Node{config: &Config{}}
+	assert.NoError(t, n.startWS("", apis, []string{"chain"}, nil, false))
+	assert.Nil(t, n.wsListener)
+	assert.NoError(t, n.startWS("127.0.0.1:0", apis, []string{"chain"}, []string{"http://lemochain.com"}, false))
+	defer n.stopWS()
+	url := "ws://" + n.wsListener.Addr().String()
+
+	// origin is not allowed
+	_, err := websocket.Dial(url, "", "http://other.com")
+	assert.Error(t, err)
+
+	conn, err := websocket.Dial(url, "", "http://lemochain.com")
+	assert.NoError(t, err)
+	defer conn.Close()
+	resp := wsCall(t, conn, "chain_echo", "hello")
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, `"hello"`, string(resp.Result))
+	// private api is not served
+	resp = wsCall(t, conn, "chain_secret")
+	assert.NotNil(t, resp.Error)
+	// module is not in whitelist
+	resp = wsCall(t, conn, "debug_echo", "hello")
+	assert.NotNil(t, resp.Error)
+
+	// subscription
+	resp = wsCall(t, conn, "chain_subscribe", "ticks")
+	assert.Nil(t, resp.Error)
+	var notification wsTestMessage
+	assert.NoError(t, websocket.JSON.Receive(conn, &notification))
+	assert.Equal(t, "chain_subscription", notification.Method)
+	assert.Equal(t, "tick", notification.Params.Result)
+
+	n.stopWS()
+	assert.Nil(t, n.wsListener)
+	_, err = websocket.Dial(url, "", "http://lemochain.com")
+	assert.Error(t, err)
+}
+
+func TestNode_startWS_exposeAll(t *testing.T) {
+	apis := []rpc.API{
+		{Namespace: "chain", Version: "1.0", Service: &SecretAPI{}, Public: false},
+	}
+	n := &Node{config: &Config{}}
+	assert.NoError(t, n.startWS("127.0.0.1:0", apis, nil, []string{"*"}, true))
+	defer n.stopWS()
+
+	conn, err := websocket.Dial("ws://"+n.wsListener.Addr().String(), "", "http://lemochain.com")
+	assert.NoError(t, err)
+	defer conn.Close()
+	resp := wsCall(t, conn, "chain_secret")
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, `"secret"`, string(resp.Result))
+}
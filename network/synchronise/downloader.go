@@ -1,6 +1,7 @@
 package synchronise
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
@@ -8,8 +9,8 @@ import (
 	"github.com/LemoFoundationLtd/lemochain-go/common/log"
 	"github.com/LemoFoundationLtd/lemochain-go/network/synchronise/blockchain"
 	"github.com/LemoFoundationLtd/lemochain-go/store"
-	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
-	"strings"
+	"math"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,6 +18,13 @@ import (
 
 const (
 	requestTimeout = 15 * time.Second
+
+	segmentSize       = 50  // 骨架中相邻区块头的间隔，即每次向peer请求的区块数
+	maxSkeletonSize   = 128 // 每次请求的骨架区块头数量
+	maxHeaderFetch    = 192 // 每次应答的区块头数量上限
+	maxQueuedSegments = 32  // 已下载但未插入本地链的区段数量上限
+	maxPeerTimeouts   = 3   // peer连续超时的次数上限
+	throughputImpact  = 0.1 // 新的吞吐量测量值对peer吞吐量的影响
 )
 
 var (
@@ -25,6 +33,10 @@ var (
 	errBadPeer       = errors.New("bad peer ignored")
 	errForceQuit     = errors.New("force quitCh")
 	errUnknownParent = errors.New("Unknown Parent")
+
+	errNoPeers         = errors.New("no peer to download blocks")
+	errInvalidSkeleton = errors.New("invalid header skeleton")
+	errInvalidBlocks   = errors.New("blocks don't match the header skeleton")
 )
 
 // peerConnection 一个网络连接对象
@@ -32,6 +44,38 @@ type peerConnection struct {
 	id    string
 	peer  *peer
 	rwMux sync.RWMutex
+
+	throughput float64 // 每秒下载的区块数
+	timeouts   int     // 连续超时次数
+}
+
+// Throughput 获取peer每秒下载的区块数
+func (p *peerConnection) Throughput() float64 {
+	p.rwMux.RLock()
+	defer p.rwMux.RUnlock()
+	return p.throughput
+}
+
+// updateThroughput 根据一次成功的下载更新peer的吞吐量
+func (p *peerConnection) updateThroughput(count int, elapsed time.Duration) {
+	p.rwMux.Lock()
+	defer p.rwMux.Unlock()
+	measured := float64(count) / math.Max(elapsed.Seconds(), 0.001)
+	if p.throughput == 0 {
+		p.throughput = measured
+	} else {
+		p.throughput = (1-throughputImpact)*p.throughput + throughputImpact*measured
+	}
+	p.timeouts = 0
+}
+
+// timeout 记录一次超时，返回连续超时次数
+func (p *peerConnection) timeout() int {
+	p.rwMux.Lock()
+	defer p.rwMux.Unlock()
+	p.throughput = 0
+	p.timeouts++
+	return p.timeouts
 }
 
 // peerSet 网络连接节点集
@@ -52,6 +96,89 @@ type blockPack struct {
 	blocks types.Blocks
 }
 
+// segment 骨架中两个相邻区块头之间的区段，包括结尾的区块头
+type segment struct {
+	index  int
+	from   uint32
+	to     uint32
+	parent common.Hash // 区段之前一个区块的hash
+	last   common.Hash // 区段最后一个区块的hash
+}
+
+// newSegments 根据区块头骨架划分区段
+func newSegments(from uint32, parent common.Hash, skeleton []*types.Header) []*segment {
+	segments := make([]*segment, len(skeleton))
+	for i, header := range skeleton {
+		segments[i] = &segment{index: i, from: from, to: header.Height, parent: parent, last: header.Hash()}
+		from, parent = header.Height+1, segments[i].last
+	}
+	return segments
+}
+
+// verify 校验下载的区块是否首尾相连并与骨架吻合，且区块体与区块头一致
+func (s *segment) verify(blocks types.Blocks) error {
+	if uint32(len(blocks)) != s.to-s.from+1 {
+		return errInvalidBlocks
+	}
+	parent := s.parent
+	for i, block := range blocks {
+		if block == nil || block.Header == nil || block.Height() != s.from+uint32(i) || block.ParentHash() != parent {
+			return errInvalidBlocks
+		}
+		if types.DeriveTxsSha(block.Txs) != block.Header.TxRoot {
+			return errInvalidBlocks
+		}
+		if len(block.DeputyNodes) == 0 && len(block.Header.DeputyRoot) > 0 {
+			return errInvalidBlocks
+		}
+		if len(block.DeputyNodes) > 0 {
+			hash := types.DeriveDeputyRootSha(block.DeputyNodes)
+			if !bytes.Equal(hash[:], block.Header.DeputyRoot) {
+				return errInvalidBlocks
+			}
+		}
+		if (block.Finality == nil) != (len(block.Header.FinalityRoot) == 0) {
+			return errInvalidBlocks
+		}
+		if block.Finality != nil && !bytes.Equal(block.Finality.Root().Bytes(), block.Header.FinalityRoot) {
+			return errInvalidBlocks
+		}
+		parent = block.Hash()
+	}
+	if parent != s.last {
+		return errInvalidBlocks
+	}
+	return nil
+}
+
+// requeueSegment 将下载失败的区段按顺序放回待下载队列
+func requeueSegment(pending []*segment, seg *segment) []*segment {
+	i := sort.Search(len(pending), func(i int) bool { return pending[i].index > seg.index })
+	pending = append(pending, nil)
+	copy(pending[i+1:], pending[i:])
+	pending[i] = seg
+	return pending
+}
+
+// segmentRequest 正在下载的区段
+type segmentRequest struct {
+	seg  *segment
+	peer *peerConnection
+	time time.Time
+}
+
+// segmentResult 下载完成的区段
+type segmentResult struct {
+	peerID string
+	blocks types.Blocks
+}
+
+// headerPack 区块头包
+type headerPack struct {
+	peerID  string
+	headers []*types.Header
+}
+
 // BestPeer get peer with highest block
 func (ps *peerSet) BestPeer() *peerConnection {
 	var p *peerConnection
@@ -89,6 +216,20 @@ func (ps *peerSet) Unregister(id string) {
 	}
 }
 
+// IdlePeers 获取没有下载任务的peer，按吞吐量从高到低排序
+func (ps *peerSet) IdlePeers(active map[string]*segmentRequest) []*peerConnection {
+	ps.mux.Lock()
+	list := make([]*peerConnection, 0, len(ps.peers))
+	for id, p := range ps.peers {
+		if _, ok := active[id]; !ok {
+			list = append(list, p)
+		}
+	}
+	ps.mux.Unlock()
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Throughput() > list[j].Throughput() })
+	return list
+}
+
 // PeersWithoutTx fetch peers which doesn't have special tx
 func (ps *peerSet) PeersWithoutTx(hash common.Hash) []*peer {
 	ps.mux.Lock()
@@ -111,26 +252,29 @@ type Downloader struct {
 	blockChain    blockchain.BlockChain
 	dropPeer      peerDropFn // 断开连接
 
-	newBlocksCh chan *blockPack   // 通过网络收到区块包
-	blockDoneCh chan *types.Block // 区块处理完毕
-	insertErrCh chan error        // 区块入链出错
-	quitCh      chan struct{}     // 退出
+	mode         SyncMode       // 同步模式
+	newStateSync newStateSyncFn // 创建快速同步的状态下载器
 
-	queueLock sync.Mutex
-	queue     *prque.Prque // 存储下载的区块队列
+	newBlocksCh   chan *blockPack       // 通过网络收到区块包
+	headersCh     chan *headerPack      // 通过网络收到区块头骨架
+	stableStateCh chan *stableStatePack // 通过网络收到稳定块状态
+	nodeDataCh    chan *nodeDataPack    // 通过网络收到trie节点或合约代码
+	quitCh        chan struct{}         // 退出
 }
 
 // New crete Downloader object
-func NewDownloader(peers *peerSet, chain blockchain.BlockChain, dropPeer peerDropFn) *Downloader {
+func NewDownloader(peers *peerSet, chain blockchain.BlockChain, mode SyncMode, newStateSync newStateSyncFn, dropPeer peerDropFn) *Downloader {
 	d := &Downloader{
-		peers:       peers,
-		blockChain:  chain,
-		dropPeer:    dropPeer,
-		newBlocksCh: make(chan *blockPack),
-		blockDoneCh: make(chan *types.Block),
-		insertErrCh: make(chan error),
-		quitCh:      make(chan struct{}),
-		queue:       prque.New(),
+		peers:         peers,
+		blockChain:    chain,
+		dropPeer:      dropPeer,
+		mode:          mode,
+		newStateSync:  newStateSync,
+		newBlocksCh:   make(chan *blockPack),
+		headersCh:     make(chan *headerPack, 1),
+		stableStateCh: make(chan *stableStatePack, 1),
+		nodeDataCh:    make(chan *nodeDataPack, 1),
+		quitCh:        make(chan struct{}),
 	}
 	return d
 }
@@ -151,72 +295,137 @@ func (d *Downloader) Synchronise(id string) error {
 	if p == nil {
 		return errors.New(fmt.Sprintf("can't get special peer. id: %s", id))
 	}
+	if d.mode == FastSync && d.newStateSync != nil {
+		if err := d.fastSyncWithPeer(p); err != nil {
+			log.Warnf("Fast sync failed. peer: %s, err: %v", id[:16], err)
+			if err != errForceQuit && d.dropPeer != nil {
+				d.dropPeer(p.id)
+			}
+			return err
+		}
+	}
 	return d.syncWithPeer(p)
 }
 
-// syncWithPeer 从某peer同步，同步时阻塞，直至同步完成
+// syncWithPeer 从某peer同步，同步时阻塞，直至同步完成。先从该peer获取区块头骨架，再从多个peer并行下载骨架之间的区块
 func (d *Downloader) syncWithPeer(p *peerConnection) error {
 	stableBlock := d.blockChain.StableBlock()
-	remoteHeight := p.peer.height
-	if stableBlock.Height() >= remoteHeight {
-		return nil
+	from, parent := stableBlock.Height()+1, stableBlock.Hash()
+	for {
+		_, remoteHeight := p.peer.Head()
+		if from > remoteHeight {
+			return nil
+		}
+		to := remoteHeight
+		if to-from >= maxSkeletonSize*segmentSize {
+			to = from + maxSkeletonSize*segmentSize - 1
+		}
+		skeleton, err := d.fetchSkeleton(p, from, to)
+		if err != nil {
+			if err != errForceQuit {
+				log.Infof("Fetch header skeleton failed, drop peer. err: %v", err)
+				if d.dropPeer != nil {
+					d.dropPeer(p.id)
+				}
+			}
+			return err
+		}
+		if err = d.fillSkeleton(newSegments(from, parent, skeleton)); err != nil {
+			return err
+		}
+		last := skeleton[len(skeleton)-1]
+		from, parent = last.Height+1, last.Hash()
 	}
-	// 发送获取区块请求
-	go p.peer.RequestBlockFromAndTo(stableBlock.Height()+1, remoteHeight)
-	// 请求超时定时器
+}
+
+// fetchSkeleton 获取区块头骨架，即[from, to]之间以to结尾、间隔为segmentSize的区块头
+func (d *Downloader) fetchSkeleton(p *peerConnection, from, to uint32) ([]*types.Header, error) {
+	first := from + (to-from)%segmentSize
+	go p.peer.RequestBlockHeaders(first, to, segmentSize)
 	timeout := time.NewTimer(requestTimeout)
 	defer timeout.Stop()
-	errMsgCh := make(chan string)
-	doneCh := make(chan struct{})
-	go func() {
-		for {
-			select {
-			case <-doneCh:
-				return
-			default:
+	for {
+		select {
+		case pack := <-d.headersCh:
+			if pack.peerID != p.id {
+				continue
+			}
+			if uint32(len(pack.headers)) != (to-first)/segmentSize+1 {
+				return nil, errInvalidSkeleton
 			}
-			// 处理已收到的区块
-			for !d.queue.Empty() {
-				d.queueLock.Lock()
-				block := d.queue.PopItem().(*types.Block)
-				localHeight := d.blockChain.CurrentBlock().Height()
-				if block.Height() > localHeight+1 || !d.blockChain.HasBlock(block.ParentHash()) {
-					d.queue.Push(block, -float32(block.Height()))
-					break
+			for i, header := range pack.headers {
+				if header == nil || header.Height != first+uint32(i)*segmentSize {
+					return nil, errInvalidSkeleton
 				}
-				d.queueLock.Unlock()
-				d.insert(block, p.id)
 			}
+			return pack.headers, nil
+		case <-timeout.C:
+			return nil, errTimeout
+		case <-d.quitCh:
+			return nil, errForceQuit
 		}
-	}()
+	}
+}
 
-	for {
+// fillSkeleton 从多个peer并行下载各个区段的区块，按顺序插入本地链。超时或数据非法的区段会重新分配给其他peer
+func (d *Downloader) fillSkeleton(segments []*segment) error {
+	pending := make([]*segment, len(segments))
+	copy(pending, segments)
+	active := make(map[string]*segmentRequest)
+	results := make(map[int]*segmentResult)
+	next, inserted := 0, 0
+
+	insertCh := make(chan *segmentResult, maxQueuedSegments)
+	doneCh := make(chan struct{}, maxQueuedSegments)
+	errCh := make(chan error, 1)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go d.insertSegments(insertCh, doneCh, errCh, stopCh)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for inserted < len(segments) {
+		pending = d.assignSegments(pending, active, inserted+maxQueuedSegments)
+		if len(active) == 0 && len(pending) > 0 && next == inserted {
+			return errNoPeers
+		}
 		select {
-		case blockPack := <-d.newBlocksCh: // 接收到网络新块
-			if strings.Compare(blockPack.peerID, p.id) == 0 {
-				d.enqueue(blockPack.blocks)
-				timeout.Reset(requestTimeout)
+		case pack := <-d.newBlocksCh:
+			req := active[pack.peerID]
+			// 忽略广播的新区块以及超时后才收到的区块
+			if req == nil || len(pack.blocks) == 0 || pack.blocks[0] == nil || pack.blocks[0].Height() != req.seg.from {
+				continue
 			}
-		case block := <-d.blockDoneCh: // 插入本地链成功
-			if block.Height() >= remoteHeight {
-				doneCh <- struct{}{}
-				return nil
+			delete(active, pack.peerID)
+			if err := req.seg.verify(pack.blocks); err != nil {
+				log.Infof("Receive invalid blocks, drop peer. peer: %s, from: %d, to: %d, err: %v", pack.peerID[:16], req.seg.from, req.seg.to, err)
+				d.penalise(req.peer)
+				pending = requeueSegment(pending, req.seg)
+				continue
 			}
-		case <-timeout.C: // 接收超时
-			log.Infof("Sync with peer timeout, drop peer.")
-			if d.dropPeer != nil {
-				d.dropPeer(p.id)
-			}
-			return errBadPeer
-		case err := <-d.insertErrCh: // 插入链出错
-			log.Infof("Insert chain err. drop peer")
-			if d.dropPeer != nil {
-				d.dropPeer(p.id)
+			req.peer.updateThroughput(len(pack.blocks), time.Since(req.time))
+			results[req.seg.index] = &segmentResult{peerID: pack.peerID, blocks: pack.blocks}
+			for result, ok := results[next]; ok; result, ok = results[next] {
+				delete(results, next)
+				insertCh <- result
+				next++
 			}
+		case <-doneCh:
+			inserted++
+		case err := <-errCh:
 			return err
-		case errMsg := <-errMsgCh:
-			log.Warn(errMsg)
-			return errors.New(errMsg)
+		case <-ticker.C:
+			for id, req := range active {
+				if d.peers.Peer(id) != nil && time.Since(req.time) < requestTimeout {
+					continue
+				}
+				delete(active, id)
+				pending = requeueSegment(pending, req.seg)
+				if req.peer.timeout() >= maxPeerTimeouts {
+					log.Infof("Download blocks timeout, drop peer. peer: %s", id[:16])
+					d.penalise(req.peer)
+				}
+			}
 		case <-d.quitCh:
 			return errForceQuit
 		}
@@ -224,23 +433,52 @@ func (d *Downloader) syncWithPeer(p *peerConnection) error {
 	return nil
 }
 
-// enqueue 经区块集合压入带处理队列中
-func (d *Downloader) enqueue(blocks types.Blocks) {
-	d.queueLock.Lock()
-	defer d.queueLock.Unlock()
-	for _, block := range blocks {
-		d.queue.Push(block, -float32(block.Height()))
+// assignSegments 将待下载的区段分配给空闲的peer，吞吐量高的peer优先获得靠前的区段。返回仍未分配的区段
+func (d *Downloader) assignSegments(pending []*segment, active map[string]*segmentRequest, limit int) []*segment {
+	for _, p := range d.peers.IdlePeers(active) {
+		_, height := p.peer.Head()
+		for i, seg := range pending {
+			if seg.index >= limit {
+				break
+			}
+			if seg.to > height {
+				continue
+			}
+			pending = append(pending[:i], pending[i+1:]...)
+			active[p.id] = &segmentRequest{seg: seg, peer: p, time: time.Now()}
+			go p.peer.RequestBlockFromAndTo(seg.from, seg.to)
+			break
+		}
 	}
+	return pending
 }
 
-// insert 将块插入本地连
-func (d *Downloader) insert(block *types.Block, peer string) {
-	err := d.blockChain.InsertChain(block, true)
-	if err == nil || err == store.ErrExist {
-		go func() { d.blockDoneCh <- block }()
-	} else {
-		go func() { d.insertErrCh <- err }()
-		log.Debugf("block import failed. peer: %s. height: %d. hash: %s. err: %v", peer, block.Height(), block.Hash().Hex(), err)
+// insertSegments 按顺序将下载好的区段插入本地链
+func (d *Downloader) insertSegments(insertCh chan *segmentResult, doneCh chan struct{}, errCh chan error, stopCh chan struct{}) {
+	for {
+		select {
+		case result := <-insertCh:
+			for _, block := range result.blocks {
+				if err := d.blockChain.InsertChain(block, true); err != nil && err != store.ErrExist {
+					log.Infof("Insert chain err, drop peer. height: %d, hash: %s, err: %v", block.Height(), block.Hash().Hex(), err)
+					if p := d.peers.Peer(result.peerID); p != nil {
+						d.penalise(p)
+					}
+					errCh <- err
+					return
+				}
+			}
+			doneCh <- struct{}{}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// penalise 断开发送了非法数据或多次超时的peer
+func (d *Downloader) penalise(p *peerConnection) {
+	if d.dropPeer != nil {
+		d.dropPeer(p.id)
 	}
 }
 
@@ -257,6 +495,15 @@ func (d *Downloader) DeliverBlocks(id string, blocks types.Blocks) error {
 	return nil
 }
 
+// DeliverHeaders 将收到的区块头骨架分发给loop
+func (d *Downloader) DeliverHeaders(id string, headers []*types.Header) {
+	select {
+	case d.headersCh <- &headerPack{peerID: id, headers: headers}:
+	default:
+		log.Debugf("drop unrequested headers. peer: %s", id[:16])
+	}
+}
+
 // Terminate 强行终止同步
 func (d *Downloader) Terminate() {
 	select {
@@ -0,0 +1,96 @@
+package synchronise
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// makeTestBlocks creates a chain of blocks from height 1 to count
+func makeTestBlocks(count int, parent common.Hash) types.Blocks {
+	blocks := make(types.Blocks, 0, count)
+	for i := 1; i <= count; i++ {
+		header := &types.Header{
+			ParentHash: parent,
+			Height:     uint32(i),
+			TxRoot:     types.DeriveTxsSha(nil),
+			Time:       uint32(i),
+		}
+		block := &types.Block{Header: header}
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+	return blocks
+}
+
+func Test_newSegments(t *testing.T) {
+	blocks := makeTestBlocks(120, common.HexToHash("0x1234"))
+	// the skeleton of [1, 120] ends at 120
+	skeleton := []*types.Header{blocks[19].Header, blocks[69].Header, blocks[119].Header}
+	segments := newSegments(1, common.HexToHash("0x1234"), skeleton)
+	assert.Len(t, segments, 3)
+	assert.Equal(t, uint32(1), segments[0].from)
+	assert.Equal(t, uint32(20), segments[0].to)
+	assert.Equal(t, uint32(21), segments[1].from)
+	assert.Equal(t, blocks[19].Hash(), segments[1].parent)
+	assert.Equal(t, blocks[69].Hash(), segments[1].last)
+
+	for i, seg := range segments {
+		assert.Equal(t, i, seg.index)
+		assert.NoError(t, seg.verify(blocks[seg.from-1:seg.to]))
+	}
+	// wrong range
+	assert.Equal(t, errInvalidBlocks, segments[1].verify(blocks[20:69]))
+	assert.Equal(t, errInvalidBlocks, segments[1].verify(blocks[21:71]))
+	// not match the skeleton
+	assert.Equal(t, errInvalidBlocks, segments[1].verify(makeTestBlocks(70, common.HexToHash("0x5678"))[20:70]))
+	// body doesn't match header
+	bad := make(types.Blocks, 50)
+	copy(bad, blocks[20:70])
+	bad[10] = &types.Block{Header: bad[10].Header, Txs: []*types.Transaction{types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil, 1, 0, "", "")}}
+	assert.Equal(t, errInvalidBlocks, segments[1].verify(bad))
+	bad[10] = nil
+	assert.Equal(t, errInvalidBlocks, segments[1].verify(bad))
+}
+
+func Test_requeueSegment(t *testing.T) {
+	pending := []*segment{{index: 1}, {index: 4}}
+	pending = requeueSegment(pending, &segment{index: 0})
+	pending = requeueSegment(pending, &segment{index: 3})
+	pending = requeueSegment(pending, &segment{index: 5})
+	indexes := make([]int, len(pending))
+	for i, seg := range pending {
+		indexes[i] = seg.index
+	}
+	assert.Equal(t, []int{0, 1, 3, 4, 5}, indexes)
+}
+
+func Test_peerThroughput(t *testing.T) {
+	ps := newPeerSet()
+	slow := &peerConnection{id: "slow"}
+	fast := &peerConnection{id: "fast"}
+	idle := &peerConnection{id: "idle"}
+	ps.Register(slow)
+	ps.Register(fast)
+	ps.Register(idle)
+
+	slow.updateThroughput(50, 10*time.Second)
+	assert.Equal(t, float64(5), slow.Throughput())
+	fast.updateThroughput(50, time.Second)
+	fast.updateThroughput(50, 10*time.Second)
+	assert.InDelta(t, 45.5, fast.Throughput(), 0.0001)
+	peers := ps.IdlePeers(map[string]*segmentRequest{})
+	assert.Equal(t, []*peerConnection{fast, slow, idle}, peers)
+	peers = ps.IdlePeers(map[string]*segmentRequest{"fast": {}})
+	assert.Equal(t, []*peerConnection{slow, idle}, peers)
+
+	// timeout resets the throughput
+	assert.Equal(t, 1, fast.timeout())
+	assert.Equal(t, 2, fast.timeout())
+	assert.Equal(t, float64(0), fast.Throughput())
+	fast.updateThroughput(50, time.Second)
+	assert.Equal(t, 1, fast.timeout())
+}
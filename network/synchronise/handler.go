@@ -34,16 +34,18 @@ type ProtocolManager struct {
 	txsCh           chan types.Transactions
 	newMinedBlockCh chan *types.Block
 	stableBlockCh   chan *types.Block
+	evidenceCh      chan *types.Evidence
 	txsSub          subscribe.Subscription
 	minedBlockSub   subscribe.Subscription
 	stableBlockSub  subscribe.Subscription
+	evidenceSub     subscribe.Subscription
 
 	quitSync chan struct{}
 
 	wg sync.WaitGroup
 }
 
-func NewProtocolManager(chainID uint64, nodeID []byte, blockchain *chain.BlockChain, txpool *chain.TxPool) *ProtocolManager {
+func NewProtocolManager(chainID uint64, nodeID []byte, blockchain *chain.BlockChain, txpool *chain.TxPool, mode SyncMode) *ProtocolManager {
 	manager := &ProtocolManager{
 		chainID:         chainID,
 		nodeID:          nodeID,
@@ -54,6 +56,7 @@ func NewProtocolManager(chainID uint64, nodeID []byte, blockchain *chain.BlockCh
 		txsCh:           make(chan types.Transactions, 10),
 		newMinedBlockCh: make(chan *types.Block, 1),
 		stableBlockCh:   make(chan *types.Block, 1),
+		evidenceCh:      make(chan *types.Evidence, 10),
 		quitSync:        make(chan struct{}),
 	}
 	// 获取本地链高度
@@ -69,7 +72,15 @@ func NewProtocolManager(chainID uint64, nodeID []byte, blockchain *chain.BlockCh
 		return blockchain.InsertChain(block, false)
 	}
 	manager.fetcher = NewFetcher(blockchain.HasBlock, manager.broadcastCurrentBlock, getLocalHeight, getConsensusHeight, insertToChain, manager.dropPeer)
-	manager.downloader = NewDownloader(manager.peers, blockchain, manager.dropPeer)
+	// 创建快速同步的状态下载器
+	newStateSync := func(pivot *types.Block, accounts []*types.AccountData) (stateSync, error) {
+		s, err := blockchain.NewStateSync(pivot, accounts)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	manager.downloader = NewDownloader(manager.peers, blockchain, mode, newStateSync, manager.dropPeer)
 
 	blockchain.BroadcastConfirmInfo = manager.broadcastConfirmInfo // 广播区块的确认信息
 	// blockchain.BroadcastStableBlock = manager.broadcastStableBlock // 广播稳定区块
@@ -149,6 +160,11 @@ func (pm *ProtocolManager) broadcastConfirmInfo(hash common.Hash, height uint32)
 		Hash:   hash,
 		Height: height,
 	}
+	// 同一高度只签名一个区块，否则会被惩罚
+	if !pm.blockchain.TrySign(height, hash) {
+		log.Debugf("refuse to confirm another block at height %d", height)
+		return
+	}
 	privateKey := deputynode.GetSelfNodeKey()
 	signInfo, err := crypto.Sign(hash[:], privateKey)
 	if err != nil {
@@ -276,7 +292,7 @@ func (pm *ProtocolManager) handleMsg(p *peerConnection) error {
 		if query.From > query.To {
 			return errResp(protocol.ErrInvalidMsg, "%v: %s", msg, "from > to")
 		}
-		total := query.To - query.From
+		total := query.To - query.From + 1
 		var count uint32
 		if total%eachSize == 0 {
 			count = total / eachSize
@@ -316,6 +332,38 @@ func (pm *ProtocolManager) handleMsg(p *peerConnection) error {
 				log.Debug("failed to deliver blocks", "err", err)
 			}
 		}
+	case protocol.GetBlockHeadersMsg: // 收到获取区块头骨架的消息
+		var query protocol.GetBlockHeadersData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(protocol.ErrDecode, "%v: %v", msg, err)
+		}
+		if query.From > query.To || query.Step == 0 {
+			return errResp(protocol.ErrInvalidMsg, "%v: %s", msg, "invalid headers range")
+		}
+		headers := make([]*types.Header, 0)
+		for height := query.From; len(headers) < maxHeaderFetch; height += query.Step {
+			block, err := pm.blockchain.LoadBlockByHeight(height)
+			if err != nil {
+				log.Warnf("Load block for headers failed. height: %d, err: %v", height, err)
+				break
+			}
+			if block == nil {
+				break
+			}
+			headers = append(headers, block.Header)
+			if query.To-height < query.Step {
+				break
+			}
+		}
+		if err := p.peer.send(protocol.BlockHeadersMsg, headers); err != nil {
+			log.Debug("send block headers message failed.")
+		}
+	case protocol.BlockHeadersMsg: // 远程节点应答的区块头骨架
+		var headers []*types.Header
+		if err := msg.Decode(&headers); err != nil {
+			return errResp(protocol.ErrDecode, "%v: %v", msg, err)
+		}
+		pm.downloader.DeliverHeaders(p.id, headers)
 	case protocol.GetSingleBlockMsg: // 收到一个获取区块的消息
 		var query protocol.GetSingleBlockData
 		if err := msg.Decode(&query); err != nil {
@@ -389,6 +437,54 @@ func (pm *ProtocolManager) handleMsg(p *peerConnection) error {
 			return errResp(protocol.ErrDecode, "%v: %v", msg, err)
 		}
 		pm.blockchain.ReceiveConfirms(pack)
+	case protocol.GetStableStateMsg: // 收到获取稳定块状态的请求
+		var query protocol.GetStableStateData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(protocol.ErrDecode, "%v: %v", msg, err)
+		}
+		// 每次只应答不超过maxStableStateBytes的账户，避免遍历整个状态
+		block, accounts, next, err := pm.blockchain.StableState(query.Pivot, query.Start, maxStableStateBytes)
+		if err != nil {
+			log.Debugf("can't get stable state. err: %v", err)
+			return nil
+		}
+		if err := p.peer.send(protocol.StableStateMsg, &protocol.StableStateData{Block: block, Accounts: accounts, Next: next}); err != nil {
+			log.Debug("send stable state message failed.")
+		}
+	case protocol.StableStateMsg: // 收到稳定块状态的答复
+		var state protocol.StableStateData
+		if err := msg.Decode(&state); err != nil {
+			return errResp(protocol.ErrDecode, "%v: %v", msg, err)
+		}
+		if state.Block == nil {
+			return errResp(protocol.ErrInvalidMsg, "%v: %s", msg, "no stable block")
+		}
+		pm.downloader.DeliverStableState(p.id, &state)
+	case protocol.GetNodeDataMsg: // 收到获取trie节点或合约代码的请求
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(protocol.ErrDecode, "%v: %v", msg, err)
+		}
+		if len(hashes) > maxNodeDataFetch {
+			hashes = hashes[:maxNodeDataFetch]
+		}
+		if err := p.peer.send(protocol.NodeDataMsg, pm.blockchain.GetNodeData(hashes)); err != nil {
+			log.Debug("send node data message failed.")
+		}
+	case protocol.NodeDataMsg: // 收到trie节点或合约代码的答复
+		var data [][]byte
+		if err := msg.Decode(&data); err != nil {
+			return errResp(protocol.ErrDecode, "%v: %v", msg, err)
+		}
+		pm.downloader.DeliverNodeData(p.id, data)
+	case protocol.EvidenceMsg: // 收到共识节点作恶的证据
+		var evidence types.Evidence
+		if err := msg.Decode(&evidence); err != nil {
+			return errResp(protocol.ErrDecode, "%v: %v", msg, err)
+		}
+		if err := pm.blockchain.AddEvidence(&evidence); err != nil {
+			log.Debugf("receive invalid evidence: %v", err)
+		}
 	default:
 		return errors.New("can not math message type")
 	}
@@ -405,9 +501,11 @@ func (pm *ProtocolManager) Start() {
 	pm.txsSub = pm.txPool.NewTxsFeed.Subscribe(pm.txsCh)
 	pm.minedBlockSub = pm.blockchain.MinedBlockFeed.Subscribe(pm.newMinedBlockCh)
 	pm.stableBlockSub = pm.blockchain.StableBlockFeed.Subscribe(pm.stableBlockCh)
+	pm.evidenceSub = pm.blockchain.EvidenceFeed.Subscribe(pm.evidenceCh)
 
 	go pm.txBroadcastLoop()
 	go pm.blockBroadcastLoop()
+	go pm.evidenceLoop()
 	go pm.syncer()
 }
 
@@ -416,6 +514,7 @@ func (pm *ProtocolManager) Stop() {
 	pm.txsSub.Unsubscribe()
 	pm.minedBlockSub.Unsubscribe()
 	pm.stableBlockSub.Unsubscribe()
+	pm.evidenceSub.Unsubscribe()
 	close(pm.quitSync)
 	pm.wg.Wait()
 	log.Info("ProtocolManager stop")
@@ -462,6 +561,39 @@ func (pm *ProtocolManager) blockBroadcastLoop() {
 	}
 }
 
+// evidenceLoop 广播共识节点作恶的证据，本节点为共识节点时提交惩罚交易
+func (pm *ProtocolManager) evidenceLoop() {
+	pm.wg.Add(1)
+	defer pm.wg.Done()
+	for {
+		select {
+		case evidence := <-pm.evidenceCh:
+			for _, p := range pm.peers.peers {
+				go p.peer.send(protocol.EvidenceMsg, evidence)
+			}
+			if pm.isSelfDeputyNode() {
+				pm.reportEvidence(evidence)
+			}
+		case <-pm.quitSync:
+			return
+		}
+	}
+}
+
+// reportEvidence 提交惩罚交易
+func (pm *ProtocolManager) reportEvidence(evidence *types.Evidence) {
+	privateKey := deputynode.GetSelfNodeKey()
+	nonce := pm.txPool.PendingNonce(crypto.PubkeyToAddress(privateKey.PublicKey))
+	tx, err := chain.NewEvidenceTx(uint16(pm.chainID), nonce, evidence, privateKey)
+	if err != nil {
+		log.Errorf("create evidence transaction failed: %v", err)
+		return
+	}
+	if err := pm.txPool.AddTx(tx); err != nil {
+		log.Warnf("add evidence transaction failed: %v", err)
+	}
+}
+
 // syncer 同步区块
 func (pm *ProtocolManager) syncer() {
 	pm.wg.Add(1)
@@ -162,12 +162,29 @@ func (p *peer) RequestBlockFromAndTo(from, to uint32) error {
 	return p.send(protocol.GetBlocksMsg, data)
 }
 
+// RequestBlockHeaders 请求区块头骨架
+func (p *peer) RequestBlockHeaders(from, to, step uint32) error {
+	data := &protocol.GetBlockHeadersData{From: from, To: to, Step: step}
+	return p.send(protocol.GetBlockHeadersMsg, data)
+}
+
 // RequestBlock 请求一个区块
 func (p *peer) RequestOneBlock(hash common.Hash, height uint32) error {
 	data := &protocol.GetSingleBlockData{Hash: hash, Height: height}
 	return p.send(protocol.GetSingleBlockMsg, data)
 }
 
+// RequestStableState 请求稳定块及其一页账户状态
+func (p *peer) RequestStableState(pivot, start common.Hash) error {
+	data := &protocol.GetStableStateData{Pivot: pivot, Start: start}
+	return p.send(protocol.GetStableStateMsg, data)
+}
+
+// RequestNodeData 请求trie节点或合约代码
+func (p *peer) RequestNodeData(hashes []common.Hash) error {
+	return p.send(protocol.GetNodeDataMsg, hashes)
+}
+
 // SendTransactions 发送交易
 func (p *peer) SendTransactions(txs types.Transactions) error {
 	for _, tx := range txs {
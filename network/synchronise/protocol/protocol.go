@@ -9,18 +9,25 @@ var ProtocolName = "lemo"
 
 // lemo 协议 codes
 const (
-	HeartbeatMsg      = 0x01 // 心跳
-	StatusMsg         = 0x02 // 用于握手时发送/接收当前节点状态包括版本号，genesis的hash，current的hash等
-	BlockHashesMsg    = 0x03 // block的hash集消息
-	TxMsg             = 0x04
-	GetBlocksMsg      = 0x05 // 获取区块集合消息
-	GetSingleBlockMsg = 0x06 // 获取一个区块
-	SingleBlockMsg    = 0x07 // 返回一个区块
-	BlocksMsg         = 0x08 // 区块集消息
-	NewBlockMsg       = 0x09 // 新的完整的block消息
-	NewConfirmMsg     = 0x0a // 新区块确认消息
-	GetConfirmInfoMsg = 0x0b // 获取确认包信息
-	ConfirmInfoMsg    = 0x0c // 收到确信包信息
+	HeartbeatMsg       = 0x01 // 心跳
+	StatusMsg          = 0x02 // 用于握手时发送/接收当前节点状态包括版本号，genesis的hash，current的hash等
+	BlockHashesMsg     = 0x03 // block的hash集消息
+	TxMsg              = 0x04
+	GetBlocksMsg       = 0x05 // 获取区块集合消息
+	GetSingleBlockMsg  = 0x06 // 获取一个区块
+	SingleBlockMsg     = 0x07 // 返回一个区块
+	BlocksMsg          = 0x08 // 区块集消息
+	NewBlockMsg        = 0x09 // 新的完整的block消息
+	NewConfirmMsg      = 0x0a // 新区块确认消息
+	GetConfirmInfoMsg  = 0x0b // 获取确认包信息
+	ConfirmInfoMsg     = 0x0c // 收到确信包信息
+	GetStableStateMsg  = 0x0d // 获取最新稳定块及其账户状态
+	StableStateMsg     = 0x0e // 最新稳定块及其账户状态
+	GetNodeDataMsg     = 0x0f // 获取trie节点或合约代码
+	NodeDataMsg        = 0x10 // trie节点或合约代码
+	GetBlockHeadersMsg = 0x11 // 获取区块头骨架
+	BlockHeadersMsg    = 0x12 // 区块头骨架
+	EvidenceMsg        = 0x13 // 共识节点在同一高度签名两个区块的证据
 
 )
 
@@ -78,6 +85,13 @@ type GetBlocksData struct {
 	To   uint32
 }
 
+// GetBlockHeadersData 获取区块头骨架，从From开始每隔Step个高度取一个区块头，直到To
+type GetBlockHeadersData struct {
+	From uint32
+	To   uint32
+	Step uint32
+}
+
 // GetSingleBlockData 单独获取一个区块
 type GetSingleBlockData struct {
 	Hash   common.Hash
@@ -103,3 +117,16 @@ type BlockConfirms struct {
 	Height uint32      //区块高度
 	Pack   []types.SignData
 }
+
+// GetStableStateData 分页获取稳定块状态中的账户。Pivot为空表示最新稳定块，Start为本页在version trie中的起始key
+type GetStableStateData struct {
+	Pivot common.Hash
+	Start common.Hash
+}
+
+// StableStateData 稳定块及其状态中的一页账户。Next为下一页的起始key，为空表示没有更多账户
+type StableStateData struct {
+	Block    *types.Block
+	Accounts []*types.AccountData
+	Next     common.Hash
+}
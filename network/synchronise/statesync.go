@@ -0,0 +1,214 @@
+package synchronise
+
+import (
+	"bytes"
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/chain/types"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"github.com/LemoFoundationLtd/lemochain-go/network/synchronise/protocol"
+	"time"
+)
+
+// SyncMode 区块同步模式
+type SyncMode string
+
+const (
+	FullSync SyncMode = "full" // 从本地稳定块开始执行每一个区块
+	FastSync SyncMode = "fast" // 下载远程节点最新稳定块的状态，不执行之前的区块
+
+	fastSyncMinDistance = 1024       // 远程稳定块比本地高出此距离才使用快速同步
+	maxNodeDataFetch    = 384        // 每次请求的trie节点或合约代码数量
+	maxStableStateBytes = 512 * 1024 // 每页稳定块账户的字节数上限
+)
+
+var (
+	ErrUnknownSyncMode = errors.New("unknown sync mode")
+	errTimeout         = errors.New("request timeout")
+	errMissingNodeData = errors.New("the peer doesn't have the requested state data")
+	errStateSyncStuck  = errors.New("no state data to request but the state sync is not finished")
+	errStableStatePage = errors.New("the stable state page is not continuous")
+)
+
+// ParseSyncMode 解析同步模式
+func ParseSyncMode(mode string) (SyncMode, error) {
+	switch SyncMode(mode) {
+	case FullSync, FastSync:
+		return SyncMode(mode), nil
+	}
+	return "", ErrUnknownSyncMode
+}
+
+// stateSync 快速同步时下载某个稳定块(pivot)的状态，由chain.StateSync实现
+type stateSync interface {
+	Pivot() *types.Block
+	NextHeight() uint32
+	InsertBlock(block *types.Block) error
+	Missing(max int) []common.Hash
+	Process(data [][]byte) error
+	Done() bool
+	Commit() error
+}
+
+type newStateSyncFn func(pivot *types.Block, accounts []*types.AccountData) (stateSync, error)
+
+// stableStatePack 稳定块及其账户状态包
+type stableStatePack struct {
+	peerID string
+	state  *protocol.StableStateData
+}
+
+// nodeDataPack trie节点或合约代码包
+type nodeDataPack struct {
+	peerID string
+	data   [][]byte
+}
+
+// fastSyncWithPeer 从远程节点的最新稳定块开始同步。先下载本地稳定块到该块之间的区块但不执行，再下载该块的账户状态。
+// 若远程节点的稳定块不够高则直接返回，由全同步处理
+func (d *Downloader) fastSyncWithPeer(p *peerConnection) error {
+	localHeight := d.blockChain.CurrentBlock().Height()
+	if p.peer.height < localHeight+fastSyncMinDistance {
+		return nil
+	}
+	timeout := time.NewTimer(requestTimeout)
+	defer timeout.Stop()
+	pivot, accounts, err := d.fetchStableState(p, localHeight, timeout)
+	if err != nil || pivot == nil {
+		return err
+	}
+	s, err := d.newStateSync(pivot, accounts)
+	if err != nil {
+		return err
+	}
+	pivotHeight := s.Pivot().Height()
+	log.Infof("Start fast sync. pivot height: %d, hash: %s, accounts: %d", pivotHeight, s.Pivot().Hash().Hex(), len(accounts))
+
+	// 下载区块
+	go p.peer.RequestBlockFromAndTo(s.NextHeight(), pivotHeight)
+	timeout.Reset(requestTimeout)
+	for s.NextHeight() <= pivotHeight {
+		select {
+		case pack := <-d.newBlocksCh:
+			if pack.peerID != p.id {
+				continue
+			}
+			for _, block := range pack.blocks {
+				// 忽略广播的新区块
+				if block == nil || block.Height() != s.NextHeight() {
+					continue
+				}
+				if err := s.InsertBlock(block); err != nil {
+					return err
+				}
+			}
+			timeout.Reset(requestTimeout)
+		case <-timeout.C:
+			return errTimeout
+		case <-d.quitCh:
+			return errForceQuit
+		}
+	}
+
+	// 下载账户状态
+	for !s.Done() {
+		hashes := s.Missing(maxNodeDataFetch)
+		if len(hashes) == 0 {
+			return errStateSyncStuck
+		}
+		go p.peer.RequestNodeData(hashes)
+		timeout.Reset(requestTimeout)
+		var data [][]byte
+		for data == nil {
+			select {
+			case pack := <-d.nodeDataCh:
+				if pack.peerID == p.id {
+					data = pack.data
+				}
+			case <-timeout.C:
+				return errTimeout
+			case <-d.quitCh:
+				return errForceQuit
+			}
+		}
+		if len(data) < len(hashes) {
+			return errMissingNodeData
+		}
+		if err := s.Process(data); err != nil {
+			return err
+		}
+	}
+	return s.Commit()
+}
+
+// fetchStableState 逐页下载远程节点最新稳定块的账户。同一账户可能出现在多页中，按地址去重。若稳定块不够高则返回nil
+func (d *Downloader) fetchStableState(p *peerConnection, localHeight uint32, timeout *time.Timer) (*types.Block, []*types.AccountData, error) {
+	var pivot *types.Block
+	accounts := make([]*types.AccountData, 0)
+	found := make(map[common.Address]bool)
+	start := common.Hash{}
+	for {
+		pivotHash := common.Hash{}
+		if pivot != nil {
+			pivotHash = pivot.Hash()
+		}
+		go p.peer.RequestStableState(pivotHash, start)
+		timeout.Reset(requestTimeout)
+		var state *protocol.StableStateData
+		for state == nil {
+			select {
+			case pack := <-d.stableStateCh:
+				if pack.peerID == p.id {
+					state = pack.state
+				}
+			case <-timeout.C:
+				return nil, nil, errTimeout
+			case <-d.quitCh:
+				return nil, nil, errForceQuit
+			}
+		}
+		if pivot == nil {
+			if state.Block.Height() < localHeight+fastSyncMinDistance {
+				return nil, nil, nil
+			}
+			pivot = state.Block
+		} else if state.Block.Hash() != pivotHash {
+			return nil, nil, errStableStatePage
+		}
+		for _, data := range state.Accounts {
+			if data != nil && !found[data.Address] {
+				found[data.Address] = true
+				accounts = append(accounts, data)
+			}
+		}
+		if state.Next == (common.Hash{}) {
+			return pivot, accounts, nil
+		}
+		// 下一页必须在本页之后，避免远程节点让本地无限请求
+		if bytes.Compare(state.Next[:], start[:]) <= 0 {
+			return nil, nil, errStableStatePage
+		}
+		start = state.Next
+	}
+}
+
+// DeliverStableState 将收到的稳定块状态分发给loop
+func (d *Downloader) DeliverStableState(id string, state *protocol.StableStateData) {
+	select {
+	case d.stableStateCh <- &stableStatePack{peerID: id, state: state}:
+	default:
+		log.Debugf("drop unrequested stable state. peer: %s", id[:16])
+	}
+}
+
+// DeliverNodeData 将收到的trie节点或合约代码分发给loop
+func (d *Downloader) DeliverNodeData(id string, data [][]byte) {
+	if data == nil {
+		data = make([][]byte, 0)
+	}
+	select {
+	case d.nodeDataCh <- &nodeDataPack{peerID: id, data: data}:
+	default:
+		log.Debugf("drop unrequested node data. peer: %s", id[:16])
+	}
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// EngineLmStore is the append-only file store LmDataBase
+	EngineLmStore = "lmstore"
+	// EngineLevelDB is the LevelDB store LevelDBBackend
+	EngineLevelDB = "leveldb"
+
+	DefaultEngine = EngineLmStore
+)
+
+var (
+	ErrUnknownEngine  = errors.New("unknown database engine")
+	ErrMigrateTarget  = errors.New("the target database is not empty")
+	ErrMigrateSameDir = errors.New("can't migrate database to itself")
+)
+
+// migrateBatchSize is the max size of records in one commit during migration
+const migrateBatchSize = 10 * 1024 * 1024
+
+// DetectEngine returns the engine of the database in path. It returns empty string if there is no database
+func DetectEngine(path string) string {
+	if fileExist(filepath.Join(path, "CURRENT")) {
+		return EngineLevelDB
+	}
+	if fileExist(filepath.Join(path, "data.context")) || fileExist(filepath.Join(path, "000.data")) {
+		return EngineLmStore
+	}
+	return ""
+}
+
+func fileExist(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// OpenBackend opens the database in path. An existing database is always opened by the engine which created it,
+// and the engine parameter only decides the engine of new database
+func OpenBackend(path string, engine string) (Backend, error) {
+	if exist := DetectEngine(path); exist != "" && exist != engine {
+		log.Warnf("The database in %s is created by %s, ignore the engine %s", path, exist, engine)
+		engine = exist
+	}
+
+	switch engine {
+	case EngineLmStore:
+		return NewLmDataBase(path)
+	case EngineLevelDB:
+		return NewLevelDBBackend(path, 256, OpenFileLimit)
+	default:
+		return nil, ErrUnknownEngine
+	}
+}
+
+// MigrateBackend copies all records and the current block from database srcPath into a new database in dstPath.
+// It returns the count of copied records
+func MigrateBackend(srcPath string, dstPath string, dstEngine string) (int, error) {
+	srcAbs, _ := filepath.Abs(srcPath)
+	dstAbs, _ := filepath.Abs(dstPath)
+	if srcAbs == dstAbs {
+		return 0, ErrMigrateSameDir
+	}
+	if DetectEngine(srcPath) == "" {
+		return 0, ErrNotExist
+	}
+	if DetectEngine(dstPath) != "" {
+		return 0, ErrMigrateTarget
+	}
+
+	src, err := OpenBackend(srcPath, DefaultEngine)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	dst, err := OpenBackend(dstPath, dstEngine)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	count := 0
+	size := 0
+	records := make([]*Record, 0)
+	err = src.Walk(func(record *Record) error {
+		records = append(records, record)
+		count++
+		size += len(record.Val)
+		if size < migrateBatchSize {
+			return nil
+		}
+		if err := dst.CommitRecords(records); err != nil {
+			return err
+		}
+		records = records[:0]
+		size = 0
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	if err := dst.CommitRecords(records); err != nil {
+		return count, err
+	}
+
+	if block := src.CurrentBlock(); block != nil {
+		if err := dst.SetCurrentBlock(block); err != nil {
+			return count, err
+		}
+	}
+	log.Infof("Migrated %d records from %s to %s", count, srcPath, dstPath)
+	return count, nil
+}
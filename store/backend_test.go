@@ -0,0 +1,141 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testBackend(t *testing.T, db Backend) {
+	key1, val1 := []byte("key1"), []byte("val1")
+	key2, val2 := []byte("key2"), make([]byte, 11*1024*1024) // bigger than the commit buffer of LmDataBase
+
+	_, err := db.Get(key1)
+	assert.Equal(t, ErrNotExist, err)
+	assert.NoError(t, db.Put(key1, val1))
+	result, err := db.Get(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, val1, result)
+	has, err := db.Has(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, true, has)
+
+	items := []*BatchItem{{Key: key1, Val: val2}, {Key: key2, Val: val2}}
+	for i := 0; i < 20000; i++ {
+		items = append(items, &BatchItem{Key: []byte{byte(i), byte(i >> 8), 1}, Val: []byte{byte(i)}})
+	}
+	assert.NoError(t, db.Commit(items))
+	result, err = db.Get(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, val2, result)
+	result, err = db.Get([]byte{0xff, 0x4d, 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xff}, result)
+
+	assert.NoError(t, db.Delete(key2))
+	has, err = db.Has(key2)
+	assert.NoError(t, err)
+	assert.Equal(t, false, has)
+
+	assert.NoError(t, db.SetCurrentBlock([]byte("block")))
+	assert.Equal(t, []byte("block"), db.CurrentBlock())
+
+	count := 0
+	err = db.Walk(func(record *Record) error {
+		count++
+		if record.Hash == key2hash(key1) {
+			assert.Equal(t, val2, record.Val)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 20001, count)
+}
+
+func TestLmDataBase_Backend(t *testing.T) {
+	ClearData()
+	db, err := OpenBackend(GetStorePath(), EngineLmStore)
+	assert.NoError(t, err)
+	assert.IsType(t, &LmDataBase{}, db)
+	testBackend(t, db)
+	assert.NoError(t, db.Close())
+}
+
+func TestLevelDBBackend(t *testing.T) {
+	ClearData()
+	db, err := OpenBackend(GetStorePath(), EngineLevelDB)
+	assert.NoError(t, err)
+	assert.IsType(t, &LevelDBBackend{}, db)
+	testBackend(t, db)
+	assert.NoError(t, db.Close())
+
+	// the existing database is opened by its own engine
+	assert.Equal(t, EngineLevelDB, DetectEngine(GetStorePath()))
+	db, err = OpenBackend(GetStorePath(), EngineLmStore)
+	assert.NoError(t, err)
+	assert.IsType(t, &LevelDBBackend{}, db)
+	assert.Equal(t, []byte("block"), db.CurrentBlock())
+	assert.NoError(t, db.Close())
+
+	_, err = OpenBackend(filepath.Join(GetStorePath(), "other"), "mysql")
+	assert.Equal(t, ErrUnknownEngine, err)
+}
+
+func TestMigrateBackend(t *testing.T) {
+	ClearData()
+	srcPath := filepath.Join(GetStorePath(), "src")
+	dstPath := filepath.Join(GetStorePath(), "dst")
+	chain, err := NewCacheChainWithEngine(srcPath, EngineLmStore)
+	assert.NoError(t, err)
+	block0 := GetBlock0()
+	assert.NoError(t, chain.SetBlock(block0.Hash(), block0))
+	assert.NoError(t, chain.SetStableBlock(block0.Hash()))
+	assert.NoError(t, chain.Close())
+
+	_, err = MigrateBackend(srcPath, srcPath, EngineLevelDB)
+	assert.Equal(t, ErrMigrateSameDir, err)
+	_, err = MigrateBackend(filepath.Join(GetStorePath(), "none"), dstPath, EngineLevelDB)
+	assert.Equal(t, ErrNotExist, err)
+
+	count, err := MigrateBackend(srcPath, dstPath, EngineLevelDB)
+	assert.NoError(t, err)
+	assert.Equal(t, true, count > 0)
+	_, err = MigrateBackend(srcPath, dstPath, EngineLevelDB)
+	assert.Equal(t, ErrMigrateTarget, err)
+
+	chain, err = NewCacheChainWithEngine(dstPath, EngineLmStore)
+	assert.NoError(t, err)
+	assert.IsType(t, &LevelDBBackend{}, chain.DB)
+	result, err := chain.GetBlockByHeight(0)
+	assert.NoError(t, err)
+	assert.Equal(t, block0.Hash(), result.Hash())
+	current, err := chain.LoadLatestBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, block0.Hash(), current.Hash())
+	assert.NoError(t, chain.Close())
+	os.RemoveAll(GetStorePath())
+}
+
+func TestLDBBatch_Put(t *testing.T) {
+	ClearData()
+	backend, err := OpenBackend(GetStorePath(), DefaultEngine)
+	assert.NoError(t, err)
+	db := NewLDBDatabase(backend, 0, 0)
+	defer backend.Close()
+
+	// the key buffer is reused by caller
+	key := []byte("key0")
+	batch := db.NewBatch()
+	assert.NoError(t, batch.Put(key, []byte{0}))
+	key[3] = '1'
+	assert.NoError(t, batch.Put(key, []byte{1}))
+	assert.Equal(t, 2, batch.ValueSize())
+	assert.NoError(t, batch.Write())
+	result, err := db.Get([]byte("key0"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0}, result)
+	result, err = db.Get([]byte("key1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1}, result)
+}
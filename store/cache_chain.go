@@ -18,13 +18,22 @@ type sBlock struct {
 	Events      []*types.Event
 	Confirms    []types.SignData
 	DeputyNodes deputynode.DeputyNodes
+	Finality    *types.FinalityCertificate `rlp:"nil"`
+}
+
+// TxLookupEntry is the position of a transaction in stable chain
+type TxLookupEntry struct {
+	BlockHash common.Hash
+	Height    uint32
+	Index     uint32
 }
 
 type CacheChain struct {
 	ConfirmNum int64
 	Blocks     map[common.Hash]*types.Block
 	Accounts   map[common.Hash]map[common.Address]*types.AccountData
-	LmDataBase *LmDataBase
+	Receipts   map[common.Hash]types.Receipts
+	DB         Backend
 	rw         sync.RWMutex
 }
 
@@ -40,6 +49,7 @@ func btoSb(block *types.Block) (*sBlock, error) {
 		Events:      block.Events,
 		Confirms:    block.Confirms,
 		DeputyNodes: block.DeputyNodes,
+		Finality:    block.Finality,
 	}
 
 	if block.Confirms == nil {
@@ -61,21 +71,28 @@ func sBtoB(sb *sBlock) (*types.Block, error) {
 	block.SetEvents(sb.Events)
 	block.SetConfirms(sb.Confirms)
 	block.SetDeputyNodes(sb.DeputyNodes)
+	block.SetFinality(sb.Finality)
 
 	return block, nil
 }
 
 func NewCacheChain(path string) (*CacheChain, error) {
+	return NewCacheChainWithEngine(path, DefaultEngine)
+}
+
+// NewCacheChainWithEngine opens the chain database in path. The engine is used if the database is not exist
+func NewCacheChainWithEngine(path string, engine string) (*CacheChain, error) {
 	cacheChain := &CacheChain{}
-	lmDataBase, err := NewLmDataBase(path)
+	db, err := OpenBackend(path, engine)
 	if err != nil {
 		return nil, err
 	}
 
-	cacheChain.LmDataBase = lmDataBase
+	cacheChain.DB = db
 	cacheChain.ConfirmNum = -1
 	cacheChain.Blocks = make(map[common.Hash]*types.Block, 65536)
 	cacheChain.Accounts = make(map[common.Hash]map[common.Address]*types.AccountData, 1024)
+	cacheChain.Receipts = make(map[common.Hash]types.Receipts, 1024)
 	return cacheChain, nil
 }
 
@@ -90,7 +107,7 @@ func (chain *CacheChain) setBlock(hash common.Hash, block *types.Block) error {
 		return err
 	}
 
-	err = chain.LmDataBase.Put(hash.Bytes(), buf)
+	err = chain.DB.Put(hash.Bytes(), buf)
 	if err != nil {
 		return err
 	} else {
@@ -98,7 +115,7 @@ func (chain *CacheChain) setBlock(hash common.Hash, block *types.Block) error {
 		if err != nil {
 			return err
 		} else {
-			return chain.LmDataBase.Put(indexHash.Bytes(), hash.Bytes())
+			return chain.DB.Put(indexHash.Bytes(), hash.Bytes())
 		}
 	}
 }
@@ -131,7 +148,7 @@ func (chain *CacheChain) writeChain(hash common.Hash) error {
 
 	err = rlp.DecodeBytes(buf, sb)
 
-	err = chain.LmDataBase.SetCurrentBlock(buf)
+	err = chain.DB.SetCurrentBlock(buf)
 	if err != nil {
 		return err
 	}
@@ -162,8 +179,7 @@ func (chain *CacheChain) writeChain(hash common.Hash) error {
 		hash = block.ParentHash()
 	}
 
-	items := make([]*BatchItem, 10000)
-	index := 0
+	items := make([]*BatchItem, 0, len(allA)+2*len(allB))
 	for _, v := range allA {
 		item := new(BatchItem)
 		item.Key = v.Address.Bytes()
@@ -179,8 +195,7 @@ func (chain *CacheChain) writeChain(hash common.Hash) error {
 			}
 			item.Val = val
 		}
-		items[index] = item
-		index = index + 1
+		items = append(items, item)
 	}
 
 	for _, v := range allB {
@@ -199,19 +214,23 @@ func (chain *CacheChain) writeChain(hash common.Hash) error {
 			item1.Val = val
 		}
 
-		items[index] = item1
-		index = index + 1
+		items = append(items, item1)
 
 		height := v.Height()
 		hash := v.Hash()
 		item2 := new(BatchItem)
 		item2.Key = encodeBlockNumber2Hash(height).Bytes()
 		item2.Val = hash.Bytes()
-		items[index] = item2
-		index = index + 1
+		items = append(items, item2)
+
+		receiptItems, err := chain.receiptItems(v)
+		if err != nil {
+			return err
+		}
+		items = append(items, receiptItems...)
 	}
 
-	return chain.LmDataBase.Commit(items)
+	return chain.DB.Commit(items)
 }
 
 func (chain *CacheChain) mergeSign(src []types.SignData, dst []types.SignData) []types.SignData {
@@ -245,7 +264,7 @@ func (chain *CacheChain) getBlock(hash common.Hash) (*types.Block, error) {
 		return block, nil
 	}
 
-	val, err := chain.LmDataBase.Get(hash.Bytes())
+	val, err := chain.DB.Get(hash.Bytes())
 	if err != nil {
 		log.Debug("[store]GET BLOCK FROM CACHE ERROR.HASH：%s, ERR:%s", hash.String(), err.Error())
 		return nil, err
@@ -330,7 +349,7 @@ func (chain *CacheChain) GetBlock(hash common.Hash, height uint32) (*types.Block
 func (chain *CacheChain) GetBlockByHeight(height uint32) (*types.Block, error) {
 	indexHash := encodeBlockNumber2Hash(height)
 
-	val, err := chain.LmDataBase.Get(indexHash.Bytes())
+	val, err := chain.DB.Get(indexHash.Bytes())
 	if err != nil {
 		return nil, err
 	} else {
@@ -358,7 +377,7 @@ func (chain *CacheChain) IsExistByHash(hash common.Hash) (bool, error) {
 		return true, nil
 	}
 
-	val, err := chain.LmDataBase.Get(hash.Bytes())
+	val, err := chain.DB.Get(hash.Bytes())
 	if err == ErrNotExist {
 		return false, nil
 	}
@@ -442,7 +461,7 @@ func (chain *CacheChain) AppendConfirmInfo(hash common.Hash, signData types.Sign
 
 	delete(chain.Blocks, hash)
 
-	val, err := chain.LmDataBase.Get(hash.Bytes())
+	val, err := chain.DB.Get(hash.Bytes())
 	if err != nil {
 		return err
 	} else {
@@ -456,7 +475,7 @@ func (chain *CacheChain) AppendConfirmInfo(hash common.Hash, signData types.Sign
 			if err != nil {
 				return err
 			} else {
-				return chain.LmDataBase.Put(hash.Bytes(), val)
+				return chain.DB.Put(hash.Bytes(), val)
 			}
 		}
 	}
@@ -468,7 +487,7 @@ func (chain *CacheChain) AppendConfirms(hash common.Hash, pack []types.SignData)
 
 	delete(chain.Blocks, hash)
 
-	val, err := chain.LmDataBase.Get(hash.Bytes())
+	val, err := chain.DB.Get(hash.Bytes())
 	if err != nil {
 		return err
 	} else {
@@ -482,7 +501,7 @@ func (chain *CacheChain) AppendConfirms(hash common.Hash, pack []types.SignData)
 			if err != nil {
 				return err
 			} else {
-				return chain.LmDataBase.Put(hash.Bytes(), val)
+				return chain.DB.Put(hash.Bytes(), val)
 			}
 		}
 	}
@@ -508,7 +527,7 @@ func encodeBlockNumber2Hash(number uint32) common.Hash {
 }
 
 func (chain *CacheChain) LoadLatestBlock() (*types.Block, error) {
-	val := chain.LmDataBase.CurrentBlock()
+	val := chain.DB.CurrentBlock()
 	if val == nil {
 		return nil, ErrNotExist
 	} else {
@@ -544,7 +563,7 @@ func (chain *CacheChain) SetStableBlock(hash common.Hash) error {
 }
 
 func (chain *CacheChain) getAccount(address common.Address) (*types.AccountData, error) {
-	val, err := chain.LmDataBase.Get(address.Bytes())
+	val, err := chain.DB.Get(address.Bytes())
 	if err != nil {
 		return nil, err
 	}
@@ -657,18 +676,18 @@ func (chain *CacheChain) SetAccounts(blockHash common.Hash, accounts []*types.Ac
 }
 
 func (chain *CacheChain) DelAccount(address common.Address) error {
-	return chain.LmDataBase.Delete(address.Bytes())
+	return chain.DB.Delete(address.Bytes())
 }
 
 // OpenStorageTrie opens the storage trie of an account.
 func (chain *CacheChain) GetTrieDatabase() *TrieDatabase {
-	db := NewLDBDatabase(chain.LmDataBase, 256, 256)
+	db := NewLDBDatabase(chain.DB, 256, 256)
 	return NewTrieDatabase(db)
 }
 
 // GetContractCode loads contract's code from db.
 func (chain *CacheChain) GetContractCode(codeHash common.Hash) (types.Code, error) {
-	val, err := chain.LmDataBase.Get(codeHash.Bytes())
+	val, err := chain.DB.Get(codeHash.Bytes())
 	if err != nil {
 		return nil, err
 	} else {
@@ -679,9 +698,134 @@ func (chain *CacheChain) GetContractCode(codeHash common.Hash) (types.Code, erro
 
 // SetContractCode saves contract's code
 func (chain *CacheChain) SetContractCode(codeHash common.Hash, code types.Code) error {
-	return chain.LmDataBase.Put(codeHash.Bytes(), code[:])
+	return chain.DB.Put(codeHash.Bytes(), code[:])
 }
 
 func (chain *CacheChain) Close() error {
-	return chain.LmDataBase.Close()
+	return chain.DB.Close()
+}
+
+func encodeReceiptsKey(blockHash common.Hash) []byte {
+	return append([]byte("receipts-"), blockHash.Bytes()...)
+}
+
+func encodeTxLookupKey(txHash common.Hash) []byte {
+	return append([]byte("tx-lookup-"), txHash.Bytes()...)
+}
+
+func encodeSelfSignKey(height uint32) []byte {
+	enc := make([]byte, 4)
+	binary.BigEndian.PutUint32(enc, height)
+	return append([]byte("self-sign-"), enc...)
+}
+
+func encodeReceipts(receipts types.Receipts) ([]byte, error) {
+	storageReceipts := make([]*types.ReceiptForStorage, len(receipts))
+	for i, receipt := range receipts {
+		storageReceipts[i] = (*types.ReceiptForStorage)(receipt)
+	}
+	return rlp.EncodeToBytes(storageReceipts)
+}
+
+// receiptItems makes the batch items of receipts and transaction lookup entries of a block which is going to be stable
+func (chain *CacheChain) receiptItems(block *types.Block) ([]*BatchItem, error) {
+	hash := block.Hash()
+	items := make([]*BatchItem, 0, len(block.Txs)+1)
+	if receipts, ok := chain.Receipts[hash]; ok {
+		val, err := encodeReceipts(receipts)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &BatchItem{Key: encodeReceiptsKey(hash), Val: val})
+		delete(chain.Receipts, hash)
+	}
+	for i, tx := range block.Txs {
+		val, err := rlp.EncodeToBytes(&TxLookupEntry{BlockHash: hash, Height: block.Height(), Index: uint32(i)})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &BatchItem{Key: encodeTxLookupKey(tx.Hash()), Val: val})
+	}
+	return items, nil
+}
+
+// SetReceipts saves the receipts of transactions in a block
+func (chain *CacheChain) SetReceipts(blockHash common.Hash, receipts types.Receipts) error {
+	chain.rw.Lock()
+	defer chain.rw.Unlock()
+
+	if chain.Blocks[blockHash] != nil {
+		chain.Receipts[blockHash] = receipts
+		return nil
+	}
+	// the block has been stable
+	val, err := encodeReceipts(receipts)
+	if err != nil {
+		return err
+	}
+	return chain.DB.Put(encodeReceiptsKey(blockHash), val)
+}
+
+// GetReceipts loads the receipts of transactions in a block
+func (chain *CacheChain) GetReceipts(blockHash common.Hash) (types.Receipts, error) {
+	chain.rw.RLock()
+	defer chain.rw.RUnlock()
+
+	if receipts, ok := chain.Receipts[blockHash]; ok {
+		return receipts, nil
+	}
+	val, err := chain.DB.Get(encodeReceiptsKey(blockHash))
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, ErrNotExist
+	}
+	var storageReceipts []*types.ReceiptForStorage
+	if err = rlp.DecodeBytes(val, &storageReceipts); err != nil {
+		return nil, err
+	}
+	receipts := make(types.Receipts, len(storageReceipts))
+	for i, receipt := range storageReceipts {
+		receipts[i] = (*types.Receipt)(receipt)
+	}
+	return receipts, nil
+}
+
+// SetSelfSign records the block hash signed by self node at the height
+func (chain *CacheChain) SetSelfSign(height uint32, hash common.Hash) error {
+	return chain.DB.Put(encodeSelfSignKey(height), hash.Bytes())
+}
+
+// GetSelfSign returns the block hash signed by self node at the height
+func (chain *CacheChain) GetSelfSign(height uint32) (common.Hash, error) {
+	val, err := chain.DB.Get(encodeSelfSignKey(height))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if val == nil {
+		return common.Hash{}, ErrNotExist
+	}
+	return common.BytesToHash(val), nil
+}
+
+// DelSelfSign removes the record of self signature at the height
+func (chain *CacheChain) DelSelfSign(height uint32) error {
+	return chain.DB.Delete(encodeSelfSignKey(height))
+}
+
+// GetTxLookup returns the position of a transaction in stable chain
+func (chain *CacheChain) GetTxLookup(txHash common.Hash) (*TxLookupEntry, error) {
+	val, err := chain.DB.Get(encodeTxLookupKey(txHash))
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, ErrNotExist
+	}
+	var entry TxLookupEntry
+	if err = rlp.DecodeBytes(val, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
 }
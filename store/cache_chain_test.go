@@ -540,3 +540,52 @@ func TestCacheChain_AppendConfirmInfo(t *testing.T) {
 	assert.Equal(t, block.Confirms[14], signs[14])
 	assert.Equal(t, block.Confirms[15], signs[15])
 }
+
+func TestCacheChain_Receipts(t *testing.T) {
+	ClearData()
+
+	cacheChain, err := NewCacheChain(GetStorePath())
+	assert.NoError(t, err)
+
+	tx := types.NewTransaction(0, common.HexToAddress("0x1"), big.NewInt(1), 21000, big.NewInt(1), nil, 1, 1544584596, "", "")
+	block := GetBlock0()
+	block.SetTxs([]*types.Transaction{tx})
+	err = cacheChain.SetBlock(block.Hash(), block)
+	assert.NoError(t, err)
+
+	receipt := types.NewReceipt(tx, block.Height(), 0, 21000, false)
+	receipt.Events = []*types.Event{{Address: common.HexToAddress("0x2"), Topics: []common.Hash{}, Data: []byte{1}, TxHash: tx.Hash()}}
+	receipts := types.Receipts{receipt}
+	receipts.SetBlockHash(block.Hash())
+	err = cacheChain.SetReceipts(block.Hash(), receipts)
+	assert.NoError(t, err)
+
+	// in cache
+	result, err := cacheChain.GetReceipts(block.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, receipts, result)
+	_, err = cacheChain.GetTxLookup(tx.Hash())
+	assert.Equal(t, ErrNotExist, err)
+
+	// in db
+	err = cacheChain.SetStableBlock(block.Hash())
+	assert.NoError(t, err)
+	result, err = cacheChain.GetReceipts(block.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, tx.Hash(), result[0].TxHash)
+	assert.Equal(t, block.Hash(), result[0].BlockHash)
+	assert.Equal(t, uint64(21000), result[0].GasUsed)
+	assert.Equal(t, types.ReceiptStatusSuccessful, result[0].Status)
+	assert.Equal(t, 1, len(result[0].Events))
+	assert.Equal(t, block.Hash(), result[0].Events[0].BlockHash)
+	assert.Equal(t, tx.Hash(), result[0].Events[0].TxHash)
+
+	entry, err := cacheChain.GetTxLookup(tx.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, &TxLookupEntry{BlockHash: block.Hash(), Height: block.Height(), Index: 0}, entry)
+
+	// not exist
+	_, err = cacheChain.GetReceipts(common.HexToHash("0x1"))
+	assert.Equal(t, ErrNotExist, err)
+}
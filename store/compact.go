@@ -0,0 +1,148 @@
+package store
+
+import (
+	"errors"
+	"github.com/LemoFoundationLtd/lemochain-go/common/log"
+	"os"
+)
+
+var (
+	ErrCompactUnsupported = errors.New("the database engine doesn't support compaction")
+)
+
+// Compactor is implemented by the backends which keep stale records on disk
+type Compactor interface {
+	// Stats returns the size of all data files and the size of alive records in them
+	Stats() (total int64, alive int64, err error)
+	// Compact rewrites the alive records into new data files and drops the stale ones
+	Compact() error
+}
+
+func (database *LmDataBase) compactPath() string {
+	return database.HomePath + ".compacting"
+}
+
+func (database *LmDataBase) backupPath() string {
+	return database.HomePath + ".old"
+}
+
+// recoverCompaction restores the database if the process exit while the compacted files are being swapped in
+func recoverCompaction(homePath string) error {
+	database := &LmDataBase{HomePath: homePath}
+	homeExist, err := database.fileIsExist(homePath)
+	if err != nil {
+		return err
+	}
+	backupExist, err := database.fileIsExist(database.backupPath())
+	if err != nil {
+		return err
+	}
+
+	if !homeExist && backupExist {
+		log.Warnf("Restore the database %s which is interrupted in compaction", homePath)
+		return os.Rename(database.backupPath(), homePath)
+	}
+	if backupExist {
+		return os.RemoveAll(database.backupPath())
+	}
+	return nil
+}
+
+func (database *LmDataBase) Stats() (int64, int64, error) {
+	database.rw.RLock()
+	defer database.rw.RUnlock()
+
+	total := int64(0)
+	for index := 0; index <= database.CurIndex; index++ {
+		info, err := os.Stat(database.getDataPath(index))
+		if err != nil {
+			return 0, 0, err
+		}
+		total += info.Size()
+	}
+
+	alive := int64(0)
+	err := database.walk(func(record *Record) error {
+		alive += int64(database.align(dataHeaderLen + keySize + uint32(len(record.Val))))
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return total, alive, nil
+}
+
+// Compact copies the alive records into a new database, then swaps it in and reloads the index.
+// The database is locked during compaction
+func (database *LmDataBase) Compact() error {
+	database.rw.Lock()
+	defer database.rw.Unlock()
+
+	tmpPath := database.compactPath()
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return err
+	}
+	if err := database.compactTo(tmpPath); err != nil {
+		os.RemoveAll(tmpPath)
+		return err
+	}
+
+	// swap the files. The old database is restored by recoverCompaction if we are interrupted here
+	if err := os.Rename(database.HomePath, database.backupPath()); err != nil {
+		os.RemoveAll(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, database.HomePath); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(database.backupPath()); err != nil {
+		return err
+	}
+
+	// reload the index. The hint files are rebuilt from the new data files
+	compacted, err := NewLmDataBase(database.HomePath)
+	if err != nil {
+		return err
+	}
+	database.CurIndex = compacted.CurIndex
+	database.CurOffset = compacted.CurOffset
+	database.Tree = compacted.Tree
+	database.Context = compacted.Context
+	return nil
+}
+
+func (database *LmDataBase) compactTo(path string) error {
+	compacted, err := NewLmDataBase(path)
+	if err != nil {
+		return err
+	}
+
+	size := 0
+	records := make([]*Record, 0)
+	err = database.walk(func(record *Record) error {
+		records = append(records, record)
+		size += len(record.Val)
+		if size < migrateBatchSize {
+			return nil
+		}
+		if err := compacted.CommitRecords(records); err != nil {
+			return err
+		}
+		records = records[:0]
+		size = 0
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := compacted.CommitRecords(records); err != nil {
+		return err
+	}
+
+	if block := database.CurrentBlock(); block != nil {
+		if err := compacted.SetCurrentBlock(block); err != nil {
+			return err
+		}
+	}
+	return compacted.Close()
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestLmDataBase_Compact(t *testing.T) {
+	ClearData()
+	db, err := NewLmDataBase(GetStorePath())
+	assert.NoError(t, err)
+
+	key1, key2, key3 := []byte("key1"), []byte("key2"), []byte("key3")
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, db.Put(key1, []byte{byte(i)}))
+	}
+	assert.NoError(t, db.Commit([]*BatchItem{{Key: key2, Val: []byte("val2")}, {Key: key3, Val: []byte("val3")}}))
+	assert.NoError(t, db.Delete(key3))
+	assert.NoError(t, db.SetCurrentBlock([]byte("block")))
+
+	total, alive, err := db.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12*256), total)
+	assert.Equal(t, int64(2*256), alive)
+
+	assert.NoError(t, db.Compact())
+	total, alive, err = db.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2*256), total)
+	assert.Equal(t, total, alive)
+
+	check := func(db *LmDataBase) {
+		val, err := db.Get(key1)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{9}, val)
+		val, err = db.Get(key2)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("val2"), val)
+		_, err = db.Get(key3)
+		assert.Equal(t, ErrNotExist, err)
+		assert.Equal(t, []byte("block"), db.CurrentBlock())
+	}
+	check(db)
+	// still writable
+	assert.NoError(t, db.Put(key3, []byte("val3")))
+	val, err := db.Get(key3)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("val3"), val)
+
+	// reload from the compacted files
+	assert.NoError(t, db.Delete(key3))
+	db, err = NewLmDataBase(GetStorePath())
+	assert.NoError(t, err)
+	check(db)
+	_, err = os.Stat(db.backupPath())
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(db.compactPath())
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLmDataBase_recoverCompaction(t *testing.T) {
+	ClearData()
+	db, err := NewLmDataBase(GetStorePath())
+	assert.NoError(t, err)
+	assert.NoError(t, db.Put([]byte("key"), []byte("val")))
+
+	// interrupted after the old files are moved away
+	assert.NoError(t, os.Rename(db.HomePath, db.backupPath()))
+	db, err = NewLmDataBase(GetStorePath())
+	assert.NoError(t, err)
+	val, err := db.Get([]byte("key"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("val"), val)
+	ClearData()
+}
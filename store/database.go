@@ -17,13 +17,14 @@
 package store
 
 import (
+	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"sync"
 )
 
 var OpenFileLimit = 64
 
 type LDBDatabase struct {
-	db *LmDataBase
+	db Backend
 
 	quitLock sync.Mutex      // Mutex protecting the quit channel access
 	quitChan chan chan error // Quit channel to stop the metrics collection before closing the database
@@ -50,7 +51,7 @@ func (db *LDBDatabase) Close() {
 }
 
 // NewLDBDatabase returns a LevelDB wrapped object.
-func NewLDBDatabase(database *LmDataBase, cache int, handles int) *LDBDatabase {
+func NewLDBDatabase(database Backend, cache int, handles int) *LDBDatabase {
 	//logger := log.New("database", file)
 
 	// Ensure we have some minimal caching and file guarantees
@@ -105,8 +106,7 @@ func (db *LDBDatabase) Get(key []byte) ([]byte, error) {
 // Delete deletes the key from the queue and database
 func (db *LDBDatabase) Delete(key []byte) error {
 	// Execute the actual operation
-	//return db.db.Delete(key, nil)
-	return db.Delete(key)
+	return db.db.Delete(key)
 }
 
 //func (db *LDBDatabase) NewIterator() iterator.Iterator {
@@ -119,10 +119,7 @@ func (db *LDBDatabase) Delete(key []byte) error {
 //}
 
 func (db *LDBDatabase) NewBatch() Batch {
-	return &LDBBatch{
-		db: db,
-		b:  make([]*BatchItem, 0, 10000),
-	}
+	return &LDBBatch{db: db}
 }
 
 func (db *LDBDatabase) commit(batch *LDBBatch) error {
@@ -131,20 +128,23 @@ func (db *LDBDatabase) commit(batch *LDBBatch) error {
 }
 
 type LDBBatch struct {
-	db *LDBDatabase
-	b  []*BatchItem
+	db   *LDBDatabase
+	b    []*BatchItem
+	size int
 }
 
-func (db *LDBDatabase) LDB() *LmDataBase {
+func (db *LDBDatabase) LDB() Backend {
 	return db.db
 }
 
 func (b *LDBBatch) Put(key, value []byte) error {
+	// the key may be an ephemeral buffer, e.g. the preimage key in TrieDatabase.Commit
 	item := &BatchItem{
-		Key: key,
+		Key: common.CopyBytes(key),
 		Val: value,
 	}
 	b.b = append(b.b, item)
+	b.size += len(value)
 	return nil
 }
 
@@ -153,11 +153,12 @@ func (b *LDBBatch) Write() error {
 }
 
 func (b *LDBBatch) ValueSize() int {
-	return len(b.b)
+	return b.size
 }
 
 func (b *LDBBatch) Reset() {
-	b.b = make([]*BatchItem, 0, 10000)
+	b.b = b.b[:0]
+	b.size = 0
 }
 
 //type table struct {
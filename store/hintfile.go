@@ -203,7 +203,8 @@ func ScanDataFile(index int, dataPath string, hintPath string) error {
 	} else {
 		dataFileModTime := dataFileInfo.ModTime().UnixNano()
 		hintFileModTime := hitFileInfo.ModTime().UnixNano()
-		if dataFileModTime > hintFileModTime {
+		// the modification time is coarse, so the records written in the same tick with hint file are rescanned
+		if dataFileModTime >= hintFileModTime {
 			dataMFile, err := OpenMFileForRead(dataPath)
 			if err != nil {
 				return err
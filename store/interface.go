@@ -16,6 +16,8 @@
 
 package store
 
+import "github.com/LemoFoundationLtd/lemochain-go/common"
+
 // Code using batches should try to add this much data to the batch.
 // The value was determined empirically.
 const IdealBatchSize = 100 * 1024
@@ -44,3 +46,30 @@ type Batch interface {
 	// Reset resets the batch for reuse
 	Reset()
 }
+
+// Record is a stored value indexed by the hash of its original key
+type Record struct {
+	Hash common.Hash
+	Val  []byte
+}
+
+// Backend is the persistent key-value engine under CacheChain and TrieDatabase.
+// Keys are hashed before they are stored, so the records can be copied between different backends
+type Backend interface {
+	Putter
+	Get(key []byte) ([]byte, error)
+	Has(key []byte) (bool, error)
+	Delete(key []byte) error
+	// Commit writes all items in one batch
+	Commit(items []*BatchItem) error
+
+	SetCurrentBlock(block []byte) error
+	CurrentBlock() []byte
+
+	// Walk calls fn with every alive record
+	Walk(fn func(record *Record) error) error
+	// CommitRecords writes the records which are read from another backend by Walk
+	CommitRecords(records []*Record) error
+
+	Close() error
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// currentBlockKey is not a hash, so it never conflicts with the records
+var currentBlockKey = []byte("LastBlock")
+
+// LevelDBBackend stores the records in LevelDB
+type LevelDBBackend struct {
+	path string
+	db   *leveldb.DB
+}
+
+// NewLevelDBBackend opens the LevelDB in path. cache is in megabytes
+func NewLevelDBBackend(path string, cache int, handles int) (*LevelDBBackend, error) {
+	// Ensure we have some minimal caching and file guarantees
+	if cache < 16 {
+		cache = 16
+	}
+	if handles < 16 {
+		handles = 16
+	}
+
+	db, err := leveldb.OpenFile(path, &opt.Options{
+		OpenFilesCacheCapacity: handles,
+		BlockCacheCapacity:     cache / 2 * opt.MiB,
+		WriteBuffer:            cache / 4 * opt.MiB, // Two of these are used internally
+		Filter:                 filter.NewBloomFilter(10),
+	})
+	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
+		db, err = leveldb.RecoverFile(path, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &LevelDBBackend{path: path, db: db}, nil
+}
+
+func (database *LevelDBBackend) Put(key []byte, val []byte) error {
+	return database.db.Put(key2hash(key).Bytes(), val, nil)
+}
+
+func (database *LevelDBBackend) Get(key []byte) ([]byte, error) {
+	val, err := database.db.Get(key2hash(key).Bytes(), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotExist
+	}
+	return val, err
+}
+
+func (database *LevelDBBackend) Has(key []byte) (bool, error) {
+	return database.db.Has(key2hash(key).Bytes(), nil)
+}
+
+func (database *LevelDBBackend) Delete(key []byte) error {
+	return database.db.Delete(key2hash(key).Bytes(), nil)
+}
+
+func (database *LevelDBBackend) Commit(items []*BatchItem) error {
+	batch := new(leveldb.Batch)
+	for _, item := range items {
+		if item == nil {
+			break
+		}
+		batch.Put(key2hash(item.Key).Bytes(), item.Val)
+	}
+	return database.db.Write(batch, nil)
+}
+
+func (database *LevelDBBackend) SetCurrentBlock(block []byte) error {
+	return database.db.Put(currentBlockKey, block, nil)
+}
+
+func (database *LevelDBBackend) CurrentBlock() []byte {
+	val, err := database.db.Get(currentBlockKey, nil)
+	if err != nil {
+		return nil
+	}
+	return val
+}
+
+func (database *LevelDBBackend) Walk(fn func(record *Record) error) error {
+	it := database.db.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) != common.HashLength {
+			continue
+		}
+		// the iterator reuses its buffers
+		record := &Record{Hash: common.BytesToHash(it.Key()), Val: common.CopyBytes(it.Value())}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+func (database *LevelDBBackend) CommitRecords(records []*Record) error {
+	batch := new(leveldb.Batch)
+	for _, record := range records {
+		batch.Put(record.Hash.Bytes(), record.Val)
+	}
+	return database.db.Write(batch, nil)
+}
+
+func (database *LevelDBBackend) Close() error {
+	return database.db.Close()
+}
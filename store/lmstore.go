@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/LemoFoundationLtd/lemochain-go/common"
 	"github.com/LemoFoundationLtd/lemochain-go/common/crypto"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -17,6 +18,8 @@ const (
 	contextLen
 )
 
+const contextMagic = 99
+
 type ContextFileHeader struct {
 	Magic     uint8
 	TCnt      uint8
@@ -191,7 +194,12 @@ func (database *LmDataBase) loadHintFiles() error {
 }
 
 func NewLmDataBase(homePath string) (*LmDataBase, error) {
-	_, err := os.Stat(homePath)
+	err := recoverCompaction(homePath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = os.Stat(homePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			err = os.MkdirAll(homePath, os.ModePerm)
@@ -208,7 +216,10 @@ func NewLmDataBase(homePath string) (*LmDataBase, error) {
 	database.Buf = bytes.NewBuffer(buf)
 
 	database.Context = make([][]byte, contextLen)
-	database.loadCurrentBlock(database.HomePath)
+	err = database.loadCurrentBlock(database.HomePath)
+	if err != nil {
+		return nil, err
+	}
 
 	tree, err := NewTree()
 	if err != nil {
@@ -278,6 +289,10 @@ func (database *LmDataBase) loadCurrentBlock(homePath string) error {
 		return err
 	}
 
+	if contextFileHeader.Magic != contextMagic || int64(contextFileHeaderLen)+int64(contextFileHeader.TLen) > info.Size() {
+		return ErrCorrupted
+	}
+
 	contextFileDataLen := contextFileHeader.TLen
 	dataBuf := make([]byte, contextFileDataLen)
 	_, err = file.Seek(int64(contextFileHeaderLen), 0)
@@ -294,28 +309,46 @@ func (database *LmDataBase) loadCurrentBlock(homePath string) error {
 }
 
 func (database *LmDataBase) initContext(contextFileHeader ContextFileHeader, buf []byte) error {
+	context, err := parseContext(buf)
+	if err != nil {
+		return err
+	}
+
+	database.Context = context
+	return nil
+}
+
+// parseContext decodes the records in context file
+func parseContext(buf []byte) ([][]byte, error) {
+	context := make([][]byte, contextLen)
 	totalLen := len(buf)
 	headerStart := 0
 	for {
 		if headerStart >= totalLen {
-			return nil
+			return context, nil
+		}
+		if headerStart+int(dataHeaderLen) > totalLen {
+			return nil, ErrCorrupted
 		}
 
 		var dHeader RecordHeader
 		err := binary.Read(bytes.NewBuffer(buf[headerStart:headerStart+int(dataHeaderLen)]), binary.LittleEndian, &dHeader)
 		if err != nil {
-			return err
-		}
-
-		if dHeader.Flg&0x01 == 1 {
-			continue
+			return nil, err
 		}
 
 		keyStart := headerStart + int(dataHeaderLen)
 		valStart := keyStart + int(dHeader.KLen)
+		if int(dHeader.Flg) >= contextLen || valStart+int(dHeader.VLen) > totalLen {
+			return nil, ErrCorrupted
+		}
+
 		val := buf[valStart : valStart+int(dHeader.VLen)]
+		if CheckSum(val) != dHeader.Crc {
+			return nil, ErrCorrupted
+		}
 
-		database.Context[int(dHeader.Flg)] = val
+		context[int(dHeader.Flg)] = val
 
 		headerStart = valStart + int(dHeader.VLen)
 	}
@@ -377,7 +410,7 @@ func (database *LmDataBase) SetCurrentBlock(block []byte) error {
 	}
 
 	contextFileHeader := &ContextFileHeader{
-		Magic:     99,
+		Magic:     contextMagic,
 		TCnt:      uint8(totalCnt),
 		TLen:      uint32(totalLen),
 		TimeStamp: 0,
@@ -426,9 +459,6 @@ func (database *LmDataBase) Close() error {
 }
 
 func (database *LmDataBase) Has(key []byte) (bool, error) {
-	database.rw.RLock()
-	defer database.rw.RUnlock()
-
 	val, err := database.Get(key)
 	if err != nil {
 		if err == ErrNotExist {
@@ -519,8 +549,10 @@ func (database *LmDataBase) encode(dataHeader []byte, key []byte, val []byte) []
 }
 
 func (database *LmDataBase) Delete(key []byte) error {
-	database.rw.RLock()
-	defer database.rw.RUnlock()
+	database.rw.Lock()
+	defer database.rw.Unlock()
+
+	key = key2hash(key).Bytes()
 	item, err := database.Tree.Get(key)
 	if err != nil {
 		return err
@@ -682,9 +714,35 @@ func (database *LmDataBase) addTree(offsets []uint32, keys [][]byte) error {
 }
 
 func (database *LmDataBase) Commit(items []*BatchItem) error {
+	keys := make([][]byte, 0, len(items))
+	vals := make([][]byte, 0, len(items))
+	for index := 0; index < len(items); index++ {
+		if items[index] == nil {
+			break
+		}
+		keys = append(keys, key2hash(items[index].Key).Bytes())
+		vals = append(vals, items[index].Val)
+	}
+
+	return database.commit(keys, vals)
+}
+
+func (database *LmDataBase) CommitRecords(records []*Record) error {
+	keys := make([][]byte, len(records))
+	vals := make([][]byte, len(records))
+	for index, record := range records {
+		keys[index] = record.Hash.Bytes()
+		vals[index] = record.Val
+	}
+
+	return database.commit(keys, vals)
+}
+
+// commit writes the records with hashed keys to the current data file
+func (database *LmDataBase) commit(keys [][]byte, vals [][]byte) error {
 	database.rw.Lock()
 	defer database.rw.Unlock()
-	if len(items) <= 0 {
+	if len(keys) <= 0 {
 		return nil
 	}
 
@@ -697,98 +755,153 @@ func (database *LmDataBase) Commit(items []*BatchItem) error {
 	var dHeader RecordHeader
 	var wOffset uint32 = 0
 
-	bufLen := 10 * 1024 * 1024
-	buf := make([]byte, 0, bufLen)
-
-	wIndex := 0
-	sOffset := make([]uint32, len(items))
-	keys := make([][]byte, len(items))
-	for index := 0; index < len(items); index++ {
-		if items[index] == nil {
-			break
+	// the buffer must be able to hold the biggest record
+	bufLen := uint32(10 * 1024 * 1024)
+	for index := 0; index < len(keys); index++ {
+		tLen := database.align(dataHeaderLen + uint32(len(keys[index])) + uint32(len(vals[index])))
+		if tLen > bufLen {
+			bufLen = tLen
 		}
+	}
+	buf := make([]byte, bufLen)
 
-		keys[index] = key2hash(items[index].Key).Bytes()
+	wIndex := 0
+	sOffset := make([]uint32, len(keys))
+	for index := 0; index < len(keys); index++ {
 		dHeader.KLen = uint8(len(keys[index]))
-		dHeader.VLen = uint32(len(items[index].Val))
+		dHeader.VLen = uint32(len(vals[index]))
+		dHeader.Num = Byte2Uint32(keys[index])
 		dHeader.TimeStamp = uint64(time.Now().UnixNano())
-		dHeader.Crc = CheckSum(items[index].Val)
+		dHeader.Crc = CheckSum(vals[index])
 		database.Buf.Reset()
 		err := binary.Write(database.Buf, binary.LittleEndian, &dHeader)
 		if err != nil {
 			return err
 		}
 
-		tLen := database.align(dataHeaderLen + uint32(len(keys[index])) + uint32(len(items[index].Val)))
-		if (uint32(bufLen) - uint32(wOffset)) >= uint32(tLen) {
-			sOffset[index] = wOffset
-
-			pBuf := database.encode(database.Buf.Bytes(), keys[index], items[index].Val)
-			err = binary.Write(NewLmBuffer(buf[wOffset:wOffset+tLen]), binary.LittleEndian, pBuf)
+		tLen := database.align(dataHeaderLen + uint32(len(keys[index])) + uint32(len(vals[index])))
+		if bufLen-wOffset < tLen {
+			err = database.flush(file, buf[0:wOffset], sOffset[wIndex:index], keys[wIndex:index])
 			if err != nil {
 				return err
 			}
 
-			wOffset = wOffset + tLen
-		} else {
-			_, err = file.Seek(int64(database.CurOffset), 0)
-			if err != nil {
-				return err
-			}
+			wIndex = index
+			wOffset = 0
+		}
 
-			n, err := file.Write(buf[0:wOffset])
-			if err != nil || n < len(buf) {
-				return err
-			}
+		sOffset[index] = wOffset
+		copy(buf[wOffset:wOffset+tLen], database.encode(database.Buf.Bytes(), keys[index], vals[index]))
+		wOffset = wOffset + tLen
+	}
 
-			err = file.Sync()
-			if err != nil {
-				return err
-			}
+	if wOffset > 0 {
+		return database.flush(file, buf[0:wOffset], sOffset[wIndex:], keys[wIndex:])
+	}
 
-			err = database.addTree(sOffset[wIndex:index], keys[wIndex:index])
-			if err != nil {
-				return err
-			}
+	return nil
+}
 
-			database.CurOffset = database.CurOffset + int64(wOffset)
+// flush appends the buffered records to data file and indexes them
+func (database *LmDataBase) flush(file *os.File, buf []byte, offsets []uint32, keys [][]byte) error {
+	_, err := file.Seek(int64(database.CurOffset), 0)
+	if err != nil {
+		return err
+	}
 
-			wOffset = 0
-			sOffset[index] = wOffset
+	n, err := file.Write(buf)
+	if err != nil {
+		return err
+	}
+	if n < len(buf) {
+		return io.ErrShortWrite
+	}
 
-			pBuf := database.encode(database.Buf.Bytes(), keys[index], items[index].Val)
-			err = binary.Write(NewLmBuffer(buf[wOffset:wOffset+tLen]), binary.LittleEndian, pBuf)
-			if err != nil {
-				return err
-			}
+	err = file.Sync()
+	if err != nil {
+		return err
+	}
 
-			wIndex = index
-			wOffset = wOffset + tLen
-		}
+	err = database.addTree(offsets, keys)
+	if err != nil {
+		return err
 	}
 
-	if wOffset > 0 {
-		_, err = file.Seek(int64(database.CurOffset), 0)
+	database.CurOffset = database.CurOffset + int64(len(buf))
+	return nil
+}
+
+// Walk calls fn with the latest version of every record which is not deleted
+func (database *LmDataBase) Walk(fn func(record *Record) error) error {
+	database.rw.RLock()
+	defer database.rw.RUnlock()
+
+	return database.walk(fn)
+}
+
+func (database *LmDataBase) walk(fn func(record *Record) error) error {
+	for index := 0; index <= database.CurIndex; index++ {
+		err := database.walkFile(index, fn)
 		if err != nil {
 			return err
 		}
+	}
 
-		n, err := file.Write(buf[0:wOffset])
-		if err != nil || n < len(buf) {
-			return err
-		}
+	return nil
+}
 
-		err = file.Sync()
+func (database *LmDataBase) walkFile(index int, fn func(record *Record) error) error {
+	file, err := os.OpenFile(database.getDataPath(index), os.O_RDONLY, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	headerBuf := make([]byte, dataHeaderLen)
+	for offset := int64(0); offset+int64(dataHeaderLen) <= info.Size(); {
+		_, err = file.ReadAt(headerBuf, offset)
 		if err != nil {
 			return err
 		}
 
-		err = database.addTree(sOffset[wIndex:], keys[wIndex:])
+		var dHeader RecordHeader
+		err = binary.Read(bytes.NewBuffer(headerBuf), binary.LittleEndian, &dHeader)
 		if err != nil {
 			return err
 		}
 
-		database.CurOffset = database.CurOffset + int64(wOffset)
+		tLen := int64(database.align(dataHeaderLen + uint32(dHeader.KLen) + dHeader.VLen))
+		if dHeader.Flg&0x01 == 0 && uint32(dHeader.KLen) == keySize {
+			tBuf := make([]byte, uint32(dHeader.KLen)+dHeader.VLen)
+			_, err = file.ReadAt(tBuf, offset+int64(dataHeaderLen))
+			if err != nil {
+				// the last record is not written completely
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+
+			// the older versions of the key are not alive
+			item, err := database.Tree.Get(tBuf[:dHeader.KLen])
+			if err != nil {
+				return err
+			}
+
+			if item != nil && item.Pos == uint32(offset)|uint32(index) {
+				err = fn(&Record{Hash: common.BytesToHash(tBuf[:dHeader.KLen]), Val: tBuf[dHeader.KLen:]})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		offset += tLen
 	}
 
 	return nil
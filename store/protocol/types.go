@@ -42,6 +42,20 @@ type ChainDB interface {
 	// SetContractCode saves contract's code
 	SetContractCode(codeHash common.Hash, code types.Code) error
 
+	// SetReceipts saves the receipts of transactions in a block
+	SetReceipts(blockHash common.Hash, receipts types.Receipts) error
+	// GetReceipts loads the receipts of transactions in a block
+	GetReceipts(blockHash common.Hash) (types.Receipts, error)
+	// GetTxLookup returns the position of a transaction in stable chain
+	GetTxLookup(txHash common.Hash) (*store.TxLookupEntry, error)
+
+	// SetSelfSign records the block hash signed by self node at the height
+	SetSelfSign(height uint32, hash common.Hash) error
+	// GetSelfSign returns the block hash signed by self node at the height
+	GetSelfSign(height uint32) (common.Hash, error)
+	// DelSelfSign removes the record of self signature at the height
+	DelSelfSign(height uint32) error
+
 	// LoadLatestBlock 程序启动时加载本地最新块
 	LoadLatestBlock() (*types.Block, error)
 	// Close 关闭数据库
@@ -84,6 +84,11 @@ func (db *TrieDatabase) DiskDB() DatabaseReader {
 	return db.diskdb
 }
 
+// DiskWriter retrieves the persistent storage to write the trie nodes which are downloaded from other nodes.
+func (db *TrieDatabase) DiskWriter() Putter {
+	return db.diskdb
+}
+
 func (db *TrieDatabase) DiskDB4Test() Database {
 	return db.diskdb
 }
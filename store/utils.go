@@ -14,6 +14,7 @@ var (
 	ErrEOF               = errors.New("file EOF")
 	ErrRlpEncode         = errors.New("rlp encode err")
 	ErrOutOfMemory       = errors.New("out of memory")
+	ErrCorrupted         = errors.New("database file is corrupted")
 	ErrUnKnown           = errors.New("")
 )
 
@@ -0,0 +1,449 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/LemoFoundationLtd/lemochain-go/common/rlp"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// The kinds of Issue
+const (
+	IssueCrc          = "crc"           // the checksum of record value is wrong
+	IssueCorrupted    = "corrupted"     // the record header is invalid, so the data after it can't be read
+	IssueTornTail     = "torn_tail"     // the last record in data file is written partially
+	IssueHint         = "hint"          // the hint file doesn't match the data file
+	IssueContext      = "context"       // the context file is invalid
+	IssueIndex        = "index"         // the block-by-height index doesn't match the stored block
+	IssueCurrentBlock = "current_block" // the current block doesn't match the block index
+)
+
+// Issue is a problem found in database
+type Issue struct {
+	Kind     string `json:"kind"`
+	File     string `json:"file,omitempty"`
+	Offset   int64  `json:"offset,omitempty"`
+	Height   uint32 `json:"height,omitempty"`
+	Detail   string `json:"detail"`
+	Repaired bool   `json:"repaired"`
+}
+
+// VerifyReport is the result of VerifyDatabase
+type VerifyReport struct {
+	Path    string   `json:"path"`
+	Engine  string   `json:"engine"`
+	Files   int      `json:"files"`   // the count of checked data files
+	Records int      `json:"records"` // the count of records in data files
+	Blocks  int      `json:"blocks"`  // the count of blocks found by height index
+	Issues  []*Issue `json:"issues"`
+}
+
+func (r *VerifyReport) addIssue(issue *Issue) {
+	r.Issues = append(r.Issues, issue)
+}
+
+// Healthy returns true if there is no issue or all issues are repaired
+func (r *VerifyReport) Healthy() bool {
+	for _, issue := range r.Issues {
+		if !issue.Repaired {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyDatabase checks the data files and block indexes of the database in path. It also fixes the issues if repair is true.
+// The records with wrong checksum are marked as deleted, the torn tail records are truncated, the corrupted data before a
+// valid record is covered by a deleted record, the hint files are rebuilt, and the current block is reset to the highest
+// block in height index
+func VerifyDatabase(path string, repair bool) (*VerifyReport, error) {
+	engine := DetectEngine(path)
+	if engine == "" {
+		return nil, ErrNotExist
+	}
+	report := &VerifyReport{Path: path, Engine: engine, Issues: make([]*Issue, 0)}
+
+	if engine == EngineLmStore {
+		if err := verifyLmFiles(path, repair, report); err != nil {
+			return report, err
+		}
+	}
+
+	db, err := OpenBackend(path, engine)
+	if err != nil {
+		return report, err
+	}
+	defer db.Close()
+	return report, verifyBlockIndex(db, repair, report)
+}
+
+func verifyLmFiles(path string, repair bool, report *VerifyReport) error {
+	database := &LmDataBase{HomePath: path}
+	for index := 0; index < int(maxBucketsCount); index++ {
+		dataPath := database.getDataPath(index)
+		isExist, err := database.fileIsExist(dataPath)
+		if err != nil {
+			return err
+		}
+		if !isExist {
+			break
+		}
+
+		report.Files++
+		dataChanged, err := verifyDataFile(dataPath, repair, report)
+		if err != nil {
+			return err
+		}
+		hintIssue, err := verifyHintFile(index, dataPath, database.getHintPath(index))
+		if err != nil {
+			return err
+		}
+		if hintIssue != nil {
+			report.addIssue(hintIssue)
+		}
+		if repair && (dataChanged || hintIssue != nil) {
+			if err := rebuildHintFile(index, dataPath, database.getHintPath(index)); err != nil {
+				return err
+			}
+			if hintIssue != nil {
+				hintIssue.Repaired = true
+			}
+		}
+	}
+
+	return verifyContextFile(filepath.Join(path, "data.context"), repair, report)
+}
+
+// verifyDataFile checks the records in data file. It returns true if the file is modified
+func verifyDataFile(dataPath string, repair bool, report *VerifyReport) (bool, error) {
+	flag := os.O_RDONLY
+	if repair {
+		flag = os.O_RDWR
+	}
+	file, err := os.OpenFile(dataPath, flag, os.ModePerm)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	size := info.Size()
+	name := filepath.Base(dataPath)
+	changed := false
+
+	offset := int64(0)
+	for offset < size {
+		if size-offset < int64(dataHeaderLen) {
+			issue := &Issue{Kind: IssueTornTail, File: name, Offset: offset, Detail: "incomplete record header"}
+			report.addIssue(issue)
+			if repair {
+				if err := truncateDataFile(file, offset); err != nil {
+					return changed, err
+				}
+				issue.Repaired = true
+				changed = true
+			}
+			break
+		}
+
+		dHeader, err := readRecordHeader(file, offset)
+		if err != nil {
+			return changed, err
+		}
+		if uint32(dHeader.KLen) != keySize || dHeader.VLen > maxFileSize {
+			// the records after the corrupted one are still readable if the next record header is found
+			next, err := findNextRecord(file, offset, size)
+			if err != nil {
+				return changed, err
+			}
+			issue := &Issue{Kind: IssueCorrupted, File: name, Offset: offset, Detail: fmt.Sprintf("invalid record header. key length: %d, value length: %d", dHeader.KLen, dHeader.VLen)}
+			report.addIssue(issue)
+			if next < 0 {
+				// the data after the corrupted record can't be read, but it is not dropped either
+				break
+			}
+			if repair {
+				// drop the corrupted data by covering it with a deleted record
+				dropped := &RecordHeader{Flg: 0x1, KLen: uint8(keySize), VLen: uint32(next-offset) - dataHeaderLen - keySize}
+				if err := writeRecordHeader(file, offset, dropped); err != nil {
+					return changed, err
+				}
+				issue.Repaired = true
+				changed = true
+			}
+			offset = next
+			continue
+		}
+		tLen := recordLen(dHeader)
+		if offset+tLen > size {
+			issue := &Issue{Kind: IssueTornTail, File: name, Offset: offset, Detail: fmt.Sprintf("record needs %d bytes, but only %d bytes left", tLen, size-offset)}
+			report.addIssue(issue)
+			if repair {
+				if err := truncateDataFile(file, offset); err != nil {
+					return changed, err
+				}
+				issue.Repaired = true
+				changed = true
+			}
+			break
+		}
+
+		val := make([]byte, dHeader.VLen)
+		if _, err := file.ReadAt(val, offset+int64(dataHeaderLen+keySize)); err != nil {
+			return changed, err
+		}
+		report.Records++
+		// the deleted records are never read
+		if dHeader.Flg&0x01 == 0 && CheckSum(val) != dHeader.Crc {
+			issue := &Issue{Kind: IssueCrc, File: name, Offset: offset, Detail: "checksum mismatch"}
+			if repair {
+				// drop the record by marking it deleted
+				dHeader.Flg = dHeader.Flg | 0x1
+				if err := writeRecordHeader(file, offset, dHeader); err != nil {
+					return changed, err
+				}
+				changed = true
+			}
+			issue.Repaired = repair
+			report.addIssue(issue)
+		}
+		offset += tLen
+	}
+	return changed, nil
+}
+
+func readRecordHeader(file *os.File, offset int64) (*RecordHeader, error) {
+	headerBuf := make([]byte, dataHeaderLen)
+	if _, err := file.ReadAt(headerBuf, offset); err != nil {
+		return nil, err
+	}
+	var dHeader RecordHeader
+	if err := binary.Read(bytes.NewBuffer(headerBuf), binary.LittleEndian, &dHeader); err != nil {
+		return nil, err
+	}
+	return &dHeader, nil
+}
+
+func writeRecordHeader(file *os.File, offset int64, dHeader *RecordHeader) error {
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.LittleEndian, dHeader); err != nil {
+		return err
+	}
+	_, err := file.WriteAt(buf.Bytes(), offset)
+	return err
+}
+
+// recordLen returns the length of record in data file, which is aligned to 256 bytes
+func recordLen(dHeader *RecordHeader) int64 {
+	tLen := int64(dataHeaderLen + keySize + dHeader.VLen)
+	if tLen%256 != 0 {
+		tLen += 256 - tLen%256
+	}
+	return tLen
+}
+
+// findNextRecord returns the offset of the first valid record after the corrupted one at offset. The records are aligned to
+// 256 bytes, and a valid record must have valid header and checksum. It returns -1 if no record is found
+func findNextRecord(file *os.File, offset, size int64) (int64, error) {
+	for next := offset + 256; next+int64(dataHeaderLen) <= size; next += 256 {
+		dHeader, err := readRecordHeader(file, next)
+		if err != nil {
+			return -1, err
+		}
+		if uint32(dHeader.KLen) != keySize || dHeader.VLen > maxFileSize || next+recordLen(dHeader) > size {
+			continue
+		}
+		if dHeader.Flg&0x01 == 0 {
+			val := make([]byte, dHeader.VLen)
+			if _, err := file.ReadAt(val, next+int64(dataHeaderLen+keySize)); err != nil {
+				return -1, err
+			}
+			if CheckSum(val) != dHeader.Crc {
+				continue
+			}
+		}
+		return next, nil
+	}
+	return -1, nil
+}
+
+// truncateDataFile drops the torn record at the end of data file
+func truncateDataFile(file *os.File, offset int64) error {
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// verifyHintFile checks if every item in hint file points to a record with the same key. It returns the first issue found
+func verifyHintFile(index int, dataPath string, hintPath string) (*Issue, error) {
+	name := filepath.Base(hintPath)
+	hintBuf, err := ioutil.ReadFile(hintPath)
+	if os.IsNotExist(err) {
+		return &Issue{Kind: IssueHint, File: name, Detail: "hint file is missing"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dataFile, err := os.OpenFile(dataPath, os.O_RDONLY, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	defer dataFile.Close()
+	info, err := dataFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	hintItemLen := binary.Size(HintItem{})
+	entryLen := hintItemLen + int(keySize)
+	if len(hintBuf)%entryLen != 0 {
+		return &Issue{Kind: IssueHint, File: name, Offset: int64(len(hintBuf) - len(hintBuf)%entryLen), Detail: "incomplete hint item"}, nil
+	}
+
+	key := make([]byte, keySize)
+	for offset := 0; offset < len(hintBuf); offset += entryLen {
+		var hintItem HintItem
+		if err := binary.Read(bytes.NewBuffer(hintBuf[offset:offset+hintItemLen]), binary.LittleEndian, &hintItem); err != nil {
+			return nil, err
+		}
+		pos := int64(hintItem.Pos & 0xffffff00)
+		if int(hintItem.Pos&0xff) != index || pos+int64(dataHeaderLen+keySize) > info.Size() {
+			return &Issue{Kind: IssueHint, File: name, Offset: int64(offset), Detail: fmt.Sprintf("invalid position %d", hintItem.Pos)}, nil
+		}
+		if _, err := dataFile.ReadAt(key, pos+int64(dataHeaderLen)); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(key, hintBuf[offset+hintItemLen:offset+entryLen]) {
+			return &Issue{Kind: IssueHint, File: name, Offset: int64(offset), Detail: "key mismatch with data file"}, nil
+		}
+	}
+	return nil, nil
+}
+
+func rebuildHintFile(index int, dataPath string, hintPath string) error {
+	if err := os.Remove(hintPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := ScanDataFile(index, dataPath, hintPath); err != nil {
+		return err
+	}
+	return nil
+}
+
+func verifyContextFile(contextPath string, repair bool, report *VerifyReport) error {
+	buf, err := ioutil.ReadFile(contextPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	headerLen := binary.Size(ContextFileHeader{})
+	if len(buf) <= headerLen {
+		return nil
+	}
+	var header ContextFileHeader
+	if err := binary.Read(bytes.NewBuffer(buf[:headerLen]), binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if header.Magic == contextMagic && headerLen+int(header.TLen) <= len(buf) {
+		if _, err = parseContext(buf[headerLen : headerLen+int(header.TLen)]); err == nil {
+			return nil
+		}
+	}
+
+	issue := &Issue{Kind: IssueContext, File: filepath.Base(contextPath), Detail: "invalid context file"}
+	report.addIssue(issue)
+	if repair {
+		// the current block is recovered from block index later
+		if err := os.Remove(contextPath); err != nil {
+			return err
+		}
+		issue.Repaired = true
+	}
+	return nil
+}
+
+// verifyBlockIndex checks if the blocks found by height index are stored correctly, and the current block is the highest one
+func verifyBlockIndex(db Backend, repair bool, report *VerifyReport) error {
+	var highest *sBlock
+	for height := uint32(0); ; height++ {
+		val, err := db.Get(encodeBlockNumber2Hash(height).Bytes())
+		if err == ErrNotExist || (err == nil && val == nil) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		hash := common.BytesToHash(val)
+		sb, detail := loadIndexedBlock(db, hash, height)
+		if sb == nil {
+			report.addIssue(&Issue{Kind: IssueIndex, Height: height, Detail: detail})
+			break
+		}
+		report.Blocks++
+		highest = sb
+	}
+
+	current := db.CurrentBlock()
+	var currentHash common.Hash
+	if current != nil {
+		var sb sBlock
+		if err := rlp.DecodeBytes(current, &sb); err == nil && sb.Header != nil {
+			currentHash = sb.Header.Hash()
+		}
+	}
+	if highest == nil {
+		if current != nil {
+			report.addIssue(&Issue{Kind: IssueCurrentBlock, Detail: "current block is set but there is no block in height index"})
+		}
+		return nil
+	}
+	if currentHash == highest.Header.Hash() {
+		return nil
+	}
+
+	issue := &Issue{Kind: IssueCurrentBlock, Height: highest.Header.Height, Detail: fmt.Sprintf("current block %s is not the highest block %s", currentHash.Hex(), highest.Header.Hash().Hex())}
+	report.addIssue(issue)
+	if repair {
+		buf, err := rlp.EncodeToBytes(highest)
+		if err != nil {
+			return err
+		}
+		if err := db.SetCurrentBlock(buf); err != nil {
+			return err
+		}
+		issue.Repaired = true
+	}
+	return nil
+}
+
+// loadIndexedBlock returns nil and the reason if the block is not stored correctly
+func loadIndexedBlock(db Backend, hash common.Hash, height uint32) (*sBlock, string) {
+	val, err := db.Get(hash.Bytes())
+	if err != nil || val == nil {
+		return nil, fmt.Sprintf("block %s is missing", hash.Hex())
+	}
+	var sb sBlock
+	if err := rlp.DecodeBytes(val, &sb); err != nil || sb.Header == nil {
+		return nil, fmt.Sprintf("block %s can't be decoded", hash.Hex())
+	}
+	if sb.Header.Height != height {
+		return nil, fmt.Sprintf("block %s has height %d", hash.Hex(), sb.Header.Height)
+	}
+	if sb.Header.Hash() != hash {
+		return nil, fmt.Sprintf("block %s has hash %s", hash.Hex(), sb.Header.Hash().Hex())
+	}
+	return &sb, ""
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"github.com/LemoFoundationLtd/lemochain-go/common"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func createVerifyChain(t *testing.T) {
+	ClearData()
+	chain, err := NewCacheChain(GetStorePath())
+	assert.NoError(t, err)
+	block0, block1, block2 := GetBlock0(), GetBlock1(), GetBlock2()
+	assert.NoError(t, chain.SetBlock(block0.Hash(), block0))
+	assert.NoError(t, chain.SetStableBlock(block0.Hash()))
+	assert.NoError(t, chain.SetBlock(block1.Hash(), block1))
+	assert.NoError(t, chain.SetStableBlock(block1.Hash()))
+	assert.NoError(t, chain.SetBlock(block2.Hash(), block2))
+	assert.NoError(t, chain.SetStableBlock(block2.Hash()))
+	assert.NoError(t, chain.Close())
+}
+
+func issueKinds(report *VerifyReport) []string {
+	kinds := make([]string, 0)
+	for _, issue := range report.Issues {
+		kinds = append(kinds, issue.Kind)
+	}
+	return kinds
+}
+
+func TestVerifyDatabase_healthy(t *testing.T) {
+	createVerifyChain(t)
+	// load once to create the hint file
+	_, err := NewLmDataBase(GetStorePath())
+	assert.NoError(t, err)
+
+	report, err := VerifyDatabase(GetStorePath(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, EngineLmStore, report.Engine)
+	assert.Equal(t, 1, report.Files)
+	assert.Equal(t, 6, report.Records)
+	assert.Equal(t, 3, report.Blocks)
+	assert.Empty(t, report.Issues)
+	assert.True(t, report.Healthy())
+
+	_, err = VerifyDatabase(filepath.Join(GetStorePath(), "none"), false)
+	assert.Equal(t, ErrNotExist, err)
+}
+
+func TestVerifyDatabase_repair(t *testing.T) {
+	createVerifyChain(t)
+	dataPath := filepath.Join(GetStorePath(), "000.data")
+	hintPath := filepath.Join(GetStorePath(), "000.hint")
+	contextPath := filepath.Join(GetStorePath(), "data.context")
+
+	data, err := ioutil.ReadFile(dataPath)
+	assert.NoError(t, err)
+	// break the value of the last record, which is the height index of block 2
+	data[len(data)-256+int(dataHeaderLen+keySize)] ^= 0xff
+	// half of a record is written
+	data = append(data, common.CopyBytes(data[:100])...)
+	assert.NoError(t, ioutil.WriteFile(dataPath, data, os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(hintPath, []byte{1, 2, 3}, os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(contextPath, []byte("broken context file"), os.ModePerm))
+	_, err = NewLmDataBase(GetStorePath())
+	assert.Equal(t, ErrCorrupted, err)
+
+	report, err := VerifyDatabase(GetStorePath(), false)
+	assert.Equal(t, ErrCorrupted, err)
+	assert.Equal(t, []string{IssueCrc, IssueTornTail, IssueHint, IssueContext}, issueKinds(report))
+	assert.False(t, report.Healthy())
+
+	report, err = VerifyDatabase(GetStorePath(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{IssueCrc, IssueTornTail, IssueHint, IssueContext, IssueCurrentBlock}, issueKinds(report))
+	assert.True(t, report.Healthy())
+	// block 2 is lost because its height index is dropped
+	assert.Equal(t, 2, report.Blocks)
+
+	report, err = VerifyDatabase(GetStorePath(), false)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Issues)
+	chain, err := NewCacheChain(GetStorePath())
+	assert.NoError(t, err)
+	current, err := chain.LoadLatestBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, GetBlock1().Hash(), current.Hash())
+	ClearData()
+}
+
+func TestVerifyDatabase_repairCorrupted(t *testing.T) {
+	createVerifyChain(t)
+	// load once to create the hint file
+	_, err := NewLmDataBase(GetStorePath())
+	assert.NoError(t, err)
+	dataPath := filepath.Join(GetStorePath(), "000.data")
+
+	data, err := ioutil.ReadFile(dataPath)
+	assert.NoError(t, err)
+	size := len(data)
+	// break the key length in header of block 1, which is followed by good records
+	data[1024+5] = 0xff
+	assert.NoError(t, ioutil.WriteFile(dataPath, data, os.ModePerm))
+
+	report, err := VerifyDatabase(GetStorePath(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{IssueCorrupted, IssueIndex, IssueCurrentBlock}, issueKinds(report))
+	assert.Equal(t, 5, report.Records)
+	assert.False(t, report.Healthy())
+
+	report, err = VerifyDatabase(GetStorePath(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{IssueCorrupted, IssueIndex, IssueCurrentBlock}, issueKinds(report))
+	assert.True(t, report.Issues[0].Repaired)
+	// the good records after the corrupted one are kept
+	data, err = ioutil.ReadFile(dataPath)
+	assert.NoError(t, err)
+	assert.Equal(t, size, len(data))
+	report, err = VerifyDatabase(GetStorePath(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{IssueIndex}, issueKinds(report))
+	assert.Equal(t, 6, report.Records)
+	db, err := NewLmDataBase(GetStorePath())
+	assert.NoError(t, err)
+	val, err := db.Get(GetBlock2().Hash().Bytes())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, val)
+	assert.NoError(t, db.Close())
+	ClearData()
+}